@@ -2,8 +2,6 @@ package fetchlog
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -16,14 +14,11 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 
 	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/megakuul/battleshiper/api/admin/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/helper/database"
-	"github.com/megakuul/battleshiper/lib/model/rbac"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 const (
@@ -35,16 +30,23 @@ const (
 var logger = log.New(os.Stderr, "ADMIN FETCHLOG: ", 0)
 
 type fetchLogInput struct {
-	LogType      string `json:"log_type"`
+	LogType      string `json:"log_type" validate:"required"`
 	StartTime    int64  `json:"start_time"`
 	EndTime      int64  `json:"end_time"`
-	Count        int32  `json:"count"`
+	Count        int32  `json:"count" validate:"min=1,max=200"`
 	FilterLambda bool   `json:"filter_lambda"`
+	Filter       string `json:"filter"`
 }
 
 type eventOutput struct {
-	Timestamp int64  `json:"timestamp"`
-	Message   string `json:"message"`
+	Timestamp           int64  `json:"timestamp"`
+	Message             string `json:"message"`
+	ExecutionIdentifier string `json:"execution_identifier,omitempty"`
+	Phase               string `json:"phase,omitempty"`
+	Step                string `json:"step,omitempty"`
+	Level               string `json:"level,omitempty"`
+	DurationMs          *int64 `json:"duration_ms,omitempty"`
+	Error               string `json:"error,omitempty"`
 }
 
 type fetchLogOutput struct {
@@ -53,75 +55,9 @@ type fetchLogOutput struct {
 }
 
 // HandleFetchLog performs a lookup for the cloudwatch logs of the internal functions and returns them.
-func HandleFetchLog(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleFetchLog(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleFetchLog(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*fetchLogOutput, int, error) {
-	fetchLogInput := &fetchLogInput{}
-	err := json.Unmarshal([]byte(request.Body), &fetchLogInput)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: invalid body")
-	}
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	if !rbac.CheckPermission(userDoc.Roles, rbac.READ_LOGS) {
-		return nil, http.StatusForbidden, fmt.Errorf("user does not have sufficient permissions for this action")
-	}
-
+func HandleFetchLog(input *fetchLogInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*fetchLogOutput, error) {
 	var logGroup string
-	switch fetchLogInput.LogType {
+	switch input.LogType {
 	case "api":
 		logGroup = routeCtx.LogConfiguration.ApiLogGroup
 	case "pipeline":
@@ -130,33 +66,41 @@ func runHandleFetchLog(request events.APIGatewayV2HTTPRequest, transportCtx cont
 		logGroup = routeCtx.LogConfiguration.RouterLogGroup
 	}
 	if logGroup == "" {
-		return nil, http.StatusBadRequest, fmt.Errorf("invalid logtype; expected 'api', 'pipeline' or 'router'")
+		return nil, router.Errorf(http.StatusBadRequest, "invalid logtype; expected 'api', 'pipeline' or 'router'")
 	}
 
-	logLimit := fetchLogInput.Count
+	logLimit := input.Count
 	if logLimit > MAX_LOG_EVENTS {
 		logLimit = MAX_LOG_EVENTS
 	}
 
 	lambdaFilter := ""
-	if fetchLogInput.FilterLambda {
+	if input.FilterLambda {
 		// filters out the lambda generated START, END, REPORT and INIT_START messages
 		lambdaFilter = "| filter @message not like /^(?:START RequestId|END RequestId|REPORT RequestId|INIT_START)/"
 	}
 
+	// the filter applies to the fields of structured pipeline log events.
+	logFilter, err := pipeline.BuildLogFilter(input.Filter)
+	if err != nil {
+		return nil, router.Errorf(http.StatusBadRequest, "%v", err)
+	}
+
 	queryRequestOutput, err := routeCtx.CloudwatchClient.StartQuery(transportCtx, &cloudwatchlogs.StartQueryInput{
 		LogGroupName: aws.String(logGroup),
-		StartTime:    aws.Int64(fetchLogInput.StartTime),
-		EndTime:      aws.Int64(fetchLogInput.EndTime),
+		StartTime:    aws.Int64(input.StartTime),
+		EndTime:      aws.Int64(input.EndTime),
 		QueryString: aws.String(fmt.Sprintf(
-			"fields @timestamp, @message, tomillis(@timestamp) as timestamp %s | sort @timestamp desc | limit %d",
+			"fields @timestamp, @message, tomillis(@timestamp) as timestamp, %s %s %s | sort @timestamp desc | limit %d",
+			pipeline.LOG_QUERY_FIELDS,
 			lambdaFilter,
+			logFilter,
 			logLimit,
 		)),
 	})
 	if err != nil {
 		logger.Printf("failed to start cloudwatch query: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to start cloudwatch query")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to start cloudwatch query")
 	}
 
 	for retries := 0; retries < LOG_RETRIEVE_RETRY_COUNT; retries++ {
@@ -165,22 +109,22 @@ func runHandleFetchLog(request events.APIGatewayV2HTTPRequest, transportCtx cont
 		})
 		if err != nil {
 			logger.Printf("failed to retrieve cloudwatch query result: %v\n", err)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to retrieve cloudwatch query result")
+			return nil, router.Errorf(http.StatusInternalServerError, "failed to retrieve cloudwatch query result")
 		}
 		if queryResultOutput.Status == cloudwatchtypes.QueryStatusComplete {
 			logEvents, err := extractLogEvents(queryResultOutput.Results)
 			if err != nil {
 				logger.Printf("failed to deserialize cloudwatch query result: %v\n", err)
-				return nil, http.StatusInternalServerError, fmt.Errorf("failed to deserialize cloudwatch query result")
+				return nil, router.Errorf(http.StatusInternalServerError, "failed to deserialize cloudwatch query result")
 			}
 			return &fetchLogOutput{
 				Message: "logs fetched",
 				Events:  logEvents,
-			}, http.StatusOK, nil
+			}, nil
 		}
 		time.Sleep(LOG_RETRIEVE_RETRY_TIMEOUT)
 	}
-	return nil, http.StatusBadRequest, fmt.Errorf("cloudwatch query timed out: try reducing the log timeframe")
+	return nil, router.Errorf(http.StatusBadRequest, "cloudwatch query timed out: try reducing the log timeframe")
 }
 
 // extractLogEvents converts the aws crap result field interface into an eventOutput slice.
@@ -189,10 +133,30 @@ func extractLogEvents(results [][]cloudwatchtypes.ResultField) ([]eventOutput, e
 	logEvents := []eventOutput{}
 	for _, event := range results {
 		logEvent := eventOutput{}
+		// structured events carry their message in the message field, other events only have the raw @message.
+		rawMessage := ""
 		for _, field := range event {
 			switch *field.Field {
 			case "@message":
+				rawMessage = *field.Value
+			case "message":
 				logEvent.Message = *field.Value
+			case "execution_identifier":
+				logEvent.ExecutionIdentifier = *field.Value
+			case "phase":
+				logEvent.Phase = *field.Value
+			case "step":
+				logEvent.Step = *field.Value
+			case "level":
+				logEvent.Level = *field.Value
+			case "error":
+				logEvent.Error = *field.Value
+			case "duration_ms":
+				fieldDuration, err := strconv.ParseFloat(*field.Value, 64)
+				if err != nil {
+					return nil, err
+				}
+				logEvent.DurationMs = aws.Int64(int64(fieldDuration))
 			case "timestamp":
 				fieldTimestamp, err := strconv.ParseFloat(*field.Value, 64)
 				if err != nil {
@@ -201,6 +165,9 @@ func extractLogEvents(results [][]cloudwatchtypes.ResultField) ([]eventOutput, e
 				logEvent.Timestamp = int64(fieldTimestamp)
 			}
 		}
+		if logEvent.Message == "" {
+			logEvent.Message = rawMessage
+		}
 		logEvents = append(logEvents, logEvent)
 	}
 	return logEvents, nil
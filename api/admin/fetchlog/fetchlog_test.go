@@ -0,0 +1,52 @@
+package fetchlog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/admin/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+func TestFetchLogCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{name: "missing count", body: `{"log_type":"unknown"}`, status: http.StatusBadRequest, message: "must be at least 1"},
+		{name: "zero count", body: `{"log_type":"unknown","count":0}`, status: http.StatusBadRequest, message: "must be at least 1"},
+		{name: "count above max", body: `{"log_type":"unknown","count":201}`, status: http.StatusBadRequest, message: "must be at most 200"},
+		// valid requests pass the validation and are rejected by the handler because the log type is unknown.
+		{name: "min count", body: `{"log_type":"unknown","count":1}`, status: http.StatusBadRequest, message: "invalid logtype"},
+		{name: "max count", body: `{"log_type":"unknown","count":200}`, status: http.StatusBadRequest, message: "invalid logtype"},
+	}
+
+	httpRouter := router.NewRouter(routecontext.Context{})
+	router.AddJSONRoute(httpRouter, "POST", "/api/admin/fetchlog", HandleFetchLog)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := events.APIGatewayV2HTTPRequest{Body: test.body}
+			request.RequestContext.HTTP.Method = "POST"
+			request.RequestContext.HTTP.Path = "/api/admin/fetchlog"
+
+			response, err := httpRouter.Route(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != test.status {
+				t.Errorf("expected status %d, got %d (%s)", test.status, response.StatusCode, response.Body)
+			}
+			if !strings.Contains(response.Body, test.message) {
+				t.Errorf("expected message '%s', got '%s'", test.message, response.Body)
+			}
+		})
+	}
+}
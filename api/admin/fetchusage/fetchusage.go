@@ -0,0 +1,87 @@
+package fetchusage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/megakuul/battleshiper/api/admin/routecontext"
+
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "ADMIN FETCHUSAGE: ", 0)
+
+type fetchUsageInput struct {
+	UserId string `query:"user_id" validate:"required"`
+}
+
+type fetchUsageOutput struct {
+	Message string          `json:"message"`
+	Usage   *pipeline.Usage `json:"usage"`
+}
+
+// HandleFetchUsage compares the resources consumed by the specified user with the limits of its subscription.
+func HandleFetchUsage(input *fetchUsageInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*fetchUsageOutput, error) {
+	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.UserTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":id": &dynamodbtypes.AttributeValueMemberS{Value: input.UserId},
+		},
+		ConditionExpr: aws.String("id = :id"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "user to fetch was not found")
+		}
+		logger.Printf("failed to load user record from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load user record from database")
+	}
+
+	subscriptionDoc, err := database.GetSingle[subscription.Subscription](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.SubscriptionTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":id": &dynamodbtypes.AttributeValueMemberS{Value: userDoc.SubscriptionId},
+		},
+		ConditionExpr: aws.String("id = :id"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusBadRequest, "user does not have a valid subscription associated")
+		}
+		logger.Printf("failed to load subscription from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load subscription from database")
+	}
+
+	projectDocs, err := database.GetMany[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetManyInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		Index: aws.String(project.GSI_OWNER_ID),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":owner_id": &dynamodbtypes.AttributeValueMemberS{Value: userDoc.Id},
+		},
+		ConditionExpr: aws.String("owner_id = :owner_id"),
+	})
+	if err != nil {
+		logger.Printf("failed to load projects from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load projects from database")
+	}
+
+	return &fetchUsageOutput{
+		Message: "usage fetched",
+		Usage:   pipeline.ComputeUsage(userDoc, subscriptionDoc, projectDocs),
+	}, nil
+}
@@ -2,9 +2,7 @@ package findproject
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -16,15 +14,15 @@ import (
 
 	"github.com/megakuul/battleshiper/api/admin/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/model/project"
-	"github.com/megakuul/battleshiper/lib/model/rbac"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "ADMIN FINDPROJECT: ", 0)
 
+const PROJECT_PAGE_SIZE = 50
+
 type repositoryOutput struct {
 	Id     int64  `json:"id"`
 	URL    string `json:"url"`
@@ -36,109 +34,66 @@ type projectOutput struct {
 	Deleted     bool                `json:"deleted"`
 	Initialized bool                `json:"initialized"`
 	Status      string              `json:"status"`
+	Version     int64               `json:"version"`
 	Aliases     map[string]struct{} `json:"aliases"`
 	Repository  repositoryOutput    `json:"repository"`
 	OwnerId     string              `json:"owner_id"`
 }
 
-type findProjectOutput struct {
-	Message  string          `json:"message"`
-	Projects []projectOutput `json:"projects"`
+type findProjectInput struct {
+	OwnerId     string `query:"owner_id"`
+	ProjectName string `query:"project_name"`
+	Cursor      string `query:"cursor"`
 }
 
-// HandleFindProject performs a lookup for the specified projects and returns them as json object.
-func HandleFindProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleFindProject(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
+type findProjectOutput struct {
+	Message    string          `json:"message"`
+	Projects   []projectOutput `json:"projects"`
+	NextCursor string          `json:"next_cursor"`
 }
 
-func runHandleFindProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*findProjectOutput, int, error) {
-	OwnerId := request.QueryStringParameters["owner_id"]
-	ProjectName := request.QueryStringParameters["project_name"]
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	if !rbac.CheckPermission(userDoc.Roles, rbac.READ_PROJECT) {
-		return nil, http.StatusForbidden, fmt.Errorf("user does not have sufficient permissions for this action")
-	}
-
+// HandleFindProject performs a lookup for the specified projects and returns them as json object.
+// Projects are returned in pages, the next_cursor of the output is passed as cursor to fetch the next page.
+func HandleFindProject(input *findProjectInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*findProjectOutput, error) {
 	var foundProjectDocs []project.Project
-	if OwnerId != "" {
-		foundProjectDocs, err = database.GetMany[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetManyInput{
+	var nextCursor string
+	var err error
+	if input.OwnerId != "" {
+		foundProjectDocs, nextCursor, err = database.GetPage[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetPageInput{
 			Table: aws.String(routeCtx.ProjectTable),
 			Index: aws.String(project.GSI_OWNER_ID),
 			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":owner_id": &dynamodbtypes.AttributeValueMemberS{Value: OwnerId},
+				":owner_id": &dynamodbtypes.AttributeValueMemberS{Value: input.OwnerId},
 			},
 			ConditionExpr: aws.String("owner_id = :owner_id"),
+			PageSize:      PROJECT_PAGE_SIZE,
+			Cursor:        input.Cursor,
+			CursorOptions: &database.CursorOptions{
+				Secret: routeCtx.CursorSecret,
+				Scope:  "findproject:" + input.OwnerId,
+			},
 		})
 		if err != nil {
+			if errors.Is(err, database.ErrInvalidCursor) {
+				return nil, router.Errorf(http.StatusBadRequest, "invalid cursor")
+			}
 			logger.Printf("failed load projects on database: %v\n", err)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed load projects on database")
+			return nil, router.Errorf(http.StatusInternalServerError, "failed load projects on database")
 		}
-	} else if ProjectName != "" {
+	} else if input.ProjectName != "" {
 		foundProjectDocs, err = database.GetMany[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetManyInput{
 			Table: aws.String(routeCtx.ProjectTable),
 			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":project_name": &dynamodbtypes.AttributeValueMemberS{Value: ProjectName},
+				":project_name": &dynamodbtypes.AttributeValueMemberS{Value: input.ProjectName},
 			},
 			ConditionExpr: aws.String("project_name = :project_name"),
 		})
 		if err != nil {
 			logger.Printf("failed load projects from database: %v\n", err)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed load projects from database")
+			return nil, router.Errorf(http.StatusInternalServerError, "failed load projects from database")
 		}
 	} else {
-		return nil, http.StatusBadRequest, fmt.Errorf("specify at least one query option")
+		return nil, router.Errorf(http.StatusBadRequest, "specify at least one query option")
 	}
 
 	foundProjectOutput := []projectOutput{}
@@ -148,6 +103,7 @@ func runHandleFindProject(request events.APIGatewayV2HTTPRequest, transportCtx c
 			Deleted:     project.Deleted,
 			Initialized: project.Initialized,
 			Status:      project.Status,
+			Version:     project.Version,
 			Aliases:     project.Aliases,
 			Repository: repositoryOutput{
 				Id:     project.Repository.Id,
@@ -159,7 +115,8 @@ func runHandleFindProject(request events.APIGatewayV2HTTPRequest, transportCtx c
 	}
 
 	return &findProjectOutput{
-		Message:  "projects fetched",
-		Projects: foundProjectOutput,
-	}, http.StatusOK, nil
+		Message:    "projects fetched",
+		Projects:   foundProjectOutput,
+		NextCursor: nextCursor,
+	}, nil
 }
@@ -2,9 +2,7 @@ package finduser
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -16,10 +14,10 @@ import (
 
 	"github.com/megakuul/battleshiper/api/admin/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/model/rbac"
 	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "ADMIN FINDUSER: ", 0)
@@ -30,6 +28,11 @@ type userOutput struct {
 	Provider       string                 `json:"provider"`
 	Roles          map[rbac.ROLE]struct{} `json:"roles"`
 	SubscriptionId string                 `json:"subscription_id"`
+	Version        int64                  `json:"version"`
+}
+
+type findUserInput struct {
+	UserId string `query:"user_id" validate:"required"`
 }
 
 type findUserOutput struct {
@@ -38,83 +41,21 @@ type findUserOutput struct {
 }
 
 // HandleFindUser performs a lookup for the specified users and returns them as json object.
-func HandleFindUser(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleFindUser(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleFindUser(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*findUserOutput, int, error) {
-	UserId := request.QueryStringParameters["user_id"]
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	if !rbac.CheckPermission(userDoc.Roles, rbac.READ_USER) {
-		return nil, http.StatusForbidden, fmt.Errorf("user does not have sufficient permissions for this action")
-	}
-
+func HandleFindUser(input *findUserInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*findUserOutput, error) {
 	foundUserDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
 		Table: aws.String(routeCtx.UserTable),
 		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: UserId},
+			":id": &dynamodbtypes.AttributeValueMemberS{Value: input.UserId},
 		},
 		ConditionExpr: aws.String("id = :id"),
 	})
 	if err != nil {
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
 		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user to fetch was not found")
+			return nil, router.Errorf(http.StatusNotFound, "user to fetch was not found")
 		}
 		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load user record from database")
 	}
 
 	return &findUserOutput{
@@ -125,6 +66,7 @@ func runHandleFindUser(request events.APIGatewayV2HTTPRequest, transportCtx cont
 			Provider:       foundUserDoc.Provider,
 			Roles:          foundUserDoc.Roles,
 			SubscriptionId: foundUserDoc.SubscriptionId,
+			Version:        foundUserDoc.Version,
 		},
-	}, http.StatusOK, nil
+	}, nil
 }
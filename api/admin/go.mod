@@ -7,7 +7,6 @@ toolchain go1.23.1
 require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go-v2/config v1.27.39
-	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.15.8
 	github.com/aws/aws-sdk-go-v2/service/eventbridge v1.34.3
 	github.com/megakuul/battleshiper/lib/helper v1.2.5
 	github.com/megakuul/battleshiper/lib/model v1.2.1
@@ -16,9 +15,13 @@ require (
 
 require (
 	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.5 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.15.8 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/v4a v1.3.18 // indirect
 	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.23.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/checksum v1.3.20 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.19 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.17.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.63.3 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 )
 
@@ -44,3 +47,9 @@ require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	golang.org/x/oauth2 v0.23.0 // indirect
 )
+
+replace (
+	github.com/megakuul/battleshiper/lib/helper => ../../lib/helper
+	github.com/megakuul/battleshiper/lib/model => ../../lib/model
+	github.com/megakuul/battleshiper/lib/router => ../../lib/router
+)
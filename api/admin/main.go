@@ -15,19 +15,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
 
 	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
-	"github.com/megakuul/battleshiper/lib/router"
-
-	"github.com/megakuul/battleshiper/api/admin/deleteproject"
-	"github.com/megakuul/battleshiper/api/admin/deleteuser"
-	"github.com/megakuul/battleshiper/api/admin/fetchlog"
-	"github.com/megakuul/battleshiper/api/admin/findproject"
-	"github.com/megakuul/battleshiper/api/admin/finduser"
-	"github.com/megakuul/battleshiper/api/admin/listsubscription"
+	"github.com/megakuul/battleshiper/lib/helper/store"
+
 	"github.com/megakuul/battleshiper/api/admin/routecontext"
-	"github.com/megakuul/battleshiper/api/admin/updaterole"
-	"github.com/megakuul/battleshiper/api/admin/updateuser"
-	"github.com/megakuul/battleshiper/api/admin/upsertsubscription"
+	"github.com/megakuul/battleshiper/api/admin/routes"
 )
 
 var (
@@ -36,8 +29,10 @@ var (
 	USERTABLE               = os.Getenv("USERTABLE")
 	PROJECTTABLE            = os.Getenv("PROJECTTABLE")
 	SUBSCRIPTIONTABLE       = os.Getenv("SUBSCRIPTIONTABLE")
-	JWT_CREDENTIAL_ARN      = os.Getenv("JWT_CREDENTIAL_ARN")
+	JWT_PUBLIC_KEY_ARN      = os.Getenv("JWT_PUBLIC_KEY_ARN")
+	CURSOR_CREDENTIAL_ARN   = os.Getenv("CURSOR_CREDENTIAL_ARN")
 	TICKET_CREDENTIAL_ARN   = os.Getenv("TICKET_CREDENTIAL_ARN")
+	TICKET_PUBLIC_KEY_ARN   = os.Getenv("TICKET_PUBLIC_KEY_ARN")
 	API_LOG_GROUP           = os.Getenv("API_LOG_GROUP")
 	PIPELINE_LOG_GROUP      = os.Getenv("PIPELINE_LOG_GROUP")
 	ROUTER_LOG_GROUP        = os.Getenv("ROUTER_LOG_GROUP")
@@ -73,30 +68,42 @@ func run() error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
-	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_CREDENTIAL_ARN, 0)
+	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_PUBLIC_KEY_ARN, 0)
 	if err != nil {
 		return err
 	}
 
-	deleteTicketTTL, err := strconv.Atoi(DELETE_EVENT_TICKET_TTL)
+	cursorSecret, err := database.LoadCursorSecret(awsConfig, bootstrapContext, CURSOR_CREDENTIAL_ARN)
 	if err != nil {
-		return fmt.Errorf("failed to parse DELETE_EVENT_TICKET_TTL environment variable")
+		return err
 	}
-	deleteTicketOptions, err := pipeline.CreateTicketOptions(
-		awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, time.Duration(deleteTicketTTL)*time.Second)
+
+	ticketSigner, err := auth.CreateSigner(awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, TICKET_PUBLIC_KEY_ARN)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load ticket keys: %v", err)
 	}
+
+	deleteTicketTTL, err := strconv.Atoi(DELETE_EVENT_TICKET_TTL)
+	if err != nil {
+		return fmt.Errorf("failed to parse DELETE_EVENT_TICKET_TTL environment variable")
+	}
+	deleteTicketOptions := pipeline.CreateTicketOptions(
+		ticketSigner, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, time.Duration(deleteTicketTTL)*time.Second)
 	deleteEventOptions := pipeline.CreateEventOptions(DELETE_EVENTBUS_NAME, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, deleteTicketOptions)
 
-	httpRouter := router.NewRouter(routecontext.Context{
+	httpRouter := routes.NewRouter(routecontext.Context{
 		DynamoClient:       dynamoClient,
 		UserTable:          USERTABLE,
 		ProjectTable:       PROJECTTABLE,
 		SubscriptionTable:  SUBSCRIPTIONTABLE,
+		UserStore:          store.NewDynamoUserStore(dynamoClient, USERTABLE),
+		ProjectStore:       store.NewDynamoProjectStore(dynamoClient, PROJECTTABLE),
+		SubscriptionStore:  store.NewDynamoSubscriptionStore(dynamoClient, SUBSCRIPTIONTABLE),
 		JwtOptions:         jwtOptions,
-		EventClient:        eventClient,
+		CursorSecret:       cursorSecret,
+		EventPublisher:     pipeline.NewEventBridgePublisher(eventClient),
 		DeleteEventOptions: deleteEventOptions,
+		TicketSigner:       ticketSigner,
 		CloudwatchClient:   cloudwatchClient,
 		LogConfiguration: &routecontext.LogConfiguration{
 			ApiLogGroup:      API_LOG_GROUP,
@@ -105,16 +112,6 @@ func run() error {
 		},
 	})
 
-	httpRouter.AddRoute("GET", "/api/admin/finduser", finduser.HandleFindUser)
-	httpRouter.AddRoute("GET", "/api/admin/findproject", findproject.HandleFindProject)
-	httpRouter.AddRoute("PATCH", "/api/admin/updateuser", updateuser.HandleUpdateUser)
-	httpRouter.AddRoute("PATCH", "/api/admin/updaterole", updaterole.HandleUpdateRole)
-	httpRouter.AddRoute("POST", "/api/admin/fetchlog", fetchlog.HandleFetchLog)
-	httpRouter.AddRoute("GET", "/api/admin/listsubscription", listsubscription.HandleListSubscription)
-	httpRouter.AddRoute("PUT", "/api/admin/upsertsubscription", upsertsubscription.HandleUpsertSubscription)
-	httpRouter.AddRoute("DELETE", "/api/admin/deleteuser", deleteuser.HandleDeleteUser)
-	httpRouter.AddRoute("DELETE", "/api/admin/deleteproject", deleteproject.HandleDeleteProject)
-
 	lambda.Start(httpRouter.Route)
 
 	return nil
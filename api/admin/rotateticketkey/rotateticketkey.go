@@ -0,0 +1,57 @@
+package rotateticketkey
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/admin/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "ADMIN ROTATETICKETKEY: ", 0)
+
+type rotateTicketKeyInput struct {
+	// Algorithm of the new key, defaults to EdDSA.
+	Algorithm string `json:"algorithm" validate:"pattern=^(EdDSA|RS256)?$"`
+}
+
+type rotateTicketKeyOutput struct {
+	Message   string `json:"message"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+}
+
+// HandleRotateTicketKey generates a new pipeline ticket signing key.
+// Tickets signed with the previous key stay valid, older keys are retired.
+// The public keys are republished, so that the pipelines accept the new key without access to the private keys.
+func HandleRotateTicketKey(input *rotateTicketKeyInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*rotateTicketKeyOutput, error) {
+	algorithm := input.Algorithm
+	if algorithm == "" {
+		algorithm = auth.ALG_EDDSA
+	}
+
+	newKey, err := routeCtx.TicketSigner.Rotate(transportCtx, algorithm)
+	if err != nil {
+		if errors.Is(err, auth.ErrStaticKeyring) {
+			return nil, router.Errorf(http.StatusNotImplemented, "ticket keys cannot be rotated on this deployment")
+		}
+		if errors.Is(err, auth.ErrKeyringConflict) {
+			return nil, router.Errorf(http.StatusConflict, "ticket keys were rotated in the meantime; try again")
+		}
+		logger.Printf("failed to rotate ticket key: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to rotate ticket key")
+	}
+
+	return &rotateTicketKeyOutput{
+		Message:   "ticket key rotated",
+		KeyId:     newKey.Id,
+		Algorithm: newKey.Algorithm,
+	}, nil
+}
@@ -3,9 +3,9 @@ package routecontext
 import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 )
 
 type LogConfiguration struct {
@@ -20,9 +20,14 @@ type Context struct {
 	UserTable          string
 	ProjectTable       string
 	SubscriptionTable  string
+	UserStore          store.UserStore
+	ProjectStore       store.ProjectStore
+	SubscriptionStore  store.SubscriptionStore
 	JwtOptions         *auth.JwtOptions
-	EventClient        *eventbridge.Client
+	CursorSecret       string
+	EventPublisher     pipeline.EventPublisher
 	DeleteEventOptions *pipeline.EventOptions
+	TicketSigner       *auth.Signer
 	CloudwatchClient   *cloudwatchlogs.Client
 	LogConfiguration   *LogConfiguration
 }
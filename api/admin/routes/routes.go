@@ -0,0 +1,46 @@
+// routes package registers the http routes of the admin api.
+package routes
+
+import (
+	"github.com/megakuul/battleshiper/lib/model/rbac"
+	"github.com/megakuul/battleshiper/lib/router"
+
+	"github.com/megakuul/battleshiper/api/admin/deleteproject"
+	"github.com/megakuul/battleshiper/api/admin/deleteuser"
+	"github.com/megakuul/battleshiper/api/admin/fetchlog"
+	"github.com/megakuul/battleshiper/api/admin/fetchusage"
+	"github.com/megakuul/battleshiper/api/admin/findproject"
+	"github.com/megakuul/battleshiper/api/admin/finduser"
+	"github.com/megakuul/battleshiper/api/admin/listsubscription"
+	"github.com/megakuul/battleshiper/api/admin/rotateticketkey"
+	"github.com/megakuul/battleshiper/api/admin/routecontext"
+	"github.com/megakuul/battleshiper/api/admin/updaterole"
+	"github.com/megakuul/battleshiper/api/admin/updateuser"
+	"github.com/megakuul/battleshiper/api/admin/upsertsubscription"
+)
+
+// NewRouter creates the router of the admin api with all routes registered.
+func NewRouter(routeCtx routecontext.Context) *router.Router[routecontext.Context] {
+	httpRouter := router.NewRouter(routeCtx)
+
+	httpRouter.Use(router.Authenticate(routeCtx.JwtOptions), router.LoadUser(routeCtx.DynamoClient, routeCtx.UserTable))
+
+	router.AddJSONRoute(httpRouter, "GET", "/api/admin/finduser", finduser.HandleFindUser, router.RequireAccess(rbac.READ_USER))
+	router.AddJSONRoute(httpRouter, "GET", "/api/admin/usage", fetchusage.HandleFetchUsage, router.RequireAccess(rbac.READ_USER, rbac.READ_PROJECT))
+	router.AddJSONRoute(httpRouter, "GET", "/api/admin/findproject", findproject.HandleFindProject, router.RequireAccess(rbac.READ_PROJECT))
+	router.AddJSONRoute(httpRouter, "PATCH", "/api/admin/updateuser", updateuser.HandleUpdateUser, router.RequireAccess(rbac.WRITE_USER))
+	router.AddJSONRoute(httpRouter, "PATCH", "/api/admin/updaterole", updaterole.HandleUpdateRole, router.RequireAccess(rbac.WRITE_ROLE))
+	router.AddJSONRoute(httpRouter, "POST", "/api/admin/fetchlog", fetchlog.HandleFetchLog, router.RequireAccess(rbac.READ_LOGS))
+	router.AddJSONRoute(httpRouter, "GET", "/api/admin/listsubscription", listsubscription.HandleListSubscription, router.RequireAccess(rbac.READ_SUBSCRIPTION))
+	router.AddJSONRoute(httpRouter, "PUT", "/api/admin/upsertsubscription", upsertsubscription.HandleUpsertSubscription, router.RequireAccess(rbac.WRITE_SUBSCRIPTION))
+	router.AddJSONRoute(httpRouter, "POST", "/api/admin/rotateticketkey", rotateticketkey.HandleRotateTicketKey, router.RequireAccess(rbac.WRITE_CREDENTIAL))
+	router.AddJSONRoute(httpRouter, "DELETE", "/api/admin/deleteuser", deleteuser.HandleDeleteUser, router.RequireAccess(rbac.WRITE_USER, rbac.WRITE_PROJECT))
+	router.AddJSONRoute(httpRouter, "DELETE", "/api/admin/deleteproject", deleteproject.HandleDeleteProject, router.RequireAccess(rbac.WRITE_PROJECT))
+
+	httpRouter.AddOpenAPIRoute("/api/admin/openapi.json", router.OpenAPIInfo{
+		Title:   "Battleshiper Admin API",
+		Version: "1.0.0",
+	})
+
+	return httpRouter
+}
@@ -2,32 +2,27 @@ package updaterole
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
-
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/megakuul/battleshiper/api/admin/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 	"github.com/megakuul/battleshiper/lib/model/rbac"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "ADMIN UPDATEROLE: ", 0)
 
 type updateRoleInput struct {
-	UserId string                 `json:"user_id"`
-	Roles  map[rbac.ROLE]struct{} `json:"rbac_roles"`
+	UserId  string                 `json:"user_id" validate:"required"`
+	Roles   map[rbac.ROLE]struct{} `json:"rbac_roles"`
+	Version *int64                 `json:"version"`
 }
 
 type updateRoleOutput struct {
@@ -35,103 +30,26 @@ type updateRoleOutput struct {
 }
 
 // HandleUpdateRole updates the roles of a user.
-func HandleUpdateRole(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleUpdateRole(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleUpdateRole(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*updateRoleOutput, int, error) {
-	var updateRoleInput updateRoleInput
-	err := json.Unmarshal([]byte(request.Body), &updateRoleInput)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: invalid body")
-	}
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
+func HandleUpdateRole(input *updateRoleInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*updateRoleOutput, error) {
+	_, err := routeCtx.UserStore.Update(transportCtx, input.UserId, store.Update{
+		Set: map[string]any{
+			"Roles":      input.Roles,
+			"Privileged": rbac.IsPrivileged(input.Roles),
 		},
-		ConditionExpr: aws.String("id = :id"),
+		Version: input.Version,
 	})
 	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, router.Errorf(http.StatusConflict, "user was modified in the meantime; reload the user and try again")
 		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	if !rbac.CheckPermission(userDoc.Roles, rbac.WRITE_ROLE) {
-		return nil, http.StatusForbidden, fmt.Errorf("user does not have sufficient permissions for this action")
-	}
-
-	roles, err := attributevalue.Marshal(&updateRoleInput.Roles)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to serialize roles")
-	}
-
-	_, err = database.UpdateSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-			"id": &dynamodbtypes.AttributeValueMemberS{Value: updateRoleInput.UserId},
-		},
-		AttributeNames: map[string]string{
-			"#roles":      "roles",
-			"#privileged": "privileged",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":roles":      roles,
-			":privileged": &dynamodbtypes.AttributeValueMemberBOOL{Value: rbac.IsPrivileged(updateRoleInput.Roles)},
-		},
-		UpdateExpr: aws.String("SET #roles = :roles, #privileged = :privileged"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user to update was not found")
+		if store.IsConditionFailed(err) {
+			return nil, router.Errorf(http.StatusNotFound, "user to update was not found")
 		}
 		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load user record from database")
 	}
 
 	return &updateRoleOutput{
 		Message: "roles updated",
-	}, http.StatusOK, nil
+	}, nil
 }
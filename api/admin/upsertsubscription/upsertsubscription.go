@@ -2,25 +2,16 @@ package upsertsubscription
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
-
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/megakuul/battleshiper/api/admin/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/helper/database"
-	"github.com/megakuul/battleshiper/lib/model/rbac"
 	"github.com/megakuul/battleshiper/lib/model/subscription"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "ADMIN UPSERTSUBSCRIPTION: ", 0)
@@ -31,24 +22,31 @@ type pipelineSpecsInput struct {
 }
 
 type projectSpecsInput struct {
-	ProjectCount     int64 `json:"project_count"`
-	AliasCount       int64 `json:"alias_count"`
-	PrerenderRoutes  int64 `json:"prerender_routes"`
-	ServerStorage    int64 `json:"server_storage"`
-	ClientStorage    int64 `json:"client_storage"`
-	PrerenderStorage int64 `json:"prerender_storage"`
+	ProjectCount        int64 `json:"project_count"`
+	AliasCount          int64 `json:"alias_count"`
+	PrerenderRoutes     int64 `json:"prerender_routes"`
+	ServerStorage       int64 `json:"server_storage"`
+	ClientStorage       int64 `json:"client_storage"`
+	PrerenderStorage    int64 `json:"prerender_storage"`
+	DeploymentRetention int64 `json:"deployment_retention" validate:"min=1,max=20"`
 }
 
 type cdnSpecsInput struct {
 	InstanceCount int64 `json:"instance_count"`
 }
 
+type quotaSpecInput struct {
+	Limit  int64  `json:"limit"`
+	Window string `json:"window"`
+}
+
 type upsertSubscriptionInput struct {
-	Id            string             `json:"id"`
-	Name          string             `json:"name"`
-	PipelineSpecs pipelineSpecsInput `json:"pipeline_specs"`
-	ProjectSpecs  projectSpecsInput  `json:"project_specs"`
-	CDNSpecs      cdnSpecsInput      `json:"cdn_specs"`
+	Id            string                    `json:"id" validate:"required"`
+	Name          string                    `json:"name" validate:"required"`
+	PipelineSpecs pipelineSpecsInput        `json:"pipeline_specs"`
+	ProjectSpecs  projectSpecsInput         `json:"project_specs"`
+	CDNSpecs      cdnSpecsInput             `json:"cdn_specs"`
+	Quotas        map[string]quotaSpecInput `json:"quotas" validate:"keymax=40,keypattern=^[a-z0-9_]+$"`
 }
 
 type upsertSubscriptionOutput struct {
@@ -56,102 +54,49 @@ type upsertSubscriptionOutput struct {
 }
 
 // HandleUpsertSubscription upserts a subscription identified by the subscription id.
-func HandleUpsertSubscription(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleUpsertSubscription(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleUpsertSubscription(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*upsertSubscriptionOutput, int, error) {
-	var upsertSubscriptionInput upsertSubscriptionInput
-	err := json.Unmarshal([]byte(request.Body), &upsertSubscriptionInput)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: invalid body")
-	}
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
+func HandleUpsertSubscription(input *upsertSubscriptionInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*upsertSubscriptionOutput, error) {
+	quotas := map[string]subscription.QuotaSpec{}
+	for name, quota := range input.Quotas {
+		switch quota.Window {
+		case subscription.QUOTA_WINDOW_DAILY, subscription.QUOTA_WINDOW_MONTHLY,
+			subscription.QUOTA_WINDOW_CALENDAR_DAY, subscription.QUOTA_WINDOW_CALENDAR_MONTH:
+		default:
+			return nil, router.Errorf(http.StatusBadRequest, "quota '%s' has an invalid window '%s'", name, quota.Window)
+		}
+		quotas[name] = subscription.QuotaSpec{
+			Limit:  quota.Limit,
+			Window: quota.Window,
 		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	if !rbac.CheckPermission(userDoc.Roles, rbac.WRITE_SUBSCRIPTION) {
-		return nil, http.StatusForbidden, fmt.Errorf("user does not have sufficient permissions for this action")
 	}
 
 	// MIG: Possible with update item and primary key
-	err = database.PutSingle(transportCtx, routeCtx.DynamoClient, &database.PutSingleInput[subscription.Subscription]{
-		Table: aws.String(routeCtx.SubscriptionTable),
-		Item: subscription.Subscription{
-			Id:   upsertSubscriptionInput.Id,
-			Name: upsertSubscriptionInput.Name,
-			PipelineSpecs: subscription.PipelineSpecs{
-				DailyBuilds:      upsertSubscriptionInput.PipelineSpecs.DailyBuilds,
-				DailyDeployments: upsertSubscriptionInput.PipelineSpecs.DailyDeployments,
-			},
-			ProjectSpecs: subscription.ProjectSpecs{
-				ProjectCount:     upsertSubscriptionInput.ProjectSpecs.ProjectCount,
-				AliasCount:       upsertSubscriptionInput.ProjectSpecs.AliasCount,
-				ServerStorage:    upsertSubscriptionInput.ProjectSpecs.ServerStorage,
-				ClientStorage:    upsertSubscriptionInput.ProjectSpecs.ClientStorage,
-				PrerenderStorage: upsertSubscriptionInput.ProjectSpecs.PrerenderStorage,
-				PrerenderRoutes:  upsertSubscriptionInput.ProjectSpecs.PrerenderRoutes,
-			},
-			CDNSpecs: subscription.CDNSpecs{
-				InstanceCount: upsertSubscriptionInput.CDNSpecs.InstanceCount,
-			},
+	err := routeCtx.SubscriptionStore.Put(transportCtx, &subscription.Subscription{
+		Id:   input.Id,
+		Name: input.Name,
+		PipelineSpecs: subscription.PipelineSpecs{
+			DailyBuilds:      input.PipelineSpecs.DailyBuilds,
+			DailyDeployments: input.PipelineSpecs.DailyDeployments,
 		},
+		ProjectSpecs: subscription.ProjectSpecs{
+			ProjectCount:        input.ProjectSpecs.ProjectCount,
+			AliasCount:          input.ProjectSpecs.AliasCount,
+			ServerStorage:       input.ProjectSpecs.ServerStorage,
+			ClientStorage:       input.ProjectSpecs.ClientStorage,
+			PrerenderStorage:    input.ProjectSpecs.PrerenderStorage,
+			PrerenderRoutes:     input.ProjectSpecs.PrerenderRoutes,
+			DeploymentRetention: input.ProjectSpecs.DeploymentRetention,
+		},
+		CDNSpecs: subscription.CDNSpecs{
+			InstanceCount: input.CDNSpecs.InstanceCount,
+		},
+		Quotas: quotas,
 	})
 	if err != nil {
 		logger.Printf("failed to update subscription: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to update subscription")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to update subscription")
 	}
 
 	return &upsertSubscriptionOutput{
 		Message: "subscription upserted",
-	}, http.StatusOK, nil
+	}, nil
 }
@@ -63,18 +63,22 @@ func runHandleCallback(request events.APIGatewayV2HTTPRequest, transportCtx cont
 		return nil, http.StatusInternalServerError, fmt.Errorf("failed to acquire user information from github")
 	}
 
+	updateExpression, err := database.NewUpdate[user.User]().
+		Set("RefreshToken", token.RefreshToken).
+		Build()
+	if err != nil {
+		logger.Printf("failed to build update expression: %v\n", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to build update expression")
+	}
+
 	_, err = database.UpdateSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
 		Table: aws.String(routeCtx.UserTable),
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"id": &dynamodbtypes.AttributeValueMemberS{Value: strconv.Itoa(int(*githubUser.ID))},
 		},
-		AttributeNames: map[string]string{
-			"#refresh_token": "refresh_token",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":refresh_token": &dynamodbtypes.AttributeValueMemberS{Value: token.RefreshToken},
-		},
-		UpdateExpr: aws.String("SET #refresh_token = :refresh_token"),
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
 	})
 	if err != nil {
 		// if the user is not registered, setting the refresh token is simply skipped (no error is emitted).
@@ -38,3 +38,9 @@ require (
 	github.com/aws/smithy-go v1.21.0 // indirect
 	golang.org/x/oauth2 v0.23.0
 )
+
+replace (
+	github.com/megakuul/battleshiper/lib/helper => ../../lib/helper
+	github.com/megakuul/battleshiper/lib/model => ../../lib/model
+	github.com/megakuul/battleshiper/lib/router => ../../lib/router
+)
@@ -0,0 +1,37 @@
+package jwks
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/auth/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "AUTH JWKS: ", 0)
+
+type jwksInput struct{}
+
+type jwksOutput struct {
+	Keys []auth.JWK `json:"keys"`
+}
+
+// HandleJWKS publishes the active public keys of the user tokens as json web key set.
+// External services use the keys to validate Battleshiper sessions, the key is selected by the kid header of the token.
+func HandleJWKS(input *jwksInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*jwksOutput, error) {
+	keys, err := routeCtx.JwtOptions.Verifier.JWKS()
+	if err != nil {
+		logger.Printf("failed to encode json web keys: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to encode json web keys")
+	}
+
+	return &jwksOutput{
+		Keys: keys,
+	}, nil
+}
@@ -59,23 +59,27 @@ func runHandleLogout(request events.APIGatewayV2HTTPRequest, transportCtx contex
 		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
 	}
 
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
+	userToken, err := auth.ParseJWT(transportCtx, routeCtx.JwtOptions, userTokenCookie.Value)
 	if err != nil {
 		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
 	}
 
+	updateExpression, err := database.NewUpdate[user.User]().
+		Set("RefreshToken", "").
+		Build()
+	if err != nil {
+		logger.Printf("failed to build update expression: %v\n", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to build update expression")
+	}
+
 	_, err = database.UpdateSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
 		Table: aws.String(routeCtx.UserTable),
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
 		},
-		AttributeNames: map[string]string{
-			"#refresh_token": "refresh_token",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":refresh_token": &dynamodbtypes.AttributeValueMemberS{Value: ""},
-		},
-		UpdateExpr: aws.String("SET #refresh_token = :refresh_token"),
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
 	})
 	if err != nil {
 		// if the user is not registered, deleting the refresh token is simply skipped (no error is emitted).
@@ -12,14 +12,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/router"
 	"golang.org/x/oauth2/github"
 
-	"github.com/megakuul/battleshiper/api/auth/authorize"
-	"github.com/megakuul/battleshiper/api/auth/callback"
-	"github.com/megakuul/battleshiper/api/auth/logout"
-	"github.com/megakuul/battleshiper/api/auth/refresh"
 	"github.com/megakuul/battleshiper/api/auth/routecontext"
+	"github.com/megakuul/battleshiper/api/auth/routes"
 )
 
 var (
@@ -28,6 +24,7 @@ var (
 	USERTABLE                    = os.Getenv("USERTABLE")
 	PROJECTTABLE                 = os.Getenv("PROJECTTABLE")
 	JWT_CREDENTIAL_ARN           = os.Getenv("JWT_CREDENTIAL_ARN")
+	JWT_PUBLIC_KEY_ARN           = os.Getenv("JWT_PUBLIC_KEY_ARN")
 	USER_TOKEN_TTL               = os.Getenv("USER_TOKEN_TTL")
 	GITHUB_CLIENT_CREDENTIAL_ARN = os.Getenv("GITHUB_CLIENT_CREDENTIAL_ARN")
 	REDIRECT_URI                 = os.Getenv("REDIRECT_URI")
@@ -60,7 +57,7 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse USER_TOKEN_TTL environment variable")
 	}
-	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_CREDENTIAL_ARN, time.Duration(userTokenTTL)*time.Second)
+	jwtOptions, err := auth.CreateJwtSigningOptions(awsConfig, bootstrapContext, JWT_CREDENTIAL_ARN, JWT_PUBLIC_KEY_ARN, time.Duration(userTokenTTL)*time.Second)
 	if err != nil {
 		return err
 	}
@@ -70,7 +67,7 @@ func run() error {
 		return err
 	}
 
-	httpRouter := router.NewRouter(routecontext.Context{
+	httpRouter := routes.NewRouter(routecontext.Context{
 		DynamoClient:        dynamoClient,
 		UserTable:           USERTABLE,
 		ProjectTable:        PROJECTTABLE,
@@ -79,11 +76,6 @@ func run() error {
 		FrontendRedirectURI: FRONTEND_REDIRECT_URI,
 	})
 
-	httpRouter.AddRoute("GET", "/api/auth/authorize", authorize.HandleAuthorization)
-	httpRouter.AddRoute("GET", "/api/auth/callback", callback.HandleCallback)
-	httpRouter.AddRoute("POST", "/api/auth/refresh", refresh.HandleRefresh)
-	httpRouter.AddRoute("POST", "/api/auth/logout", logout.HandleLogout)
-
 	lambda.Start(httpRouter.Route)
 
 	return nil
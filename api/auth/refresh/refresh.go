@@ -62,7 +62,7 @@ func runHandleRefresh(request events.APIGatewayV2HTTPRequest, transportCtx conte
 }
 
 func refreshByUserToken(transportCtx context.Context, routeCtx routecontext.Context, userTokenRaw string) ([]string, int, error) {
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenRaw)
+	userToken, err := auth.ParseJWT(transportCtx, routeCtx.JwtOptions, userTokenRaw)
 	if err != nil {
 		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
 	}
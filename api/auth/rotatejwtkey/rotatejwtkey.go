@@ -0,0 +1,58 @@
+package rotatejwtkey
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/auth/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "AUTH ROTATEJWTKEY: ", 0)
+
+type rotateJwtKeyInput struct {
+	// Algorithm of the new key, defaults to EdDSA.
+	Algorithm string `json:"algorithm" validate:"pattern=^(EdDSA|RS256)?$"`
+}
+
+type rotateJwtKeyOutput struct {
+	Message   string `json:"message"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+}
+
+// HandleRotateJwtKey generates a new user token signing key.
+// User tokens signed with the previous key stay valid, older keys are retired.
+// The public keys are republished, so that the apis accept the new key without access to the private keys.
+// The route is served by the auth api because it is the only api with access to the private keys.
+func HandleRotateJwtKey(input *rotateJwtKeyInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*rotateJwtKeyOutput, error) {
+	algorithm := input.Algorithm
+	if algorithm == "" {
+		algorithm = auth.ALG_EDDSA
+	}
+
+	newKey, err := routeCtx.JwtOptions.Signer.Rotate(transportCtx, algorithm)
+	if err != nil {
+		if errors.Is(err, auth.ErrStaticKeyring) {
+			return nil, router.Errorf(http.StatusNotImplemented, "jwt keys cannot be rotated on this deployment")
+		}
+		if errors.Is(err, auth.ErrKeyringConflict) {
+			return nil, router.Errorf(http.StatusConflict, "jwt keys were rotated in the meantime; try again")
+		}
+		logger.Printf("failed to rotate jwt key: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to rotate jwt key")
+	}
+
+	return &rotateJwtKeyOutput{
+		Message:   "jwt key rotated",
+		KeyId:     newKey.Id,
+		Algorithm: newKey.Algorithm,
+	}, nil
+}
@@ -0,0 +1,38 @@
+// routes package registers the http routes of the auth api.
+package routes
+
+import (
+	"github.com/megakuul/battleshiper/lib/model/rbac"
+	"github.com/megakuul/battleshiper/lib/router"
+
+	"github.com/megakuul/battleshiper/api/auth/authorize"
+	"github.com/megakuul/battleshiper/api/auth/callback"
+	"github.com/megakuul/battleshiper/api/auth/jwks"
+	"github.com/megakuul/battleshiper/api/auth/logout"
+	"github.com/megakuul/battleshiper/api/auth/refresh"
+	"github.com/megakuul/battleshiper/api/auth/rotatejwtkey"
+	"github.com/megakuul/battleshiper/api/auth/routecontext"
+)
+
+// NewRouter creates the router of the auth api with all routes registered.
+func NewRouter(routeCtx routecontext.Context) *router.Router[routecontext.Context] {
+	httpRouter := router.NewRouter(routeCtx)
+
+	httpRouter.AddRoute("GET", "/api/auth/authorize", authorize.HandleAuthorization)
+	httpRouter.AddRoute("GET", "/api/auth/callback", callback.HandleCallback)
+	httpRouter.AddRoute("POST", "/api/auth/refresh", refresh.HandleRefresh)
+	httpRouter.AddRoute("POST", "/api/auth/logout", logout.HandleLogout)
+	router.AddJSONRoute(httpRouter, "GET", "/api/auth/.well-known/jwks.json", jwks.HandleJWKS)
+	router.AddJSONRoute(httpRouter, "POST", "/api/auth/rotatejwtkey", rotatejwtkey.HandleRotateJwtKey,
+		router.Authenticate(routeCtx.JwtOptions),
+		router.LoadUser(routeCtx.DynamoClient, routeCtx.UserTable),
+		router.RequireAccess(rbac.WRITE_CREDENTIAL),
+	)
+
+	httpRouter.AddOpenAPIRoute("/api/auth/openapi.json", router.OpenAPIInfo{
+		Title:   "Battleshiper Auth API",
+		Version: "1.0.0",
+	})
+
+	return httpRouter
+}
@@ -8,7 +8,6 @@ import (
 	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/go-playground/webhooks/v6/github"
 	"github.com/megakuul/battleshiper/api/pipeline/routecontext"
@@ -28,10 +27,13 @@ func handleAppInstallation(transportCtx context.Context, routeCtx routecontext.C
 		}
 	}
 
-	repositories, err := attributevalue.Marshal(&installedRepos)
+	updateExpression, err := database.NewUpdate[user.User]().
+		Set("InstallationId", event.Installation.ID).
+		Set("Repositories", installedRepos).
+		Build()
 	if err != nil {
-		logger.Printf("failed to serialize repositories: %v\n", err)
-		return http.StatusInternalServerError, fmt.Errorf("failed to serialize repositories")
+		logger.Printf("failed to build update expression: %v\n", err)
+		return http.StatusInternalServerError, fmt.Errorf("failed to build update expression")
 	}
 
 	_, err = database.UpdateSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
@@ -39,15 +41,9 @@ func handleAppInstallation(transportCtx context.Context, routeCtx routecontext.C
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"id": &dynamodbtypes.AttributeValueMemberS{Value: strconv.Itoa(int(userId))},
 		},
-		AttributeNames: map[string]string{
-			"#installation_id": "installation_id",
-			"#repositories":    "repositories",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":installation_id": &dynamodbtypes.AttributeValueMemberN{Value: strconv.Itoa(int(event.Installation.ID))},
-			":repositories":    repositories,
-		},
-		UpdateExpr: aws.String("SET #installation_id = :installation_id, #repositories = :repositories"),
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
 	})
 	if err != nil {
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
@@ -2,7 +2,6 @@ package event
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,10 +10,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
-	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
 	"github.com/go-playground/webhooks/v6/github"
 	"github.com/google/uuid"
 	"github.com/megakuul/battleshiper/api/pipeline/routecontext"
@@ -82,10 +78,13 @@ func handleRepoPush(transportCtx context.Context, routeCtx routecontext.Context,
 			eventResult.Successful = false
 			eventResult.Timepoint = time.Now().Unix()
 
-			eventResultAttributes, sErr := attributevalue.Marshal(&eventResult)
-			if sErr != nil {
-				logger.Printf("failed to serialize eventresult: %v\n", sErr)
-				return http.StatusInternalServerError, fmt.Errorf("failed to serialize eventresult")
+			updateExpression, bErr := database.NewUpdate[project.Project]().
+				Set("LastEventResult", &eventResult).
+				Set("Status", fmt.Sprintf("EVENT FAILED: %v", err)).
+				Build()
+			if bErr != nil {
+				logger.Printf("failed to build update expression: %v\n", bErr)
+				return http.StatusInternalServerError, fmt.Errorf("failed to build update expression")
 			}
 
 			_, uErr := database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
@@ -93,15 +92,9 @@ func handleRepoPush(transportCtx context.Context, routeCtx routecontext.Context,
 				PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 					"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 				},
-				AttributeNames: map[string]string{
-					"#last_event_result": "last_event_result",
-					"#status":            "status",
-				},
-				AttributeValues: map[string]dynamodbtypes.AttributeValue{
-					":last_event_result": eventResultAttributes,
-					":status":            &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("EVENT FAILED: %v", err)},
-				},
-				UpdateExpr: aws.String("SET #last_event_result = :last_event_result, #status = :status"),
+				AttributeNames:  updateExpression.AttributeNames,
+				AttributeValues: updateExpression.AttributeValues,
+				UpdateExpr:      updateExpression.UpdateExpr,
 			})
 			if uErr != nil {
 				logger.Printf("failed to update project: %v\n", uErr)
@@ -113,10 +106,12 @@ func handleRepoPush(transportCtx context.Context, routeCtx routecontext.Context,
 			eventResult.Successful = true
 			eventResult.Timepoint = time.Now().Unix()
 
-			eventResultAttributes, sErr := attributevalue.Marshal(&eventResult)
-			if sErr != nil {
-				logger.Printf("failed to serialize eventresult: %v\n", sErr)
-				return http.StatusInternalServerError, fmt.Errorf("failed to serialize eventresult")
+			updateExpression, bErr := database.NewUpdate[project.Project]().
+				Set("LastEventResult", &eventResult).
+				Build()
+			if bErr != nil {
+				logger.Printf("failed to build update expression: %v\n", bErr)
+				return http.StatusInternalServerError, fmt.Errorf("failed to build update expression")
 			}
 
 			_, uErr := database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
@@ -124,13 +119,9 @@ func handleRepoPush(transportCtx context.Context, routeCtx routecontext.Context,
 				PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 					"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 				},
-				AttributeNames: map[string]string{
-					"#last_event_result": "last_event_result",
-				},
-				AttributeValues: map[string]dynamodbtypes.AttributeValue{
-					":last_event_result": eventResultAttributes,
-				},
-				UpdateExpr: aws.String("SET #last_event_result = :last_event_result"),
+				AttributeNames:  updateExpression.AttributeNames,
+				AttributeValues: updateExpression.AttributeValues,
+				UpdateExpr:      updateExpression.UpdateExpr,
 			})
 			if uErr != nil {
 				logger.Printf("failed to update project: %v\n", uErr)
@@ -142,63 +133,63 @@ func handleRepoPush(transportCtx context.Context, routeCtx routecontext.Context,
 	return http.StatusOK, nil
 }
 
-func initiateProjectBuild(transportCtx context.Context, routeCtx routecontext.Context, execId string, userDoc *user.User, projectDoc *project.Project) error {
-	cloudLogger, err := pipeline.NewCloudLogger(transportCtx, routeCtx.CloudwatchClient, projectDoc.DedicatedInfrastructure.EventLogGroup, execId)
+func initiateProjectBuild(transportCtx context.Context, routeCtx routecontext.Context, execId string, userDoc *user.User, projectDoc *project.Project) (err error) {
+	cloudLogger, err := pipeline.NewCloudLogger(transportCtx, routeCtx.CloudwatchClient, projectDoc.DedicatedInfrastructure.EventLogGroup, execId, pipeline.LOG_PHASE_EVENT, routeCtx.CloudLoggerOptions)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if cErr := cloudLogger.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
 
 	cloudLogger.WriteLog("START INIT %s", execId)
 	cloudLogger.WriteLog("Event triggered by github webhook")
-	if err := cloudLogger.PushLogs(); err != nil {
-		return err
-	}
 
 	cloudLogger.WriteLog("Generating installation token...")
 	appClient, err := auth.CreateGithubAppClient(transportCtx, routeCtx.GithubAppOptions)
 	if err != nil {
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
+		cloudLogger.WriteError("failed to generate github app client")
 		return fmt.Errorf("failed to generate github app client: %v", err)
 	}
 	installToken, _, err := appClient.Apps.CreateInstallationToken(transportCtx, userDoc.InstallationId, nil)
 	if err != nil {
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
+		cloudLogger.WriteError("failed to generate installation token")
 		return fmt.Errorf("failed to generate installation token: %v", err)
 	}
 
 	cloudLogger.WriteLog("Emitting event to pipeline...")
 	err = emitBuildEvent(transportCtx, routeCtx, execId, installToken.GetToken(), userDoc, projectDoc)
 	if err != nil {
-		cloudLogger.WriteLog("failed to emit build event: %v", err)
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
+		cloudLogger.WriteError("failed to emit build event: %v", err)
 		return fmt.Errorf("failed to emit build event: %v", err)
-	} else {
-		cloudLogger.WriteLog("project build was successfully initiated")
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 	}
+	cloudLogger.WriteLog("project build was successfully initiated")
 
 	return nil
 }
 
-func emitBuildEvent(transportCtx context.Context, routeCtx routecontext.Context, execId, installToken string, userDoc *user.User, projectDoc *project.Project) error {
-	err := pipeline.CheckBuildSubscriptionLimit(transportCtx, routeCtx.DynamoClient, &pipeline.CheckBuildSubscriptionLimitInput{
+func emitBuildEvent(transportCtx context.Context, routeCtx routecontext.Context, execId, installToken string, userDoc *user.User, projectDoc *project.Project) (err error) {
+	quotaUsage, err := pipeline.CheckQuota(transportCtx, routeCtx.DynamoClient, &pipeline.CheckQuotaInput{
 		UserTable:         routeCtx.UserTable,
 		SubscriptionTable: routeCtx.SubscriptionTable,
 		UserDoc:           *userDoc,
+		Name:              pipeline.QUOTA_PIPELINE_BUILDS,
 	})
 	if err != nil {
 		return err
 	}
+	// the counted build is refunded if the event does not reach the pipeline.
+	defer func() {
+		if err != nil {
+			if rErr := pipeline.RefundQuota(transportCtx, routeCtx.DynamoClient, quotaUsage); rErr != nil {
+				logger.Printf("failed to refund build quota: %v\n", rErr)
+			}
+		}
+	}()
 
-	deployTicket, err := pipeline.CreateTicket(routeCtx.DeployTicketOptions, userDoc.Id, projectDoc.ProjectName)
+	deployTicket, err := pipeline.CreateTicket(routeCtx.DeployTicketOptions, userDoc.Id, projectDoc.ProjectName, execId)
 	if err != nil {
 		return fmt.Errorf("failed to create pipeline ticket")
 	}
@@ -219,24 +210,24 @@ func emitBuildEvent(transportCtx context.Context, routeCtx routecontext.Context,
 		BuildCommand:        projectDoc.BuildCommand,
 		OutputDirectory:     projectDoc.OutputDirectory,
 	}
-	buildRequestRaw, err := json.Marshal(buildRequest)
+	buildEnvironment, err := pipeline.DecryptEnvironment(routeCtx.EnvironmentCipher, projectDoc.Environment.Build)
 	if err != nil {
-		return fmt.Errorf("failed to serialize build request")
+		logger.Printf("failed to load build environment: %v\n", err)
+		return fmt.Errorf("failed to load build environment")
 	}
-
-	eventEntry := eventbridgetypes.PutEventsRequestEntry{
-		Source:       aws.String(routeCtx.BuildEventOptions.Source),
-		DetailType:   aws.String(fmt.Sprintf("%s.%s", routeCtx.BuildEventOptions.Action, projectDoc.ProjectName)),
-		Detail:       aws.String(string(buildRequestRaw)),
-		EventBusName: aws.String(routeCtx.BuildEventOptions.EventBus),
+	// project variables are passed through the build environment secret, the event only contains the request fields.
+	_, err = pipeline.WriteEnvironmentSecret(transportCtx, routeCtx.SecretClient,
+		pipeline.BuildEnvironmentSecretName(projectDoc.ProjectName), pipeline.CreateBuildScript(buildEnvironment))
+	if err != nil {
+		logger.Printf("failed to write build environment: %v\n", err)
+		return fmt.Errorf("failed to write build environment")
 	}
-	res, err := routeCtx.EventClient.PutEvents(transportCtx, &eventbridge.PutEventsInput{
-		Entries: []eventbridgetypes.PutEventsRequestEntry{eventEntry},
-	})
+	buildRequest.Environment = pipeline.CreateBuildEnvironment(buildRequest)
+
+	err = routeCtx.EventPublisher.PublishBuild(transportCtx, routeCtx.BuildEventOptions, projectDoc.ProjectName, buildRequest)
 	if err != nil {
+		logger.Printf("failed to publish build event: %v\n", err)
 		return fmt.Errorf("failed to emit build event to the pipeline")
-	} else if res.FailedEntryCount > 0 {
-		return fmt.Errorf("failed to ingest build event")
 	}
 
 	return nil
@@ -8,7 +8,6 @@ import (
 	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/go-playground/webhooks/v6/github"
 	"github.com/megakuul/battleshiper/api/pipeline/routecontext"
@@ -19,53 +18,35 @@ import (
 func handleRepoUpdate(transportCtx context.Context, routeCtx routecontext.Context, event github.InstallationRepositoriesPayload) (int, error) {
 	userId := event.Installation.Account.ID
 
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+	// repositories are merged into the current user document, the update is retried if the user was modified in the meantime.
+	_, err := database.UpdateWithRetry(transportCtx, routeCtx.DynamoClient, &database.UpdateWithRetryInput[user.User]{
 		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: strconv.Itoa(int(userId))},
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"id": &dynamodbtypes.AttributeValueMemberS{Value: strconv.Itoa(int(userId))},
+		},
+		Merge: func(userDoc *user.User) (*database.UpdateBuilder[user.User], error) {
+			repositories := map[int64]user.Repository{}
+			for id, repo := range userDoc.Repositories {
+				repositories[id] = repo
+			}
+			for _, repo := range event.RepositoriesAdded {
+				repositories[repo.ID] = user.Repository{
+					Id:       repo.ID,
+					Name:     repo.Name,
+					FullName: repo.FullName,
+				}
+			}
+			for _, repo := range event.RepositoriesRemoved {
+				delete(repositories, repo.ID)
+			}
+			return database.NewUpdate[user.User]().Set("Repositories", repositories), nil
 		},
-		ConditionExpr: aws.String("id = :id"),
 	})
 	if err != nil {
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
 		if ok := errors.As(err, &cErr); ok {
 			return http.StatusNotFound, fmt.Errorf("user not found")
 		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	for _, repo := range event.RepositoriesAdded {
-		userDoc.Repositories[repo.ID] = user.Repository{
-			Id:       repo.ID,
-			Name:     repo.Name,
-			FullName: repo.FullName,
-		}
-	}
-	for _, repo := range event.RepositoriesRemoved {
-		delete(userDoc.Repositories, repo.ID)
-	}
-
-	repositories, err := attributevalue.Marshal(&userDoc.Repositories)
-	if err != nil {
-		logger.Printf("failed to serialize repositories: %v\n", err)
-		return http.StatusInternalServerError, fmt.Errorf("failed to serialize repositories")
-	}
-
-	_, err = database.UpdateSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-			"id": &dynamodbtypes.AttributeValueMemberS{Value: userDoc.Id},
-		},
-		AttributeNames: map[string]string{
-			"#repositories": "repositories",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":repositories": repositories,
-		},
-		UpdateExpr: aws.String("SET #repositories = :repositories"),
-	})
-	if err != nil {
 		logger.Printf("failed to update user: %v\n", err)
 		return http.StatusInternalServerError, fmt.Errorf("failed to update user")
 	}
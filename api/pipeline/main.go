@@ -13,12 +13,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 
-	"github.com/megakuul/battleshiper/api/pipeline/event"
 	"github.com/megakuul/battleshiper/api/pipeline/routecontext"
+	"github.com/megakuul/battleshiper/api/pipeline/routes"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
-	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var (
@@ -29,6 +29,8 @@ var (
 	SUBSCRIPTIONTABLE            = os.Getenv("SUBSCRIPTIONTABLE")
 	GITHUB_CLIENT_CREDENTIAL_ARN = os.Getenv("GITHUB_CLIENT_CREDENTIAL_ARN")
 	TICKET_CREDENTIAL_ARN        = os.Getenv("TICKET_CREDENTIAL_ARN")
+	TICKET_PUBLIC_KEY_ARN        = os.Getenv("TICKET_PUBLIC_KEY_ARN")
+	ENVIRONMENT_CREDENTIAL_ARN   = os.Getenv("ENVIRONMENT_CREDENTIAL_ARN")
 	BUILD_EVENTBUS_NAME          = os.Getenv("BUILD_EVENTBUS_NAME")
 	BUILD_EVENT_SOURCE           = os.Getenv("BUILD_EVENT_SOURCE")
 	BUILD_EVENT_ACTION           = os.Getenv("BUILD_EVENT_ACTION")
@@ -63,6 +65,8 @@ func run() error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+
 	webhookClient, err := auth.CreateGithubWebhookClient(awsConfig, bootstrapContext, GITHUB_CLIENT_CREDENTIAL_ARN)
 	if err != nil {
 		return err
@@ -74,18 +78,24 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse DEPLOY_EVENT_TICKET_TTL environment variable")
 	}
-	deployTicketOptions, err := pipeline.CreateTicketOptions(
-		awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, DEPLOY_EVENT_SOURCE, DEPLOY_EVENT_ACTION, time.Duration(deployTicketTTL)*time.Second)
+	ticketSigner, err := auth.CreateSigner(awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, TICKET_PUBLIC_KEY_ARN)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load ticket keys: %v", err)
 	}
+	deployTicketOptions := pipeline.CreateTicketOptions(
+		ticketSigner, DEPLOY_EVENT_SOURCE, DEPLOY_EVENT_ACTION, time.Duration(deployTicketTTL)*time.Second)
 
 	githubAppOptions, err := auth.CreateGithubAppOptions(awsConfig, bootstrapContext, GITHUB_CLIENT_CREDENTIAL_ARN)
 	if err != nil {
 		return err
 	}
 
-	httpRouter := router.NewRouter(routecontext.Context{
+	environmentCipher, err := auth.CreateCipher(awsConfig, bootstrapContext, ENVIRONMENT_CREDENTIAL_ARN)
+	if err != nil {
+		return fmt.Errorf("failed to load environment cipher: %v", err)
+	}
+
+	httpRouter := routes.NewRouter(routecontext.Context{
 		DynamoClient:        dynamoClient,
 		UserTable:           USERTABLE,
 		ProjectTable:        PROJECTTABLE,
@@ -93,13 +103,13 @@ func run() error {
 		WebhookClient:       webhookClient,
 		GithubAppOptions:    githubAppOptions,
 		CloudwatchClient:    cloudwatchClient,
-		EventClient:         eventbridgeClient,
+		EventPublisher:      pipeline.NewEventBridgePublisher(eventbridgeClient),
 		BuildEventOptions:   buildEventOptions,
 		DeployTicketOptions: deployTicketOptions,
+		EnvironmentCipher:   environmentCipher,
+		SecretClient:        secretClient,
 	})
 
-	httpRouter.AddRoute("POST", "/api/pipeline/event", event.HandleEvent)
-
 	lambda.Start(httpRouter.Route)
 
 	return nil
@@ -3,7 +3,7 @@ package routecontext
 import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	webhook "github.com/go-playground/webhooks/v6/github"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
@@ -18,7 +18,10 @@ type Context struct {
 	WebhookClient       *webhook.Webhook
 	GithubAppOptions    *auth.GithubAppOptions
 	CloudwatchClient    *cloudwatchlogs.Client
-	EventClient         *eventbridge.Client
+	CloudLoggerOptions  *pipeline.CloudLoggerOptions
+	EventPublisher      pipeline.EventPublisher
 	BuildEventOptions   *pipeline.EventOptions
 	DeployTicketOptions *pipeline.TicketOptions
+	EnvironmentCipher   *auth.Cipher
+	SecretClient        *secretsmanager.Client
 }
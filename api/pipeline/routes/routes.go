@@ -0,0 +1,23 @@
+// routes package registers the http routes of the pipeline api.
+package routes
+
+import (
+	"github.com/megakuul/battleshiper/lib/router"
+
+	"github.com/megakuul/battleshiper/api/pipeline/event"
+	"github.com/megakuul/battleshiper/api/pipeline/routecontext"
+)
+
+// NewRouter creates the router of the pipeline api with all routes registered.
+func NewRouter(routeCtx routecontext.Context) *router.Router[routecontext.Context] {
+	httpRouter := router.NewRouter(routeCtx)
+
+	httpRouter.AddRoute("POST", "/api/pipeline/event", event.HandleEvent)
+
+	httpRouter.AddOpenAPIRoute("/api/pipeline/openapi.json", router.OpenAPIInfo{
+		Title:   "Battleshiper Pipeline API",
+		Version: "1.0.0",
+	})
+
+	return httpRouter
+}
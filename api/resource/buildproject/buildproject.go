@@ -2,8 +2,6 @@ package buildproject
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,27 +10,23 @@ import (
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
-	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
 	"github.com/google/uuid"
 
 	"github.com/megakuul/battleshiper/api/resource/routecontext"
 
 	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 	"github.com/megakuul/battleshiper/lib/model/event"
 	"github.com/megakuul/battleshiper/lib/model/project"
 	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "RESOURCE BUILDPROJECT: ", 0)
 
 type buildProjectInput struct {
-	ProjectName string `json:"project_name"`
+	ProjectName string `json:"project_name" validate:"required"`
 }
 
 type buildProjectOutput struct {
@@ -40,92 +34,28 @@ type buildProjectOutput struct {
 }
 
 // HandleBuildProject manually triggers a project build.
-func HandleBuildProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleBuildProject(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleBuildProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*buildProjectOutput, int, error) {
-	var buildProjectInput buildProjectInput
-	err := json.Unmarshal([]byte(request.Body), &buildProjectInput)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: invalid body")
+func HandleBuildProject(input *buildProjectInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*buildProjectOutput, error) {
+	userDoc, ok := router.GetUser(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
 
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
+	projectDoc, err := routeCtx.ProjectStore.Get(transportCtx, input.ProjectName)
 	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user record from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user record from database")
-	}
-
-	projectDoc, err := database.GetSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.ProjectTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: buildProjectInput.ProjectName},
-		},
-		ConditionExpr: aws.String("project_name = :project_name"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("project not found")
+		if store.IsConditionFailed(err) {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
 		}
 		logger.Printf("failed to load project from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load project from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
 	}
 	if projectDoc.OwnerId != userDoc.Id {
-		return nil, http.StatusForbidden, fmt.Errorf("unauthorized to build this project")
+		return nil, router.Errorf(http.StatusForbidden, "unauthorized to build this project")
 	}
 	if !projectDoc.Initialized {
-		return nil, http.StatusBadRequest, fmt.Errorf("project is not initialized")
+		return nil, router.Errorf(http.StatusBadRequest, "project is not initialized")
 	}
 	if projectDoc.Deleted {
-		return nil, http.StatusBadRequest, fmt.Errorf("project was already deleted")
+		return nil, router.Errorf(http.StatusBadRequest, "project was already deleted")
 	}
 
 	execId := uuid.New().String()
@@ -136,124 +66,93 @@ func runHandleBuildProject(request events.APIGatewayV2HTTPRequest, transportCtx
 		eventResult.Successful = false
 		eventResult.Timepoint = time.Now().Unix()
 
-		eventResultAttributes, sErr := attributevalue.Marshal(&eventResult)
-		if sErr != nil {
-			logger.Printf("failed to serialize eventresult: %v\n", sErr)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to serialize eventresult")
-		}
-
-		_, uErr := database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
-			Table: aws.String(routeCtx.ProjectTable),
-			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
-			},
-			AttributeNames: map[string]string{
-				"#last_event_result": "last_event_result",
-				"#status":            "status",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":last_event_result": eventResultAttributes,
-				":status":            &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("EVENT FAILED: %v", err)},
+		_, uErr := routeCtx.ProjectStore.Update(transportCtx, projectDoc.ProjectName, store.Update{
+			Set: map[string]any{
+				"LastEventResult": &eventResult,
+				"Status":          fmt.Sprintf("EVENT FAILED: %v", err),
 			},
-			UpdateExpr: aws.String("SET #last_event_result = :last_event_result, #status = :status"),
 		})
 		if uErr != nil {
 			logger.Printf("failed to update project: %v\n", uErr)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to update project")
+			return nil, router.Errorf(http.StatusInternalServerError, "failed to update project")
 		}
 		logger.Printf("failed to initiate project build: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to initiate project build")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to initiate project build")
 	} else {
 		eventResult.Successful = true
 		eventResult.Timepoint = time.Now().Unix()
 
-		eventResultAttributes, sErr := attributevalue.Marshal(&eventResult)
-		if sErr != nil {
-			logger.Printf("failed to serialize eventresult: %v\n", sErr)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to serialize eventresult")
-		}
-
-		_, uErr := database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
-			Table: aws.String(routeCtx.ProjectTable),
-			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
-			},
-			AttributeNames: map[string]string{
-				"#last_event_result": "last_event_result",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":last_event_result": eventResultAttributes,
-			},
-			UpdateExpr: aws.String("SET #last_event_result = :last_event_result"),
+		_, uErr := routeCtx.ProjectStore.Update(transportCtx, projectDoc.ProjectName, store.Update{
+			Set: map[string]any{"LastEventResult": &eventResult},
 		})
 		if uErr != nil {
 			logger.Printf("failed to update project: %v\n", uErr)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to update project")
+			return nil, router.Errorf(http.StatusInternalServerError, "failed to update project")
 		}
 	}
 
 	return &buildProjectOutput{
 		Message: "project build initiated",
-	}, http.StatusOK, nil
+	}, nil
 }
 
-func initiateProjectBuild(transportCtx context.Context, routeCtx routecontext.Context, execId string, userDoc *user.User, projectDoc *project.Project) error {
-	cloudLogger, err := pipeline.NewCloudLogger(transportCtx, routeCtx.CloudwatchClient, projectDoc.DedicatedInfrastructure.EventLogGroup, execId)
+func initiateProjectBuild(transportCtx context.Context, routeCtx routecontext.Context, execId string, userDoc *user.User, projectDoc *project.Project) (err error) {
+	cloudLogger, err := pipeline.NewCloudLogger(transportCtx, routeCtx.CloudwatchClient, projectDoc.DedicatedInfrastructure.EventLogGroup, execId, pipeline.LOG_PHASE_EVENT, routeCtx.CloudLoggerOptions)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if cErr := cloudLogger.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
 
 	cloudLogger.WriteLog("START INIT %s", execId)
 	cloudLogger.WriteLog("Event triggered by api request")
-	if err := cloudLogger.PushLogs(); err != nil {
-		return err
-	}
 
 	cloudLogger.WriteLog("Generating installation token...")
 	appClient, err := auth.CreateGithubAppClient(transportCtx, routeCtx.GithubAppOptions)
 	if err != nil {
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
+		cloudLogger.WriteError("failed to generate github app client")
 		return fmt.Errorf("failed to generate github app client: %v", err)
 	}
 	installToken, _, err := appClient.Apps.CreateInstallationToken(transportCtx, userDoc.InstallationId, nil)
 	if err != nil {
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
+		cloudLogger.WriteError("failed to generate installation token")
 		return fmt.Errorf("failed to generate installation token: %v", err)
 	}
 
 	cloudLogger.WriteLog("Emitting event to pipeline...")
 	err = emitBuildEvent(transportCtx, routeCtx, execId, installToken.GetToken(), userDoc, projectDoc)
 	if err != nil {
-		cloudLogger.WriteLog("failed to emit build event: %v", err)
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
+		cloudLogger.WriteError("failed to emit build event: %v", err)
 		return fmt.Errorf("failed to emit build event: %v", err)
-	} else {
-		cloudLogger.WriteLog("project build was successfully initiated")
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 	}
+	cloudLogger.WriteLog("project build was successfully initiated")
 
 	return nil
 }
 
-func emitBuildEvent(transportCtx context.Context, routeCtx routecontext.Context, execId, installToken string, userDoc *user.User, projectDoc *project.Project) error {
-	err := pipeline.CheckBuildSubscriptionLimit(transportCtx, routeCtx.DynamoClient, &pipeline.CheckBuildSubscriptionLimitInput{
+func emitBuildEvent(transportCtx context.Context, routeCtx routecontext.Context, execId, installToken string, userDoc *user.User, projectDoc *project.Project) (err error) {
+	quotaUsage, err := pipeline.CheckQuota(transportCtx, routeCtx.DynamoClient, &pipeline.CheckQuotaInput{
 		UserTable:         routeCtx.UserTable,
 		SubscriptionTable: routeCtx.SubscriptionTable,
 		UserDoc:           *userDoc,
+		Name:              pipeline.QUOTA_PIPELINE_BUILDS,
 	})
 	if err != nil {
 		return err
 	}
+	// the counted build is refunded if the event does not reach the pipeline.
+	defer func() {
+		if err != nil {
+			if rErr := pipeline.RefundQuota(transportCtx, routeCtx.DynamoClient, quotaUsage); rErr != nil {
+				logger.Printf("failed to refund build quota: %v\n", rErr)
+			}
+		}
+	}()
 
-	deployTicket, err := pipeline.CreateTicket(routeCtx.DeployTicketOptions, userDoc.Id, projectDoc.ProjectName)
+	deployTicket, err := pipeline.CreateTicket(routeCtx.DeployTicketOptions, userDoc.Id, projectDoc.ProjectName, execId)
 	if err != nil {
 		return fmt.Errorf("failed to create pipeline ticket")
 	}
@@ -274,24 +173,24 @@ func emitBuildEvent(transportCtx context.Context, routeCtx routecontext.Context,
 		BuildCommand:        projectDoc.BuildCommand,
 		OutputDirectory:     projectDoc.OutputDirectory,
 	}
-	buildRequestRaw, err := json.Marshal(buildRequest)
+	buildEnvironment, err := pipeline.DecryptEnvironment(routeCtx.EnvironmentCipher, projectDoc.Environment.Build)
 	if err != nil {
-		return fmt.Errorf("failed to serialize build request")
+		logger.Printf("failed to load build environment: %v\n", err)
+		return fmt.Errorf("failed to load build environment")
 	}
-
-	eventEntry := eventbridgetypes.PutEventsRequestEntry{
-		Source:       aws.String(routeCtx.BuildEventOptions.Source),
-		DetailType:   aws.String(fmt.Sprintf("%s.%s", routeCtx.BuildEventOptions.Action, projectDoc.ProjectName)),
-		Detail:       aws.String(string(buildRequestRaw)),
-		EventBusName: aws.String(routeCtx.BuildEventOptions.EventBus),
+	// project variables are passed through the build environment secret, the event only contains the request fields.
+	_, err = pipeline.WriteEnvironmentSecret(transportCtx, routeCtx.SecretClient,
+		pipeline.BuildEnvironmentSecretName(projectDoc.ProjectName), pipeline.CreateBuildScript(buildEnvironment))
+	if err != nil {
+		logger.Printf("failed to write build environment: %v\n", err)
+		return fmt.Errorf("failed to write build environment")
 	}
-	res, err := routeCtx.EventClient.PutEvents(transportCtx, &eventbridge.PutEventsInput{
-		Entries: []eventbridgetypes.PutEventsRequestEntry{eventEntry},
-	})
+	buildRequest.Environment = pipeline.CreateBuildEnvironment(buildRequest)
+
+	err = routeCtx.EventPublisher.PublishBuild(transportCtx, routeCtx.BuildEventOptions, projectDoc.ProjectName, buildRequest)
 	if err != nil {
+		logger.Printf("failed to publish build event: %v\n", err)
 		return fmt.Errorf("failed to emit build event to the pipeline")
-	} else if res.FailedEntryCount > 0 {
-		return fmt.Errorf("failed to ingest build event")
 	}
 
 	return nil
@@ -0,0 +1,78 @@
+package createproject
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/store"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+// TestHandleCreateProjectRejected covers the requests that are rejected before any infrastructure is touched.
+func TestHandleCreateProjectRejected(t *testing.T) {
+	tests := []struct {
+		name           string
+		subscriptionId string
+		projectName    string
+		status         int
+	}{
+		{name: "missing subscription", subscriptionId: "missing", projectName: "delta", status: http.StatusBadRequest},
+		{name: "project limit reached", subscriptionId: "single", projectName: "delta", status: http.StatusForbidden},
+		{name: "project name taken", subscriptionId: "basic", projectName: "Beta", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subscriptionStore := store.NewMemorySubscriptionStore()
+			projectStore := store.NewMemoryProjectStore()
+			for _, subscriptionDoc := range []subscription.Subscription{
+				{Id: "single", ProjectSpecs: subscription.ProjectSpecs{ProjectCount: 1}},
+				{Id: "basic", ProjectSpecs: subscription.ProjectSpecs{ProjectCount: 3}},
+			} {
+				if err := subscriptionStore.Put(context.Background(), &subscriptionDoc); err != nil {
+					t.Fatalf("failed to put subscription: %v", err)
+				}
+			}
+			for _, projectDoc := range []project.Project{
+				{ProjectName: "alpha", OwnerId: "user-1"},
+				{ProjectName: "beta", OwnerId: "user-2"},
+			} {
+				if err := projectStore.Create(context.Background(), &projectDoc); err != nil {
+					t.Fatalf("failed to create project: %v", err)
+				}
+			}
+
+			routeCtx := routecontext.Context{
+				ProjectStore:      projectStore,
+				SubscriptionStore: subscriptionStore,
+			}
+			transportCtx := router.WithUser(context.Background(), &user.User{Id: "user-1", SubscriptionId: test.subscriptionId})
+
+			_, err := HandleCreateProject(&createProjectInput{ProjectName: test.projectName}, events.APIGatewayV2HTTPRequest{}, transportCtx, routeCtx)
+			var httpErr *router.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("expected http error, got %v", err)
+			}
+			if httpErr.Code != test.status {
+				t.Errorf("expected status %d, got %d (%s)", test.status, httpErr.Code, httpErr.Message)
+			}
+
+			projectDocs, err := projectStore.ListByOwner(context.Background(), "user-1")
+			if err != nil {
+				t.Fatalf("failed to list projects: %v", err)
+			}
+			if len(projectDocs) != 1 {
+				t.Errorf("expected the rejected project not to be created, got %d projects", len(projectDocs))
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package fetchlog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+func TestFetchLogCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "missing count", body: `{"project_name":"test","log_type":"build"}`, status: http.StatusBadRequest},
+		{name: "zero count", body: `{"project_name":"test","log_type":"build","count":0}`, status: http.StatusBadRequest},
+		{name: "count above max", body: `{"project_name":"test","log_type":"build","count":51}`, status: http.StatusBadRequest},
+		// valid requests pass the validation and are rejected by the handler because no user is authenticated.
+		{name: "min count", body: `{"project_name":"test","log_type":"build","count":1}`, status: http.StatusUnauthorized},
+		{name: "max count", body: `{"project_name":"test","log_type":"build","count":50}`, status: http.StatusUnauthorized},
+	}
+
+	httpRouter := router.NewRouter(routecontext.Context{})
+	router.AddJSONRoute(httpRouter, "POST", "/api/resource/fetchlog", HandleFetchLog)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := events.APIGatewayV2HTTPRequest{Body: test.body}
+			request.RequestContext.HTTP.Method = "POST"
+			request.RequestContext.HTTP.Path = "/api/resource/fetchlog"
+
+			response, err := httpRouter.Route(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != test.status {
+				t.Errorf("expected status %d, got %d (%s)", test.status, response.StatusCode, response.Body)
+			}
+		})
+	}
+}
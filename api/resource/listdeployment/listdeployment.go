@@ -0,0 +1,100 @@
+package listdeployment
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/model/deployment"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "RESOURCE LISTDEPLOYMENT: ", 0)
+
+type listDeploymentInput struct {
+	ProjectName string `query:"project_name" validate:"required"`
+}
+
+type deploymentOutput struct {
+	ExecutionIdentifier string `json:"execution_identifier"`
+	Timestamp           int64  `json:"timestamp"`
+	Commit              string `json:"commit"`
+	PageCount           int    `json:"page_count"`
+	Active              bool   `json:"active"`
+}
+
+type listDeploymentOutput struct {
+	Message     string             `json:"message"`
+	Deployments []deploymentOutput `json:"deployments"`
+}
+
+// HandleListDeployment lists the retained deployments of the project, the newest deployment comes first.
+func HandleListDeployment(input *listDeploymentInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*listDeploymentOutput, error) {
+	userToken, ok := router.GetUserClaims(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
+	}
+
+	projectDoc, err := database.GetSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: input.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
+		}
+		logger.Printf("failed to load project from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
+	}
+	if projectDoc.OwnerId != userToken.Id {
+		return nil, router.Errorf(http.StatusForbidden, "unauthorized to read the deployments of this project")
+	}
+
+	// the history is limited by the deployment retention, therefore all records are fetched at once.
+	deploymentDocs, err := database.GetMany[deployment.Deployment](transportCtx, routeCtx.DynamoClient, &database.GetManyInput{
+		Table: aws.String(routeCtx.DeploymentTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		logger.Printf("failed to load deployments from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load deployments from database")
+	}
+
+	sort.Slice(deploymentDocs, func(i, j int) bool {
+		return deploymentDocs[i].Timepoint > deploymentDocs[j].Timepoint
+	})
+
+	deploymentOutputs := []deploymentOutput{}
+	for _, deploymentDoc := range deploymentDocs {
+		deploymentOutputs = append(deploymentOutputs, deploymentOutput{
+			ExecutionIdentifier: deploymentDoc.ExecutionIdentifier,
+			Timestamp:           deploymentDoc.Timepoint,
+			Commit:              deploymentDoc.Commit,
+			PageCount:           len(deploymentDoc.PageKeys),
+			Active:              deploymentDoc.ExecutionIdentifier == projectDoc.ActiveDeployment,
+		})
+	}
+
+	return &listDeploymentOutput{
+		Message:     "deployments fetched",
+		Deployments: deploymentOutputs,
+	}, nil
+}
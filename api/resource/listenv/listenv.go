@@ -0,0 +1,99 @@
+package listenv
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "RESOURCE LISTENV: ", 0)
+
+type listEnvInput struct {
+	ProjectName string `query:"project_name" validate:"required"`
+}
+
+type variableOutput struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	// Value is empty for secret variables.
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+type listEnvOutput struct {
+	Message   string           `json:"message"`
+	Variables []variableOutput `json:"variables"`
+	Version   int64            `json:"version"`
+}
+
+// HandleListEnv lists the environment variables of the project, the values of secret variables are not returned.
+func HandleListEnv(input *listEnvInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*listEnvOutput, error) {
+	userToken, ok := router.GetUserClaims(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
+	}
+
+	projectDoc, err := database.GetSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: input.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
+		}
+		logger.Printf("failed to load project from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
+	}
+	if projectDoc.OwnerId != userToken.Id {
+		return nil, router.Errorf(http.StatusForbidden, "unauthorized to read the environment of this project")
+	}
+
+	variables := append(
+		scopeVariables(pipeline.ENV_SCOPE_BUILD, projectDoc.Environment.Build),
+		scopeVariables(pipeline.ENV_SCOPE_RUNTIME, projectDoc.Environment.Runtime)...,
+	)
+
+	return &listEnvOutput{
+		Message:   "environment fetched",
+		Variables: variables,
+		Version:   projectDoc.Version,
+	}, nil
+}
+
+// scopeVariables converts the variables of a scope to the output sorted by name.
+func scopeVariables(scope string, variables map[string]project.EnvironmentVariable) []variableOutput {
+	outputs := []variableOutput{}
+	for name, variable := range variables {
+		output := variableOutput{
+			Name:   name,
+			Scope:  scope,
+			Secret: variable.Secret,
+		}
+		if !variable.Secret {
+			output.Value = variable.Value
+		}
+		outputs = append(outputs, output)
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Name < outputs[j].Name
+	})
+	return outputs
+}
@@ -2,8 +2,7 @@ package listproject
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -15,13 +14,15 @@ import (
 
 	"github.com/megakuul/battleshiper/api/resource/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "RESOURCE LISTPROJECT: ", 0)
 
+const PROJECT_PAGE_SIZE = 50
+
 type eventResultOutput struct {
 	ExecutionIdentifier string `json:"execution_identifier"`
 	Timestamp           int64  `json:"timestamp"`
@@ -46,11 +47,23 @@ type repositoryOutput struct {
 	Branch string `json:"branch"`
 }
 
+type trafficShiftOutput struct {
+	Strategy       string `json:"strategy"`
+	Percentage     int64  `json:"percentage"`
+	Interval       int64  `json:"interval"`
+	ErrorThreshold int64  `json:"error_threshold"`
+}
+
+type usageOutput struct {
+	PrerenderRoutes int64 `json:"prerender_routes"`
+}
+
 type projectOutput struct {
 	Name                 string                 `json:"name"`
 	Deleted              bool                   `json:"deleted"`
 	Initialized          bool                   `json:"initialized"`
 	Status               string                 `json:"status"`
+	Version              int64                  `json:"version"`
 	BuildImage           string                 `json:"build_image"`
 	BuildCommand         string                 `json:"build_command"`
 	OutputDirectory      string                 `json:"output_directory"`
@@ -59,66 +72,49 @@ type projectOutput struct {
 	LastEventResult      eventResultOutput      `json:"last_event_result"`
 	LastBuildResult      buildResultOutput      `json:"last_build_result"`
 	LastDeploymentResult deploymentResultOutput `json:"last_deployment_result"`
+	ActiveDeployment     string                 `json:"active_deployment"`
+	TrafficShift         trafficShiftOutput     `json:"traffic_shift"`
+	Usage                usageOutput            `json:"usage"`
 }
 
-type listProjectOutput struct {
-	Message  string          `json:"message"`
-	Projects []projectOutput `json:"projects"`
+type listProjectInput struct {
+	Cursor string `query:"cursor"`
 }
 
-// HandleListProject performs a lookup for the projects that are owned by the user and returns them as json object.
-func HandleListProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleListProject(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
+type listProjectOutput struct {
+	Message    string          `json:"message"`
+	Projects   []projectOutput `json:"projects"`
+	NextCursor string          `json:"next_cursor"`
 }
 
-func runHandleListProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*listProjectOutput, int, error) {
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
+// HandleListProject performs a lookup for the projects that are owned by the user and returns them as json object.
+// Projects are returned in pages, the next_cursor of the output is passed as cursor to fetch the next page.
+func HandleListProject(input *listProjectInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*listProjectOutput, error) {
+	userToken, ok := router.GetUserClaims(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
 
-	foundProjectDocs, err := database.GetMany[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetManyInput{
+	foundProjectDocs, nextCursor, err := database.GetPage[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetPageInput{
 		Table: aws.String(routeCtx.ProjectTable),
 		Index: aws.String(project.GSI_OWNER_ID),
 		AttributeValues: map[string]dynamodbtypes.AttributeValue{
 			":owner_id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
 		},
 		ConditionExpr: aws.String("owner_id = :owner_id"),
+		PageSize:      PROJECT_PAGE_SIZE,
+		Cursor:        input.Cursor,
+		CursorOptions: &database.CursorOptions{
+			Secret: routeCtx.CursorSecret,
+			Scope:  "listproject:" + userToken.Id,
+		},
 	})
 	if err != nil {
+		if errors.Is(err, database.ErrInvalidCursor) {
+			return nil, router.Errorf(http.StatusBadRequest, "invalid cursor")
+		}
 		logger.Printf("failed load projects from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed load projects from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed load projects from database")
 	}
 
 	foundProjectOutput := []projectOutput{}
@@ -128,6 +124,7 @@ func runHandleListProject(request events.APIGatewayV2HTTPRequest, transportCtx c
 			Deleted:         project.Deleted,
 			Initialized:     project.Initialized,
 			Status:          project.Status,
+			Version:         project.Version,
 			BuildImage:      project.BuildImage,
 			BuildCommand:    project.BuildCommand,
 			OutputDirectory: project.OutputDirectory,
@@ -146,17 +143,29 @@ func runHandleListProject(request events.APIGatewayV2HTTPRequest, transportCtx c
 				Timestamp:           project.LastDeploymentResult.Timepoint,
 				Successful:          project.LastDeploymentResult.Successful,
 			},
+			ActiveDeployment: project.ActiveDeployment,
+			TrafficShift: trafficShiftOutput{
+				Strategy:       project.TrafficShift.Strategy,
+				Percentage:     project.TrafficShift.Percentage,
+				Interval:       project.TrafficShift.Interval,
+				ErrorThreshold: project.TrafficShift.ErrorThreshold,
+			},
 			Aliases: project.Aliases,
 			Repository: repositoryOutput{
 				Id:     project.Repository.Id,
 				URL:    project.Repository.URL,
 				Branch: project.Repository.Branch,
 			},
+			Usage: usageOutput{
+				// every page key of the last deployment occupies one prerender route.
+				PrerenderRoutes: int64(len(project.SharedInfrastructure.PrerenderPageKeys)),
+			},
 		})
 	}
 
 	return &listProjectOutput{
-		Message:  "projects fetched",
-		Projects: foundProjectOutput,
-	}, http.StatusOK, nil
+		Message:    "projects fetched",
+		Projects:   foundProjectOutput,
+		NextCursor: nextCursor,
+	}, nil
 }
@@ -14,19 +14,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 
-	"github.com/megakuul/battleshiper/api/resource/buildproject"
-	"github.com/megakuul/battleshiper/api/resource/createproject"
-	"github.com/megakuul/battleshiper/api/resource/deleteproject"
-	"github.com/megakuul/battleshiper/api/resource/fetchlog"
-	"github.com/megakuul/battleshiper/api/resource/listproject"
-	"github.com/megakuul/battleshiper/api/resource/listrepository"
 	"github.com/megakuul/battleshiper/api/resource/routecontext"
-	"github.com/megakuul/battleshiper/api/resource/updatealias"
-	"github.com/megakuul/battleshiper/api/resource/updateproject"
+	"github.com/megakuul/battleshiper/api/resource/routes"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
-	"github.com/megakuul/battleshiper/lib/router"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 )
 
 var (
@@ -35,9 +30,13 @@ var (
 	USERTABLE                    = os.Getenv("USERTABLE")
 	PROJECTTABLE                 = os.Getenv("PROJECTTABLE")
 	SUBSCRIPTIONTABLE            = os.Getenv("SUBSCRIPTIONTABLE")
-	JWT_CREDENTIAL_ARN           = os.Getenv("JWT_CREDENTIAL_ARN")
+	DEPLOYMENTTABLE              = os.Getenv("DEPLOYMENTTABLE")
+	JWT_PUBLIC_KEY_ARN           = os.Getenv("JWT_PUBLIC_KEY_ARN")
+	CURSOR_CREDENTIAL_ARN        = os.Getenv("CURSOR_CREDENTIAL_ARN")
+	ENVIRONMENT_CREDENTIAL_ARN   = os.Getenv("ENVIRONMENT_CREDENTIAL_ARN")
 	GITHUB_CLIENT_CREDENTIAL_ARN = os.Getenv("GITHUB_CLIENT_CREDENTIAL_ARN")
 	TICKET_CREDENTIAL_ARN        = os.Getenv("TICKET_CREDENTIAL_ARN")
+	TICKET_PUBLIC_KEY_ARN        = os.Getenv("TICKET_PUBLIC_KEY_ARN")
 	INIT_EVENTBUS_NAME           = os.Getenv("INIT_EVENTBUS_NAME")
 	INIT_EVENT_SOURCE            = os.Getenv("INIT_EVENT_SOURCE")
 	INIT_EVENT_ACTION            = os.Getenv("INIT_EVENT_ACTION")
@@ -52,6 +51,10 @@ var (
 	DELETE_EVENT_SOURCE          = os.Getenv("DELETE_EVENT_SOURCE")
 	DELETE_EVENT_ACTION          = os.Getenv("DELETE_EVENT_ACTION")
 	DELETE_EVENT_TICKET_TTL      = os.Getenv("DELETE_EVENT_TICKET_TTL")
+	ROLLBACK_EVENTBUS_NAME       = os.Getenv("ROLLBACK_EVENTBUS_NAME")
+	ROLLBACK_EVENT_SOURCE        = os.Getenv("ROLLBACK_EVENT_SOURCE")
+	ROLLBACK_EVENT_ACTION        = os.Getenv("ROLLBACK_EVENT_ACTION")
+	ROLLBACK_EVENT_TICKET_TTL    = os.Getenv("ROLLBACK_EVENT_TICKET_TTL")
 	CLOUDFRONT_CACHE_ARN         = os.Getenv("CLOUDFRONT_CACHE_ARN")
 )
 
@@ -83,20 +86,34 @@ func run() error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
-	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_CREDENTIAL_ARN, 0)
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+
+	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_PUBLIC_KEY_ARN, 0)
 	if err != nil {
 		return err
 	}
 
-	initTicketTTL, err := strconv.Atoi(INIT_EVENT_TICKET_TTL)
+	cursorSecret, err := database.LoadCursorSecret(awsConfig, bootstrapContext, CURSOR_CREDENTIAL_ARN)
 	if err != nil {
-		return fmt.Errorf("failed to parse INIT_EVENT_TICKET_TTL environment variable")
+		return err
 	}
-	initTicketOptions, err := pipeline.CreateTicketOptions(
-		awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, INIT_EVENT_SOURCE, INIT_EVENT_ACTION, time.Duration(initTicketTTL)*time.Second)
+
+	environmentCipher, err := auth.CreateCipher(awsConfig, bootstrapContext, ENVIRONMENT_CREDENTIAL_ARN)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load environment cipher: %v", err)
+	}
+
+	ticketSigner, err := auth.CreateSigner(awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, TICKET_PUBLIC_KEY_ARN)
+	if err != nil {
+		return fmt.Errorf("failed to load ticket keys: %v", err)
+	}
+
+	initTicketTTL, err := strconv.Atoi(INIT_EVENT_TICKET_TTL)
+	if err != nil {
+		return fmt.Errorf("failed to parse INIT_EVENT_TICKET_TTL environment variable")
 	}
+	initTicketOptions := pipeline.CreateTicketOptions(
+		ticketSigner, INIT_EVENT_SOURCE, INIT_EVENT_ACTION, time.Duration(initTicketTTL)*time.Second)
 	initEventOptions := pipeline.CreateEventOptions(INIT_EVENTBUS_NAME, INIT_EVENT_SOURCE, INIT_EVENT_ACTION, initTicketOptions)
 
 	buildEventOptions := pipeline.CreateEventOptions(BUILD_EVENTBUS_NAME, BUILD_EVENT_SOURCE, BUILD_EVENT_ACTION, nil)
@@ -105,54 +122,55 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse DEPLOY_EVENT_TICKET_TTL environment variable")
 	}
-	deployTicketOptions, err := pipeline.CreateTicketOptions(
-		awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, DEPLOY_EVENT_SOURCE, DEPLOY_EVENT_ACTION, time.Duration(deployTicketTTL)*time.Second)
-	if err != nil {
-		return err
-	}
+	deployTicketOptions := pipeline.CreateTicketOptions(
+		ticketSigner, DEPLOY_EVENT_SOURCE, DEPLOY_EVENT_ACTION, time.Duration(deployTicketTTL)*time.Second)
 
 	deleteTicketTTL, err := strconv.Atoi(DELETE_EVENT_TICKET_TTL)
 	if err != nil {
 		return fmt.Errorf("failed to parse DELETE_EVENT_TICKET_TTL environment variable")
 	}
-	deleteTicketOptions, err := pipeline.CreateTicketOptions(
-		awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, time.Duration(deleteTicketTTL)*time.Second)
+	deleteTicketOptions := pipeline.CreateTicketOptions(
+		ticketSigner, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, time.Duration(deleteTicketTTL)*time.Second)
+	deleteEventOptions := pipeline.CreateEventOptions(DELETE_EVENTBUS_NAME, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, deleteTicketOptions)
+
+	rollbackTicketTTL, err := strconv.Atoi(ROLLBACK_EVENT_TICKET_TTL)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to parse ROLLBACK_EVENT_TICKET_TTL environment variable")
 	}
-	deleteEventOptions := pipeline.CreateEventOptions(DELETE_EVENTBUS_NAME, DELETE_EVENT_SOURCE, DELETE_EVENT_ACTION, deleteTicketOptions)
+	rollbackTicketOptions := pipeline.CreateTicketOptions(
+		ticketSigner, ROLLBACK_EVENT_SOURCE, ROLLBACK_EVENT_ACTION, time.Duration(rollbackTicketTTL)*time.Second)
+	rollbackEventOptions := pipeline.CreateEventOptions(ROLLBACK_EVENTBUS_NAME, ROLLBACK_EVENT_SOURCE, ROLLBACK_EVENT_ACTION, rollbackTicketOptions)
 
 	githubAppOptions, err := auth.CreateGithubAppOptions(awsConfig, bootstrapContext, GITHUB_CLIENT_CREDENTIAL_ARN)
 	if err != nil {
 		return err
 	}
 
-	httpRouter := router.NewRouter(routecontext.Context{
+	httpRouter := routes.NewRouter(routecontext.Context{
 		DynamoClient:          dynamoClient,
 		UserTable:             USERTABLE,
 		ProjectTable:          PROJECTTABLE,
 		SubscriptionTable:     SUBSCRIPTIONTABLE,
+		DeploymentTable:       DEPLOYMENTTABLE,
+		UserStore:             store.NewDynamoUserStore(dynamoClient, USERTABLE),
+		ProjectStore:          store.NewDynamoProjectStore(dynamoClient, PROJECTTABLE),
+		SubscriptionStore:     store.NewDynamoSubscriptionStore(dynamoClient, SUBSCRIPTIONTABLE),
 		CloudwatchClient:      cloudwatchClient,
 		GithubAppOptions:      githubAppOptions,
 		JwtOptions:            jwtOptions,
-		EventClient:           eventClient,
+		CursorSecret:          cursorSecret,
+		EnvironmentCipher:     environmentCipher,
+		SecretClient:          secretClient,
+		EventPublisher:        pipeline.NewEventBridgePublisher(eventClient),
 		InitEventOptions:      initEventOptions,
 		BuildEventOptions:     buildEventOptions,
 		DeployTicketOptions:   deployTicketOptions,
 		DeleteEventOptions:    deleteEventOptions,
+		RollbackEventOptions:  rollbackEventOptions,
 		CloudfrontCacheClient: cloudfrontClient,
 		CloudfrontCacheArn:    CLOUDFRONT_CACHE_ARN,
 	})
 
-	httpRouter.AddRoute("GET", "/api/resource/listrepository", listrepository.HandleListRepositories)
-	httpRouter.AddRoute("GET", "/api/resource/listproject", listproject.HandleListProject)
-	httpRouter.AddRoute("POST", "/api/resource/fetchlog", fetchlog.HandleFetchLog)
-	httpRouter.AddRoute("POST", "/api/resource/createproject", createproject.HandleCreateProject)
-	httpRouter.AddRoute("POST", "/api/resource/buildproject", buildproject.HandleBuildProject)
-	httpRouter.AddRoute("POST", "/api/resource/updatealias", updatealias.HandleUpdateAlias)
-	httpRouter.AddRoute("PATCH", "/api/resource/updateproject", updateproject.HandleUpdateProject)
-	httpRouter.AddRoute("DELETE", "/api/resource/deleteproject", deleteproject.HandleDeleteProject)
-
 	lambda.Start(httpRouter.Route)
 
 	return nil
@@ -0,0 +1,110 @@
+package rollbackproject
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/deployment"
+	"github.com/megakuul/battleshiper/lib/model/event"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "RESOURCE ROLLBACKPROJECT: ", 0)
+
+type rollbackProjectInput struct {
+	ProjectName         string `json:"project_name" validate:"required"`
+	ExecutionIdentifier string `json:"execution_identifier" validate:"required"`
+}
+
+type rollbackProjectOutput struct {
+	Message string `json:"message"`
+}
+
+// HandleRollbackProject initiates the rollback of the project to a retained deployment.
+// The deployment is restored by the deploy pipeline from the retained artifacts, the project is not rebuilt.
+func HandleRollbackProject(input *rollbackProjectInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*rollbackProjectOutput, error) {
+	userToken, ok := router.GetUserClaims(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
+	}
+
+	projectDoc, err := database.GetSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: input.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
+		}
+		logger.Printf("failed to load project from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
+	}
+	if projectDoc.OwnerId != userToken.Id {
+		return nil, router.Errorf(http.StatusForbidden, "unauthorized to roll back this project")
+	}
+	if !projectDoc.Initialized {
+		return nil, router.Errorf(http.StatusBadRequest, "project is not initialized")
+	}
+	if projectDoc.Deleted {
+		return nil, router.Errorf(http.StatusBadRequest, "project was already deleted")
+	}
+	if projectDoc.PipelineLock {
+		return nil, router.Errorf(http.StatusConflict, "project pipeline is running; try again after the current deployment")
+	}
+	if projectDoc.ActiveDeployment == input.ExecutionIdentifier {
+		return nil, router.Errorf(http.StatusBadRequest, "deployment is already active")
+	}
+
+	_, err = database.GetSingle[deployment.Deployment](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.DeploymentTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name":         &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+			":execution_identifier": &dynamodbtypes.AttributeValueMemberS{Value: input.ExecutionIdentifier},
+		},
+		ConditionExpr: aws.String("project_name = :project_name AND execution_identifier = :execution_identifier"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "deployment not found; it may exceed the deployment retention")
+		}
+		logger.Printf("failed to load deployment from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load deployment from database")
+	}
+
+	execId := uuid.New().String()
+	rollbackTicket, err := pipeline.CreateTicket(routeCtx.RollbackEventOptions.TicketOpts, userToken.Id, projectDoc.ProjectName, execId)
+	if err != nil {
+		logger.Printf("failed to create pipeline ticket: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to create pipeline ticket")
+	}
+	err = routeCtx.EventPublisher.PublishRollback(transportCtx, routeCtx.RollbackEventOptions, &event.RollbackRequest{
+		RollbackTicket:       rollbackTicket,
+		DeploymentIdentifier: input.ExecutionIdentifier,
+	})
+	if err != nil {
+		logger.Printf("failed to publish rollback event: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to publish rollback event")
+	}
+
+	return &rollbackProjectOutput{
+		Message: "project rollback initiated",
+	}, nil
+}
@@ -4,9 +4,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 )
 
 // Context provides data to route handlers.
@@ -15,14 +16,23 @@ type Context struct {
 	UserTable             string
 	ProjectTable          string
 	SubscriptionTable     string
+	DeploymentTable       string
+	UserStore             store.UserStore
+	ProjectStore          store.ProjectStore
+	SubscriptionStore     store.SubscriptionStore
 	CloudwatchClient      *cloudwatchlogs.Client
+	CloudLoggerOptions    *pipeline.CloudLoggerOptions
 	GithubAppOptions      *auth.GithubAppOptions
 	JwtOptions            *auth.JwtOptions
-	EventClient           *eventbridge.Client
+	CursorSecret          string
+	EnvironmentCipher     *auth.Cipher
+	SecretClient          *secretsmanager.Client
+	EventPublisher        pipeline.EventPublisher
 	InitEventOptions      *pipeline.EventOptions
 	BuildEventOptions     *pipeline.EventOptions
 	DeployTicketOptions   *pipeline.TicketOptions
 	DeleteEventOptions    *pipeline.EventOptions
+	RollbackEventOptions  *pipeline.EventOptions
 	CloudfrontCacheClient *cloudfrontkeyvaluestore.Client
 	CloudfrontCacheArn    string
 }
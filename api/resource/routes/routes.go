@@ -0,0 +1,50 @@
+// routes package registers the http routes of the resource api.
+package routes
+
+import (
+	"github.com/megakuul/battleshiper/lib/router"
+
+	"github.com/megakuul/battleshiper/api/resource/buildproject"
+	"github.com/megakuul/battleshiper/api/resource/createproject"
+	"github.com/megakuul/battleshiper/api/resource/deleteenv"
+	"github.com/megakuul/battleshiper/api/resource/deleteproject"
+	"github.com/megakuul/battleshiper/api/resource/fetchlog"
+	"github.com/megakuul/battleshiper/api/resource/listdeployment"
+	"github.com/megakuul/battleshiper/api/resource/listenv"
+	"github.com/megakuul/battleshiper/api/resource/listproject"
+	"github.com/megakuul/battleshiper/api/resource/listrepository"
+	"github.com/megakuul/battleshiper/api/resource/rollbackproject"
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+	"github.com/megakuul/battleshiper/api/resource/updatealias"
+	"github.com/megakuul/battleshiper/api/resource/updateproject"
+	"github.com/megakuul/battleshiper/api/resource/upsertenv"
+)
+
+// NewRouter creates the router of the resource api with all routes registered.
+func NewRouter(routeCtx routecontext.Context) *router.Router[routecontext.Context] {
+	httpRouter := router.NewRouter(routeCtx)
+
+	httpRouter.Use(router.Authenticate(routeCtx.JwtOptions))
+	userLoader := router.LoadUser(routeCtx.DynamoClient, routeCtx.UserTable)
+
+	router.AddJSONRoute(httpRouter, "GET", "/api/resource/listrepository", listrepository.HandleListRepositories, userLoader)
+	router.AddJSONRoute(httpRouter, "GET", "/api/resource/listproject", listproject.HandleListProject)
+	router.AddJSONRoute(httpRouter, "POST", "/api/resource/fetchlog", fetchlog.HandleFetchLog)
+	router.AddJSONRoute(httpRouter, "POST", "/api/resource/createproject", createproject.HandleCreateProject, userLoader)
+	router.AddJSONRoute(httpRouter, "POST", "/api/resource/buildproject", buildproject.HandleBuildProject, userLoader)
+	router.AddJSONRoute(httpRouter, "POST", "/api/resource/updatealias", updatealias.HandleUpdateAlias, userLoader)
+	router.AddJSONRoute(httpRouter, "PATCH", "/api/resource/updateproject", updateproject.HandleUpdateProject, userLoader)
+	router.AddJSONRoute(httpRouter, "DELETE", "/api/resource/deleteproject", deleteproject.HandleDeleteProject)
+	router.AddJSONRoute(httpRouter, "GET", "/api/resource/listdeployment", listdeployment.HandleListDeployment)
+	router.AddJSONRoute(httpRouter, "POST", "/api/resource/rollbackproject", rollbackproject.HandleRollbackProject)
+	router.AddJSONRoute(httpRouter, "GET", "/api/resource/project/env", listenv.HandleListEnv)
+	router.AddJSONRoute(httpRouter, "PUT", "/api/resource/project/env", upsertenv.HandleUpsertEnv, userLoader)
+	router.AddJSONRoute(httpRouter, "DELETE", "/api/resource/project/env", deleteenv.HandleDeleteEnv, userLoader)
+
+	httpRouter.AddOpenAPIRoute("/api/resource/openapi.json", router.OpenAPIInfo{
+		Title:   "Battleshiper Resource API",
+		Version: "1.0.0",
+	})
+
+	return httpRouter
+}
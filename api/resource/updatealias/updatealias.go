@@ -2,7 +2,6 @@ package updatealias
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -12,27 +11,24 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	cloudfrontkeyvaluetypes "github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/megakuul/battleshiper/api/resource/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/model/project"
 	"github.com/megakuul/battleshiper/lib/model/subscription"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
-const MAX_ALIAS_SIZE = 30
-
 var logger = log.New(os.Stderr, "RESOURCE UPDATEALIAS: ", 0)
 
 type updateAliasInput struct {
-	ProjectName string              `json:"project_name"`
-	Aliases     map[string]struct{} `json:"aliases"`
+	ProjectName string              `json:"project_name" validate:"required"`
+	Aliases     map[string]struct{} `json:"aliases" validate:"keymax=30"`
+	Version     *int64              `json:"version"`
 }
 
 type updateAliasOutput struct {
@@ -40,93 +36,39 @@ type updateAliasOutput struct {
 }
 
 // HandleUpdateAlias updates specified aliases on the cdn cache.
-func HandleUpdateAlias(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleUpdateAlias(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleUpdateAlias(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*updateAliasOutput, int, error) {
-	var updateAliasInput updateAliasInput
-	err := json.Unmarshal([]byte(request.Body), &updateAliasInput)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: invalid body")
-	}
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user from database")
+func HandleUpdateAlias(input *updateAliasInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*updateAliasOutput, error) {
+	userDoc, ok := router.GetUser(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
 
 	projectDoc, err := database.GetSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
 		Table: aws.String(routeCtx.ProjectTable),
 		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: updateAliasInput.ProjectName},
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: input.ProjectName},
 		},
 		ConditionExpr: aws.String("project_name = :project_name"),
 	})
 	if err != nil {
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
 		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("project not found")
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
 		}
 		logger.Printf("failed to load project from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load project from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
 	}
 	if projectDoc.OwnerId != userDoc.Id {
-		return nil, http.StatusForbidden, fmt.Errorf("unauthorized to build this project")
+		return nil, router.Errorf(http.StatusForbidden, "unauthorized to build this project")
 	}
 	if projectDoc.Deleted {
-		return nil, http.StatusBadRequest, fmt.Errorf("project was already deleted")
+		return nil, router.Errorf(http.StatusBadRequest, "project was already deleted")
+	}
+	if input.Version != nil && *input.Version != projectDoc.Version {
+		return nil, router.Errorf(http.StatusConflict, "project was modified in the meantime; reload the project and try again")
 	}
 
-	if err := validateAliases(projectDoc.ProjectName, updateAliasInput.Aliases); err != nil {
-		return nil, http.StatusBadRequest, err
+	if err := validateAliases(projectDoc.ProjectName, input.Aliases); err != nil {
+		return nil, router.Errorf(http.StatusBadRequest, "%v", err)
 	}
 
 	subscriptionDoc, err := database.GetSingle[subscription.Subscription](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
@@ -139,52 +81,45 @@ func runHandleUpdateAlias(request events.APIGatewayV2HTTPRequest, transportCtx c
 	if err != nil {
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
 		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusBadRequest, fmt.Errorf("user does not have a valid subscription associated")
+			return nil, router.Errorf(http.StatusBadRequest, "user does not have a valid subscription associated")
 		}
 		logger.Printf("failed to load subscription from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load subscription from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load subscription from database")
 	}
 
-	if len(updateAliasInput.Aliases) > int(subscriptionDoc.ProjectSpecs.AliasCount) {
-		return nil, http.StatusBadRequest, fmt.Errorf("subscription limit reached; no additional aliases can be created")
-	}
-
-	if err := updateAliases(transportCtx, routeCtx, projectDoc.ProjectName, projectDoc.Aliases, updateAliasInput.Aliases); err != nil {
-		logger.Printf("%v\n", err)
-		return nil, http.StatusInternalServerError, err
+	if len(input.Aliases) > int(subscriptionDoc.ProjectSpecs.AliasCount) {
+		return nil, router.Errorf(http.StatusBadRequest, "subscription limit reached; no additional aliases can be created")
 	}
 
-	aliasAttributes, err := attributevalue.Marshal(&updateAliasInput.Aliases)
-	if err != nil {
-		logger.Printf("failed to serialize alias attributes: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to serialize alias attributes")
-	}
-
-	_, err = database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
-		Table: aws.String(routeCtx.ProjectTable),
-		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
-		},
-		AttributeNames: map[string]string{
-			"#aliases": "aliases",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":aliases": aliasAttributes,
-		},
-		UpdateExpr: aws.String("SET #aliases = :aliases"),
-	})
+	// the aliases are written to the database first, the version condition ensures that
+	// the old aliases removed from the cdn store below are the ones that were active before this update.
+	updatedProjectDoc, err := setAliases(transportCtx, routeCtx, projectDoc.ProjectName, input.Aliases, projectDoc.Version)
 	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, router.Errorf(http.StatusConflict, "project was modified in the meantime; reload the project and try again")
+		}
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
 		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("project not found")
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
 		}
-		logger.Printf("failed to load project from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load project from database")
+		logger.Printf("failed to update project on database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to update project on database")
+	}
+
+	if err := updateAliases(transportCtx, routeCtx, projectDoc.ProjectName, projectDoc.Aliases, input.Aliases); err != nil {
+		logger.Printf("%v\n", err)
+		// restore the old aliases unless the project was modified again in the meantime,
+		// otherwise the database would list aliases that are not served by the cdn.
+		_, restoreErr := setAliases(transportCtx, routeCtx, projectDoc.ProjectName, projectDoc.Aliases, updatedProjectDoc.Version)
+		if restoreErr != nil {
+			logger.Printf("failed to restore aliases on database: %v\n", restoreErr)
+		}
+		return nil, router.Errorf(http.StatusInternalServerError, "%v", err)
 	}
 
 	return &updateAliasOutput{
 		Message: "aliases updated",
-	}, http.StatusOK, nil
+	}, nil
 }
 
 // validateAliases checks if the aliases are valid.
@@ -192,9 +127,6 @@ func validateAliases(projectName string, aliases map[string]struct{}) error {
 	expectedSuffix := fmt.Sprintf(".%s", projectName)
 
 	for alias := range aliases {
-		if len(alias) > MAX_ALIAS_SIZE {
-			return fmt.Errorf("invalid alias: alias cannot be longer then %d", MAX_ALIAS_SIZE)
-		}
 		if !strings.HasSuffix(alias, expectedSuffix) && alias != projectName {
 			return fmt.Errorf("invalid alias: alias must end with '%s'", expectedSuffix)
 		}
@@ -203,6 +135,29 @@ func validateAliases(projectName string, aliases map[string]struct{}) error {
 	return nil
 }
 
+// setAliases replaces the aliases of the project on the database if the project holds the expected version.
+func setAliases(transportCtx context.Context, routeCtx routecontext.Context, projectName string, aliases map[string]struct{}, version int64) (*project.Project, error) {
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("Aliases", aliases).
+		IfVersion(version).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update expression: %v", err)
+	}
+
+	return database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectName},
+		},
+		ExpectedVersion: updateExpression.ExpectedVersion,
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		ConditionExpr:   updateExpression.ConditionExpr,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+}
+
 // updateAliases merges the old and new aliases and uploads them to the cloudfront cache.
 func updateAliases(transportCtx context.Context, routeCtx routecontext.Context, projectName string, oldAliases, newAliases map[string]struct{}) error {
 	addAliasKeys := []cloudfrontkeyvaluetypes.PutKeyRequestListItem{}
@@ -2,40 +2,51 @@ package updateproject
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
-
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/megakuul/battleshiper/api/resource/routecontext"
 
-	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 	"github.com/megakuul/battleshiper/lib/model/project"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "RESOURCE UPDATEPROJECT: ", 0)
 
+const (
+	// TRAFFIC_SHIFT_MIN_INTERVAL is the minimum number of seconds between two shift steps,
+	// lambda publishes the error metrics in one minute periods.
+	TRAFFIC_SHIFT_MIN_INTERVAL = 60
+	// TRAFFIC_SHIFT_MAX_WINDOW limits the total number of seconds of a traffic shift, it must fit into the deploy function runtime.
+	TRAFFIC_SHIFT_MAX_WINDOW = 300
+)
+
 type repositoryInput struct {
 	Id     int64  `json:"id"`
 	URL    string `json:"url"`
 	Branch string `json:"branch"`
 }
 
+type trafficShiftInput struct {
+	Strategy       string `json:"strategy" validate:"required,pattern=^(all_at_once|canary|linear)$"`
+	Percentage     int64  `json:"percentage" validate:"min=0,max=99"`
+	Interval       int64  `json:"interval" validate:"min=0,max=300"`
+	ErrorThreshold int64  `json:"error_threshold" validate:"min=0,max=100"`
+}
+
 type updateProjectInput struct {
-	ProjectName     string          `json:"project_name"`
-	BuildCommand    string          `json:"build_command"`
-	OutputDirectory string          `json:"output_directory"`
-	Repository      repositoryInput `json:"repository"`
+	ProjectName     string             `json:"project_name" validate:"required"`
+	BuildCommand    string             `json:"build_command"`
+	OutputDirectory string             `json:"output_directory"`
+	Repository      repositoryInput    `json:"repository"`
+	TrafficShift    *trafficShiftInput `json:"traffic_shift"`
+	Version         *int64             `json:"version"`
 }
 
 type updateProjectOutput struct {
@@ -43,140 +54,71 @@ type updateProjectOutput struct {
 }
 
 // HandleUpdateProject updates specified project fields.
-func HandleUpdateProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleUpdateProject(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
+func HandleUpdateProject(input *updateProjectInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*updateProjectOutput, error) {
+	userDoc, ok := router.GetUser(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
 
-func runHandleUpdateProject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*updateProjectOutput, int, error) {
-	var updateProjectInput updateProjectInput
-	err := json.Unmarshal([]byte(request.Body), &updateProjectInput)
-	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to deserialize request: invalid body")
+	if input.BuildCommand == "" && input.OutputDirectory == "" && input.Repository.Id == 0 && input.TrafficShift == nil {
+		return nil, router.Errorf(http.StatusBadRequest, "no project attribute to update was specified")
 	}
 
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
+	update := store.Update{
+		Set: map[string]any{},
+		Condition: map[string]any{
+			"OwnerId": userDoc.Id,
+			"Deleted": false,
+		},
+		Version: input.Version,
 	}
-
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
+	if input.BuildCommand != "" {
+		update.Set["BuildCommand"] = input.BuildCommand
 	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user from database")
+	if input.OutputDirectory != "" {
+		update.Set["OutputDirectory"] = input.OutputDirectory
 	}
-
-	updateSpec := map[string]dynamodbtypes.AttributeValue{}
-	if updateProjectInput.BuildCommand != "" {
-		updateSpec["build_command"] = &dynamodbtypes.AttributeValueMemberS{
-			Value: updateProjectInput.BuildCommand,
+	if input.Repository.Id != 0 {
+		update.Set["Repository"] = &project.Repository{
+			Id:     input.Repository.Id,
+			URL:    input.Repository.URL,
+			Branch: input.Repository.Branch,
 		}
 	}
-	if updateProjectInput.OutputDirectory != "" {
-		updateSpec["output_directory"] = &dynamodbtypes.AttributeValueMemberS{
-			Value: updateProjectInput.OutputDirectory,
+	if input.TrafficShift != nil {
+		trafficShift := &project.TrafficShift{
+			Strategy:       input.TrafficShift.Strategy,
+			Percentage:     input.TrafficShift.Percentage,
+			Interval:       input.TrafficShift.Interval,
+			ErrorThreshold: input.TrafficShift.ErrorThreshold,
 		}
-	}
-	if updateProjectInput.Repository.Id != 0 {
-		repositoryAttributes, err := attributevalue.Marshal(&project.Repository{
-			Id:     updateProjectInput.Repository.Id,
-			URL:    updateProjectInput.Repository.URL,
-			Branch: updateProjectInput.Repository.Branch,
-		})
-		if err != nil {
-			logger.Printf("failed to serialize repository: %v\n", err)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to serialize repository")
+		if trafficShift.Strategy != project.TRAFFIC_SHIFT_ALL_AT_ONCE {
+			if trafficShift.Percentage < 1 || trafficShift.Interval < TRAFFIC_SHIFT_MIN_INTERVAL || trafficShift.ErrorThreshold < 1 {
+				return nil, router.Errorf(http.StatusBadRequest,
+					"strategy '%s' requires a percentage, an error_threshold and an interval of at least %d seconds",
+					trafficShift.Strategy, TRAFFIC_SHIFT_MIN_INTERVAL)
+			}
+			window := int64(len(trafficShift.ShiftWeights())) * trafficShift.Interval
+			if window > TRAFFIC_SHIFT_MAX_WINDOW {
+				return nil, router.Errorf(http.StatusBadRequest,
+					"traffic shift takes %d seconds; exceeded maximum of %d seconds", window, TRAFFIC_SHIFT_MAX_WINDOW)
+			}
 		}
-		updateSpec["repository"] = repositoryAttributes
+		update.Set["TrafficShift"] = trafficShift
 	}
-
-	updateAttributeNames, updateAttributeValues, updateExpression := constructFromSpec(updateSpec)
-
-	updateAttributeNames["#owner_id"] = "owner_id"
-	updateAttributeNames["#deleted"] = "deleted"
-	updateAttributeValues[":owner_id"] = &dynamodbtypes.AttributeValueMemberS{Value: userDoc.Id}
-	updateAttributeValues[":deleted"] = &dynamodbtypes.AttributeValueMemberBOOL{Value: false}
-
-	_, err = database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
-		Table: aws.String(routeCtx.ProjectTable),
-		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: updateProjectInput.ProjectName},
-		},
-		AttributeNames:  updateAttributeNames,
-		AttributeValues: updateAttributeValues,
-		ConditionExpr:   aws.String("#owner_id = :owner_id AND #deleted = :deleted"),
-		UpdateExpr:      aws.String(updateExpression),
-	})
+	_, err := routeCtx.ProjectStore.Update(transportCtx, input.ProjectName, update)
 	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("project not found")
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, router.Errorf(http.StatusConflict, "project was modified in the meantime; reload the project and try again")
+		}
+		if store.IsConditionFailed(err) {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
 		}
 		logger.Printf("failed to load project from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load project from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
 	}
 
 	return &updateProjectOutput{
 		Message: "project updated",
-	}, http.StatusOK, nil
-}
-
-// constructFromSpec converts a updateSpec map into attributeNames, attributeValues and a updateExpression.
-func constructFromSpec(updateSpec map[string]dynamodbtypes.AttributeValue) (map[string]string, map[string]dynamodbtypes.AttributeValue, string) {
-	var (
-		attributeNames   = map[string]string{}
-		attributeValues  = map[string]dynamodbtypes.AttributeValue{}
-		updateExpression = ""
-	)
-	for key, value := range updateSpec {
-		attributeNames[fmt.Sprintf("#%s", key)] = key
-		attributeValues[fmt.Sprintf(":%s", key)] = value
-		if updateExpression == "" {
-			updateExpression = "SET "
-		} else {
-			updateExpression += ","
-		}
-		updateExpression += fmt.Sprintf("#%s = :%s", key, key)
-	}
-	return attributeNames, attributeValues, updateExpression
+	}, nil
 }
@@ -0,0 +1,147 @@
+package upsertenv
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/megakuul/battleshiper/api/resource/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "RESOURCE UPSERTENV: ", 0)
+
+type upsertEnvInput struct {
+	ProjectName string `json:"project_name" validate:"required"`
+	Name        string `json:"name" validate:"required,max=128"`
+	Value       string `json:"value" validate:"max=4096"`
+	Secret      bool   `json:"secret"`
+	Scope       string `json:"scope" validate:"required,pattern=^(build|runtime)$"`
+	Version     *int64 `json:"version"`
+}
+
+type upsertEnvOutput struct {
+	Message string `json:"message"`
+}
+
+// HandleUpsertEnv creates or replaces an environment variable of the project.
+// Secret values are encrypted before they are written to the database, changes are applied on the next build or deployment.
+func HandleUpsertEnv(input *upsertEnvInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*upsertEnvOutput, error) {
+	userDoc, ok := router.GetUser(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
+	}
+
+	if err := pipeline.ValidateEnvironmentName(input.Scope, input.Name); err != nil {
+		return nil, router.Errorf(http.StatusBadRequest, "%v", err)
+	}
+
+	projectDoc, err := database.GetSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: input.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
+		}
+		logger.Printf("failed to load project from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load project from database")
+	}
+	if projectDoc.OwnerId != userDoc.Id {
+		return nil, router.Errorf(http.StatusForbidden, "unauthorized to update the environment of this project")
+	}
+	if projectDoc.Deleted {
+		return nil, router.Errorf(http.StatusBadRequest, "project was already deleted")
+	}
+	if input.Version != nil && *input.Version != projectDoc.Version {
+		return nil, router.Errorf(http.StatusConflict, "project was modified in the meantime; reload the project and try again")
+	}
+
+	variable := project.EnvironmentVariable{
+		Value:  input.Value,
+		Secret: input.Secret,
+	}
+	if input.Secret {
+		variable.Value, err = routeCtx.EnvironmentCipher.Encrypt(input.Value)
+		if err != nil {
+			logger.Printf("failed to encrypt variable: %v\n", err)
+			return nil, router.Errorf(http.StatusInternalServerError, "failed to encrypt variable")
+		}
+	}
+
+	environment := copyEnvironment(projectDoc.Environment)
+	variables := environment.Build
+	if input.Scope == pipeline.ENV_SCOPE_RUNTIME {
+		variables = environment.Runtime
+	}
+	if _, exists := variables[input.Name]; !exists && len(variables) >= pipeline.ENV_MAX_VARIABLES {
+		return nil, router.Errorf(http.StatusBadRequest, "variable limit reached; no additional %s variables can be created", input.Scope)
+	}
+	variables[input.Name] = variable
+
+	// the environment is replaced as a whole, therefore the update fails if it was modified in the meantime.
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("Environment", environment).
+		IfVersion(projectDoc.Version).
+		Build()
+	if err != nil {
+		logger.Printf("failed to build update expression: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to build update expression")
+	}
+
+	_, err = database.UpdateSingle[project.Project](transportCtx, routeCtx.DynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(routeCtx.ProjectTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+		},
+		ExpectedVersion: updateExpression.ExpectedVersion,
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		ConditionExpr:   updateExpression.ConditionExpr,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return nil, router.Errorf(http.StatusConflict, "project was modified in the meantime; reload the project and try again")
+		}
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil, router.Errorf(http.StatusNotFound, "project not found")
+		}
+		logger.Printf("failed to update project: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to update project")
+	}
+
+	return &upsertEnvOutput{
+		Message: "variable updated; changes are applied on the next build",
+	}, nil
+}
+
+// copyEnvironment returns a copy of the environment with initialized scopes.
+func copyEnvironment(environment project.Environment) project.Environment {
+	environmentCopy := project.Environment{
+		Build:   map[string]project.EnvironmentVariable{},
+		Runtime: map[string]project.EnvironmentVariable{},
+	}
+	for name, variable := range environment.Build {
+		environmentCopy.Build[name] = variable
+	}
+	for name, variable := range environment.Runtime {
+		environmentCopy.Runtime[name] = variable
+	}
+	return environmentCopy
+}
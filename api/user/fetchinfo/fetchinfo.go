@@ -2,25 +2,18 @@ package fetchinfo
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 
 	"github.com/megakuul/battleshiper/api/user/routecontext"
 
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-
-	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 	"github.com/megakuul/battleshiper/lib/model/rbac"
-	"github.com/megakuul/battleshiper/lib/model/subscription"
-	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "USER FETCHINFO: ", 0)
@@ -31,26 +24,34 @@ type pipelineSpecsOutput struct {
 }
 
 type projectSpecsOutput struct {
-	ProjectCount     int64 `json:"project_count"`
-	AliasCount       int64 `json:"alias_count"`
-	PrerenderRoutes  int64 `json:"prerender_routes"`
-	ServerStorage    int64 `json:"server_storage"`
-	ClientStorage    int64 `json:"client_storage"`
-	PrerenderStorage int64 `json:"prerender_storage"`
+	ProjectCount        int64 `json:"project_count"`
+	AliasCount          int64 `json:"alias_count"`
+	PrerenderRoutes     int64 `json:"prerender_routes"`
+	ServerStorage       int64 `json:"server_storage"`
+	ClientStorage       int64 `json:"client_storage"`
+	PrerenderStorage    int64 `json:"prerender_storage"`
+	DeploymentRetention int64 `json:"deployment_retention"`
 }
 
 type cdnSpecsOutput struct {
 	InstanceCount int64 `json:"instance_count"`
 }
 
+type remainingOutput struct {
+	DailyDeployments int64 `json:"daily_deployments"`
+}
+
 type subscriptionOutput struct {
 	Id            string              `json:"id"`
 	Name          string              `json:"name"`
 	PipelineSpecs pipelineSpecsOutput `json:"pipeline_specs"`
 	ProjectSpecs  projectSpecsOutput  `json:"project_specs"`
 	CDNSpecs      cdnSpecsOutput      `json:"cdn_specs"`
+	Remaining     remainingOutput     `json:"remaining"`
 }
 
+type fetchInfoInput struct{}
+
 type fetchInfoOutput struct {
 	Id        string                 `json:"id"`
 	Name      string                 `json:"name"`
@@ -62,62 +63,15 @@ type fetchInfoOutput struct {
 }
 
 // HandleFetchInfo fetches user information from the database cluster.
-func HandleFetchInfo(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	response, code, err := runHandleFetchInfo(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	rawResponse, err := json.Marshal(response)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: "failed to serialize response",
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-		},
-		Body: string(rawResponse),
-	}, nil
-}
-
-func runHandleFetchInfo(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*fetchInfoOutput, int, error) {
-
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("no user_token provided")
+func HandleFetchInfo(input *fetchInfoInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*fetchInfoOutput, error) {
+	userToken, ok := router.GetUserClaims(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
 
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
-	}
-
-	userDoc, err := database.GetSingle[user.User](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.UserTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
-	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return nil, http.StatusNotFound, fmt.Errorf("user not found")
-		}
-		logger.Printf("failed to load user from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load user from database")
+	userDoc, ok := router.GetUser(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
 
 	if userDoc.SubscriptionId == "" {
@@ -128,19 +82,12 @@ func runHandleFetchInfo(request events.APIGatewayV2HTTPRequest, transportCtx con
 			Provider:     userToken.Provider,
 			AvatarURL:    userToken.AvatarURL,
 			Subscription: nil,
-		}, http.StatusOK, nil
+		}, nil
 	}
 
-	subscriptionDoc, err := database.GetSingle[subscription.Subscription](transportCtx, routeCtx.DynamoClient, &database.GetSingleInput{
-		Table: aws.String(routeCtx.SubscriptionTable),
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":id": &dynamodbtypes.AttributeValueMemberS{Value: userDoc.SubscriptionId},
-		},
-		ConditionExpr: aws.String("id = :id"),
-	})
+	subscriptionDoc, err := routeCtx.SubscriptionStore.Get(transportCtx, userDoc.SubscriptionId)
 	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
+		if store.IsConditionFailed(err) {
 			return &fetchInfoOutput{
 				Id:           userToken.Id,
 				Name:         userToken.Username,
@@ -148,12 +95,14 @@ func runHandleFetchInfo(request events.APIGatewayV2HTTPRequest, transportCtx con
 				Provider:     userToken.Provider,
 				AvatarURL:    userToken.AvatarURL,
 				Subscription: nil,
-			}, http.StatusOK, nil
+			}, nil
 		}
 		logger.Printf("failed to load subscription from database: %v\n", err)
-		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load subscription from database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load subscription from database")
 	}
 
+	remainingDeployments, _ := pipeline.RemainingQuota(userDoc, subscriptionDoc, pipeline.QUOTA_PIPELINE_DEPLOYMENTS)
+
 	return &fetchInfoOutput{
 		Id:        userToken.Id,
 		Name:      userToken.Username,
@@ -168,16 +117,20 @@ func runHandleFetchInfo(request events.APIGatewayV2HTTPRequest, transportCtx con
 				DailyDeployments: subscriptionDoc.PipelineSpecs.DailyDeployments,
 			},
 			ProjectSpecs: projectSpecsOutput{
-				ProjectCount:     subscriptionDoc.ProjectSpecs.ProjectCount,
-				AliasCount:       subscriptionDoc.ProjectSpecs.AliasCount,
-				ServerStorage:    subscriptionDoc.ProjectSpecs.ServerStorage,
-				ClientStorage:    subscriptionDoc.ProjectSpecs.ClientStorage,
-				PrerenderStorage: subscriptionDoc.ProjectSpecs.PrerenderStorage,
-				PrerenderRoutes:  subscriptionDoc.ProjectSpecs.PrerenderRoutes,
+				ProjectCount:        subscriptionDoc.ProjectSpecs.ProjectCount,
+				AliasCount:          subscriptionDoc.ProjectSpecs.AliasCount,
+				ServerStorage:       subscriptionDoc.ProjectSpecs.ServerStorage,
+				ClientStorage:       subscriptionDoc.ProjectSpecs.ClientStorage,
+				PrerenderStorage:    subscriptionDoc.ProjectSpecs.PrerenderStorage,
+				PrerenderRoutes:     subscriptionDoc.ProjectSpecs.PrerenderRoutes,
+				DeploymentRetention: subscriptionDoc.ProjectSpecs.DeploymentRetention,
 			},
 			CDNSpecs: cdnSpecsOutput{
 				InstanceCount: subscriptionDoc.CDNSpecs.InstanceCount,
 			},
+			Remaining: remainingOutput{
+				DailyDeployments: remainingDeployments,
+			},
 		},
-	}, http.StatusOK, nil
+	}, nil
 }
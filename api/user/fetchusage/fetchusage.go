@@ -0,0 +1,53 @@
+package fetchusage
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/user/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/helper/store"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+var logger = log.New(os.Stderr, "USER FETCHUSAGE: ", 0)
+
+type fetchUsageInput struct{}
+
+type fetchUsageOutput struct {
+	Message string          `json:"message"`
+	Usage   *pipeline.Usage `json:"usage"`
+}
+
+// HandleFetchUsage compares the resources consumed by the user with the limits of the subscription.
+func HandleFetchUsage(input *fetchUsageInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*fetchUsageOutput, error) {
+	userDoc, ok := router.GetUser(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
+	}
+
+	subscriptionDoc, err := routeCtx.SubscriptionStore.Get(transportCtx, userDoc.SubscriptionId)
+	if err != nil {
+		if store.IsConditionFailed(err) {
+			return nil, router.Errorf(http.StatusBadRequest, "user does not have a valid subscription associated")
+		}
+		logger.Printf("failed to load subscription from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load subscription from database")
+	}
+
+	projectDocs, err := routeCtx.ProjectStore.ListByOwner(transportCtx, userDoc.Id)
+	if err != nil {
+		logger.Printf("failed to load projects from database: %v\n", err)
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to load projects from database")
+	}
+
+	return &fetchUsageOutput{
+		Message: "usage fetched",
+		Usage:   pipeline.ComputeUsage(userDoc, subscriptionDoc, projectDocs),
+	}, nil
+}
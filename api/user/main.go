@@ -12,19 +12,19 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 
 	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/router"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 
-	"github.com/megakuul/battleshiper/api/user/fetchinfo"
-	"github.com/megakuul/battleshiper/api/user/registeruser"
 	"github.com/megakuul/battleshiper/api/user/routecontext"
+	"github.com/megakuul/battleshiper/api/user/routes"
 )
 
 var (
 	REGION                = os.Getenv("AWS_REGION")
 	BOOTSTRAP_TIMEOUT     = os.Getenv("BOOTSTRAP_TIMEOUT")
 	USERTABLE             = os.Getenv("USERTABLE")
+	PROJECTTABLE          = os.Getenv("PROJECTTABLE")
 	SUBSCRIPTIONTABLE     = os.Getenv("SUBSCRIPTIONTABLE")
-	JWT_CREDENTIAL_ARN    = os.Getenv("JWT_CREDENTIAL_ARN")
+	JWT_PUBLIC_KEY_ARN    = os.Getenv("JWT_PUBLIC_KEY_ARN")
 	ADMIN_GITHUB_USERNAME = os.Getenv("ADMIN_GITHUB_USERNAME")
 )
 
@@ -50,24 +50,25 @@ func run() error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
-	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_CREDENTIAL_ARN, 0)
+	jwtOptions, err := auth.CreateJwtOptions(awsConfig, bootstrapContext, JWT_PUBLIC_KEY_ARN, 0)
 	if err != nil {
 		return err
 	}
 
-	httpRouter := router.NewRouter(routecontext.Context{
+	httpRouter := routes.NewRouter(routecontext.Context{
 		DynamoClient:      dynamoClient,
 		UserTable:         USERTABLE,
+		ProjectTable:      PROJECTTABLE,
 		SubscriptionTable: SUBSCRIPTIONTABLE,
+		UserStore:         store.NewDynamoUserStore(dynamoClient, USERTABLE),
+		ProjectStore:      store.NewDynamoProjectStore(dynamoClient, PROJECTTABLE),
+		SubscriptionStore: store.NewDynamoSubscriptionStore(dynamoClient, SUBSCRIPTIONTABLE),
 		JwtOptions:        jwtOptions,
 		UserConfiguration: &routecontext.UserConfiguration{
 			AdminUsername: ADMIN_GITHUB_USERNAME,
 		},
 	})
 
-	httpRouter.AddRoute("GET", "/api/user/fetchinfo", fetchinfo.HandleFetchInfo)
-	httpRouter.AddRoute("POST", "/api/user/registeruser", registeruser.HandleRegisterUser)
-
 	lambda.Start(httpRouter.Route)
 
 	return nil
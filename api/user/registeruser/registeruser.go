@@ -2,53 +2,34 @@ package registeruser
 
 import (
 	"context"
-	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go-v2/aws"
 
 	"github.com/megakuul/battleshiper/api/user/routecontext"
 
-	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/megakuul/battleshiper/lib/helper/auth"
-	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 	"github.com/megakuul/battleshiper/lib/model/rbac"
 	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
 )
 
 var logger = log.New(os.Stderr, "USER REGISTERUSER: ", 0)
 
-// HandleRegisterUser registers a user in the database (if not existent).
-func HandleRegisterUser(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
-	code, err := runHandleRegisterUser(request, transportCtx, routeCtx)
-	if err != nil {
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode: code,
-			Headers: map[string]string{
-				"Content-Type": "text/plain",
-			},
-			Body: err.Error(),
-		}, nil
-	}
-	return events.APIGatewayV2HTTPResponse{
-		StatusCode: code,
-	}, nil
-}
+type registerUserInput struct{}
 
-func runHandleRegisterUser(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (int, error) {
-	userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
-	if err != nil {
-		return http.StatusUnauthorized, fmt.Errorf("no user_token provided")
-	}
+type registerUserOutput struct {
+	Message string `json:"message"`
+}
 
-	userToken, err := auth.ParseJWT(routeCtx.JwtOptions, userTokenCookie.Value)
-	if err != nil {
-		return http.StatusUnauthorized, fmt.Errorf("user_token is invalid: %v", err)
+// HandleRegisterUser registers a user in the database (if not existent).
+func HandleRegisterUser(input *registerUserInput, request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (*registerUserOutput, error) {
+	userToken, ok := router.GetUserClaims(transportCtx)
+	if !ok {
+		return nil, router.Errorf(http.StatusUnauthorized, "user is not authenticated")
 	}
 
 	newDoc := user.User{
@@ -58,12 +39,7 @@ func runHandleRegisterUser(request events.APIGatewayV2HTTPRequest, transportCtx
 		Roles:          map[rbac.ROLE]struct{}{rbac.USER: {}},
 		RefreshToken:   "",
 		SubscriptionId: "",
-		LimitCounter: user.ExecutionLimitCounter{
-			PipelineBuildsExpiration:      0,
-			PipelineBuilds:                0,
-			PipelineDeploymentsExpiration: 0,
-			PipelineDeployments:           0,
-		},
+		Quotas:         map[string]user.QuotaCounter{},
 		InstallationId: 0,
 		Repositories:   map[int64]user.Repository{},
 	}
@@ -76,19 +52,18 @@ func runHandleRegisterUser(request events.APIGatewayV2HTTPRequest, transportCtx
 		}
 	}
 
-	err = database.PutSingle(transportCtx, routeCtx.DynamoClient, &database.PutSingleInput[user.User]{
-		Table:                   aws.String(routeCtx.UserTable),
-		Item:                    newDoc,
-		ProtectionAttributeName: aws.String("id"),
-	})
+	err := routeCtx.UserStore.Create(transportCtx, &newDoc)
 	if err != nil {
-		var cErr *dynamodbtypes.ConditionalCheckFailedException
-		if ok := errors.As(err, &cErr); ok {
-			return http.StatusOK, nil
+		if store.IsConditionFailed(err) {
+			return &registerUserOutput{
+				Message: "user already registered",
+			}, nil
 		}
 		logger.Printf("failed to add user to database: %v\n", err)
-		return http.StatusInternalServerError, fmt.Errorf("failed to add user to database")
+		return nil, router.Errorf(http.StatusInternalServerError, "failed to add user to database")
 	}
 
-	return http.StatusOK, nil
+	return &registerUserOutput{
+		Message: "user registered",
+	}, nil
 }
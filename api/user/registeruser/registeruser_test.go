@@ -0,0 +1,65 @@
+package registeruser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/megakuul/battleshiper/api/user/routecontext"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/store"
+	"github.com/megakuul/battleshiper/lib/model/rbac"
+	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/lib/router"
+)
+
+func TestHandleRegisterUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		username   string
+		existing   bool
+		message    string
+		privileged bool
+	}{
+		{name: "new user", username: "octocat", message: "user registered"},
+		{name: "admin user", username: "Admin", message: "user registered", privileged: true},
+		{name: "existing user", username: "Admin", existing: true, message: "user already registered"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userStore := store.NewMemoryUserStore()
+			if test.existing {
+				if err := userStore.Create(context.Background(), &user.User{Id: "user-1", Provider: "github"}); err != nil {
+					t.Fatalf("failed to create user: %v", err)
+				}
+			}
+			routeCtx := routecontext.Context{
+				UserStore:         userStore,
+				UserConfiguration: &routecontext.UserConfiguration{AdminUsername: "admin"},
+			}
+			transportCtx := router.WithUserClaims(context.Background(), &auth.UserClaims{Id: "user-1", Username: test.username})
+
+			output, err := HandleRegisterUser(&registerUserInput{}, events.APIGatewayV2HTTPRequest{}, transportCtx, routeCtx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output.Message != test.message {
+				t.Errorf("expected message '%s', got '%s'", test.message, output.Message)
+			}
+
+			userDoc, err := userStore.Get(context.Background(), "user-1")
+			if err != nil {
+				t.Fatalf("failed to get user: %v", err)
+			}
+			if userDoc.Privileged != test.privileged {
+				t.Errorf("expected privileged %t, got %t", test.privileged, userDoc.Privileged)
+			}
+			if _, ok := userDoc.Roles[rbac.ROLE_MANAGER]; ok != test.privileged {
+				t.Errorf("expected role manager %t, got %t", test.privileged, ok)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package routecontext
 import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/store"
 )
 
 type UserConfiguration struct {
@@ -13,7 +14,11 @@ type UserConfiguration struct {
 type Context struct {
 	DynamoClient      *dynamodb.Client
 	UserTable         string
+	ProjectTable      string
 	SubscriptionTable string
+	UserStore         store.UserStore
+	ProjectStore      store.ProjectStore
+	SubscriptionStore store.SubscriptionStore
 	JwtOptions        *auth.JwtOptions
 	UserConfiguration *UserConfiguration
 }
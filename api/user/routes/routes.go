@@ -0,0 +1,29 @@
+// routes package registers the http routes of the user api.
+package routes
+
+import (
+	"github.com/megakuul/battleshiper/lib/router"
+
+	"github.com/megakuul/battleshiper/api/user/fetchinfo"
+	"github.com/megakuul/battleshiper/api/user/fetchusage"
+	"github.com/megakuul/battleshiper/api/user/registeruser"
+	"github.com/megakuul/battleshiper/api/user/routecontext"
+)
+
+// NewRouter creates the router of the user api with all routes registered.
+func NewRouter(routeCtx routecontext.Context) *router.Router[routecontext.Context] {
+	httpRouter := router.NewRouter(routeCtx)
+
+	httpRouter.Use(router.Authenticate(routeCtx.JwtOptions))
+
+	router.AddJSONRoute(httpRouter, "GET", "/api/user/fetchinfo", fetchinfo.HandleFetchInfo, router.LoadUser(routeCtx.DynamoClient, routeCtx.UserTable))
+	router.AddJSONRoute(httpRouter, "GET", "/api/user/usage", fetchusage.HandleFetchUsage, router.LoadUser(routeCtx.DynamoClient, routeCtx.UserTable))
+	router.AddJSONRoute(httpRouter, "POST", "/api/user/registeruser", registeruser.HandleRegisterUser)
+
+	httpRouter.AddOpenAPIRoute("/api/user/openapi.json", router.OpenAPIInfo{
+		Title:   "Battleshiper User API",
+		Version: "1.0.0",
+	})
+
+	return httpRouter
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// MAX_PAYLOAD_SIZE is the maximum request body size, it matches the lambda invocation payload limit.
+const MAX_PAYLOAD_SIZE = 6 * 1024 * 1024
+
+// routeFunc is the signature of router.Router[T].Route.
+type routeFunc func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+
+// lambdaHandler adapts a lambda route function to a http.Handler.
+// The request is converted to the payload api gateway sends to the lambda (format version 2.0)
+// and the lambda response is converted back to a http response.
+func lambdaHandler(route routeFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MAX_PAYLOAD_SIZE)
+		request, err := createLambdaRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response, err := route(r.Context(), request)
+		if err != nil {
+			// api gateway responds with 500 if the lambda returns an error.
+			logger.Printf("ERROR: %s %s: %v\n", r.Method, r.URL.Path, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := writeLambdaResponse(w, response); err != nil {
+			logger.Printf("ERROR: %s %s: %v\n", r.Method, r.URL.Path, err)
+		}
+	})
+}
+
+// createLambdaRequest converts the http request to an api gateway v2 request.
+func createLambdaRequest(r *http.Request) (events.APIGatewayV2HTTPRequest, error) {
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, fmt.Errorf("failed to read request body: %v", err)
+	}
+
+	// api gateway lowercases header names and joins repeated headers with a comma.
+	// cookies are moved to a dedicated field.
+	headers := map[string]string{}
+	cookies := []string{}
+	for name, values := range r.Header {
+		if name == "Cookie" {
+			for _, value := range values {
+				for _, cookie := range strings.Split(value, ";") {
+					if cookie = strings.TrimSpace(cookie); cookie != "" {
+						cookies = append(cookies, cookie)
+					}
+				}
+			}
+			continue
+		}
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	headers["host"] = r.Host
+
+	queryParameters := map[string]string{}
+	for name, values := range r.URL.Query() {
+		queryParameters[name] = strings.Join(values, ",")
+	}
+
+	sourceIp, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIp = r.RemoteAddr
+	}
+
+	request := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: queryParameters,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:   "$default",
+			Stage:      "$default",
+			RequestID:  uuid.New().String(),
+			DomainName: r.Host,
+			Time:       time.Now().UTC().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:  time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIp,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	if utf8.Valid(rawBody) {
+		request.Body = string(rawBody)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(rawBody)
+		request.IsBase64Encoded = true
+	}
+
+	return request, nil
+}
+
+// writeLambdaResponse writes the api gateway v2 response to the http response writer.
+func writeLambdaResponse(w http.ResponseWriter, response events.APIGatewayV2HTTPResponse) error {
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decodedBody, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return fmt.Errorf("failed to decode base64 response body: %v", err)
+		}
+		body = decodedBody
+	}
+
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range response.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	_, err := w.Write(body)
+	return err
+}
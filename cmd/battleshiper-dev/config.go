@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// devConfig holds the configuration of the development server.
+// It replaces the environment variables and secretsmanager secrets used by the deployed lambdas.
+type devConfig struct {
+	// Listen is the address the http server listens on.
+	Listen string `json:"listen"`
+	// Region is the aws region used by all clients.
+	Region string `json:"region"`
+	// Credentials are static aws credentials, if empty the default credential chain is used.
+	Credentials devCredentials `json:"credentials"`
+	// Endpoints overrides the endpoint of aws services, the key is the sdk service id
+	// (e.g. "DynamoDB" for DynamoDB Local or "S3" for MinIO). Services without entry use the aws endpoint.
+	Endpoints map[string]string `json:"endpoints"`
+
+	Tables    devTables    `json:"tables"`
+	Auth      devAuth      `json:"auth"`
+	Github    devGithub    `json:"github"`
+	Events    devEvents    `json:"events"`
+	Pipeline  devPipeline  `json:"pipeline"`
+	LogGroups devLogGroups `json:"log_groups"`
+
+	AdminGithubUsername string `json:"admin_github_username"`
+	CloudfrontCacheArn  string `json:"cloudfront_cache_arn"`
+}
+
+type devCredentials struct {
+	AccessKeyId     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+type devTables struct {
+	User         string `json:"user"`
+	Project      string `json:"project"`
+	Subscription string `json:"subscription"`
+	Ticket       string `json:"ticket"`
+	Deployment   string `json:"deployment"`
+}
+
+type devAuth struct {
+	// JwtKeyFile and TicketKeyFile contain the private keyrings, missing files are generated on startup.
+	JwtKeyFile          string   `json:"jwt_key_file"`
+	CursorSecret        string   `json:"cursor_secret"`
+	TicketKeyFile       string   `json:"ticket_key_file"`
+	EnvironmentSecret   string   `json:"environment_secret"`
+	UserTokenTTL        duration `json:"user_token_ttl"`
+	RedirectURI         string   `json:"redirect_uri"`
+	FrontendRedirectURI string   `json:"frontend_redirect_uri"`
+}
+
+type devGithub struct {
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	AppId        string `json:"app_id"`
+	// AppPrivateKeyFile is the path to the pem encoded private key of the github app.
+	AppPrivateKeyFile string `json:"app_private_key_file"`
+	WebhookSecret     string `json:"webhook_secret"`
+}
+
+type devEvents struct {
+	Init   devEvent `json:"init"`
+	Build  devEvent `json:"build"`
+	Deploy devEvent `json:"deploy"`
+	Delete devEvent `json:"delete"`
+	// Rollback events are emitted by the resource api and handled by the deploy step.
+	Rollback devEvent `json:"rollback"`
+}
+
+type devEvent struct {
+	EventBus  string   `json:"eventbus"`
+	Source    string   `json:"source"`
+	Action    string   `json:"action"`
+	TicketTTL duration `json:"ticket_ttl"`
+}
+
+// devPipeline configures the pipeline steps that run inside the dev server if the local publisher is used.
+type devPipeline struct {
+	// Publisher selects how pipeline events are emitted: "eventbridge" emits them to the eventbuses of the events config,
+	// "local" runs the init, build, deploy and delete steps in the dev server process.
+	Publisher string `json:"publisher"`
+	// LogFile optionally receives a copy of the event and deployment logs that are sent to cloudwatch.
+	LogFile string `json:"log_file"`
+
+	StaticBucket             string   `json:"static_bucket"`
+	BuildAssetBucket         string   `json:"build_asset_bucket"`
+	ArtifactBucket           string   `json:"artifact_bucket"`
+	DeploymentServiceRoleArn string   `json:"deployment_service_role_arn"`
+	DeploymentTimeout        duration `json:"deployment_timeout"`
+	ChangeSetTimeout         duration `json:"changeset_timeout"`
+	DeletionTimeout          duration `json:"deletion_timeout"`
+	// BuildTimeout limits the local build (clone, build command and upload).
+	BuildTimeout             duration `json:"build_timeout"`
+	CloudfrontDistributionId string   `json:"cloudfront_distribution_id"`
+	// TransferConcurrency is the number of parallel s3 requests the deploy and delete steps use for project assets.
+	TransferConcurrency int `json:"transfer_concurrency"`
+
+	EventLogPrefix   string `json:"event_log_prefix"`
+	BuildLogPrefix   string `json:"build_log_prefix"`
+	DeployLogPrefix  string `json:"deploy_log_prefix"`
+	ServerLogPrefix  string `json:"server_log_prefix"`
+	LogRetentionDays int    `json:"log_retention_days"`
+
+	BuildJobQueueArn string `json:"build_job_queue_arn"`
+	BuildJobVCPUS    string `json:"build_job_vcpus"`
+	BuildJobMemory   string `json:"build_job_memory"`
+
+	ServerNamePrefix string `json:"server_name_prefix"`
+	ServerRuntime    string `json:"server_runtime"`
+	ServerMemory     int    `json:"server_memory"`
+	ServerTimeout    int    `json:"server_timeout"`
+}
+
+type devLogGroups struct {
+	Api      string `json:"api"`
+	Pipeline string `json:"pipeline"`
+	Router   string `json:"router"`
+}
+
+// duration is a time.Duration that is decoded from a json duration string (e.g. "48h").
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("expected duration string: %v", err)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// defaultConfig returns the configuration used for all fields that are not set in the config file.
+// The defaults match the values of the sam template.
+func defaultConfig() devConfig {
+	return devConfig{
+		Listen: "localhost:8080",
+		Region: "us-east-1",
+		Tables: devTables{
+			User:         "battleshiper-users",
+			Project:      "battleshiper-projects",
+			Subscription: "battleshiper-subscriptions",
+			Ticket:       "battleshiper-tickets",
+			Deployment:   "battleshiper-deployments",
+		},
+		Auth: devAuth{
+			JwtKeyFile:          "battleshiper-dev.jwt-keys.json",
+			TicketKeyFile:       "battleshiper-dev.ticket-keys.json",
+			UserTokenTTL:        duration(48 * time.Hour),
+			RedirectURI:         "http://localhost:8080/api/auth/callback",
+			FrontendRedirectURI: "http://localhost:5173/profile",
+		},
+		Events: devEvents{
+			Init: devEvent{
+				EventBus:  "battleshiper-pipeline-eventbus",
+				Source:    "ch.megakuul.battleshiper",
+				Action:    "battleshiper.init",
+				TicketTTL: duration(800 * time.Second),
+			},
+			Build: devEvent{
+				EventBus: "battleshiper-pipeline-eventbus",
+				Source:   "ch.megakuul.battleshiper",
+				Action:   "battleshiper.build",
+			},
+			Deploy: devEvent{
+				Source:    "aws.batch",
+				Action:    "Batch Job State Change",
+				TicketTTL: duration(1400 * time.Second),
+			},
+			Delete: devEvent{
+				EventBus:  "battleshiper-pipeline-eventbus",
+				Source:    "ch.megakuul.battleshiper",
+				Action:    "battleshiper.delete",
+				TicketTTL: duration(800 * time.Second),
+			},
+			Rollback: devEvent{
+				EventBus:  "battleshiper-pipeline-eventbus",
+				Source:    "ch.megakuul.battleshiper",
+				Action:    "battleshiper.rollback",
+				TicketTTL: duration(800 * time.Second),
+			},
+		},
+		Pipeline: devPipeline{
+			Publisher:           "eventbridge",
+			DeploymentTimeout:   duration(400 * time.Second),
+			ChangeSetTimeout:    duration(100 * time.Second),
+			DeletionTimeout:     duration(400 * time.Second),
+			BuildTimeout:        duration(600 * time.Second),
+			TransferConcurrency: 16,
+			EventLogPrefix:      "/battleshiper/project/event",
+			BuildLogPrefix:      "/battleshiper/project/build",
+			DeployLogPrefix:     "/battleshiper/project/deploy",
+			ServerLogPrefix:     "/battleshiper/project/server",
+			LogRetentionDays:    14,
+			BuildJobVCPUS:       "0.5",
+			BuildJobMemory:      "1024",
+			ServerNamePrefix:    "battleshiper-project-server-",
+			ServerRuntime:       "nodejs20.x",
+			ServerMemory:        128,
+			ServerTimeout:       3,
+		},
+		LogGroups: devLogGroups{
+			Api:      "/aws/lambda/battleshiper-api-logs",
+			Pipeline: "/aws/lambda/battleshiper-pipeline-logs",
+			Router:   "/aws/lambda/battleshiper-router-logs",
+		},
+	}
+}
+
+// loadConfig reads the json config file and applies it on top of the default configuration.
+func loadConfig(path string) (*devConfig, error) {
+	rawConfig, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	config := defaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(rawConfig))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %v", err)
+	}
+
+	if config.Auth.JwtKeyFile == "" {
+		return nil, fmt.Errorf("auth.jwt_key_file must be set")
+	}
+	if config.Auth.CursorSecret == "" {
+		return nil, fmt.Errorf("auth.cursor_secret must be set")
+	}
+	if config.Auth.TicketKeyFile == "" {
+		return nil, fmt.Errorf("auth.ticket_key_file must be set")
+	}
+	if config.Auth.EnvironmentSecret == "" {
+		return nil, fmt.Errorf("auth.environment_secret must be set")
+	}
+	if config.Pipeline.Publisher != "eventbridge" && config.Pipeline.Publisher != "local" {
+		return nil, fmt.Errorf("pipeline.publisher must be 'eventbridge' or 'local'")
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,376 @@
+// battleshiper-dev runs all battleshiper api modules in one local http server.
+// Each module router is served through a net/http adapter that emulates the api gateway (payload format 2.0),
+// aws services can be replaced by local emulators (e.g. DynamoDB Local or MinIO) through the config file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	webhook "github.com/go-playground/webhooks/v6/github"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/helper/store"
+
+	adminctx "github.com/megakuul/battleshiper/api/admin/routecontext"
+	adminroutes "github.com/megakuul/battleshiper/api/admin/routes"
+	authctx "github.com/megakuul/battleshiper/api/auth/routecontext"
+	authroutes "github.com/megakuul/battleshiper/api/auth/routes"
+	pipelinectx "github.com/megakuul/battleshiper/api/pipeline/routecontext"
+	pipelineroutes "github.com/megakuul/battleshiper/api/pipeline/routes"
+	resourcectx "github.com/megakuul/battleshiper/api/resource/routecontext"
+	resourceroutes "github.com/megakuul/battleshiper/api/resource/routes"
+	userctx "github.com/megakuul/battleshiper/api/user/routecontext"
+	userroutes "github.com/megakuul/battleshiper/api/user/routes"
+)
+
+var (
+	configPath   = flag.String("config", "battleshiper-dev.json", "path to the json config file")
+	createTables = flag.Bool("create-tables", false, "create missing dynamodb tables before starting the server")
+)
+
+var logger = log.New(os.Stderr, "DEV: ", 0)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		logger.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	devConfig, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	awsConfig, err := loadAwsConfig(ctx, devConfig)
+	if err != nil {
+		return err
+	}
+
+	dynamoClient := dynamodb.NewFromConfig(awsConfig)
+	cloudwatchClient := cloudwatchlogs.NewFromConfig(awsConfig)
+	eventClient := eventbridge.NewFromConfig(awsConfig)
+	cloudfrontClient := cloudfrontkeyvaluestore.NewFromConfig(awsConfig)
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+
+	if *createTables {
+		if err := createMissingTables(ctx, dynamoClient, devConfig.Tables); err != nil {
+			return err
+		}
+	}
+
+	// the dev server signs with the keys of the configured keyring files, the keys cannot be rotated.
+	jwtSigner, err := createStaticSigner(devConfig.Auth.JwtKeyFile)
+	if err != nil {
+		return err
+	}
+	jwtVerifier, err := jwtSigner.Verifier()
+	if err != nil {
+		return err
+	}
+	jwtOptions := &auth.JwtOptions{
+		Signer:   jwtSigner,
+		Verifier: jwtVerifier,
+		TTL:      time.Duration(devConfig.Auth.UserTokenTTL),
+	}
+
+	oauthConfig := &oauth2.Config{
+		ClientID:     devConfig.Github.ClientId,
+		ClientSecret: devConfig.Github.ClientSecret,
+		Endpoint:     github.Endpoint,
+		RedirectURL:  devConfig.Auth.RedirectURI,
+		Scopes:       []string{"read:user"},
+	}
+
+	githubAppOptions, err := loadGithubAppOptions(devConfig.Github)
+	if err != nil {
+		return err
+	}
+
+	webhookClient, err := webhook.New(webhook.Options.Secret(devConfig.Github.WebhookSecret))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook client: %v", err)
+	}
+
+	ticketSigner, err := createStaticSigner(devConfig.Auth.TicketKeyFile)
+	if err != nil {
+		return err
+	}
+	environmentCipher, err := auth.NewCipher(devConfig.Auth.EnvironmentSecret)
+	if err != nil {
+		return err
+	}
+
+	initEventOptions := createEventOptions(ticketSigner, devConfig.Events.Init, true)
+	buildEventOptions := createEventOptions(ticketSigner, devConfig.Events.Build, false)
+	deployTicketOptions := createEventOptions(ticketSigner, devConfig.Events.Deploy, true).TicketOpts
+	deleteEventOptions := createEventOptions(ticketSigner, devConfig.Events.Delete, true)
+	rollbackEventOptions := createEventOptions(ticketSigner, devConfig.Events.Rollback, true)
+
+	cloudLoggerOptions := &pipeline.CloudLoggerOptions{}
+	if devConfig.Pipeline.LogFile != "" {
+		logFile, err := os.OpenFile(devConfig.Pipeline.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open pipeline log file: %v", err)
+		}
+		defer logFile.Close()
+		cloudLoggerOptions.Sink = logFile
+	}
+
+	var eventPublisher pipeline.EventPublisher = pipeline.NewEventBridgePublisher(eventClient)
+	var localPipeline *localPublisher
+	if devConfig.Pipeline.Publisher == "local" {
+		ticketVerifier, err := ticketSigner.Verifier()
+		if err != nil {
+			return err
+		}
+		localPipeline = newLocalPublisher(awsConfig, devConfig, ticketVerifier, environmentCipher, cloudLoggerOptions)
+		eventPublisher = localPipeline
+	}
+
+	userStore := store.NewDynamoUserStore(dynamoClient, devConfig.Tables.User)
+	projectStore := store.NewDynamoProjectStore(dynamoClient, devConfig.Tables.Project)
+	subscriptionStore := store.NewDynamoSubscriptionStore(dynamoClient, devConfig.Tables.Subscription)
+
+	mux := http.NewServeMux()
+
+	mux.Handle("/api/admin/", lambdaHandler(adminroutes.NewRouter(adminctx.Context{
+		DynamoClient:       dynamoClient,
+		UserTable:          devConfig.Tables.User,
+		ProjectTable:       devConfig.Tables.Project,
+		SubscriptionTable:  devConfig.Tables.Subscription,
+		UserStore:          userStore,
+		ProjectStore:       projectStore,
+		SubscriptionStore:  subscriptionStore,
+		JwtOptions:         jwtOptions,
+		CursorSecret:       devConfig.Auth.CursorSecret,
+		EventPublisher:     eventPublisher,
+		DeleteEventOptions: deleteEventOptions,
+		TicketSigner:       ticketSigner,
+		CloudwatchClient:   cloudwatchClient,
+		LogConfiguration: &adminctx.LogConfiguration{
+			ApiLogGroup:      devConfig.LogGroups.Api,
+			PipelineLogGroup: devConfig.LogGroups.Pipeline,
+			RouterLogGroup:   devConfig.LogGroups.Router,
+		},
+	}).Route))
+
+	mux.Handle("/api/auth/", lambdaHandler(authroutes.NewRouter(authctx.Context{
+		DynamoClient:        dynamoClient,
+		UserTable:           devConfig.Tables.User,
+		ProjectTable:        devConfig.Tables.Project,
+		JwtOptions:          jwtOptions,
+		OAuthConfig:         oauthConfig,
+		FrontendRedirectURI: devConfig.Auth.FrontendRedirectURI,
+	}).Route))
+
+	mux.Handle("/api/pipeline/", lambdaHandler(pipelineroutes.NewRouter(pipelinectx.Context{
+		DynamoClient:        dynamoClient,
+		UserTable:           devConfig.Tables.User,
+		ProjectTable:        devConfig.Tables.Project,
+		SubscriptionTable:   devConfig.Tables.Subscription,
+		WebhookClient:       webhookClient,
+		GithubAppOptions:    githubAppOptions,
+		CloudwatchClient:    cloudwatchClient,
+		CloudLoggerOptions:  cloudLoggerOptions,
+		EventPublisher:      eventPublisher,
+		BuildEventOptions:   buildEventOptions,
+		DeployTicketOptions: deployTicketOptions,
+		EnvironmentCipher:   environmentCipher,
+		SecretClient:        secretClient,
+	}).Route))
+
+	mux.Handle("/api/resource/", lambdaHandler(resourceroutes.NewRouter(resourcectx.Context{
+		DynamoClient:          dynamoClient,
+		UserTable:             devConfig.Tables.User,
+		ProjectTable:          devConfig.Tables.Project,
+		SubscriptionTable:     devConfig.Tables.Subscription,
+		DeploymentTable:       devConfig.Tables.Deployment,
+		UserStore:             userStore,
+		ProjectStore:          projectStore,
+		SubscriptionStore:     subscriptionStore,
+		CloudwatchClient:      cloudwatchClient,
+		CloudLoggerOptions:    cloudLoggerOptions,
+		GithubAppOptions:      githubAppOptions,
+		JwtOptions:            jwtOptions,
+		CursorSecret:          devConfig.Auth.CursorSecret,
+		EnvironmentCipher:     environmentCipher,
+		SecretClient:          secretClient,
+		EventPublisher:        eventPublisher,
+		InitEventOptions:      initEventOptions,
+		BuildEventOptions:     buildEventOptions,
+		DeployTicketOptions:   deployTicketOptions,
+		DeleteEventOptions:    deleteEventOptions,
+		RollbackEventOptions:  rollbackEventOptions,
+		CloudfrontCacheClient: cloudfrontClient,
+		CloudfrontCacheArn:    devConfig.CloudfrontCacheArn,
+	}).Route))
+
+	mux.Handle("/api/user/", lambdaHandler(userroutes.NewRouter(userctx.Context{
+		DynamoClient:      dynamoClient,
+		UserTable:         devConfig.Tables.User,
+		ProjectTable:      devConfig.Tables.Project,
+		SubscriptionTable: devConfig.Tables.Subscription,
+		UserStore:         userStore,
+		ProjectStore:      projectStore,
+		SubscriptionStore: subscriptionStore,
+		JwtOptions:        jwtOptions,
+		UserConfiguration: &userctx.UserConfiguration{
+			AdminUsername: devConfig.AdminGithubUsername,
+		},
+	}).Route))
+
+	server := &http.Server{
+		Addr:    devConfig.Listen,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Printf("listening on http://%s\n", devConfig.Listen)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to serve: %v", err)
+	}
+	if localPipeline != nil {
+		logger.Printf("waiting for running pipeline steps...\n")
+		localPipeline.Wait()
+	}
+	return nil
+}
+
+// loadAwsConfig creates the aws config shared by all clients.
+// Endpoints of the config file are resolved with an immutable hostname (path style addressing),
+// which is required by most local emulators like MinIO.
+func loadAwsConfig(ctx context.Context, devConfig *devConfig) (aws.Config, error) {
+	options := []func(*config.LoadOptions) error{
+		config.WithRegion(devConfig.Region),
+	}
+
+	if devConfig.Credentials.AccessKeyId != "" {
+		options = append(options, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			devConfig.Credentials.AccessKeyId, devConfig.Credentials.SecretAccessKey, "",
+		)))
+	}
+
+	if len(devConfig.Endpoints) > 0 {
+		endpoints := map[string]string{}
+		for service, endpoint := range devConfig.Endpoints {
+			endpoints[strings.ToLower(service)] = endpoint
+		}
+		options = append(options, config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
+			func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
+				endpoint, ok := endpoints[strings.ToLower(service)]
+				if !ok {
+					// fallback to the default endpoint resolution.
+					return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+				}
+				return aws.Endpoint{
+					URL:               endpoint,
+					SigningRegion:     region,
+					HostnameImmutable: true,
+				}, nil
+			},
+		)))
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load aws config: %v", err)
+	}
+	return awsConfig, nil
+}
+
+// loadGithubAppOptions reads the github app private key. If no key file is configured,
+// nil is returned and handlers that need the github app fail at runtime.
+func loadGithubAppOptions(githubConfig devGithub) (*auth.GithubAppOptions, error) {
+	if githubConfig.AppPrivateKeyFile == "" {
+		logger.Printf("WARNING: github.app_private_key_file is not set, github app requests will fail\n")
+		return nil, nil
+	}
+	pemAppSecret, err := os.ReadFile(githubConfig.AppPrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read github app private key: %v", err)
+	}
+	appSecret, err := jwt.ParseRSAPrivateKeyFromPEM(pemAppSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse github app private key: %v", err)
+	}
+	return &auth.GithubAppOptions{
+		AppId:     githubConfig.AppId,
+		AppSecret: appSecret,
+	}, nil
+}
+
+// createStaticSigner creates a signer with the keys of the keyring file.
+// If the file does not exist, a random Ed25519 key is generated and written to the file,
+// so that issued tokens stay valid across restarts.
+func createStaticSigner(keyFile string) (*auth.Signer, error) {
+	keys := []auth.PrivateKey{}
+	rawKeys, err := os.ReadFile(keyFile)
+	if err == nil {
+		if err := json.Unmarshal(rawKeys, &keys); err != nil {
+			return nil, fmt.Errorf("failed to decode keyring file '%s': %v", keyFile, err)
+		}
+		return auth.NewStaticSigner(keys)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read keyring file '%s': %v", keyFile, err)
+	}
+
+	key, err := auth.GeneratePrivateKey(auth.ALG_EDDSA)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, *key)
+	rawKeys, err = json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keyring: %v", err)
+	}
+	if err := os.WriteFile(keyFile, rawKeys, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write keyring file '%s': %v", keyFile, err)
+	}
+	logger.Printf("generated keyring file '%s'\n", keyFile)
+	return auth.NewStaticSigner(keys)
+}
+
+// createEventOptions creates the pipeline event options, ticket options are only attached if withTicket is set.
+func createEventOptions(ticketSigner *auth.Signer, event devEvent, withTicket bool) *pipeline.EventOptions {
+	var ticketOptions *pipeline.TicketOptions
+	if withTicket {
+		ticketOptions = pipeline.CreateTicketOptions(ticketSigner, event.Source, event.Action, time.Duration(event.TicketTTL))
+	}
+	return pipeline.CreateEventOptions(event.EventBus, event.Source, event.Action, ticketOptions)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	function "github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/event"
+
+	"github.com/megakuul/battleshiper/pipeline/delete/deleteproject"
+	deletectx "github.com/megakuul/battleshiper/pipeline/delete/eventcontext"
+	"github.com/megakuul/battleshiper/pipeline/deploy/deployproject"
+	deployctx "github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
+	initctx "github.com/megakuul/battleshiper/pipeline/init/eventcontext"
+	"github.com/megakuul/battleshiper/pipeline/init/initproject"
+)
+
+// LOCAL_STEP_TIMEOUT limits a single pipeline step, it matches the maximum runtime of a lambda function.
+const LOCAL_STEP_TIMEOUT = 15 * time.Minute
+
+// pipelineHandler is the signature of the pipeline lambda handlers.
+type pipelineHandler func(context.Context, events.CloudWatchEvent) error
+
+// localPublisher is an EventPublisher that runs the pipeline steps in the dev server process.
+// Events are dispatched in the background like on eventbridge, the api handler returns before the step is finished.
+// Build requests are built on the local machine and handed to the deploy step in the form of an aws.batch event.
+type localPublisher struct {
+	initHandler     pipelineHandler
+	deployHandler   pipelineHandler
+	rollbackHandler pipelineHandler
+	deleteHandler   pipelineHandler
+
+	s3Client         *s3.Client
+	secretClient     *secretsmanager.Client
+	buildAssetBucket string
+	buildTimeout     time.Duration
+	deployEvent      devEvent
+
+	running sync.WaitGroup
+}
+
+// newLocalPublisher creates the pipeline handlers with the same configuration the pipeline lambdas receive.
+func newLocalPublisher(awsConfig aws.Config, devConfig *devConfig, ticketVerifier *auth.Verifier, environmentCipher *auth.Cipher, cloudLoggerOptions *pipeline.CloudLoggerOptions) *localPublisher {
+	dynamoClient := dynamodb.NewFromConfig(awsConfig)
+	s3Client := s3.NewFromConfig(awsConfig)
+	cloudformationClient := cloudformation.NewFromConfig(awsConfig)
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+	cloudfrontCacheClient := cloudfrontkeyvaluestore.NewFromConfig(awsConfig)
+	ticketOptions := pipeline.CreateTicketVerificationOptions(ticketVerifier)
+	pipelineConfig := devConfig.Pipeline
+	transferOptions := pipeline.CreateTransferOptions(pipelineConfig.TransferConcurrency)
+
+	deployCtx := deployctx.Context{
+		DynamoClient:          dynamoClient,
+		UserTable:             devConfig.Tables.User,
+		ProjectTable:          devConfig.Tables.Project,
+		TicketTable:           devConfig.Tables.Ticket,
+		SubscriptionTable:     devConfig.Tables.Subscription,
+		DeploymentTable:       devConfig.Tables.Deployment,
+		TicketOptions:         ticketOptions,
+		EnvironmentCipher:     environmentCipher,
+		CloudformationClient:  cloudformationClient,
+		SecretClient:          secretClient,
+		S3Client:              s3Client,
+		TransferOptions:       transferOptions,
+		CloudwatchClient:      cloudwatchlogs.NewFromConfig(awsConfig),
+		CloudLoggerOptions:    cloudLoggerOptions,
+		CloudfrontClient:      cloudfront.NewFromConfig(awsConfig),
+		CloudfrontCacheClient: cloudfrontCacheClient,
+		FunctionClient:        function.NewFromConfig(awsConfig),
+		MetricClient:          cloudwatch.NewFromConfig(awsConfig),
+		DeploymentConfiguration: &deployctx.DeploymentConfiguration{
+			ChangeSetTimeout:  time.Duration(pipelineConfig.ChangeSetTimeout),
+			DeplyomentTimeout: time.Duration(pipelineConfig.DeploymentTimeout),
+		},
+		BucketConfiguration: &deployctx.BucketConfiguration{
+			ArtifactBucketName: pipelineConfig.ArtifactBucket,
+		},
+		ProjectConfiguration: &deployctx.ProjectConfiguration{
+			ServerNamePrefix:         pipelineConfig.ServerNamePrefix,
+			ServerRuntime:            pipelineConfig.ServerRuntime,
+			ServerMemory:             pipelineConfig.ServerMemory,
+			ServerTimeout:            pipelineConfig.ServerTimeout,
+			CloudfrontDistributionId: pipelineConfig.CloudfrontDistributionId,
+			CloudfrontCacheArn:       devConfig.CloudfrontCacheArn,
+		},
+	}
+
+	return &localPublisher{
+		initHandler: initproject.HandleInitProject(initctx.Context{
+			DynamoClient:         dynamoClient,
+			UserTable:            devConfig.Tables.User,
+			ProjectTable:         devConfig.Tables.Project,
+			TicketTable:          devConfig.Tables.Ticket,
+			SubscriptionTable:    devConfig.Tables.Subscription,
+			TicketOptions:        ticketOptions,
+			CloudformationClient: cloudformationClient,
+			SecretClient:         secretClient,
+			DeploymentConfiguration: &initctx.DeploymentConfiguration{
+				ServiceRoleArn: pipelineConfig.DeploymentServiceRoleArn,
+				Timeout:        time.Duration(pipelineConfig.DeploymentTimeout),
+			},
+			BucketConfiguration: &initctx.BucketConfiguration{
+				StaticBucketName:     pipelineConfig.StaticBucket,
+				BuildAssetBucketName: pipelineConfig.BuildAssetBucket,
+			},
+			ProjectConfiguration: &initctx.ProjectConfiguration{
+				EventLogPrefix:    pipelineConfig.EventLogPrefix,
+				BuildLogPrefix:    pipelineConfig.BuildLogPrefix,
+				DeployLogPrefix:   pipelineConfig.DeployLogPrefix,
+				ServerLogPrefix:   pipelineConfig.ServerLogPrefix,
+				LogRetentionDays:  pipelineConfig.LogRetentionDays,
+				BuildEventbusName: devConfig.Events.Build.EventBus,
+				BuildEventSource:  devConfig.Events.Build.Source,
+				BuildEventAction:  devConfig.Events.Build.Action,
+				BuildJobQueueArn:  pipelineConfig.BuildJobQueueArn,
+				BuildJobTimeout:   time.Duration(pipelineConfig.BuildTimeout),
+				BuildJobVCPUS:     pipelineConfig.BuildJobVCPUS,
+				BuildJobMemory:    pipelineConfig.BuildJobMemory,
+			},
+		}),
+		deployHandler:   deployproject.HandleDeployProject(deployCtx),
+		rollbackHandler: deployproject.HandleRollbackProject(deployCtx),
+		deleteHandler: deleteproject.HandleDeleteProject(deletectx.Context{
+			DynamoClient:          dynamoClient,
+			ProjectTable:          devConfig.Tables.Project,
+			TicketTable:           devConfig.Tables.Ticket,
+			DeploymentTable:       devConfig.Tables.Deployment,
+			TicketOptions:         ticketOptions,
+			S3Client:              s3Client,
+			TransferOptions:       transferOptions,
+			CloudformationClient:  cloudformationClient,
+			SecretClient:          secretClient,
+			CloudfrontCacheClient: cloudfrontCacheClient,
+			DeletionConfiguration: &deletectx.DeletionConfiguration{
+				Timeout: time.Duration(pipelineConfig.DeletionTimeout),
+			},
+			BucketConfiguration: &deletectx.BucketConfiguration{
+				StaticBucketName: pipelineConfig.StaticBucket,
+			},
+			CloudfrontConfiguration: &deletectx.CloudfrontConfiguration{
+				CacheArn: devConfig.CloudfrontCacheArn,
+			},
+		}),
+		s3Client:         s3Client,
+		secretClient:     secretClient,
+		buildAssetBucket: pipelineConfig.BuildAssetBucket,
+		buildTimeout:     time.Duration(pipelineConfig.BuildTimeout),
+		deployEvent:      devConfig.Events.Deploy,
+	}
+}
+
+func (p *localPublisher) PublishInit(transportCtx context.Context, options *pipeline.EventOptions, request *event.InitRequest) error {
+	return p.dispatch("init", p.initHandler, options.Source, options.Action, request)
+}
+
+func (p *localPublisher) PublishBuild(transportCtx context.Context, options *pipeline.EventOptions, projectName string, request *event.BuildRequest) error {
+	p.running.Add(1)
+	go func() {
+		defer p.running.Done()
+
+		deployRequest := &event.DeployRequest{
+			Parameters: event.DeployParameters{
+				DeployTicket:        request.DeployTicket,
+				ExecutionIdentifier: request.ExecutionIdentifier,
+			},
+			Status: "SUCCEEDED",
+		}
+		if err := p.build(projectName, request); err != nil {
+			logger.Printf("LOCAL PIPELINE: build '%s' failed: %v\n", request.ExecutionIdentifier, err)
+			deployRequest.Status = "FAILED"
+			deployRequest.StatusReason = err.Error()
+		}
+
+		if err := p.dispatch("deploy", p.deployHandler, p.deployEvent.Source, p.deployEvent.Action, deployRequest); err != nil {
+			logger.Printf("LOCAL PIPELINE: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+func (p *localPublisher) PublishDelete(transportCtx context.Context, options *pipeline.EventOptions, request *event.DeleteRequest) error {
+	return p.dispatch("delete", p.deleteHandler, options.Source, options.Action, request)
+}
+
+func (p *localPublisher) PublishRollback(transportCtx context.Context, options *pipeline.EventOptions, request *event.RollbackRequest) error {
+	return p.dispatch("rollback", p.rollbackHandler, options.Source, options.Action, request)
+}
+
+// Wait blocks until all dispatched pipeline steps are finished.
+func (p *localPublisher) Wait() {
+	p.running.Wait()
+}
+
+// dispatch runs the handler in the background with an event equal to the one eventbridge delivers.
+func (p *localPublisher) dispatch(step string, handler pipelineHandler, source, detailType string, request any) error {
+	requestRaw, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to serialize %s request: %v", step, err)
+	}
+	cloudwatchEvent := events.CloudWatchEvent{
+		Version:    "0",
+		Source:     source,
+		DetailType: detailType,
+		Time:       time.Now(),
+		Detail:     requestRaw,
+	}
+
+	p.running.Add(1)
+	go func() {
+		defer p.running.Done()
+		// the request context ends with the api call, the step runs with its own timeout like a separate lambda.
+		ctx, cancel := context.WithTimeout(context.Background(), LOCAL_STEP_TIMEOUT)
+		defer cancel()
+
+		logger.Printf("LOCAL PIPELINE: running %s step\n", step)
+		if err := handler(ctx, cloudwatchEvent); err != nil {
+			logger.Printf("LOCAL PIPELINE: %s step failed: %v\n", step, err)
+			return
+		}
+		logger.Printf("LOCAL PIPELINE: %s step finished\n", step)
+	}()
+	return nil
+}
+
+// build runs the steps of the build job on the local machine (clone, build command, upload of the output directory).
+// The output is uploaded to the build asset path of the project where the deploy step expects it.
+func (p *localPublisher) build(projectName string, request *event.BuildRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.buildTimeout)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "battleshiper-build-")
+	if err != nil {
+		return fmt.Errorf("failed to create build directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// like the build job, the project variables are loaded from the build environment secret.
+	buildEnvironment, err := p.secretClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(pipeline.BuildEnvironmentSecretName(projectName)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load build environment: %v", err)
+	}
+	environment := append(request.Environment, event.EnvironmentVariable{
+		Name:  pipeline.BUILD_ENVIRONMENT_VARIABLE,
+		Value: aws.ToString(buildEnvironment.SecretString),
+	})
+
+	logger.Printf("LOCAL PIPELINE: START BUILD %s\n", request.ExecutionIdentifier)
+	if err := runCommand(ctx, workDir, environment, "git", "clone", "--branch", request.RepositoryBranch, request.RepositoryURL, "."); err != nil {
+		return fmt.Errorf("failed to clone repository: %v", err)
+	}
+	buildCommand := fmt.Sprintf("eval \"$%s\" && %s", pipeline.BUILD_ENVIRONMENT_VARIABLE, request.BuildCommand)
+	if err := runCommand(ctx, workDir, environment, "/bin/sh", "-c", buildCommand); err != nil {
+		return fmt.Errorf("failed to run build command: %v", err)
+	}
+
+	outputDir := filepath.Join(workDir, request.OutputDirectory)
+	keyPrefix := path.Join(projectName, request.ExecutionIdentifier)
+	err = filepath.WalkDir(outputDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(outputDir, filePath)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(p.buildAssetBucket),
+			Key:    aws.String(path.Join(keyPrefix, filepath.ToSlash(relPath))),
+			Body:   file,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload build output: %v", err)
+	}
+
+	commitCmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	commitCmd.Dir = workDir
+	commit, err := commitCmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to resolve build commit: %v", err)
+	}
+	_, err = p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(p.buildAssetBucket),
+		Key:    aws.String(path.Join(keyPrefix, "commit")),
+		Body:   bytes.NewReader(commit),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload build commit: %v", err)
+	}
+	return nil
+}
+
+// runCommand runs the command in the directory, the output is forwarded to the dev server output.
+// The environment of the build container is added to the environment of the dev server.
+func runCommand(ctx context.Context, dir string, environment []event.EnvironmentVariable, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for _, variable := range environment {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", variable.Name, variable.Value))
+	}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// createMissingTables creates the battleshiper tables that do not exist yet.
+// The key schemas and indexes mirror the table definitions of the sam template.
+func createMissingTables(transportCtx context.Context, dynamoClient *dynamodb.Client, tables devTables) error {
+	definitions := []*dynamodb.CreateTableInput{
+		tableDefinition(tables.User, "id", types.ScalarAttributeTypeS, map[string]types.ScalarAttributeType{
+			"installation_id": types.ScalarAttributeTypeN,
+		}),
+		tableDefinition(tables.Project, "project_name", types.ScalarAttributeTypeS, map[string]types.ScalarAttributeType{
+			"owner_id": types.ScalarAttributeTypeS,
+		}),
+		tableDefinition(tables.Subscription, "id", types.ScalarAttributeTypeS, nil),
+		tableDefinition(tables.Ticket, "jti", types.ScalarAttributeTypeS, nil),
+		withSortKey(tableDefinition(tables.Deployment, "project_name", types.ScalarAttributeTypeS, nil),
+			"execution_identifier", types.ScalarAttributeTypeS),
+	}
+
+	for _, definition := range definitions {
+		_, err := dynamoClient.DescribeTable(transportCtx, &dynamodb.DescribeTableInput{
+			TableName: definition.TableName,
+		})
+		if err == nil {
+			continue
+		}
+		var notFoundErr *types.ResourceNotFoundException
+		if !errors.As(err, &notFoundErr) {
+			return fmt.Errorf("failed to describe table '%s': %v", *definition.TableName, err)
+		}
+
+		if _, err := dynamoClient.CreateTable(transportCtx, definition); err != nil {
+			return fmt.Errorf("failed to create table '%s': %v", *definition.TableName, err)
+		}
+		logger.Printf("created table '%s'\n", *definition.TableName)
+	}
+	return nil
+}
+
+// tableDefinition creates a pay per request table with a hash key and one global secondary index
+// (named "gsi_<attribute>") per index attribute.
+func tableDefinition(name, key string, keyType types.ScalarAttributeType, indexes map[string]types.ScalarAttributeType) *dynamodb.CreateTableInput {
+	definition := &dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: types.BillingModePayPerRequest,
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(key), AttributeType: keyType},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(key), KeyType: types.KeyTypeHash},
+		},
+	}
+
+	for attribute, attributeType := range indexes {
+		definition.AttributeDefinitions = append(definition.AttributeDefinitions, types.AttributeDefinition{
+			AttributeName: aws.String(attribute), AttributeType: attributeType,
+		})
+		definition.GlobalSecondaryIndexes = append(definition.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+			IndexName: aws.String("gsi_" + attribute),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String(attribute), KeyType: types.KeyTypeHash},
+			},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		})
+	}
+	return definition
+}
+
+// withSortKey adds a range key to the key schema of the table definition.
+func withSortKey(definition *dynamodb.CreateTableInput, key string, keyType types.ScalarAttributeType) *dynamodb.CreateTableInput {
+	definition.AttributeDefinitions = append(definition.AttributeDefinitions, types.AttributeDefinition{
+		AttributeName: aws.String(key), AttributeType: keyType,
+	})
+	definition.KeySchema = append(definition.KeySchema, types.KeySchemaElement{
+		AttributeName: aws.String(key), KeyType: types.KeyTypeRange,
+	})
+	return definition
+}
@@ -0,0 +1,76 @@
+// battleshiper-openapi generates the OpenAPI document of all battleshiper api modules.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/megakuul/battleshiper/lib/router"
+
+	adminctx "github.com/megakuul/battleshiper/api/admin/routecontext"
+	adminroutes "github.com/megakuul/battleshiper/api/admin/routes"
+	authctx "github.com/megakuul/battleshiper/api/auth/routecontext"
+	authroutes "github.com/megakuul/battleshiper/api/auth/routes"
+	pipelinectx "github.com/megakuul/battleshiper/api/pipeline/routecontext"
+	pipelineroutes "github.com/megakuul/battleshiper/api/pipeline/routes"
+	resourcectx "github.com/megakuul/battleshiper/api/resource/routecontext"
+	resourceroutes "github.com/megakuul/battleshiper/api/resource/routes"
+	userctx "github.com/megakuul/battleshiper/api/user/routecontext"
+	userroutes "github.com/megakuul/battleshiper/api/user/routes"
+)
+
+var (
+	format  = flag.String("format", "json", "output format of the document ('json' or 'yaml')")
+	output  = flag.String("out", "", "file the document is written to (defaults to stdout)")
+	version = flag.String("version", "1.0.0", "api version written to the document")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// routers are only created to inspect the registered routes, therefore the route contexts stay empty.
+	routes := []router.RouteInfo{}
+	routes = append(routes, adminroutes.NewRouter(adminctx.Context{}).Routes()...)
+	routes = append(routes, authroutes.NewRouter(authctx.Context{}).Routes()...)
+	routes = append(routes, pipelineroutes.NewRouter(pipelinectx.Context{}).Routes()...)
+	routes = append(routes, resourceroutes.NewRouter(resourcectx.Context{}).Routes()...)
+	routes = append(routes, userroutes.NewRouter(userctx.Context{}).Routes()...)
+
+	document := router.GenerateOpenAPI(router.OpenAPIInfo{
+		Title:       "Battleshiper API",
+		Description: "http api of the battleshiper admin, auth, pipeline, resource and user modules.",
+		Version:     *version,
+	}, routes)
+
+	var rawDocument []byte
+	switch *format {
+	case "json":
+		jsonDocument, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize document: %v", err)
+		}
+		rawDocument = append(jsonDocument, '\n')
+	case "yaml":
+		rawDocument = marshalYAML(document)
+	default:
+		return fmt.Errorf("unsupported format '%s'; expected 'json' or 'yaml'", *format)
+	}
+
+	if *output == "" {
+		_, err := os.Stdout.Write(rawDocument)
+		return err
+	}
+	if err := os.WriteFile(*output, rawDocument, 0644); err != nil {
+		return fmt.Errorf("failed to write document: %v", err)
+	}
+	return nil
+}
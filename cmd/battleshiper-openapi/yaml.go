@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// plainScalarRegex matches strings that can be written as plain yaml scalars without being misinterpreted.
+var plainScalarRegex = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./-]*$`)
+
+// marshalYAML serializes the generic document to yaml.
+// Only the types produced by router.GenerateOpenAPI are supported.
+func marshalYAML(document map[string]any) []byte {
+	builder := &strings.Builder{}
+	writeYAMLMap(builder, document, 0)
+	return []byte(builder.String())
+}
+
+func writeYAMLMap(builder *strings.Builder, value map[string]any, indent int) {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		builder.WriteString(strings.Repeat("  ", indent))
+		builder.WriteString(yamlScalar(key))
+		builder.WriteString(":")
+		writeYAMLValue(builder, value[key], indent)
+	}
+}
+
+func writeYAMLList(builder *strings.Builder, value []any, indent int) {
+	for _, item := range value {
+		builder.WriteString(strings.Repeat("  ", indent))
+		builder.WriteString("-")
+		writeYAMLValue(builder, item, indent)
+	}
+}
+
+// writeYAMLValue writes the value after a key or list marker.
+func writeYAMLValue(builder *strings.Builder, value any, indent int) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			builder.WriteString(" {}\n")
+			return
+		}
+		builder.WriteString("\n")
+		writeYAMLMap(builder, v, indent+1)
+	case []any:
+		if len(v) == 0 {
+			builder.WriteString(" []\n")
+			return
+		}
+		builder.WriteString("\n")
+		writeYAMLList(builder, v, indent+1)
+	default:
+		builder.WriteString(" ")
+		builder.WriteString(yamlScalar(v))
+		builder.WriteString("\n")
+	}
+}
+
+func yamlScalar(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if plainScalarRegex.MatchString(v) && !isYAMLKeyword(v) {
+			return v
+		}
+		// json strings are valid yaml double quoted scalars.
+		rawString, _ := json.Marshal(v)
+		return string(rawString)
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isYAMLKeyword checks if the plain string would be interpreted as bool or null.
+func isYAMLKeyword(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+		return true
+	default:
+		return false
+	}
+}
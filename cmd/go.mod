@@ -0,0 +1,74 @@
+module github.com/megakuul/battleshiper/cmd
+
+go 1.23.0
+
+require (
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/aws/aws-sdk-go-v2 v1.32.7
+	github.com/aws/aws-sdk-go-v2/config v1.27.39
+	github.com/aws/aws-sdk-go-v2/credentials v1.17.37
+	github.com/aws/aws-sdk-go-v2/service/cloudformation v1.54.3
+	github.com/aws/aws-sdk-go-v2/service/cloudfront v1.44.1
+	github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore v1.7.3
+	github.com/aws/aws-sdk-go-v2/service/cloudwatch v1.43.4
+	github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs v1.40.3
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.35.3
+	github.com/aws/aws-sdk-go-v2/service/eventbridge v1.34.3
+	github.com/aws/aws-sdk-go-v2/service/lambda v1.69.2
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.63.3
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.33.3
+	github.com/go-playground/webhooks/v6 v6.4.0
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/google/uuid v1.6.0
+	github.com/megakuul/battleshiper/api/admin v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/api/auth v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/api/pipeline v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/api/resource v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/api/user v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/lib/helper v1.2.5
+	github.com/megakuul/battleshiper/lib/model v1.2.1
+	github.com/megakuul/battleshiper/lib/router v0.1.0
+	github.com/megakuul/battleshiper/pipeline/delete v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/pipeline/deploy v0.0.0-00010101000000-000000000000
+	github.com/megakuul/battleshiper/pipeline/init v0.0.0-00010101000000-000000000000
+	golang.org/x/oauth2 v0.23.0
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.7 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.15.8 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.16.14 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.26 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.26 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.1 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/v4a v1.3.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.23.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.5 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/checksum v1.3.20 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.19 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.20 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.17.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.23.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.27.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.31.3 // indirect
+	github.com/aws/smithy-go v1.22.1 // indirect
+	github.com/awslabs/goformation/v7 v7.14.9 // indirect
+	github.com/google/go-github/v63 v63.0.0 // indirect
+	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/megakuul/battleshiper/api/admin => ../api/admin
+	github.com/megakuul/battleshiper/api/auth => ../api/auth
+	github.com/megakuul/battleshiper/api/pipeline => ../api/pipeline
+	github.com/megakuul/battleshiper/api/resource => ../api/resource
+	github.com/megakuul/battleshiper/api/user => ../api/user
+	github.com/megakuul/battleshiper/lib/helper => ../lib/helper
+	github.com/megakuul/battleshiper/lib/model => ../lib/model
+	github.com/megakuul/battleshiper/lib/router => ../lib/router
+	github.com/megakuul/battleshiper/pipeline/delete => ../pipeline/delete
+	github.com/megakuul/battleshiper/pipeline/deploy => ../pipeline/deploy
+	github.com/megakuul/battleshiper/pipeline/init => ../pipeline/init
+)
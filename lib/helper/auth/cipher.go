@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// ErrInvalidCiphertext is returned if a value cannot be decoded or was not encrypted with the key of the cipher.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Cipher encrypts values with AES-256-GCM, the key is derived from a secret.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+type cipherCredentials struct {
+	Secret string `json:"secret"`
+}
+
+// CreateCipher fetches the credentialSecret containing "secret" from SecretsManager and creates a cipher from it.
+// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on the provided credentialARN.
+func CreateCipher(awsConfig aws.Config, transportCtx context.Context, credentialARN string) (*Cipher, error) {
+	secretManagerClient := secretsmanager.NewFromConfig(awsConfig)
+
+	secretResponse, err := secretManagerClient.GetSecretValue(transportCtx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(credentialARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire cipher secret: %v", err)
+	}
+
+	var credentials cipherCredentials
+	if err := json.Unmarshal([]byte(*secretResponse.SecretString), &credentials); err != nil {
+		return nil, fmt.Errorf("failed to decode cipher credential secret string: %v", err)
+	}
+	return NewCipher(credentials.Secret)
+}
+
+// NewCipher creates a cipher with the key derived from secret.
+func NewCipher(secret string) (*Cipher, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("cipher secret must not be empty")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts the plaintext with a random nonce and returns the base64 encoded nonce and ciphertext.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	ciphertext := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts a value created by Encrypt.
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	rawCiphertext, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(rawCiphertext) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := rawCiphertext[:c.aead.NonceSize()], rawCiphertext[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
@@ -2,18 +2,18 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type JwtOptions struct {
-	Secret string
-	TTL    time.Duration
+	// Signer is only available on the instance issuing session tokens.
+	Signer   *Signer
+	Verifier *Verifier
+	TTL      time.Duration
 }
 
 type UserClaims struct {
@@ -24,33 +24,39 @@ type UserClaims struct {
 	jwt.RegisteredClaims
 }
 
-type jwtCredentials struct {
-	Secret string `json:"secret"`
-}
-
-// CreateJwtOptions fetches the jwtSecret containing "secret" from SecretsManager and constructs the auth.JwtOptions.
-// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on the provided jwtSecretARN.
-func CreateJwtOptions(awsConfig aws.Config, transportCtx context.Context, jwtSecretARN string, ttl time.Duration) (*JwtOptions, error) {
-
-	secretManagerClient := secretsmanager.NewFromConfig(awsConfig)
-
-	secretRequest := &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(jwtSecretARN),
+// CreateJwtOptions loads the jwt public keys from the jwtPublicKeyARN and constructs auth.JwtOptions that can only verify tokens.
+// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on the provided jwtPublicKeyARN.
+func CreateJwtOptions(awsConfig aws.Config, transportCtx context.Context, jwtPublicKeyARN string, ttl time.Duration) (*JwtOptions, error) {
+	verifier, err := CreateVerifier(awsConfig, transportCtx, jwtPublicKeyARN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwt public keys: %v", err)
 	}
 
-	secretResponse, err := secretManagerClient.GetSecretValue(transportCtx, secretRequest)
+	return &JwtOptions{
+		Verifier: verifier,
+		TTL:      ttl,
+	}, nil
+}
+
+// CreateJwtSigningOptions loads the jwt private keys from the jwtCredentialARN and constructs auth.JwtOptions that can sign tokens.
+// The public keys of the signer are published to the jwtPublicKeyARN before the verifier loads them.
+// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on both secrets
+// and "secretsmanager:PutSecretValue" on the jwtPublicKeyARN.
+func CreateJwtSigningOptions(awsConfig aws.Config, transportCtx context.Context, jwtCredentialARN, jwtPublicKeyARN string, ttl time.Duration) (*JwtOptions, error) {
+	signer, err := CreateSigner(awsConfig, transportCtx, jwtCredentialARN, jwtPublicKeyARN)
 	if err != nil {
-		return nil, fmt.Errorf("failed to acquire jwt secret: %v", err)
+		return nil, fmt.Errorf("failed to load jwt private keys: %v", err)
 	}
 
-	var jwtCredentials jwtCredentials
-	if err := json.Unmarshal([]byte(*secretResponse.SecretString), &jwtCredentials); err != nil {
-		return nil, fmt.Errorf("failed to decode jwt credential secret string: %v", err)
+	verifier, err := CreateVerifier(awsConfig, transportCtx, jwtPublicKeyARN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwt public keys: %v", err)
 	}
 
 	return &JwtOptions{
-		Secret: jwtCredentials.Secret,
-		TTL:    ttl,
+		Signer:   signer,
+		Verifier: verifier,
+		TTL:      ttl,
 	}, nil
 }
 
@@ -66,8 +72,11 @@ func CreateJWT(options *JwtOptions, id, provider, username, avatarUrl string) (s
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(options.Secret))
+	if options.Signer == nil {
+		return "", fmt.Errorf("jwt options do not contain a signing key")
+	}
+
+	tokenString, err := options.Signer.Sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -76,11 +85,12 @@ func CreateJWT(options *JwtOptions, id, provider, username, avatarUrl string) (s
 }
 
 // ParseJWT verifies the jwt string based on the provided options. It returns the user claims or an error if invalid.
-func ParseJWT(options *JwtOptions, token string) (*UserClaims, error) {
+func ParseJWT(transportCtx context.Context, options *JwtOptions, token string) (*UserClaims, error) {
 	claims := &UserClaims{}
-	parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(options.Secret), nil
-	}, jwt.WithValidMethods([]string{"HS256"}))
+	parsedToken, err := jwt.ParseWithClaims(token, claims,
+		options.Verifier.Keyfunc(transportCtx),
+		jwt.WithValidMethods(options.Verifier.Algorithms()),
+	)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,608 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	secretsmanagertypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms supported by the keyring.
+const (
+	ALG_EDDSA = "EdDSA"
+	ALG_RS256 = "RS256"
+)
+
+// KEY_RETENTION is the number of newest keys that are accepted for verification after a rotation.
+// With a retention of 2, tokens signed with the previous key stay valid until the next rotation.
+const KEY_RETENTION = 2
+
+// KEYRING_RELOAD_INTERVAL limits how often a verifier reloads the public keys when a token references an unknown key.
+const KEYRING_RELOAD_INTERVAL = time.Minute
+
+// RSA_KEY_BITS is the size of generated RS256 keys.
+const RSA_KEY_BITS = 2048
+
+// ErrStaticKeyring is returned if a keyring that is not backed by a secret is rotated.
+var ErrStaticKeyring = errors.New("keyring is not backed by a secret")
+
+// ErrKeyringConflict is returned if the private key secret was updated by another instance during a rotation.
+var ErrKeyringConflict = errors.New("keyring was modified concurrently")
+
+// PrivateKey is a signing key as stored in the private key secret.
+type PrivateKey struct {
+	Id        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	// PrivateKey contains the pem encoded PKCS #8 private key.
+	PrivateKey string `json:"private_key"`
+	Created    int64  `json:"created"`
+	Retired    bool   `json:"retired"`
+}
+
+// PublicKey is a verification key as stored in the public key secret.
+type PublicKey struct {
+	Id        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	// PublicKey contains the pem encoded PKIX public key.
+	PublicKey string `json:"public_key"`
+	Created   int64  `json:"created"`
+	Retired   bool   `json:"retired"`
+}
+
+// JWK is the json web key representation of a public key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Id        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type signingKey struct {
+	PrivateKey
+	signer crypto.Signer
+}
+
+type verificationKey struct {
+	PublicKey
+	key crypto.PublicKey
+}
+
+// Signer signs tokens with the newest active key of the private keyring.
+// The public keys of the keyring are published to the public key secret, where verifiers load them.
+type Signer struct {
+	mutex   sync.RWMutex
+	keys    []signingKey
+	version string
+
+	client       *secretsmanager.Client
+	privateArn   string
+	publicKeyArn string
+}
+
+// Verifier verifies tokens with the active keys of the public keyring, it cannot sign tokens.
+type Verifier struct {
+	mutex    sync.RWMutex
+	keys     []verificationKey
+	loadedAt time.Time
+
+	client       *secretsmanager.Client
+	publicKeyArn string
+}
+
+// CreateSigner loads the private keyring from the credentialARN and publishes its public keys to the publicKeyARN.
+// The private key secret contains a json array of keys, if the array is empty a random Ed25519 key is generated
+// and written to the secret. Secrets in any other format are rejected.
+// The calling instance needs to have IAM access to the actions "secretsmanager:GetSecretValue"
+// and "secretsmanager:PutSecretValue" on both secrets.
+func CreateSigner(awsConfig aws.Config, transportCtx context.Context, credentialARN, publicKeyARN string) (*Signer, error) {
+	signer := &Signer{
+		client:       secretsmanager.NewFromConfig(awsConfig),
+		privateArn:   credentialARN,
+		publicKeyArn: publicKeyARN,
+	}
+	if err := signer.reload(transportCtx); err != nil {
+		return nil, err
+	}
+	if err := signer.bootstrap(transportCtx); err != nil {
+		return nil, err
+	}
+	if err := signer.publish(transportCtx); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// NewStaticSigner creates a signer from the provided keys, the signer does not publish its keys and cannot be rotated.
+func NewStaticSigner(keys []PrivateKey) (*Signer, error) {
+	signingKeys, err := parsePrivateKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{
+		keys: signingKeys,
+	}, nil
+}
+
+// Sign signs the claims with the newest active key, the key id is added as kid header.
+func (s *Signer) Sign(claims jwt.Claims) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var key *signingKey
+	for i := range s.keys {
+		if s.keys[i].Retired {
+			continue
+		}
+		if key == nil || s.keys[i].Created >= key.Created {
+			key = &s.keys[i]
+		}
+	}
+	if key == nil {
+		return "", fmt.Errorf("keyring does not contain an active key")
+	}
+
+	token := jwt.NewWithClaims(signingMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.Id
+	return token.SignedString(key.signer)
+}
+
+// PublicKeys returns the public keys of the keyring.
+func (s *Signer) PublicKeys() ([]PublicKey, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	publicKeys := []PublicKey{}
+	for _, key := range s.keys {
+		publicKey, err := encodePublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys = append(publicKeys, *publicKey)
+	}
+	return publicKeys, nil
+}
+
+// Verifier returns a static verifier for the keys of the signer.
+func (s *Signer) Verifier() (*Verifier, error) {
+	publicKeys, err := s.PublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	return NewStaticVerifier(publicKeys)
+}
+
+// Rotate generates a new signing key with the algorithm and writes the keyring back to both secrets.
+// Only the newest KEY_RETENTION keys stay active, older keys are retired and removed on the next rotation.
+// The calling instance needs to have IAM access to the action "secretsmanager:PutSecretValue" on both secrets.
+func (s *Signer) Rotate(transportCtx context.Context, algorithm string) (*PublicKey, error) {
+	if s.client == nil {
+		return nil, ErrStaticKeyring
+	}
+	// the secret is reloaded to include keys rotated by other instances.
+	if err := s.reload(transportCtx); err != nil {
+		return nil, err
+	}
+
+	newKey, err := GeneratePrivateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	keys := []PrivateKey{}
+	for _, key := range s.keys {
+		if !key.Retired {
+			keys = append(keys, key.PrivateKey)
+		}
+	}
+	version := s.version
+	s.mutex.RUnlock()
+	keys = append(keys, *newKey)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Created < keys[j].Created
+	})
+	for i := range keys[:max(len(keys)-KEY_RETENTION, 0)] {
+		keys[i].Retired = true
+	}
+
+	signingKeys, err := parsePrivateKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.write(transportCtx, keys, version); err != nil {
+		return nil, err
+	}
+	if err := s.reload(transportCtx); err != nil {
+		return nil, err
+	}
+
+	if err := s.publish(transportCtx); err != nil {
+		return nil, err
+	}
+	return encodePublicKey(signingKeys[len(signingKeys)-1])
+}
+
+// bootstrap generates the first key of an empty keyring.
+// If another instance bootstrapped the keyring in the meantime, its key is used instead.
+func (s *Signer) bootstrap(transportCtx context.Context) error {
+	s.mutex.RLock()
+	empty, version := len(s.keys) < 1, s.version
+	s.mutex.RUnlock()
+	if !empty {
+		return nil
+	}
+
+	key, err := GeneratePrivateKey(ALG_EDDSA)
+	if err != nil {
+		return err
+	}
+	if err := s.write(transportCtx, []PrivateKey{*key}, version); err != nil && !errors.Is(err, ErrKeyringConflict) {
+		return err
+	}
+	if err := s.reload(transportCtx); err != nil {
+		return err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if len(s.keys) < 1 {
+		return fmt.Errorf("keyring does not contain a key after bootstrap")
+	}
+	return nil
+}
+
+// write stores the keys in the private key secret, if the secret still holds the version the keys are based on.
+// The request token is derived from this version, therefore only the first of several instances writing
+// on top of the same version succeeds, the others fail with ErrKeyringConflict.
+func (s *Signer) write(transportCtx context.Context, keys []PrivateKey, version string) error {
+	secretString, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode private keys: %v", err)
+	}
+	requestToken := sha256.Sum256([]byte("keyring:" + version))
+	_, err = s.client.PutSecretValue(transportCtx, &secretsmanager.PutSecretValueInput{
+		SecretId:           aws.String(s.privateArn),
+		SecretString:       aws.String(string(secretString)),
+		ClientRequestToken: aws.String(hex.EncodeToString(requestToken[:])),
+	})
+	if err != nil {
+		var eErr *secretsmanagertypes.ResourceExistsException
+		if ok := errors.As(err, &eErr); ok {
+			return ErrKeyringConflict
+		}
+		return fmt.Errorf("failed to update private key secret: %v", err)
+	}
+	return nil
+}
+
+func (s *Signer) reload(transportCtx context.Context) error {
+	secretResponse, err := s.client.GetSecretValue(transportCtx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.privateArn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to acquire secret: %v", err)
+	}
+	keys := []PrivateKey{}
+	if err := json.Unmarshal([]byte(aws.ToString(secretResponse.SecretString)), &keys); err != nil {
+		return fmt.Errorf("failed to decode private key secret string: expected json array of keys: %v", err)
+	}
+	signingKeys, err := parsePrivateKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = signingKeys
+	s.version = aws.ToString(secretResponse.VersionId)
+	return nil
+}
+
+// publish writes the public keys to the public key secret if they differ from the published keys.
+func (s *Signer) publish(transportCtx context.Context) error {
+	publicKeys, err := s.PublicKeys()
+	if err != nil {
+		return err
+	}
+	secretString, err := json.Marshal(publicKeys)
+	if err != nil {
+		return fmt.Errorf("failed to encode public keys: %v", err)
+	}
+
+	publishedString, err := readSecret(transportCtx, s.client, s.publicKeyArn)
+	if err != nil {
+		return err
+	}
+	if publishedString == string(secretString) {
+		return nil
+	}
+
+	_, err = s.client.PutSecretValue(transportCtx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(s.publicKeyArn),
+		SecretString: aws.String(string(secretString)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish public keys: %v", err)
+	}
+	return nil
+}
+
+// CreateVerifier loads the public keyring from the publicKeyARN.
+// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on the provided publicKeyARN.
+func CreateVerifier(awsConfig aws.Config, transportCtx context.Context, publicKeyARN string) (*Verifier, error) {
+	verifier := &Verifier{
+		client:       secretsmanager.NewFromConfig(awsConfig),
+		publicKeyArn: publicKeyARN,
+	}
+	if err := verifier.reload(transportCtx); err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+// NewStaticVerifier creates a verifier from the provided keys, the verifier is never reloaded.
+func NewStaticVerifier(keys []PublicKey) (*Verifier, error) {
+	verificationKeys, err := parsePublicKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		keys: verificationKeys,
+	}, nil
+}
+
+// Algorithms returns the signing algorithms accepted by the verifier.
+func (v *Verifier) Algorithms() []string {
+	return []string{ALG_EDDSA, ALG_RS256}
+}
+
+// Keyfunc returns the jwt.Keyfunc that resolves the verification key referenced by the kid header.
+// If the key is unknown, the keyring is reloaded, so that keys rotated in the meantime are accepted.
+// Tokens without kid header are rejected.
+func (v *Verifier) Keyfunc(transportCtx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token does not reference a key")
+		}
+		key, err := v.key(transportCtx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("key '%s' is not valid for algorithm '%s'", kid, token.Method.Alg())
+		}
+		return key.key, nil
+	}
+}
+
+// JWKS returns the active public keys as json web keys.
+func (v *Verifier) JWKS() ([]JWK, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	jwks := []JWK{}
+	for _, key := range v.keys {
+		if key.Retired {
+			continue
+		}
+		jwk := JWK{
+			Id:        key.Id,
+			Algorithm: key.Algorithm,
+			Use:       "sig",
+		}
+		switch publicKey := key.key.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		default:
+			return nil, fmt.Errorf("key '%s' has an unsupported type", key.Id)
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks, nil
+}
+
+func (v *Verifier) key(transportCtx context.Context, kid string) (*verificationKey, error) {
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+
+	v.mutex.RLock()
+	reloadable := v.client != nil && time.Since(v.loadedAt) > KEYRING_RELOAD_INTERVAL
+	v.mutex.RUnlock()
+	if reloadable {
+		if err := v.reload(transportCtx); err != nil {
+			return nil, err
+		}
+		if key, ok := v.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key '%s' is unknown or retired", kid)
+}
+
+func (v *Verifier) lookup(kid string) (*verificationKey, bool) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	for i, key := range v.keys {
+		if key.Id == kid && !key.Retired {
+			return &v.keys[i], true
+		}
+	}
+	return nil, false
+}
+
+func (v *Verifier) reload(transportCtx context.Context) error {
+	secretString, err := readSecret(transportCtx, v.client, v.publicKeyArn)
+	if err != nil {
+		return err
+	}
+	keys := []PublicKey{}
+	if err := json.Unmarshal([]byte(secretString), &keys); err != nil {
+		return fmt.Errorf("failed to decode public key secret string: %v", err)
+	}
+	verificationKeys, err := parsePublicKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.keys = verificationKeys
+	v.loadedAt = time.Now()
+	return nil
+}
+
+func readSecret(transportCtx context.Context, client *secretsmanager.Client, secretArn string) (string, error) {
+	secretResponse, err := client.GetSecretValue(transportCtx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretArn),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire secret: %v", err)
+	}
+	return *secretResponse.SecretString, nil
+}
+
+func parsePrivateKeys(keys []PrivateKey) ([]signingKey, error) {
+	signingKeys := []signingKey{}
+	for _, key := range keys {
+		if key.Id == "" {
+			return nil, fmt.Errorf("keys require a kid")
+		}
+		block, _ := pem.Decode([]byte(key.PrivateKey))
+		if block == nil {
+			return nil, fmt.Errorf("key '%s' does not contain a pem encoded private key", key.Id)
+		}
+		parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key '%s': %v", key.Id, err)
+		}
+		signer, err := algorithmSigner(key.Algorithm, parsedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key '%s': %v", key.Id, err)
+		}
+		signingKeys = append(signingKeys, signingKey{PrivateKey: key, signer: signer})
+	}
+	return signingKeys, nil
+}
+
+func parsePublicKeys(keys []PublicKey) ([]verificationKey, error) {
+	verificationKeys := []verificationKey{}
+	for _, key := range keys {
+		block, _ := pem.Decode([]byte(key.PublicKey))
+		if block == nil {
+			return nil, fmt.Errorf("key '%s' does not contain a pem encoded public key", key.Id)
+		}
+		parsedKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key '%s': %v", key.Id, err)
+		}
+		verificationKeys = append(verificationKeys, verificationKey{PublicKey: key, key: parsedKey})
+	}
+	return verificationKeys, nil
+}
+
+// algorithmSigner checks that the key type matches the algorithm.
+func algorithmSigner(algorithm string, key any) (crypto.Signer, error) {
+	switch typedKey := key.(type) {
+	case ed25519.PrivateKey:
+		if algorithm == ALG_EDDSA {
+			return typedKey, nil
+		}
+	case *rsa.PrivateKey:
+		if algorithm == ALG_RS256 {
+			return typedKey, nil
+		}
+	}
+	return nil, fmt.Errorf("key type does not match algorithm '%s'", algorithm)
+}
+
+func signingMethod(algorithm string) jwt.SigningMethod {
+	if algorithm == ALG_RS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// GeneratePrivateKey generates a random signing key for the algorithm.
+func GeneratePrivateKey(algorithm string) (*PrivateKey, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %v", err)
+	}
+
+	var key crypto.Signer
+	switch algorithm {
+	case ALG_EDDSA:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key: %v", err)
+		}
+		key = edKey
+	case ALG_RS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, RSA_KEY_BITS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key: %v", err)
+		}
+		key = rsaKey
+	default:
+		return nil, fmt.Errorf("unsupported algorithm '%s'", algorithm)
+	}
+	return encodePrivateKey(hex.EncodeToString(idBytes), algorithm, key, time.Now().Unix())
+}
+
+func encodePrivateKey(id, algorithm string, key crypto.Signer, created int64) (*PrivateKey, error) {
+	rawKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %v", err)
+	}
+	return &PrivateKey{
+		Id:         id,
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rawKey})),
+		Created:    created,
+	}, nil
+}
+
+func encodePublicKey(key signingKey) (*PublicKey, error) {
+	rawKey, err := x509.MarshalPKIXPublicKey(key.signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	return &PublicKey{
+		Id:        key.Id,
+		Algorithm: key.Algorithm,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rawKey})),
+		Created:   key.Created,
+		Retired:   key.Retired,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestSigner(t *testing.T, algorithm string) *Signer {
+	key, err := GeneratePrivateKey(algorithm)
+	if err != nil {
+		t.Fatalf("GeneratePrivateKey returned unexpected error: %v", err)
+	}
+	signer, err := NewStaticSigner([]PrivateKey{*key})
+	if err != nil {
+		t.Fatalf("NewStaticSigner returned unexpected error: %v", err)
+	}
+	return signer
+}
+
+func parseTestToken(t *testing.T, verifier *Verifier, token string) error {
+	_, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{},
+		verifier.Keyfunc(context.Background()),
+		jwt.WithValidMethods(verifier.Algorithms()),
+	)
+	return err
+}
+
+func TestSignerVerifier(t *testing.T) {
+	claims := &jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+
+	for _, algorithm := range []string{ALG_EDDSA, ALG_RS256} {
+		signer := newTestSigner(t, algorithm)
+		verifier, err := signer.Verifier()
+		if err != nil {
+			t.Fatalf("%s: Verifier returned unexpected error: %v", algorithm, err)
+		}
+		token, err := signer.Sign(claims)
+		if err != nil {
+			t.Fatalf("%s: Sign returned unexpected error: %v", algorithm, err)
+		}
+		if err := parseTestToken(t, verifier, token); err != nil {
+			t.Errorf("%s: token of the signer was rejected: %v", algorithm, err)
+		}
+
+		otherVerifier, err := newTestSigner(t, algorithm).Verifier()
+		if err != nil {
+			t.Fatalf("%s: Verifier returned unexpected error: %v", algorithm, err)
+		}
+		if err := parseTestToken(t, otherVerifier, token); err == nil {
+			t.Errorf("%s: token was accepted by the verifier of another keyring", algorithm)
+		}
+	}
+}
+
+func TestVerifierRejectsTokenWithoutKid(t *testing.T) {
+	key, err := GeneratePrivateKey(ALG_EDDSA)
+	if err != nil {
+		t.Fatalf("GeneratePrivateKey returned unexpected error: %v", err)
+	}
+	signingKeys, err := parsePrivateKeys([]PrivateKey{*key})
+	if err != nil {
+		t.Fatalf("parsePrivateKeys returned unexpected error: %v", err)
+	}
+	signer := &Signer{keys: signingKeys}
+	verifier, err := signer.Verifier()
+	if err != nil {
+		t.Fatalf("Verifier returned unexpected error: %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &jwt.RegisteredClaims{}).SignedString(signingKeys[0].signer)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if err := parseTestToken(t, verifier, token); err == nil {
+		t.Errorf("token without kid was accepted")
+	}
+}
+
+func TestStaticSignerRotation(t *testing.T) {
+	if _, err := newTestSigner(t, ALG_EDDSA).Rotate(context.Background(), ALG_EDDSA); err != ErrStaticKeyring {
+		t.Errorf("Rotate on static signer returned %v, expected ErrStaticKeyring", err)
+	}
+}
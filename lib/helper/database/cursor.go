@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// ErrInvalidCursor is returned if a cursor cannot be decoded or its signature does not match.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type CursorOptions struct {
+	// Secret is used to sign the cursor, so that clients cannot forge arbitrary start keys.
+	Secret string
+	// Scope binds the cursor to a query (e.g. "listproject:<user_id>"), a cursor is only accepted with the same scope.
+	Scope string
+}
+
+type cursorCredentials struct {
+	Secret string `json:"secret"`
+}
+
+// LoadCursorSecret fetches the cursorSecret containing "secret" from SecretsManager.
+// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on the provided cursorSecretARN.
+func LoadCursorSecret(awsConfig aws.Config, transportCtx context.Context, cursorSecretARN string) (string, error) {
+	secretManagerClient := secretsmanager.NewFromConfig(awsConfig)
+
+	secretResponse, err := secretManagerClient.GetSecretValue(transportCtx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(cursorSecretARN),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire cursor secret: %v", err)
+	}
+
+	var cursorCredentials cursorCredentials
+	if err := json.Unmarshal([]byte(*secretResponse.SecretString), &cursorCredentials); err != nil {
+		return "", fmt.Errorf("failed to decode cursor credential secret string: %v", err)
+	}
+	return cursorCredentials.Secret, nil
+}
+
+type cursorValue struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+	B []byte  `json:"b,omitempty"`
+}
+
+// EncodeCursor serializes the LastEvaluatedKey of a query or scan into an opaque signed cursor.
+// An empty key (last page) is encoded as empty cursor.
+func EncodeCursor(options *CursorOptions, key map[string]dynamodbtypes.AttributeValue) (string, error) {
+	if len(key) < 1 {
+		return "", nil
+	}
+
+	cursorKey := map[string]cursorValue{}
+	for name, value := range key {
+		switch value := value.(type) {
+		case *dynamodbtypes.AttributeValueMemberS:
+			cursorKey[name] = cursorValue{S: &value.Value}
+		case *dynamodbtypes.AttributeValueMemberN:
+			cursorKey[name] = cursorValue{N: &value.Value}
+		case *dynamodbtypes.AttributeValueMemberB:
+			cursorKey[name] = cursorValue{B: value.Value}
+		default:
+			return "", fmt.Errorf("unsupported key attribute type on '%s'", name)
+		}
+	}
+
+	rawPayload, err := json.Marshal(cursorKey)
+	if err != nil {
+		return "", fmt.Errorf("cannot serialize cursor")
+	}
+	payload := base64.RawURLEncoding.EncodeToString(rawPayload)
+	return payload + "." + signCursor(options, payload), nil
+}
+
+// DecodeCursor verifies the cursor and deserializes the contained start key.
+// An empty cursor (first page) is decoded as nil key.
+func DecodeCursor(options *CursorOptions, cursor string) (map[string]dynamodbtypes.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	payload, signature, ok := strings.Cut(cursor, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(options, payload))) {
+		return nil, ErrInvalidCursor
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursorKey := map[string]cursorValue{}
+	if err := json.Unmarshal(rawPayload, &cursorKey); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	key := map[string]dynamodbtypes.AttributeValue{}
+	for name, value := range cursorKey {
+		switch {
+		case value.S != nil:
+			key[name] = &dynamodbtypes.AttributeValueMemberS{Value: *value.S}
+		case value.N != nil:
+			key[name] = &dynamodbtypes.AttributeValueMemberN{Value: *value.N}
+		case value.B != nil:
+			key[name] = &dynamodbtypes.AttributeValueMemberB{Value: value.B}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
+
+// signCursor creates the hmac signature of the payload bound to the cursor scope.
+func signCursor(options *CursorOptions, payload string) string {
+	mac := hmac.New(sha256.New, []byte(options.Secret))
+	mac.Write([]byte(options.Scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	options := &CursorOptions{Secret: "secret", Scope: "listproject:user"}
+	key := map[string]dynamodbtypes.AttributeValue{
+		"project_name": &dynamodbtypes.AttributeValueMemberS{Value: "project"},
+		"created":      &dynamodbtypes.AttributeValueMemberN{Value: "42"},
+		"hash":         &dynamodbtypes.AttributeValueMemberB{Value: []byte{0x01, 0x02}},
+	}
+
+	cursor, err := EncodeCursor(options, key)
+	if err != nil {
+		t.Fatalf("EncodeCursor returned unexpected error: %v", err)
+	}
+	decodedKey, err := DecodeCursor(options, cursor)
+	if err != nil {
+		t.Fatalf("DecodeCursor returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decodedKey, key) {
+		t.Errorf("decoded key = %v, expected %v", decodedKey, key)
+	}
+}
+
+func TestCursorEmpty(t *testing.T) {
+	options := &CursorOptions{Secret: "secret", Scope: "scope"}
+
+	cursor, err := EncodeCursor(options, nil)
+	if err != nil || cursor != "" {
+		t.Errorf("EncodeCursor(nil) = %q, %v, expected empty cursor", cursor, err)
+	}
+	key, err := DecodeCursor(options, "")
+	if err != nil || key != nil {
+		t.Errorf("DecodeCursor(\"\") = %v, %v, expected nil key", key, err)
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	options := &CursorOptions{Secret: "secret", Scope: "listproject:user"}
+	cursor, err := EncodeCursor(options, map[string]dynamodbtypes.AttributeValue{
+		"project_name": &dynamodbtypes.AttributeValueMemberS{Value: "project"},
+	})
+	if err != nil {
+		t.Fatalf("EncodeCursor returned unexpected error: %v", err)
+	}
+	payload, signature, _ := strings.Cut(cursor, ".")
+	otherCursor, _ := EncodeCursor(options, map[string]dynamodbtypes.AttributeValue{
+		"project_name": &dynamodbtypes.AttributeValueMemberS{Value: "other"},
+	})
+	otherPayload, _, _ := strings.Cut(otherCursor, ".")
+
+	tests := []struct {
+		name    string
+		options *CursorOptions
+		cursor  string
+	}{
+		{name: "other scope", options: &CursorOptions{Secret: "secret", Scope: "listproject:other"}, cursor: cursor},
+		{name: "other secret", options: &CursorOptions{Secret: "other", Scope: "listproject:user"}, cursor: cursor},
+		{name: "swapped payload", options: options, cursor: otherPayload + "." + signature},
+		{name: "missing signature", options: options, cursor: payload},
+		{name: "invalid payload", options: options, cursor: "!!!." + signature},
+	}
+
+	for _, test := range tests {
+		if _, err := DecodeCursor(test.options, test.cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: DecodeCursor error = %v, expected ErrInvalidCursor", test.name, err)
+		}
+	}
+}
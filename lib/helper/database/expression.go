@@ -0,0 +1,264 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// UpdateExpression holds the expressions and attributes for UpdateSingleInput.
+type UpdateExpression struct {
+	AttributeNames  map[string]string
+	AttributeValues map[string]dynamodbtypes.AttributeValue
+	UpdateExpr      *string
+	ConditionExpr   *string
+	// ExpectedVersion is set if the condition checks the version of the item (see UpdateBuilder.IfVersion).
+	ExpectedVersion *int64
+}
+
+// UpdateBuilder builds update and condition expressions for items of type T.
+// Attributes are addressed with go field paths (e.g. "SharedInfrastructure.StaticBucketPath"), which are translated
+// to the attribute paths of the dynamodbav tags (e.g. "shared_infrastructure.static_bucket_path").
+// Segments following a map field are used as map keys (e.g. "Aliases.example").
+// Invalid field paths are reported by Build, therefore a typo fails in every test that builds the expression.
+// If T contains the VERSION_FIELD, every built update increments the version of the item.
+type UpdateBuilder[T any] struct {
+	names           map[string]string
+	values          map[string]dynamodbtypes.AttributeValue
+	sets            []string
+	adds            []string
+	removes         []string
+	conditions      []string
+	expectedVersion *int64
+	err             error
+}
+
+// NewUpdate creates an empty update builder for items of type T.
+func NewUpdate[T any]() *UpdateBuilder[T] {
+	return &UpdateBuilder[T]{
+		names:  map[string]string{},
+		values: map[string]dynamodbtypes.AttributeValue{},
+	}
+}
+
+// Set assigns the value to the attribute.
+func (b *UpdateBuilder[T]) Set(fieldPath string, value any) *UpdateBuilder[T] {
+	path, valuePlaceholder, ok := b.pathWithValue(fieldPath, value)
+	if ok {
+		b.sets = append(b.sets, fmt.Sprintf("%s = %s", path, valuePlaceholder))
+	}
+	return b
+}
+
+// Add adds the value to a number attribute or the elements to a set attribute.
+func (b *UpdateBuilder[T]) Add(fieldPath string, value any) *UpdateBuilder[T] {
+	path, valuePlaceholder, ok := b.pathWithValue(fieldPath, value)
+	if ok {
+		b.adds = append(b.adds, fmt.Sprintf("%s %s", path, valuePlaceholder))
+	}
+	return b
+}
+
+// Remove removes the attribute from the item.
+func (b *UpdateBuilder[T]) Remove(fieldPath string) *UpdateBuilder[T] {
+	path, ok := b.path(fieldPath)
+	if ok {
+		b.removes = append(b.removes, path)
+	}
+	return b
+}
+
+// AttributeEquals adds the condition that the attribute holds the value.
+func (b *UpdateBuilder[T]) AttributeEquals(fieldPath string, value any) *UpdateBuilder[T] {
+	path, valuePlaceholder, ok := b.pathWithValue(fieldPath, value)
+	if ok {
+		b.conditions = append(b.conditions, fmt.Sprintf("%s = %s", path, valuePlaceholder))
+	}
+	return b
+}
+
+// AttributeLessThan adds the condition that the attribute is less than the value.
+func (b *UpdateBuilder[T]) AttributeLessThan(fieldPath string, value any) *UpdateBuilder[T] {
+	path, valuePlaceholder, ok := b.pathWithValue(fieldPath, value)
+	if ok {
+		b.conditions = append(b.conditions, fmt.Sprintf("%s < %s", path, valuePlaceholder))
+	}
+	return b
+}
+
+// AttributeGreaterThan adds the condition that the attribute is greater than the value.
+func (b *UpdateBuilder[T]) AttributeGreaterThan(fieldPath string, value any) *UpdateBuilder[T] {
+	path, valuePlaceholder, ok := b.pathWithValue(fieldPath, value)
+	if ok {
+		b.conditions = append(b.conditions, fmt.Sprintf("%s > %s", path, valuePlaceholder))
+	}
+	return b
+}
+
+// AttributeExists adds the condition that the attribute exists.
+func (b *UpdateBuilder[T]) AttributeExists(fieldPath string) *UpdateBuilder[T] {
+	path, ok := b.path(fieldPath)
+	if ok {
+		b.conditions = append(b.conditions, fmt.Sprintf("attribute_exists(%s)", path))
+	}
+	return b
+}
+
+// AttributeNotExists adds the condition that the attribute does not exist.
+func (b *UpdateBuilder[T]) AttributeNotExists(fieldPath string) *UpdateBuilder[T] {
+	path, ok := b.path(fieldPath)
+	if ok {
+		b.conditions = append(b.conditions, fmt.Sprintf("attribute_not_exists(%s)", path))
+	}
+	return b
+}
+
+// IfVersion adds the condition that the item holds the version.
+// Items written before versioning was introduced have no version attribute, they match version 0.
+func (b *UpdateBuilder[T]) IfVersion(version int64) *UpdateBuilder[T] {
+	path, valuePlaceholder, ok := b.pathWithValue(VERSION_FIELD, version)
+	if ok {
+		if version == 0 {
+			b.conditions = append(b.conditions, fmt.Sprintf("(attribute_not_exists(%s) OR %s = %s)", path, path, valuePlaceholder))
+		} else {
+			b.conditions = append(b.conditions, fmt.Sprintf("%s = %s", path, valuePlaceholder))
+		}
+		b.expectedVersion = &version
+	}
+	return b
+}
+
+// Build returns the update expression. All conditions are combined with AND.
+// It fails if a field path is invalid, a value cannot be serialized or no update action was added.
+func (b *UpdateBuilder[T]) Build() (*UpdateExpression, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if len(b.sets) < 1 && len(b.adds) < 1 && len(b.removes) < 1 {
+		return nil, fmt.Errorf("update does not contain any action")
+	}
+	adds := append([]string{}, b.adds...)
+	if _, err := AttributePath[T](VERSION_FIELD); err == nil {
+		path, valuePlaceholder, ok := b.pathWithValue(VERSION_FIELD, 1)
+		if !ok {
+			return nil, b.err
+		}
+		adds = append(adds, fmt.Sprintf("%s %s", path, valuePlaceholder))
+	}
+
+	clauses := []string{}
+	if len(b.sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(b.sets, ", "))
+	}
+	if len(adds) > 0 {
+		clauses = append(clauses, "ADD "+strings.Join(adds, ", "))
+	}
+	if len(b.removes) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(b.removes, ", "))
+	}
+
+	expression := &UpdateExpression{
+		AttributeNames:  b.names,
+		AttributeValues: b.values,
+		UpdateExpr:      aws.String(strings.Join(clauses, " ")),
+		ExpectedVersion: b.expectedVersion,
+	}
+	if len(b.conditions) > 0 {
+		expression.ConditionExpr = aws.String(strings.Join(b.conditions, " AND "))
+	}
+	if len(expression.AttributeValues) < 1 {
+		// dynamodb rejects an empty attribute value map.
+		expression.AttributeValues = nil
+	}
+	return expression, nil
+}
+
+// path resolves the field path and returns it with attribute name placeholders.
+func (b *UpdateBuilder[T]) path(fieldPath string) (string, bool) {
+	if b.err != nil {
+		return "", false
+	}
+	attributePath, err := AttributePath[T](fieldPath)
+	if err != nil {
+		b.err = err
+		return "", false
+	}
+
+	segments := strings.Split(attributePath, ".")
+	for i, segment := range segments {
+		placeholder := b.namePlaceholder(segment)
+		segments[i] = placeholder
+	}
+	return strings.Join(segments, "."), true
+}
+
+// pathWithValue resolves the field path and registers the value, it returns the path and the value placeholder.
+func (b *UpdateBuilder[T]) pathWithValue(fieldPath string, value any) (string, string, bool) {
+	path, ok := b.path(fieldPath)
+	if !ok {
+		return "", "", false
+	}
+	attributeValue, err := attributevalue.Marshal(value)
+	if err != nil {
+		b.err = fmt.Errorf("cannot serialize value of '%s': %v", fieldPath, err)
+		return "", "", false
+	}
+	placeholder := fmt.Sprintf(":v%d", len(b.values))
+	b.values[placeholder] = attributeValue
+	return path, placeholder, true
+}
+
+// namePlaceholder returns the placeholder of the attribute name, equal names share a placeholder.
+func (b *UpdateBuilder[T]) namePlaceholder(name string) string {
+	for placeholder, existingName := range b.names {
+		if existingName == name {
+			return placeholder
+		}
+	}
+	placeholder := fmt.Sprintf("#n%d", len(b.names))
+	b.names[placeholder] = name
+	return placeholder
+}
+
+// AttributePath translates the go field path of type T (e.g. "SharedInfrastructure.StaticBucketPath")
+// to the dot separated attribute path defined by the dynamodbav tags (e.g. "shared_infrastructure.static_bucket_path").
+func AttributePath[T any](fieldPath string) (string, error) {
+	currentType := reflect.TypeFor[T]()
+	segments := strings.Split(fieldPath, ".")
+	attributeSegments := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		for currentType.Kind() == reflect.Pointer {
+			currentType = currentType.Elem()
+		}
+		switch currentType.Kind() {
+		case reflect.Struct:
+			field, ok := currentType.FieldByName(segment)
+			if !ok || !field.IsExported() {
+				return "", fmt.Errorf("invalid field path '%s': %s has no field '%s'", fieldPath, currentType.Name(), segment)
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("dynamodbav"), ",")
+			if name == "-" {
+				return "", fmt.Errorf("invalid field path '%s': field '%s' is not stored", fieldPath, segment)
+			}
+			if name == "" {
+				name = field.Name
+			}
+			attributeSegments = append(attributeSegments, name)
+			currentType = field.Type
+		case reflect.Map:
+			if segment == "" {
+				return "", fmt.Errorf("invalid field path '%s': empty map key", fieldPath)
+			}
+			attributeSegments = append(attributeSegments, segment)
+			currentType = currentType.Elem()
+		default:
+			return "", fmt.Errorf("invalid field path '%s': cannot access '%s' on %s", fieldPath, segment, currentType.Kind())
+		}
+	}
+	return strings.Join(attributeSegments, "."), nil
+}
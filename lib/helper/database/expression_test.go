@@ -0,0 +1,203 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type testInfrastructure struct {
+	StaticBucketPath string `dynamodbav:"static_bucket_path"`
+}
+
+type testItem struct {
+	Id                   string              `dynamodbav:"id"`
+	Name                 string              `dynamodbav:"name,omitempty"`
+	Untagged             string              ``
+	Ignored              string              `dynamodbav:"-"`
+	SharedInfrastructure *testInfrastructure `dynamodbav:"shared_infrastructure"`
+	Aliases              map[string]testInfrastructure
+	Counters             map[string]int64 `dynamodbav:"counters"`
+	unexported           string
+}
+
+type testVersionedItem struct {
+	Id      string `dynamodbav:"id"`
+	Name    string `dynamodbav:"name"`
+	Version int64  `dynamodbav:"version"`
+}
+
+func TestAttributePath(t *testing.T) {
+	tests := []struct {
+		fieldPath string
+		expected  string
+		valid     bool
+	}{
+		{fieldPath: "Id", expected: "id", valid: true},
+		{fieldPath: "Name", expected: "name", valid: true},
+		{fieldPath: "Untagged", expected: "Untagged", valid: true},
+		{fieldPath: "SharedInfrastructure.StaticBucketPath", expected: "shared_infrastructure.static_bucket_path", valid: true},
+		{fieldPath: "Aliases.example.StaticBucketPath", expected: "Aliases.example.static_bucket_path", valid: true},
+		{fieldPath: "Counters.builds", expected: "counters.builds", valid: true},
+		{fieldPath: "Missing", valid: false},
+		{fieldPath: "Ignored", valid: false},
+		{fieldPath: "unexported", valid: false},
+		{fieldPath: "Id.Length", valid: false},
+		{fieldPath: "Counters.builds.Count", valid: false},
+		{fieldPath: "Aliases..StaticBucketPath", valid: false},
+		{fieldPath: "SharedInfrastructure.Missing", valid: false},
+	}
+
+	for _, test := range tests {
+		path, err := AttributePath[testItem](test.fieldPath)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("AttributePath(%q) = %q, expected error", test.fieldPath, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("AttributePath(%q) returned unexpected error: %v", test.fieldPath, err)
+			continue
+		}
+		if path != test.expected {
+			t.Errorf("AttributePath(%q) = %q, expected %q", test.fieldPath, path, test.expected)
+		}
+	}
+}
+
+func TestUpdateBuilder(t *testing.T) {
+	expression, err := NewUpdate[testItem]().
+		Set("SharedInfrastructure.StaticBucketPath", "bucket/project").
+		Set("Aliases.example.StaticBucketPath", "bucket/example").
+		Add("Counters.builds", 1).
+		Remove("Name").
+		AttributeEquals("Id", "item").
+		AttributeExists("SharedInfrastructure").
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned unexpected error: %v", err)
+	}
+
+	expectedUpdate := "SET #n0.#n1 = :v0, #n2.#n3.#n1 = :v1 ADD #n4.#n5 :v2 REMOVE #n6"
+	if aws.ToString(expression.UpdateExpr) != expectedUpdate {
+		t.Errorf("update expression = %q, expected %q", aws.ToString(expression.UpdateExpr), expectedUpdate)
+	}
+	expectedCondition := "#n7 = :v3 AND attribute_exists(#n0)"
+	if aws.ToString(expression.ConditionExpr) != expectedCondition {
+		t.Errorf("condition expression = %q, expected %q", aws.ToString(expression.ConditionExpr), expectedCondition)
+	}
+
+	expectedNames := map[string]string{
+		"#n0": "shared_infrastructure", "#n1": "static_bucket_path", "#n2": "Aliases", "#n3": "example",
+		"#n4": "counters", "#n5": "builds", "#n6": "name", "#n7": "id",
+	}
+	if len(expression.AttributeNames) != len(expectedNames) {
+		t.Errorf("attribute names = %v, expected %v", expression.AttributeNames, expectedNames)
+	}
+	for placeholder, name := range expectedNames {
+		if expression.AttributeNames[placeholder] != name {
+			t.Errorf("attribute name %s = %q, expected %q", placeholder, expression.AttributeNames[placeholder], name)
+		}
+	}
+
+	if len(expression.AttributeValues) != 4 {
+		t.Errorf("attribute values = %v, expected 4 values", expression.AttributeValues)
+	}
+	if value, ok := expression.AttributeValues[":v2"].(*dynamodbtypes.AttributeValueMemberN); !ok || value.Value != "1" {
+		t.Errorf("attribute value :v2 = %v, expected number 1", expression.AttributeValues[":v2"])
+	}
+	if expression.ExpectedVersion != nil {
+		t.Errorf("expected version = %d, expected none on unversioned items", *expression.ExpectedVersion)
+	}
+}
+
+func TestUpdateBuilderVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		builder           *UpdateBuilder[testVersionedItem]
+		expectedUpdate    string
+		expectedCondition string
+		expectedVersion   *int64
+	}{
+		{
+			name:           "unconditional",
+			builder:        NewUpdate[testVersionedItem]().Set("Name", "name"),
+			expectedUpdate: "SET #n0 = :v0 ADD #n1 :v1",
+		},
+		{
+			name:              "version",
+			builder:           NewUpdate[testVersionedItem]().Set("Name", "name").IfVersion(3),
+			expectedUpdate:    "SET #n0 = :v0 ADD #n1 :v2",
+			expectedCondition: "#n1 = :v1",
+			expectedVersion:   aws.Int64(3),
+		},
+		{
+			name:              "unversioned item",
+			builder:           NewUpdate[testVersionedItem]().Remove("Name").IfVersion(0),
+			expectedUpdate:    "ADD #n1 :v1 REMOVE #n0",
+			expectedCondition: "(attribute_not_exists(#n1) OR #n1 = :v0)",
+			expectedVersion:   aws.Int64(0),
+		},
+	}
+
+	for _, test := range tests {
+		expression, err := test.builder.Build()
+		if err != nil {
+			t.Errorf("%s: Build returned unexpected error: %v", test.name, err)
+			continue
+		}
+		if aws.ToString(expression.UpdateExpr) != test.expectedUpdate {
+			t.Errorf("%s: update expression = %q, expected %q", test.name, aws.ToString(expression.UpdateExpr), test.expectedUpdate)
+		}
+		if aws.ToString(expression.ConditionExpr) != test.expectedCondition {
+			t.Errorf("%s: condition expression = %q, expected %q", test.name, aws.ToString(expression.ConditionExpr), test.expectedCondition)
+		}
+		if expression.AttributeNames["#n1"] != "version" {
+			t.Errorf("%s: attribute names = %v, expected version at #n1", test.name, expression.AttributeNames)
+		}
+		if (expression.ExpectedVersion == nil) != (test.expectedVersion == nil) ||
+			(test.expectedVersion != nil && *expression.ExpectedVersion != *test.expectedVersion) {
+			t.Errorf("%s: expected version = %v, expected %v", test.name, expression.ExpectedVersion, test.expectedVersion)
+		}
+	}
+}
+
+func TestUpdateBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *UpdateBuilder[testItem]
+		message string
+	}{
+		{name: "no action", builder: NewUpdate[testItem]().AttributeEquals("Id", "item"), message: "does not contain any action"},
+		{name: "invalid set", builder: NewUpdate[testItem]().Set("Missing", "value"), message: "'Missing'"},
+		{name: "invalid condition", builder: NewUpdate[testItem]().Set("Name", "name").AttributeExists("Ignored"), message: "'Ignored'"},
+		{name: "first error wins", builder: NewUpdate[testItem]().Remove("First").Remove("Second"), message: "'First'"},
+	}
+
+	for _, test := range tests {
+		_, err := test.builder.Build()
+		if err == nil {
+			t.Errorf("%s: Build succeeded, expected error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: Build error = %q, expected it to contain %q", test.name, err, test.message)
+		}
+	}
+}
+
+func TestUpdateBuilderEmptyValues(t *testing.T) {
+	expression, err := NewUpdate[testItem]().Remove("Name").Build()
+	if err != nil {
+		t.Fatalf("Build returned unexpected error: %v", err)
+	}
+	if expression.AttributeValues != nil {
+		t.Errorf("attribute values = %v, expected nil map", expression.AttributeValues)
+	}
+	if expression.ConditionExpr != nil {
+		t.Errorf("condition expression = %q, expected none", aws.ToString(expression.ConditionExpr))
+	}
+}
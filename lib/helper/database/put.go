@@ -14,15 +14,24 @@ type PutSingleInput[T any] struct {
 	Table                   *string
 	Item                    T
 	ProtectionAttributeName *string
+	// ConditionExpr allows to overwrite a protected item if the existing item matches the condition.
+	ConditionExpr   *string
+	AttributeNames  map[string]string
+	AttributeValues map[string]dynamodbtypes.AttributeValue
 }
 
 // PutSingle inserts a single item to the database.
 // If you want to ensure no item is overwritten, you can set the ProtectionAttributeName
 // to an attribute key that must NOT alread exist (usually the partition key is used for this).
+// If a ConditionExpr is set in addition, an existing item is only overwritten if it matches the condition.
 func PutSingle[T any](transportCtx context.Context, dynamoClient *dynamodb.Client, input *PutSingleInput[T]) error {
-	var conditionExpr *string = nil
+	conditionExpr := input.ConditionExpr
 	if input.ProtectionAttributeName != nil {
-		conditionExpr = aws.String(fmt.Sprintf("attribute_not_exists(%s)", *input.ProtectionAttributeName))
+		if conditionExpr == nil {
+			conditionExpr = aws.String(fmt.Sprintf("attribute_not_exists(%s)", *input.ProtectionAttributeName))
+		} else {
+			conditionExpr = aws.String(fmt.Sprintf("attribute_not_exists(%s) OR (%s)", *input.ProtectionAttributeName, *conditionExpr))
+		}
 	}
 
 	inputStructure, err := attributevalue.MarshalMap(&input.Item)
@@ -31,10 +40,12 @@ func PutSingle[T any](transportCtx context.Context, dynamoClient *dynamodb.Clien
 	}
 
 	_, err = dynamoClient.PutItem(transportCtx, &dynamodb.PutItemInput{
-		TableName:           input.Table,
-		Item:                inputStructure,
-		ConditionExpression: conditionExpr,
-		ReturnValues:        dynamodbtypes.ReturnValueNone,
+		TableName:                 input.Table,
+		Item:                      inputStructure,
+		ConditionExpression:       conditionExpr,
+		ExpressionAttributeNames:  input.AttributeNames,
+		ExpressionAttributeValues: input.AttributeValues,
+		ReturnValues:              dynamodbtypes.ReturnValueNone,
 	})
 	if err != nil {
 		return err
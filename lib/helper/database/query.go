@@ -57,25 +57,89 @@ type GetManyInput struct {
 }
 
 // GetMany fetches items from the database and tries to deserialize it into a list of the provided struct type.
+// All result pages are read until the limit is reached.
 // Set the index to "" to query the main table.
-// Set the limit to '-1' to fetch all items.
+// Set the limit to nil to fetch all items.
 func GetMany[T any](transportCtx context.Context, dynamoClient *dynamodb.Client, input *GetManyInput) ([]T, error) {
+	outputStructureList := []T{}
+	var startKey map[string]dynamodbtypes.AttributeValue
+	for {
+		queryInput := &dynamodb.QueryInput{
+			IndexName:                 input.Index,
+			TableName:                 input.Table,
+			ExpressionAttributeValues: input.AttributeValues,
+			KeyConditionExpression:    input.ConditionExpr,
+			ExclusiveStartKey:         startKey,
+		}
+		if input.Limit != nil {
+			queryInput.Limit = aws.Int32(*input.Limit - int32(len(outputStructureList)))
+		}
+		output, err := dynamoClient.Query(transportCtx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageStructureList []T
+		err = attributevalue.UnmarshalListOfMaps(output.Items, &pageStructureList)
+		if err != nil {
+			return nil, fmt.Errorf("cannot deserialize database items")
+		}
+		outputStructureList = append(outputStructureList, pageStructureList...)
+
+		if len(output.LastEvaluatedKey) < 1 {
+			break
+		}
+		if input.Limit != nil && int32(len(outputStructureList)) >= *input.Limit {
+			break
+		}
+		startKey = output.LastEvaluatedKey
+	}
+
+	return outputStructureList, nil
+}
+
+type GetPageInput struct {
+	Table           *string
+	Index           *string
+	AttributeValues map[string]dynamodbtypes.AttributeValue
+	ConditionExpr   *string
+	PageSize        int32
+	Cursor          string
+	CursorOptions   *CursorOptions
+}
+
+// GetPage fetches a single page of items from the database and tries to deserialize it into a list of the provided struct type.
+// The page starts after the provided cursor (use "" for the first page), the returned cursor points to the next page
+// and is empty if no further items exist. Returns ErrInvalidCursor if the cursor was not issued for this query scope.
+// Set the index to "" to query the main table.
+func GetPage[T any](transportCtx context.Context, dynamoClient *dynamodb.Client, input *GetPageInput) ([]T, string, error) {
+	startKey, err := DecodeCursor(input.CursorOptions, input.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
 	output, err := dynamoClient.Query(transportCtx, &dynamodb.QueryInput{
 		IndexName:                 input.Index,
 		TableName:                 input.Table,
 		ExpressionAttributeValues: input.AttributeValues,
 		KeyConditionExpression:    input.ConditionExpr,
-		Limit:                     input.Limit,
+		ExclusiveStartKey:         startKey,
+		Limit:                     aws.Int32(input.PageSize),
 	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	var outputStructureList []T
+	outputStructureList := []T{}
 	err = attributevalue.UnmarshalListOfMaps(output.Items, &outputStructureList)
 	if err != nil {
-		return nil, fmt.Errorf("cannot deserialize database items")
+		return nil, "", fmt.Errorf("cannot deserialize database items")
 	}
 
-	return outputStructureList, nil
+	nextCursor, err := EncodeCursor(input.CursorOptions, output.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return outputStructureList, nextCursor, nil
 }
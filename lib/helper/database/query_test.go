@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// FAKE_PAGE_SIZE is the maximum number of items the fake table returns per request.
+const FAKE_PAGE_SIZE = 2
+
+type fakeItem struct {
+	Id int `dynamodbav:"id"`
+}
+
+// fakeTable emulates the paginated Query and Scan operations of a table with the numeric hash key "id".
+type fakeTable struct {
+	mutex  sync.Mutex
+	items  int
+	limits []int32
+}
+
+type fakeRequest struct {
+	Limit             *int32                       `json:"Limit"`
+	ExclusiveStartKey map[string]map[string]string `json:"ExclusiveStartKey"`
+}
+
+func (f *fakeTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request fakeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mutex.Lock()
+	limit := int32(0)
+	if request.Limit != nil {
+		limit = *request.Limit
+	}
+	f.limits = append(f.limits, limit)
+	f.mutex.Unlock()
+
+	start := 1
+	if request.ExclusiveStartKey != nil {
+		lastId, _ := strconv.Atoi(request.ExclusiveStartKey["id"]["N"])
+		start = lastId + 1
+	}
+	pageSize := FAKE_PAGE_SIZE
+	if limit > 0 && int(limit) < pageSize {
+		pageSize = int(limit)
+	}
+
+	items := []map[string]map[string]string{}
+	for id := start; id <= f.items && len(items) < pageSize; id++ {
+		items = append(items, map[string]map[string]string{"id": {"N": strconv.Itoa(id)}})
+	}
+	response := map[string]any{
+		"Items": items,
+		"Count": len(items),
+	}
+	if last := start + len(items) - 1; len(items) > 0 && last < f.items {
+		response["LastEvaluatedKey"] = map[string]map[string]string{"id": {"N": strconv.Itoa(last)}}
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(response)
+}
+
+// newFakeClient starts a fake table with the number of items and returns a client connected to it.
+func newFakeClient(t *testing.T, items int) (*dynamodb.Client, *fakeTable) {
+	table := &fakeTable{items: items}
+	server := httptest.NewServer(table)
+	t.Cleanup(server.Close)
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	return client, table
+}
+
+func itemIds(items []fakeItem) []int {
+	ids := []int{}
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+func TestManyPagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    int
+		limit    *int32
+		expected int
+		limits   []int32
+	}{
+		{name: "all pages", items: 5, limit: nil, expected: 5, limits: []int32{0, 0, 0}},
+		{name: "single page", items: 2, limit: nil, expected: 2, limits: []int32{0}},
+		{name: "empty table", items: 0, limit: nil, expected: 0, limits: []int32{0}},
+		{name: "limit across pages", items: 5, limit: aws.Int32(3), expected: 3, limits: []int32{3, 1}},
+		{name: "limit on page boundary", items: 5, limit: aws.Int32(4), expected: 4, limits: []int32{4, 2}},
+		{name: "limit above items", items: 3, limit: aws.Int32(10), expected: 3, limits: []int32{10, 8}},
+	}
+
+	operations := map[string]func(*dynamodb.Client, *int32) ([]fakeItem, error){
+		"GetMany": func(client *dynamodb.Client, limit *int32) ([]fakeItem, error) {
+			return GetMany[fakeItem](context.Background(), client, &GetManyInput{
+				Table: aws.String("table"),
+				Limit: limit,
+			})
+		},
+		"ScanMany": func(client *dynamodb.Client, limit *int32) ([]fakeItem, error) {
+			return ScanMany[fakeItem](context.Background(), client, &ScanManyInput{
+				Table: aws.String("table"),
+				Limit: limit,
+			})
+		},
+	}
+
+	for operationName, operation := range operations {
+		for _, test := range tests {
+			t.Run(operationName+" "+test.name, func(t *testing.T) {
+				client, table := newFakeClient(t, test.items)
+				items, err := operation(client, test.limit)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(items) != test.expected {
+					t.Fatalf("items = %v, expected %d items", itemIds(items), test.expected)
+				}
+				for i, item := range items {
+					if item.Id != i+1 {
+						t.Fatalf("items = %v, expected ascending ids without gaps", itemIds(items))
+					}
+				}
+				if len(table.limits) != len(test.limits) {
+					t.Fatalf("requests with limits %v, expected %v", table.limits, test.limits)
+				}
+				for i := range test.limits {
+					if table.limits[i] != test.limits[i] {
+						t.Fatalf("requests with limits %v, expected %v", table.limits, test.limits)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGetPageCursor(t *testing.T) {
+	client, _ := newFakeClient(t, 3)
+	options := &CursorOptions{Secret: "secret", Scope: "test"}
+
+	ids := []int{}
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		items, nextCursor, err := GetPage[fakeItem](context.Background(), client, &GetPageInput{
+			Table:         aws.String("table"),
+			PageSize:      FAKE_PAGE_SIZE,
+			Cursor:        cursor,
+			CursorOptions: options,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, itemIds(items)...)
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("pages returned ids %v, expected [1 2 3]", ids)
+	}
+
+	_, _, err := GetPage[fakeItem](context.Background(), client, &GetPageInput{
+		Table:         aws.String("table"),
+		PageSize:      FAKE_PAGE_SIZE,
+		Cursor:        cursor,
+		CursorOptions: &CursorOptions{Secret: "secret", Scope: "other"},
+	})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of other scope returned %v, expected ErrInvalidCursor", err)
+	}
+}
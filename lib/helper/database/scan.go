@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type ScanManyInput struct {
@@ -14,21 +16,78 @@ type ScanManyInput struct {
 }
 
 // ScanMany reads all items from the database. Only use this on very very small datasets.
-// Set the limit to '-1' to fetch all items.
+// All result pages are read until the limit is reached.
+// Set the limit to nil to fetch all items.
 func ScanMany[T any](transportCtx context.Context, dynamoClient *dynamodb.Client, input *ScanManyInput) ([]T, error) {
+	outputStructureList := []T{}
+	var startKey map[string]dynamodbtypes.AttributeValue
+	for {
+		scanInput := &dynamodb.ScanInput{
+			TableName:         input.Table,
+			ExclusiveStartKey: startKey,
+		}
+		if input.Limit != nil {
+			scanInput.Limit = aws.Int32(*input.Limit - int32(len(outputStructureList)))
+		}
+		output, err := dynamoClient.Scan(transportCtx, scanInput)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageStructureList []T
+		err = attributevalue.UnmarshalListOfMaps(output.Items, &pageStructureList)
+		if err != nil {
+			return nil, fmt.Errorf("cannot deserialize database items")
+		}
+		outputStructureList = append(outputStructureList, pageStructureList...)
+
+		if len(output.LastEvaluatedKey) < 1 {
+			break
+		}
+		if input.Limit != nil && int32(len(outputStructureList)) >= *input.Limit {
+			break
+		}
+		startKey = output.LastEvaluatedKey
+	}
+
+	return outputStructureList, nil
+}
+
+type ScanPageInput struct {
+	Table         *string
+	PageSize      int32
+	Cursor        string
+	CursorOptions *CursorOptions
+}
+
+// ScanPage reads a single page of items from the database.
+// The page starts after the provided cursor (use "" for the first page), the returned cursor points to the next page
+// and is empty if no further items exist. Returns ErrInvalidCursor if the cursor was not issued for this scan scope.
+func ScanPage[T any](transportCtx context.Context, dynamoClient *dynamodb.Client, input *ScanPageInput) ([]T, string, error) {
+	startKey, err := DecodeCursor(input.CursorOptions, input.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
 	output, err := dynamoClient.Scan(transportCtx, &dynamodb.ScanInput{
-		TableName: input.Table,
-		Limit:     input.Limit,
+		TableName:         input.Table,
+		ExclusiveStartKey: startKey,
+		Limit:             aws.Int32(input.PageSize),
 	})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	var outputStructureList []T
+	outputStructureList := []T{}
 	err = attributevalue.UnmarshalListOfMaps(output.Items, &outputStructureList)
 	if err != nil {
-		return nil, fmt.Errorf("cannot deserialize database items")
+		return nil, "", fmt.Errorf("cannot deserialize database items")
 	}
 
-	return outputStructureList, nil
+	nextCursor, err := EncodeCursor(input.CursorOptions, output.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return outputStructureList, nextCursor, nil
 }
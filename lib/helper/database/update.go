@@ -19,6 +19,9 @@ type UpdateSingleInput struct {
 	AttributeNames  map[string]string
 	AttributeValues map[string]dynamodbtypes.AttributeValue
 	UpdateExpr      *string
+	// ExpectedVersion must be set if the condition checks the version of the item (see UpdateBuilder.IfVersion).
+	// If the item holds another version, ErrVersionConflict is returned instead of a ConditionalCheckFailedException.
+	ExpectedVersion *int64
 }
 
 // UpdateSingle updates a single item on the database.
@@ -40,16 +43,26 @@ func UpdateSingle[T any](transportCtx context.Context, dynamoClient *dynamodb.Cl
 		returnValue = dynamodbtypes.ReturnValueAllOld
 	}
 
+	// The old item is returned on a failed condition to distinguish a version conflict from other failures.
+	returnValueOnFailure := dynamodbtypes.ReturnValuesOnConditionCheckFailureNone
+	if input.ExpectedVersion != nil {
+		returnValueOnFailure = dynamodbtypes.ReturnValuesOnConditionCheckFailureAllOld
+	}
+
 	output, err := dynamoClient.UpdateItem(transportCtx, &dynamodb.UpdateItemInput{
-		TableName:                 input.Table,
-		Key:                       input.PrimaryKey,
-		ExpressionAttributeNames:  input.AttributeNames,
-		ExpressionAttributeValues: input.AttributeValues,
-		UpdateExpression:          input.UpdateExpr,
-		ConditionExpression:       conditionExpr,
-		ReturnValues:              returnValue,
+		TableName:                           input.Table,
+		Key:                                 input.PrimaryKey,
+		ExpressionAttributeNames:            input.AttributeNames,
+		ExpressionAttributeValues:           input.AttributeValues,
+		UpdateExpression:                    input.UpdateExpr,
+		ConditionExpression:                 conditionExpr,
+		ReturnValues:                        returnValue,
+		ReturnValuesOnConditionCheckFailure: returnValueOnFailure,
 	})
 	if err != nil {
+		if input.ExpectedVersion != nil && versionConflict[T](err, *input.ExpectedVersion) {
+			return nil, ErrVersionConflict
+		}
 		return nil, err
 	}
 
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// VERSION_FIELD is the go field that holds the version of a versioned item.
+// Every update built with UpdateBuilder increments the version of items that contain this field.
+const VERSION_FIELD = "Version"
+
+// DEFAULT_UPDATE_ATTEMPTS is the number of attempts UpdateWithRetry uses if no attempts are specified.
+const DEFAULT_UPDATE_ATTEMPTS = 3
+
+// ErrVersionConflict is returned if a versioned update expected another version than the item holds.
+var ErrVersionConflict = errors.New("version conflict")
+
+// versionConflict checks if the failed condition of a versioned update was caused by another version.
+// The check relies on the old item returned with the ConditionalCheckFailedException.
+func versionConflict[T any](err error, expectedVersion int64) bool {
+	var cErr *dynamodbtypes.ConditionalCheckFailedException
+	if !errors.As(err, &cErr) || len(cErr.Item) < 1 {
+		return false
+	}
+	versionPath, pathErr := AttributePath[T](VERSION_FIELD)
+	if pathErr != nil {
+		return false
+	}
+	var version int64
+	if versionAttribute, ok := cErr.Item[versionPath]; ok {
+		if err := attributevalue.Unmarshal(versionAttribute, &version); err != nil {
+			return false
+		}
+	}
+	return version != expectedVersion
+}
+
+// itemVersion returns the version of the item, items without version field are not versioned.
+func itemVersion[T any](item *T) (int64, error) {
+	value := reflect.ValueOf(item).Elem()
+	if value.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("item of type %s is not versioned", value.Type())
+	}
+	field := value.FieldByName(VERSION_FIELD)
+	if !field.IsValid() || !field.CanInt() {
+		return 0, fmt.Errorf("item of type %s is not versioned", value.Type())
+	}
+	return field.Int(), nil
+}
+
+type UpdateWithRetryInput[T any] struct {
+	Table      *string
+	PrimaryKey map[string]dynamodbtypes.AttributeValue
+	// Attempts limits how often the update is retried on a version conflict (DEFAULT_UPDATE_ATTEMPTS if 0).
+	Attempts int
+	// Merge is called with the current item and returns the update that is applied to this version of the item.
+	// It is called again with the newer item if another writer updated the item in the meantime.
+	Merge func(current *T) (*UpdateBuilder[T], error)
+}
+
+// UpdateWithRetry performs a read-modify-write on a versioned item.
+// The item is read, the update returned by Merge is applied conditionally on the read version
+// and if another writer updated the item in the meantime, the flow is repeated with the newer item.
+// A missing item is reported as ConditionalCheckFailedException, exhausted attempts as ErrVersionConflict.
+func UpdateWithRetry[T any](transportCtx context.Context, dynamoClient *dynamodb.Client, input *UpdateWithRetryInput[T]) (*T, error) {
+	attempts := input.Attempts
+	if attempts < 1 {
+		attempts = DEFAULT_UPDATE_ATTEMPTS
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		output, err := dynamoClient.GetItem(transportCtx, &dynamodb.GetItemInput{
+			TableName:      input.Table,
+			Key:            input.PrimaryKey,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(output.Item) < 1 {
+			return nil, &dynamodbtypes.ConditionalCheckFailedException{
+				Message: aws.String("item not found"),
+			}
+		}
+		var current T
+		if err := attributevalue.UnmarshalMap(output.Item, &current); err != nil {
+			return nil, fmt.Errorf("cannot deserialize database item")
+		}
+		version, err := itemVersion(&current)
+		if err != nil {
+			return nil, err
+		}
+
+		updateBuilder, err := input.Merge(&current)
+		if err != nil {
+			return nil, err
+		}
+		updateExpression, err := updateBuilder.IfVersion(version).Build()
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := UpdateSingle[T](transportCtx, dynamoClient, &UpdateSingleInput{
+			Table:           input.Table,
+			PrimaryKey:      input.PrimaryKey,
+			ExpectedVersion: updateExpression.ExpectedVersion,
+			AttributeNames:  updateExpression.AttributeNames,
+			AttributeValues: updateExpression.AttributeValues,
+			ConditionExpr:   updateExpression.ConditionExpr,
+			UpdateExpr:      updateExpression.UpdateExpr,
+		})
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		return updated, err
+	}
+	return nil, ErrVersionConflict
+}
@@ -2,7 +2,11 @@ package pipeline
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -10,16 +14,92 @@ import (
 	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 )
 
+type LogLevel string
+
+const (
+	LOG_INFO  LogLevel = "info"
+	LOG_WARN  LogLevel = "warn"
+	LOG_ERROR LogLevel = "error"
+)
+
+// LogPhase is the pipeline phase that emitted a log event.
+type LogPhase string
+
+const (
+	LOG_PHASE_EVENT  LogPhase = "event"
+	LOG_PHASE_DEPLOY LogPhase = "deploy"
+)
+
+// LogEvent is the json message of a log event written by the CloudLogger.
+// The fields are discovered by logs insights, which allows to filter and aggregate them in queries.
+type LogEvent struct {
+	ExecutionIdentifier string   `json:"execution_identifier"`
+	Phase               LogPhase `json:"phase"`
+	Step                string   `json:"step,omitempty"`
+	Level               LogLevel `json:"level"`
+	Message             string   `json:"message"`
+	DurationMs          *int64   `json:"duration_ms,omitempty"`
+	Error               string   `json:"error,omitempty"`
+}
+
+// Limits of a single PutLogEvents call (https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutLogEvents.html).
+const (
+	CLOUDWATCH_BATCH_MAX_EVENTS = 10000
+	CLOUDWATCH_BATCH_MAX_BYTES  = 1048576
+	CLOUDWATCH_BATCH_MAX_SPAN   = 24 * time.Hour
+	// CLOUDWATCH_EVENT_OVERHEAD is added to the message size of every event when the batch size is calculated.
+	CLOUDWATCH_EVENT_OVERHEAD  = 26
+	CLOUDWATCH_EVENT_MAX_BYTES = 262144 - CLOUDWATCH_EVENT_OVERHEAD
+)
+
+// Default flush thresholds of the CloudLogger.
+const (
+	LOG_FLUSH_INTERVAL = 2 * time.Second
+	LOG_FLUSH_EVENTS   = 200
+	LOG_FLUSH_BYTES    = 256 * 1024
+	// LOG_FLUSH_TIMEOUT limits the final flush that runs after the context of the logger is cancelled.
+	LOG_FLUSH_TIMEOUT = 3 * time.Second
+)
+
+type CloudLoggerOptions struct {
+	// FlushInterval is the maximum time an event stays in the buffer.
+	FlushInterval time.Duration
+	// FlushEvents and FlushBytes trigger a flush as soon as the buffer reaches one of them.
+	FlushEvents int
+	FlushBytes  int
+	// Sink optionally receives every event as json line (e.g. a local file during development).
+	Sink io.Writer
+}
+
+// CloudLogger buffers log events and sends them to a cloudwatch log stream.
+// The buffer is flushed in the background when a threshold is reached, periodically and when the context is cancelled.
+// Close must be called when the logger is not used anymore, it flushes the remaining events.
 type CloudLogger struct {
 	client       *cloudwatchlogs.Client
 	transportCtx context.Context
 	logGroup     string
 	logStream    string
-	logBuffer    []cloudwatchtypes.InputLogEvent
+	execId       string
+	phase        LogPhase
+	options      CloudLoggerOptions
+
+	mutex       sync.Mutex
+	logBuffer   []cloudwatchtypes.InputLogEvent
+	bufferBytes int
+	flushErr    error
+
+	flushMutex sync.Mutex
+	flushChan  chan struct{}
+	closeChan  chan struct{}
+	closeOnce  sync.Once
+	done       chan struct{}
 }
 
-func NewCloudLogger(transportCtx context.Context, client *cloudwatchlogs.Client, logGroupName, logStreamSuffix string) (*CloudLogger, error) {
-	logStreamName := fmt.Sprintf("%s/%s", time.Now().Format("2006/01/02"), logStreamSuffix)
+// NewCloudLogger creates the log stream "<date>/<execId>" and starts the background flusher.
+// All events of the logger are tagged with the execution identifier and the phase.
+// If options is nil, the default thresholds are used.
+func NewCloudLogger(transportCtx context.Context, client *cloudwatchlogs.Client, logGroupName, execId string, phase LogPhase, options *CloudLoggerOptions) (*CloudLogger, error) {
+	logStreamName := fmt.Sprintf("%s/%s", time.Now().Format("2006/01/02"), execId)
 	_, err := client.CreateLogStream(transportCtx, &cloudwatchlogs.CreateLogStreamInput{
 		LogGroupName:  aws.String(logGroupName),
 		LogStreamName: aws.String(logStreamName),
@@ -27,37 +107,268 @@ func NewCloudLogger(transportCtx context.Context, client *cloudwatchlogs.Client,
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logstream on %s", logGroupName)
 	}
-	return &CloudLogger{
+
+	loggerOptions := CloudLoggerOptions{}
+	if options != nil {
+		loggerOptions = *options
+	}
+	if loggerOptions.FlushInterval <= 0 {
+		loggerOptions.FlushInterval = LOG_FLUSH_INTERVAL
+	}
+	if loggerOptions.FlushEvents <= 0 || loggerOptions.FlushEvents > CLOUDWATCH_BATCH_MAX_EVENTS {
+		loggerOptions.FlushEvents = LOG_FLUSH_EVENTS
+	}
+	if loggerOptions.FlushBytes <= 0 || loggerOptions.FlushBytes > CLOUDWATCH_BATCH_MAX_BYTES {
+		loggerOptions.FlushBytes = LOG_FLUSH_BYTES
+	}
+
+	logger := &CloudLogger{
 		client:       client,
 		transportCtx: transportCtx,
 		logGroup:     logGroupName,
 		logStream:    logStreamName,
+		execId:       execId,
+		phase:        phase,
+		options:      loggerOptions,
 		logBuffer:    []cloudwatchtypes.InputLogEvent{},
-	}, nil
+		flushChan:    make(chan struct{}, 1),
+		closeChan:    make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go logger.run()
+	return logger, nil
 }
 
-// WriteLog writes a log event to a local buffer.
+// WriteLog writes an info event to the buffer.
 func (c *CloudLogger) WriteLog(format string, args ...interface{}) {
+	c.Write(LOG_INFO, format, args...)
+}
+
+// WriteWarn writes a warning event to the buffer.
+func (c *CloudLogger) WriteWarn(format string, args ...interface{}) {
+	c.Write(LOG_WARN, format, args...)
+}
+
+// WriteError writes an error event to the buffer.
+func (c *CloudLogger) WriteError(format string, args ...interface{}) {
+	c.Write(LOG_ERROR, format, args...)
+}
+
+// Write writes an event with the level to the buffer.
+func (c *CloudLogger) Write(level LogLevel, format string, args ...interface{}) {
+	c.WriteEvent(LogEvent{
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// StartStep writes the message as start event of the step and returns the step.
+// The step must be finished with Finish, which records its duration.
+func (c *CloudLogger) StartStep(step, message string) *LogStep {
+	c.WriteEvent(LogEvent{
+		Step:    step,
+		Level:   LOG_INFO,
+		Message: message,
+	})
+	return &LogStep{
+		logger:  c,
+		name:    step,
+		started: time.Now(),
+	}
+}
+
+// WriteEvent writes the event to the buffer, the execution identifier and phase are set by the logger.
+// Events that exceed the cloudwatch event size are written with a truncated message.
+func (c *CloudLogger) WriteEvent(logEvent LogEvent) {
+	timestamp := time.Now()
+	logEvent.ExecutionIdentifier = c.execId
+	logEvent.Phase = c.phase
+
+	messageRaw, err := json.Marshal(&logEvent)
+	if err != nil {
+		messageRaw = []byte(logEvent.Message)
+	}
+	if overflow := len(messageRaw) - CLOUDWATCH_EVENT_MAX_BYTES; overflow > 0 {
+		logEvent.Message = logEvent.Message[:max(len(logEvent.Message)-overflow, 0)]
+		logEvent.Error = logEvent.Error[:min(len(logEvent.Error), CLOUDWATCH_EVENT_MAX_BYTES/2)]
+		messageRaw, _ = json.Marshal(&logEvent)
+		if len(messageRaw) > CLOUDWATCH_EVENT_MAX_BYTES {
+			messageRaw = messageRaw[:CLOUDWATCH_EVENT_MAX_BYTES]
+		}
+	}
+	message := string(messageRaw)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.options.Sink != nil {
+		fmt.Fprintf(c.options.Sink, "%s\n", message)
+	}
+
 	c.logBuffer = append(c.logBuffer, cloudwatchtypes.InputLogEvent{
-		Message:   aws.String(fmt.Sprintf(format, args...)),
-		Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+		Message:   aws.String(message),
+		Timestamp: aws.Int64(timestamp.UnixMilli()),
 	})
+	c.bufferBytes += len(message) + CLOUDWATCH_EVENT_OVERHEAD
+	if len(c.logBuffer) >= c.options.FlushEvents || c.bufferBytes >= c.options.FlushBytes {
+		select {
+		case c.flushChan <- struct{}{}:
+		default:
+		}
+	}
 }
 
-// PushLogs pushes the current log event buffer to cloudwatch.
+// PushLogs sends the buffered events to cloudwatch immediately.
+// It also reports errors of previous background flushes.
 func (c *CloudLogger) PushLogs() error {
-	if len(c.logBuffer) < 1 {
-		return nil
+	if err := c.flush(c.transportCtx); err != nil {
+		return err
 	}
 
-	_, err := c.client.PutLogEvents(c.transportCtx, &cloudwatchlogs.PutLogEventsInput{
-		LogGroupName:  aws.String(c.logGroup),
-		LogStreamName: aws.String(c.logStream),
-		LogEvents:     c.logBuffer,
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	err := c.flushErr
+	c.flushErr = nil
+	return err
+}
+
+// Close stops the background flusher and sends the remaining events.
+// The final flush also runs if the context of the logger is already cancelled.
+func (c *CloudLogger) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
 	})
-	if err != nil {
-		return fmt.Errorf("failed to send logevents to %s - %s", c.logGroup, c.logStream)
+	<-c.done
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.transportCtx), LOG_FLUSH_TIMEOUT)
+	defer cancel()
+	if err := c.flush(ctx); err != nil {
+		return err
 	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	err := c.flushErr
+	c.flushErr = nil
+	return err
+}
+
+// run flushes the buffer on the flush interval, when a threshold is reached and when the context is cancelled.
+func (c *CloudLogger) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closeChan:
+			return
+		case <-c.transportCtx.Done():
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(c.transportCtx), LOG_FLUSH_TIMEOUT)
+			c.backgroundFlush(ctx)
+			cancel()
+			return
+		case <-ticker.C:
+			c.backgroundFlush(c.transportCtx)
+		case <-c.flushChan:
+			c.backgroundFlush(c.transportCtx)
+		}
+	}
+}
+
+func (c *CloudLogger) backgroundFlush(ctx context.Context) {
+	if err := c.flush(ctx); err != nil {
+		c.mutex.Lock()
+		c.flushErr = err
+		c.mutex.Unlock()
+	}
+}
+
+// flush sends the buffered events in batches that respect the cloudwatch limits.
+// Events of a failed batch are put back to the buffer, so that they are sent with the next flush.
+func (c *CloudLogger) flush(ctx context.Context) error {
+	c.flushMutex.Lock()
+	defer c.flushMutex.Unlock()
+
+	c.mutex.Lock()
+	logEvents := c.logBuffer
 	c.logBuffer = []cloudwatchtypes.InputLogEvent{}
+	c.bufferBytes = 0
+	c.mutex.Unlock()
+
+	// cloudwatch rejects batches that are not in chronological order.
+	sort.SliceStable(logEvents, func(i, j int) bool {
+		return *logEvents[i].Timestamp < *logEvents[j].Timestamp
+	})
+
+	for len(logEvents) > 0 {
+		batchSize := nextBatchSize(logEvents)
+		_, err := c.client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(c.logGroup),
+			LogStreamName: aws.String(c.logStream),
+			LogEvents:     logEvents[:batchSize],
+		})
+		if err != nil {
+			c.mutex.Lock()
+			c.logBuffer = append(logEvents, c.logBuffer...)
+			for _, logEvent := range logEvents {
+				c.bufferBytes += len(*logEvent.Message) + CLOUDWATCH_EVENT_OVERHEAD
+			}
+			c.mutex.Unlock()
+			return fmt.Errorf("failed to send logevents to %s - %s", c.logGroup, c.logStream)
+		}
+		logEvents = logEvents[batchSize:]
+	}
 	return nil
 }
+
+// nextBatchSize returns the number of events that fit into the next PutLogEvents call.
+func nextBatchSize(logEvents []cloudwatchtypes.InputLogEvent) int {
+	batchBytes := 0
+	firstTimestamp := time.UnixMilli(*logEvents[0].Timestamp)
+	for i, logEvent := range logEvents {
+		eventBytes := len(*logEvent.Message) + CLOUDWATCH_EVENT_OVERHEAD
+		if i >= CLOUDWATCH_BATCH_MAX_EVENTS ||
+			batchBytes+eventBytes > CLOUDWATCH_BATCH_MAX_BYTES ||
+			time.UnixMilli(*logEvent.Timestamp).Sub(firstTimestamp) >= CLOUDWATCH_BATCH_MAX_SPAN {
+			return i
+		}
+		batchBytes += eventBytes
+	}
+	return len(logEvents)
+}
+
+// LogStep measures the duration of a pipeline step.
+type LogStep struct {
+	logger  *CloudLogger
+	name    string
+	started time.Time
+}
+
+// WriteLog writes an info event that is associated with the step.
+func (s *LogStep) WriteLog(format string, args ...interface{}) {
+	s.logger.WriteEvent(LogEvent{
+		Step:    s.name,
+		Level:   LOG_INFO,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Finish writes the end event of the step with its duration.
+// If err is not nil, the step is recorded as failed with the error.
+func (s *LogStep) Finish(err error) {
+	durationMs := time.Since(s.started).Milliseconds()
+	logEvent := LogEvent{
+		Step:       s.name,
+		Level:      LOG_INFO,
+		Message:    fmt.Sprintf("%s finished", s.name),
+		DurationMs: &durationMs,
+	}
+	if err != nil {
+		logEvent.Level = LOG_ERROR
+		logEvent.Message = fmt.Sprintf("%s failed", s.name)
+		logEvent.Error = err.Error()
+	}
+	s.logger.WriteEvent(logEvent)
+}
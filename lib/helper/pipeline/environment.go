@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	secretsmanagertypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/model/event"
+	"github.com/megakuul/battleshiper/lib/model/project"
+)
+
+// Scopes of project environment variables.
+const (
+	ENV_SCOPE_BUILD   = "build"
+	ENV_SCOPE_RUNTIME = "runtime"
+)
+
+// Limits of project environment variables per scope.
+const (
+	ENV_MAX_VARIABLES = 50
+	// ENV_MAX_RUNTIME_BYTES is the size limit of the lambda environment, it is checked with the decrypted values on deployment.
+	ENV_MAX_RUNTIME_BYTES = 4096
+)
+
+// Secret name prefixes of the project environment, the project name is appended.
+const (
+	BUILD_ENVIRONMENT_SECRET_PREFIX   = "battleshiper-project-build-env-"
+	RUNTIME_ENVIRONMENT_SECRET_PREFIX = "battleshiper-project-runtime-env-"
+)
+
+// BUILD_ENVIRONMENT_VARIABLE is the build container variable the build environment secret is injected into.
+const BUILD_ENVIRONMENT_VARIABLE = "BUILD_ENVIRONMENT"
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// reservedBuildVariables are set by the pipeline on the build container.
+var reservedBuildVariables = map[string]struct{}{
+	"EXECUTION_IDENTIFIER":    {},
+	"REPOSITORY_URL":          {},
+	"REPOSITORY_BRANCH":       {},
+	"BUILD_COMMAND":           {},
+	"OUTPUT_DIRECTORY":        {},
+	"BUILD_ASSET_BUCKET_PATH": {},
+	"BUILD_ENVIRONMENT":       {},
+}
+
+// reservedRuntimeVariables are set by the lambda runtime (https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html).
+var reservedRuntimeVariables = map[string]struct{}{
+	"LAMBDA_TASK_ROOT":   {},
+	"LAMBDA_RUNTIME_DIR": {},
+	"TZ":                 {},
+}
+
+// ValidateEnvironmentName checks if the variable name is valid and not reserved in the scope.
+func ValidateEnvironmentName(scope, name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name '%s': expected letters, digits and underscores starting with a letter", name)
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "AWS_") {
+		return fmt.Errorf("invalid variable name '%s': the AWS_ prefix is reserved", name)
+	}
+	switch scope {
+	case ENV_SCOPE_BUILD:
+		if _, reserved := reservedBuildVariables[name]; reserved {
+			return fmt.Errorf("invalid variable name '%s': the variable is set by the build pipeline", name)
+		}
+	case ENV_SCOPE_RUNTIME:
+		if _, reserved := reservedRuntimeVariables[name]; reserved {
+			return fmt.Errorf("invalid variable name '%s': the variable is set by the server runtime", name)
+		}
+	default:
+		return fmt.Errorf("invalid scope '%s': expected '%s' or '%s'", scope, ENV_SCOPE_BUILD, ENV_SCOPE_RUNTIME)
+	}
+	return nil
+}
+
+// DecryptEnvironment returns the plain values of the variables, secret values are decrypted with the cipher.
+func DecryptEnvironment(cipher *auth.Cipher, variables map[string]project.EnvironmentVariable) (map[string]string, error) {
+	environment := map[string]string{}
+	for name, variable := range variables {
+		if !variable.Secret {
+			environment[name] = variable.Value
+			continue
+		}
+		value, err := cipher.Decrypt(variable.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt variable '%s': %v", name, err)
+		}
+		environment[name] = value
+	}
+	return environment, nil
+}
+
+// CreateBuildEnvironment returns the environment of the build container with the variables of the request.
+// Project variables are not part of the environment, they are loaded from the build environment secret (see CreateBuildScript).
+func CreateBuildEnvironment(request *event.BuildRequest) []event.EnvironmentVariable {
+	return []event.EnvironmentVariable{
+		{Name: "EXECUTION_IDENTIFIER", Value: request.ExecutionIdentifier},
+		{Name: "REPOSITORY_URL", Value: request.RepositoryURL},
+		{Name: "REPOSITORY_BRANCH", Value: request.RepositoryBranch},
+		{Name: "BUILD_COMMAND", Value: request.BuildCommand},
+		{Name: "OUTPUT_DIRECTORY", Value: request.OutputDirectory},
+	}
+}
+
+// CreateBuildScript returns a shell script that exports the project variables (sorted by name).
+// The script is stored in the build environment secret and evaluated by the build job before the build command runs.
+func CreateBuildScript(variables map[string]string) string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// the script is never empty, as secrets manager does not accept an empty secret string.
+	script := "# battleshiper build environment\n"
+	for _, name := range names {
+		if _, reserved := reservedBuildVariables[name]; reserved {
+			continue
+		}
+		script += fmt.Sprintf("export %s='%s'\n", name, strings.ReplaceAll(variables[name], "'", `'\''`))
+	}
+	return script
+}
+
+// BuildEnvironmentSecretName returns the name of the secret holding the build script of the project.
+func BuildEnvironmentSecretName(projectName string) string {
+	return fmt.Sprintf("%s%s", BUILD_ENVIRONMENT_SECRET_PREFIX, projectName)
+}
+
+// RuntimeEnvironmentSecretName returns the name of the secret holding the secret runtime variables of the project.
+func RuntimeEnvironmentSecretName(projectName string) string {
+	return fmt.Sprintf("%s%s", RUNTIME_ENVIRONMENT_SECRET_PREFIX, projectName)
+}
+
+// EnvironmentSecret references a stored version of an environment secret.
+type EnvironmentSecret struct {
+	Arn       string
+	VersionId string
+}
+
+// WriteEnvironmentSecret stores the value as new version of the secret, the secret is created if it does not exist yet.
+func WriteEnvironmentSecret(transportCtx context.Context, client *secretsmanager.Client, name, value string) (*EnvironmentSecret, error) {
+	putOutput, err := client.PutSecretValue(transportCtx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(name),
+		SecretString: aws.String(value),
+	})
+	if err == nil {
+		return &EnvironmentSecret{
+			Arn:       aws.ToString(putOutput.ARN),
+			VersionId: aws.ToString(putOutput.VersionId),
+		}, nil
+	}
+	var notFoundErr *secretsmanagertypes.ResourceNotFoundException
+	if !errors.As(err, &notFoundErr) {
+		return nil, fmt.Errorf("failed to write secret: %v", err)
+	}
+
+	createOutput, err := client.CreateSecret(transportCtx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		Description:  aws.String("battleshiper project environment"),
+		SecretString: aws.String(value),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret: %v", err)
+	}
+	return &EnvironmentSecret{
+		Arn:       aws.ToString(createOutput.ARN),
+		VersionId: aws.ToString(createOutput.VersionId),
+	}, nil
+}
+
+// DeleteEnvironmentSecret removes the secret without recovery window, a missing secret is ignored.
+func DeleteEnvironmentSecret(transportCtx context.Context, client *secretsmanager.Client, name string) error {
+	_, err := client.DeleteSecret(transportCtx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(name),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if err != nil {
+		var notFoundErr *secretsmanagertypes.ResourceNotFoundException
+		if errors.As(err, &notFoundErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete secret: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package pipeline
+
+import "testing"
+
+func TestCreateBuildScript(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables map[string]string
+		expected  string
+	}{
+		{
+			name:      "empty",
+			variables: map[string]string{},
+			expected:  "# battleshiper build environment\n",
+		},
+		{
+			name:      "sorted",
+			variables: map[string]string{"B": "2", "A": "1"},
+			expected:  "# battleshiper build environment\nexport A='1'\nexport B='2'\n",
+		},
+		{
+			name:      "quoted",
+			variables: map[string]string{"TOKEN": "it's $HOME `id`"},
+			expected:  "# battleshiper build environment\nexport TOKEN='it'\\''s $HOME `id`'\n",
+		},
+		{
+			name:      "reserved",
+			variables: map[string]string{"BUILD_COMMAND": "rm -rf /", "A": "1"},
+			expected:  "# battleshiper build environment\nexport A='1'\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script := CreateBuildScript(test.variables)
+			if script != test.expected {
+				t.Errorf("expected script %q, got %q", test.expected, script)
+			}
+		})
+	}
+}
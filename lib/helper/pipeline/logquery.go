@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LOG_QUERY_FIELDS selects the structured fields of the LogEvent in a logs insights query.
+const LOG_QUERY_FIELDS = "`execution_identifier`, `phase`, `step`, `level`, `message`, `duration_ms`, `error`"
+
+var (
+	logFilterExpression = regexp.MustCompile(`^([a-z_]+)(=|!=|>=|<=|>|<)(.+)$`)
+	logFilterValue      = regexp.MustCompile(`^[A-Za-z0-9_.:\-]+$`)
+	logFilterNumber     = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// BuildLogFilter translates a filter like "phase=deploy level=error duration_ms>1000" into a logs insights filter command.
+// Filters are separated by whitespace and combined with "and". The fields execution_identifier, phase, step and level
+// support "=" and "!=", error matches if the error contains the value and duration_ms supports numeric comparisons.
+// An empty filter returns an empty command.
+func BuildLogFilter(filter string) (string, error) {
+	conditions := []string{}
+	for _, expression := range strings.Fields(filter) {
+		match := logFilterExpression.FindStringSubmatch(expression)
+		if match == nil {
+			return "", fmt.Errorf("invalid filter '%s'; expected <field><operator><value>", expression)
+		}
+		field, operator, value := match[1], match[2], match[3]
+
+		switch field {
+		case "execution_identifier", "phase", "step", "level":
+			if operator != "=" && operator != "!=" {
+				return "", fmt.Errorf("invalid operator '%s' for %s; expected '=' or '!='", operator, field)
+			}
+			if !logFilterValue.MatchString(value) {
+				return "", fmt.Errorf("invalid value '%s' for %s", value, field)
+			}
+			conditions = append(conditions, fmt.Sprintf("`%s` %s \"%s\"", field, operator, value))
+		case "error":
+			if operator != "=" {
+				return "", fmt.Errorf("invalid operator '%s' for error; expected '='", operator)
+			}
+			if !logFilterValue.MatchString(value) {
+				return "", fmt.Errorf("invalid value '%s' for error", value)
+			}
+			conditions = append(conditions, fmt.Sprintf("`error` like \"%s\"", value))
+		case "duration_ms":
+			if !logFilterNumber.MatchString(value) {
+				return "", fmt.Errorf("invalid value '%s' for duration_ms; expected milliseconds", value)
+			}
+			conditions = append(conditions, fmt.Sprintf("`duration_ms` %s %s", operator, value))
+		default:
+			return "", fmt.Errorf("invalid filter field '%s'; expected execution_identifier, phase, step, level, error or duration_ms", field)
+		}
+	}
+	if len(conditions) < 1 {
+		return "", nil
+	}
+	return fmt.Sprintf("| filter %s", strings.Join(conditions, " and ")), nil
+}
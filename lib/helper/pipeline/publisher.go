@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/megakuul/battleshiper/lib/model/event"
+)
+
+// EventPublisher emits the requests that start the pipeline steps.
+// The source and action of the event are taken from the options, they must match the ticket of the request.
+type EventPublisher interface {
+	PublishInit(transportCtx context.Context, options *EventOptions, request *event.InitRequest) error
+	// PublishBuild emits the build request, the action is suffixed with the project name
+	// so that the event is routed to the build queue of the project.
+	PublishBuild(transportCtx context.Context, options *EventOptions, projectName string, request *event.BuildRequest) error
+	PublishDelete(transportCtx context.Context, options *EventOptions, request *event.DeleteRequest) error
+	PublishRollback(transportCtx context.Context, options *EventOptions, request *event.RollbackRequest) error
+}
+
+// EventBridgePublisher emits the pipeline events to eventbridge.
+// The calling instance needs to have IAM access to the action "events:PutEvents" on the eventbus of the options.
+type EventBridgePublisher struct {
+	client *eventbridge.Client
+}
+
+// NewEventBridgePublisher creates an EventPublisher that emits events with the provided client.
+func NewEventBridgePublisher(client *eventbridge.Client) *EventBridgePublisher {
+	return &EventBridgePublisher{
+		client: client,
+	}
+}
+
+func (p *EventBridgePublisher) PublishInit(transportCtx context.Context, options *EventOptions, request *event.InitRequest) error {
+	return p.put(transportCtx, options, options.Action, request)
+}
+
+func (p *EventBridgePublisher) PublishBuild(transportCtx context.Context, options *EventOptions, projectName string, request *event.BuildRequest) error {
+	return p.put(transportCtx, options, fmt.Sprintf("%s.%s", options.Action, projectName), request)
+}
+
+func (p *EventBridgePublisher) PublishDelete(transportCtx context.Context, options *EventOptions, request *event.DeleteRequest) error {
+	return p.put(transportCtx, options, options.Action, request)
+}
+
+func (p *EventBridgePublisher) PublishRollback(transportCtx context.Context, options *EventOptions, request *event.RollbackRequest) error {
+	return p.put(transportCtx, options, options.Action, request)
+}
+
+func (p *EventBridgePublisher) put(transportCtx context.Context, options *EventOptions, detailType string, request any) error {
+	requestRaw, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to serialize request: %v", err)
+	}
+
+	res, err := p.client.PutEvents(transportCtx, &eventbridge.PutEventsInput{
+		Entries: []eventbridgetypes.PutEventsRequestEntry{{
+			Source:       aws.String(options.Source),
+			DetailType:   aws.String(detailType),
+			Detail:       aws.String(string(requestRaw)),
+			EventBusName: aws.String(options.EventBus),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to emit event: %v", err)
+	} else if res.FailedEntryCount > 0 && len(res.Entries) > 0 {
+		return fmt.Errorf("failed to ingest event: %s", aws.ToString(res.Entries[0].ErrorMessage))
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+// Quotas with a limit derived from the subscription specs.
+// Other quotas are declared with subscription.Quotas.
+const (
+	QUOTA_PIPELINE_BUILDS      = "pipeline_builds"
+	QUOTA_PIPELINE_DEPLOYMENTS = "pipeline_deployments"
+)
+
+// ErrQuotaExceeded is returned if the quota of the subscription is exhausted.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// ResolveQuota returns the quota spec of the subscription, ok is false if the subscription does not limit the quota.
+func ResolveQuota(subscriptionDoc *subscription.Subscription, name string) (subscription.QuotaSpec, bool) {
+	if spec, ok := subscriptionDoc.Quotas[name]; ok {
+		return spec, true
+	}
+	switch name {
+	case QUOTA_PIPELINE_BUILDS:
+		return subscription.QuotaSpec{
+			Limit:  subscriptionDoc.PipelineSpecs.DailyBuilds,
+			Window: subscription.QUOTA_WINDOW_DAILY,
+		}, true
+	case QUOTA_PIPELINE_DEPLOYMENTS:
+		return subscription.QuotaSpec{
+			Limit:  subscriptionDoc.PipelineSpecs.DailyDeployments,
+			Window: subscription.QUOTA_WINDOW_DAILY,
+		}, true
+	default:
+		return subscription.QuotaSpec{}, false
+	}
+}
+
+// RemainingQuota returns the amount the user can still count on the quota in the current window.
+// An expired counter is reset on the next count, therefore the full limit is remaining.
+// If the subscription does not limit the quota, ok is false.
+func RemainingQuota(userDoc *user.User, subscriptionDoc *subscription.Subscription, name string) (int64, bool) {
+	spec, ok := ResolveQuota(subscriptionDoc, name)
+	if !ok {
+		return 0, false
+	}
+	counter, exists := userDoc.Quotas[name]
+	if !exists || counter.Expiration <= time.Now().Unix() {
+		return max(spec.Limit, 0), true
+	}
+	return max(spec.Limit-counter.Count, 0), true
+}
+
+// UsedQuota returns the amount the user counted on the quota in the current window.
+func UsedQuota(userDoc *user.User, name string) int64 {
+	counter, exists := userDoc.Quotas[name]
+	if !exists || counter.Expiration <= time.Now().Unix() {
+		return 0
+	}
+	return counter.Count
+}
+
+// windowExpiration returns the expiration of a window that is started now.
+func windowExpiration(window string, now time.Time) (int64, error) {
+	now = now.UTC()
+	switch window {
+	case subscription.QUOTA_WINDOW_DAILY:
+		return now.Add(24 * time.Hour).Unix(), nil
+	case subscription.QUOTA_WINDOW_MONTHLY:
+		return now.AddDate(0, 1, 0).Unix(), nil
+	case subscription.QUOTA_WINDOW_CALENDAR_DAY:
+		return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Unix(), nil
+	case subscription.QUOTA_WINDOW_CALENDAR_MONTH:
+		return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Unix(), nil
+	default:
+		return 0, fmt.Errorf("unknown quota window '%s'", window)
+	}
+}
+
+type CheckQuotaInput struct {
+	UserTable         string
+	SubscriptionTable string
+	UserDoc           user.User
+	// Name of the quota (e.g. QUOTA_PIPELINE_BUILDS).
+	Name string
+	// Amount that is counted (1 if 0).
+	Amount int64
+}
+
+// QuotaUsage describes a counted amount, it is used to refund the amount with RefundQuota.
+type QuotaUsage struct {
+	Name       string
+	Amount     int64
+	Count      int64
+	Limit      int64
+	Expiration int64
+
+	userTable string
+	userId    string
+}
+
+// CheckQuota counts the amount on the quota counter of the user and fails with ErrQuotaExceeded if the limit is reached.
+// The check and the increment are performed in a single conditional write, an expired counter is reset in the same write.
+// Quotas that are not limited by the subscription are not counted, in this case the returned usage is nil.
+func CheckQuota(transportCtx context.Context, dynamoClient *dynamodb.Client, input *CheckQuotaInput) (*QuotaUsage, error) {
+	subscriptionDoc, err := database.GetSingle[subscription.Subscription](transportCtx, dynamoClient, &database.GetSingleInput{
+		Table: aws.String(input.SubscriptionTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":id": &dynamodbtypes.AttributeValueMemberS{Value: input.UserDoc.SubscriptionId},
+		},
+		ConditionExpr: aws.String("id = :id"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subscription: %v", err)
+	}
+
+	spec, ok := ResolveQuota(subscriptionDoc, input.Name)
+	if !ok {
+		return nil, nil
+	}
+	amount := input.Amount
+	if amount < 1 {
+		amount = 1
+	}
+	if amount > spec.Limit {
+		return nil, fmt.Errorf("%w: %s limit of %d reached", ErrQuotaExceeded, input.Name, spec.Limit)
+	}
+
+	now := time.Now()
+	expiration, err := windowExpiration(spec.Window, now)
+	if err != nil {
+		return nil, err
+	}
+
+	counterPath := "Quotas." + input.Name
+	newCounter := user.QuotaCounter{Count: amount, Expiration: expiration}
+	writes := map[string]*database.UpdateBuilder[user.User]{
+		// create the quota map on users that never counted a quota.
+		"create": database.NewUpdate[user.User]().
+			Set("Quotas", map[string]user.QuotaCounter{input.Name: newCounter}).
+			AttributeNotExists("Quotas"),
+		// start the counter on users that never counted this quota.
+		"start": database.NewUpdate[user.User]().
+			Set(counterPath, newCounter).
+			AttributeNotExists(counterPath),
+		// count on the active window if the amount fits into the limit.
+		"increment": database.NewUpdate[user.User]().
+			Add(counterPath+".Count", amount).
+			AttributeGreaterThan(counterPath+".Expiration", now.Unix()).
+			AttributeLessThan(counterPath+".Count", spec.Limit-amount+1),
+		// replace the counter of an expired window.
+		"reset": database.NewUpdate[user.User]().
+			Set(counterPath, newCounter).
+			AttributeLessThan(counterPath+".Expiration", now.Unix()+1),
+	}
+
+	// every write is conditional, so only the write matching the current counter state succeeds.
+	// The loaded user document determines the first attempt, the others cover concurrent modifications.
+	var order []string
+	counter, exists := input.UserDoc.Quotas[input.Name]
+	switch {
+	case input.UserDoc.Quotas == nil:
+		order = []string{"create", "start", "increment", "reset"}
+	case !exists:
+		order = []string{"start", "increment", "reset"}
+	case counter.Expiration > now.Unix():
+		order = []string{"increment", "reset"}
+	default:
+		order = []string{"reset", "increment"}
+	}
+
+	for _, write := range order {
+		updateExpression, err := writes[write].Build()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build quota expression: %v", err)
+		}
+		updatedUserDoc, err := database.UpdateSingle[user.User](transportCtx, dynamoClient, &database.UpdateSingleInput{
+			Table: aws.String(input.UserTable),
+			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+				"id": &dynamodbtypes.AttributeValueMemberS{Value: input.UserDoc.Id},
+			},
+			AttributeNames:  updateExpression.AttributeNames,
+			AttributeValues: updateExpression.AttributeValues,
+			ConditionExpr:   updateExpression.ConditionExpr,
+			UpdateExpr:      updateExpression.UpdateExpr,
+		})
+		if err != nil {
+			var cErr *dynamodbtypes.ConditionalCheckFailedException
+			if ok := errors.As(err, &cErr); ok {
+				continue
+			}
+			return nil, fmt.Errorf("failed to update quota counter: %v", err)
+		}
+
+		updatedCounter := updatedUserDoc.Quotas[input.Name]
+		return &QuotaUsage{
+			Name:       input.Name,
+			Amount:     amount,
+			Count:      updatedCounter.Count,
+			Limit:      spec.Limit,
+			Expiration: updatedCounter.Expiration,
+			userTable:  input.UserTable,
+			userId:     input.UserDoc.Id,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s limit of %d reached", ErrQuotaExceeded, input.Name, spec.Limit)
+}
+
+// RefundQuota subtracts the counted amount from the quota counter, e.g. if the counted execution could not be started.
+// If the window of the counter was reset in the meantime, nothing is refunded.
+func RefundQuota(transportCtx context.Context, dynamoClient *dynamodb.Client, usage *QuotaUsage) error {
+	if usage == nil {
+		return nil
+	}
+
+	counterPath := "Quotas." + usage.Name
+	updateExpression, err := database.NewUpdate[user.User]().
+		Add(counterPath+".Count", -usage.Amount).
+		AttributeEquals(counterPath+".Expiration", usage.Expiration).
+		AttributeGreaterThan(counterPath+".Count", usage.Amount-1).
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to build quota expression: %v", err)
+	}
+	_, err = database.UpdateSingle[user.User](transportCtx, dynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(usage.userTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"id": &dynamodbtypes.AttributeValueMemberS{Value: usage.userId},
+		},
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		ConditionExpr:   updateExpression.ConditionExpr,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return nil
+		}
+		return fmt.Errorf("failed to refund quota counter: %v", err)
+	}
+	return nil
+}
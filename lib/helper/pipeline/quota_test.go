@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+func TestWindowExpiration(t *testing.T) {
+	now := time.Date(2024, time.January, 31, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		window   string
+		now      time.Time
+		expected time.Time
+		valid    bool
+	}{
+		{window: subscription.QUOTA_WINDOW_DAILY, now: now, expected: time.Date(2024, time.February, 1, 15, 30, 0, 0, time.UTC), valid: true},
+		// AddDate normalizes the overflowing day (February 31st is March 2nd in a leap year).
+		{window: subscription.QUOTA_WINDOW_MONTHLY, now: now, expected: time.Date(2024, time.March, 2, 15, 30, 0, 0, time.UTC), valid: true},
+		{window: subscription.QUOTA_WINDOW_CALENDAR_DAY, now: now, expected: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), valid: true},
+		{window: subscription.QUOTA_WINDOW_CALENDAR_MONTH, now: now, expected: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), valid: true},
+		{
+			window:   subscription.QUOTA_WINDOW_CALENDAR_MONTH,
+			now:      time.Date(2024, time.December, 15, 10, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			valid:    true,
+		},
+		// calendar windows are aligned to UTC regardless of the location of the current time.
+		{
+			window:   subscription.QUOTA_WINDOW_CALENDAR_DAY,
+			now:      time.Date(2024, time.January, 31, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60)),
+			expected: time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC),
+			valid:    true,
+		},
+		{window: "weekly", now: now, valid: false},
+		{window: "", now: now, valid: false},
+	}
+
+	for _, test := range tests {
+		expiration, err := windowExpiration(test.window, test.now)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("windowExpiration(%q) = %d, expected error", test.window, expiration)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("windowExpiration(%q) returned unexpected error: %v", test.window, err)
+			continue
+		}
+		if expiration != test.expected.Unix() {
+			t.Errorf("windowExpiration(%q, %s) = %s, expected %s", test.window, test.now,
+				time.Unix(expiration, 0).UTC(), test.expected)
+		}
+	}
+}
+
+func TestResolveQuota(t *testing.T) {
+	subscriptionDoc := &subscription.Subscription{
+		PipelineSpecs: subscription.PipelineSpecs{
+			DailyBuilds:      10,
+			DailyDeployments: 5,
+		},
+		Quotas: map[string]subscription.QuotaSpec{
+			QUOTA_PIPELINE_DEPLOYMENTS: {Limit: 20, Window: subscription.QUOTA_WINDOW_CALENDAR_MONTH},
+			"build_minutes":            {Limit: 300, Window: subscription.QUOTA_WINDOW_MONTHLY},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		expected subscription.QuotaSpec
+		ok       bool
+	}{
+		{name: QUOTA_PIPELINE_BUILDS, expected: subscription.QuotaSpec{Limit: 10, Window: subscription.QUOTA_WINDOW_DAILY}, ok: true},
+		{name: QUOTA_PIPELINE_DEPLOYMENTS, expected: subscription.QuotaSpec{Limit: 20, Window: subscription.QUOTA_WINDOW_CALENDAR_MONTH}, ok: true},
+		{name: "build_minutes", expected: subscription.QuotaSpec{Limit: 300, Window: subscription.QUOTA_WINDOW_MONTHLY}, ok: true},
+		{name: "unknown", ok: false},
+	}
+
+	for _, test := range tests {
+		spec, ok := ResolveQuota(subscriptionDoc, test.name)
+		if ok != test.ok || spec != test.expected {
+			t.Errorf("ResolveQuota(%q) = %v, %t, expected %v, %t", test.name, spec, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestRemainingQuota(t *testing.T) {
+	subscriptionDoc := &subscription.Subscription{
+		PipelineSpecs: subscription.PipelineSpecs{DailyBuilds: 10},
+	}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name      string
+		quota     string
+		counters  map[string]user.QuotaCounter
+		remaining int64
+		used      int64
+		ok        bool
+	}{
+		{name: "no counters", quota: QUOTA_PIPELINE_BUILDS, counters: nil, remaining: 10, used: 0, ok: true},
+		{name: "active counter", quota: QUOTA_PIPELINE_BUILDS, counters: map[string]user.QuotaCounter{
+			QUOTA_PIPELINE_BUILDS: {Count: 4, Expiration: now + 3600},
+		}, remaining: 6, used: 4, ok: true},
+		{name: "expired counter", quota: QUOTA_PIPELINE_BUILDS, counters: map[string]user.QuotaCounter{
+			QUOTA_PIPELINE_BUILDS: {Count: 4, Expiration: now - 1},
+		}, remaining: 10, used: 0, ok: true},
+		{name: "exceeded counter", quota: QUOTA_PIPELINE_BUILDS, counters: map[string]user.QuotaCounter{
+			QUOTA_PIPELINE_BUILDS: {Count: 12, Expiration: now + 3600},
+		}, remaining: 0, used: 12, ok: true},
+		{name: "unlimited quota", quota: "unknown", counters: map[string]user.QuotaCounter{
+			"unknown": {Count: 3, Expiration: now + 3600},
+		}, remaining: 0, used: 3, ok: false},
+	}
+
+	for _, test := range tests {
+		userDoc := &user.User{Quotas: test.counters}
+		remaining, ok := RemainingQuota(userDoc, subscriptionDoc, test.quota)
+		if remaining != test.remaining || ok != test.ok {
+			t.Errorf("%s: RemainingQuota = %d, %t, expected %d, %t", test.name, remaining, ok, test.remaining, test.ok)
+		}
+		if used := UsedQuota(userDoc, test.quota); used != test.used {
+			t.Errorf("%s: UsedQuota = %d, expected %d", test.name, used, test.used)
+		}
+	}
+}
@@ -2,20 +2,40 @@ package pipeline
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/model/ticket"
 )
 
+// ErrTicketConsumed is returned if a ticket is used a second time.
+var ErrTicketConsumed = errors.New("ticket already consumed")
+
+// TICKET_CLAIM_TIMEOUT is the duration after which a claimed ticket can be claimed again.
+// It matches the maximum lambda timeout, an invocation holding an older claim was aborted.
+const TICKET_CLAIM_TIMEOUT = 15 * time.Minute
+
+// TICKET_MAX_ATTEMPTS is the number of times a ticket can be claimed.
+// It covers the initial invocation and the two retries of an asynchronous lambda event.
+const TICKET_MAX_ATTEMPTS = 3
+
 type TicketOptions struct {
-	Secret string
-	Source string
-	Action string
-	TTL    time.Duration
+	// Signer is only available on instances issuing tickets.
+	Signer   *auth.Signer
+	Verifier *auth.Verifier
+	Source   string
+	Action   string
+	TTL      time.Duration
 }
 
 type TicketClaims struct {
@@ -26,51 +46,50 @@ type TicketClaims struct {
 	jwt.RegisteredClaims
 }
 
-type ticketCredentials struct {
-	Secret string `json:"secret"`
-}
-
-// CreateTicketOptions fetches the ticketSecretArn containing "secret" from SecretsManager and constructs the TicketOptions.
-// The calling instance needs to have IAM access to the action "secretsmanager:GetSecretValue" on the provided ticketSecretArn.
-func CreateTicketOptions(awsConfig aws.Config, transportCtx context.Context, ticketSecretARN string, source string, action string, ttl time.Duration) (*TicketOptions, error) {
-	secretManagerClient := secretsmanager.NewFromConfig(awsConfig)
-
-	secretRequest := &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(ticketSecretARN),
-	}
-
-	secretResponse, err := secretManagerClient.GetSecretValue(transportCtx, secretRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to acquire jwt secret: %v", err)
-	}
-
-	var ticketCredentials ticketCredentials
-	if err := json.Unmarshal([]byte(*secretResponse.SecretString), &ticketCredentials); err != nil {
-		return nil, fmt.Errorf("failed to decode ticket credential secret string: %v", err)
-	}
-
+// CreateTicketOptions constructs the TicketOptions of a ticket issuer.
+func CreateTicketOptions(signer *auth.Signer, source string, action string, ttl time.Duration) *TicketOptions {
 	return &TicketOptions{
-		Secret: ticketCredentials.Secret,
+		Signer: signer,
 		Source: source,
 		Action: action,
 		TTL:    ttl,
-	}, nil
+	}
 }
 
-// CreateTicket generates a ticket based on the input options.
-func CreateTicket(options *TicketOptions, userId, project string) (string, error) {
+// CreateTicketVerificationOptions constructs TicketOptions that can only verify tickets.
+func CreateTicketVerificationOptions(verifier *auth.Verifier) *TicketOptions {
+	return &TicketOptions{
+		Verifier: verifier,
+	}
+}
+
+// CreateTicket generates a ticket based on the input options, it is signed with the newest key of the signer.
+// The ticket id (jti) is the execution identifier, if no execution identifier is provided a random id is used.
+func CreateTicket(options *TicketOptions, userId, project, execIdentifier string) (string, error) {
+	if execIdentifier == "" {
+		idBytes := make([]byte, 16)
+		if _, err := rand.Read(idBytes); err != nil {
+			return "", fmt.Errorf("failed to generate ticket id: %v", err)
+		}
+		execIdentifier = hex.EncodeToString(idBytes)
+	}
+
 	claims := &TicketClaims{
 		UserID:  userId,
 		Project: project,
 		Source:  options.Source,
 		Action:  options.Action,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        execIdentifier,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(options.TTL)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(options.Secret))
+	if options.Signer == nil {
+		return "", fmt.Errorf("ticket options do not contain a signing key")
+	}
+
+	tokenString, err := options.Signer.Sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -79,11 +98,13 @@ func CreateTicket(options *TicketOptions, userId, project string) (string, error
 }
 
 // ParseTicket verifies the ticket based on the provided options. It returns the ticket claims or an error if invalid.
-func ParseTicket(options *TicketOptions, ticket string) (*TicketClaims, error) {
+// The ticket is verified with the public key referenced by the kid header, tickets without kid are rejected.
+func ParseTicket(transportCtx context.Context, options *TicketOptions, ticket string) (*TicketClaims, error) {
 	claims := &TicketClaims{}
-	parsedToken, err := jwt.ParseWithClaims(ticket, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(options.Secret), nil
-	}, jwt.WithValidMethods([]string{"HS256"}))
+	parsedToken, err := jwt.ParseWithClaims(ticket, claims,
+		options.Verifier.Keyfunc(transportCtx),
+		jwt.WithValidMethods(options.Verifier.Algorithms()),
+	)
 
 	if err != nil {
 		return nil, err
@@ -95,3 +116,104 @@ func ParseTicket(options *TicketOptions, ticket string) (*TicketClaims, error) {
 
 	return claims, nil
 }
+
+// ClaimTicket records the ticket in the ticket ledger before the execution is started.
+// A ticket can only be claimed again if its execution failed (see FinishTicket) or if the claim is older than
+// TICKET_CLAIM_TIMEOUT, in this case the claiming invocation was aborted. Every claim counts as an attempt,
+// a ticket that was claimed TICKET_MAX_ATTEMPTS times cannot be claimed again. Otherwise ErrTicketConsumed is returned.
+// This rejects replayed events while a bounded number of retries of a failed execution are still accepted.
+func ClaimTicket(transportCtx context.Context, dynamoClient *dynamodb.Client, ticketTable string, claims *TicketClaims) error {
+	if claims.ID == "" {
+		return fmt.Errorf("ticket does not contain a ticket id")
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("ticket does not contain an expiration")
+	}
+
+	now := time.Now()
+	// the attempt counter is kept across claims, therefore the ticket is upserted instead of replaced.
+	_, err := database.UpdateSingle[ticket.Ticket](transportCtx, dynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(ticketTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"jti": &dynamodbtypes.AttributeValueMemberS{Value: claims.ID},
+		},
+		Upsert: true,
+		ConditionExpr: aws.String("attribute_not_exists(jti) OR " +
+			"((#state = :released OR (#state = :claimed AND #consumed < :timeout)) AND #attempts < :max_attempts)"),
+		AttributeNames: map[string]string{
+			"#action":     "action",
+			"#project":    "project",
+			"#user_id":    "user_id",
+			"#state":      "state",
+			"#consumed":   "consumed",
+			"#expiration": "expiration",
+			"#attempts":   "attempts",
+		},
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":action":       &dynamodbtypes.AttributeValueMemberS{Value: claims.Action},
+			":project":      &dynamodbtypes.AttributeValueMemberS{Value: claims.Project},
+			":user_id":      &dynamodbtypes.AttributeValueMemberS{Value: claims.UserID},
+			":released":     &dynamodbtypes.AttributeValueMemberS{Value: ticket.STATE_RELEASED},
+			":claimed":      &dynamodbtypes.AttributeValueMemberS{Value: ticket.STATE_CLAIMED},
+			":consumed":     &dynamodbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":timeout":      &dynamodbtypes.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(-TICKET_CLAIM_TIMEOUT).Unix(), 10)},
+			":expiration":   &dynamodbtypes.AttributeValueMemberN{Value: strconv.FormatInt(claims.ExpiresAt.Unix(), 10)},
+			":max_attempts": &dynamodbtypes.AttributeValueMemberN{Value: strconv.Itoa(TICKET_MAX_ATTEMPTS)},
+			":one":          &dynamodbtypes.AttributeValueMemberN{Value: "1"},
+		},
+		UpdateExpr: aws.String("SET #action = :action, #project = :project, #user_id = :user_id, " +
+			"#state = :claimed, #consumed = :consumed, #expiration = :expiration ADD #attempts :one"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return ErrTicketConsumed
+		}
+		return fmt.Errorf("failed to record ticket in ledger: %v", err)
+	}
+	return nil
+}
+
+// FinishTicket updates the claimed ticket after the execution finished.
+// A successful execution completes the ticket, a failed execution releases it so that the event can be retried.
+// If the failed execution was the last allowed attempt, the ticket is marked as failed instead.
+func FinishTicket(transportCtx context.Context, dynamoClient *dynamodb.Client, ticketTable string, claims *TicketClaims, executionErr error) error {
+	if executionErr == nil {
+		return updateTicketState(transportCtx, dynamoClient, ticketTable, claims.ID, ticket.STATE_COMPLETED, false)
+	}
+	err := updateTicketState(transportCtx, dynamoClient, ticketTable, claims.ID, ticket.STATE_RELEASED, true)
+	var cErr *dynamodbtypes.ConditionalCheckFailedException
+	if ok := errors.As(err, &cErr); ok {
+		return updateTicketState(transportCtx, dynamoClient, ticketTable, claims.ID, ticket.STATE_FAILED, false)
+	}
+	return err
+}
+
+// updateTicketState sets the state of a claimed ticket. If retryable is set, the update fails with
+// ConditionalCheckFailedException if the ticket has no attempts left.
+func updateTicketState(transportCtx context.Context, dynamoClient *dynamodb.Client, ticketTable, ticketId, state string, retryable bool) error {
+	updateBuilder := database.NewUpdate[ticket.Ticket]().
+		Set("State", state).
+		AttributeEquals("State", ticket.STATE_CLAIMED)
+	if retryable {
+		updateBuilder.AttributeLessThan("Attempts", TICKET_MAX_ATTEMPTS)
+	}
+	updateExpression, err := updateBuilder.Build()
+	if err != nil {
+		return fmt.Errorf("failed to build ticket expression: %v", err)
+	}
+	_, err = database.UpdateSingle[ticket.Ticket](transportCtx, dynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(ticketTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"jti": &dynamodbtypes.AttributeValueMemberS{Value: ticketId},
+		},
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		ConditionExpr:   updateExpression.ConditionExpr,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update ticket in ledger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/megakuul/battleshiper/lib/model/ticket"
+)
+
+// fakeLedger emulates the UpdateItem operation of the ticket ledger.
+// Updates whose condition checks the attempt counter fail if exhausted is set.
+type fakeLedger struct {
+	mutex     sync.Mutex
+	exhausted bool
+	states    []string
+}
+
+type fakeUpdateRequest struct {
+	ConditionExpression       string                       `json:"ConditionExpression"`
+	ExpressionAttributeNames  map[string]string            `json:"ExpressionAttributeNames"`
+	ExpressionAttributeValues map[string]map[string]string `json:"ExpressionAttributeValues"`
+}
+
+func (f *fakeLedger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request fakeUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	attemptsChecked := false
+	state := ""
+	for placeholder, name := range request.ExpressionAttributeNames {
+		if name == "attempts" && strings.Contains(request.ConditionExpression, placeholder) {
+			attemptsChecked = true
+		}
+	}
+	for _, value := range request.ExpressionAttributeValues {
+		switch value["S"] {
+		case ticket.STATE_COMPLETED, ticket.STATE_RELEASED, ticket.STATE_FAILED:
+			state = value["S"]
+		}
+	}
+
+	f.mutex.Lock()
+	f.states = append(f.states, state)
+	exhausted := f.exhausted
+	f.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	if attemptsChecked && exhausted {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":  "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",
+			"message": "The conditional request failed",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"Attributes": map[string]any{}})
+}
+
+func TestFinishTicket(t *testing.T) {
+	tests := []struct {
+		name         string
+		executionErr error
+		exhausted    bool
+		expected     []string
+	}{
+		{name: "successful execution", expected: []string{ticket.STATE_COMPLETED}},
+		{name: "successful last attempt", exhausted: true, expected: []string{ticket.STATE_COMPLETED}},
+		{name: "failed execution", executionErr: errors.New("build failed"),
+			expected: []string{ticket.STATE_RELEASED}},
+		{name: "failed last attempt", executionErr: errors.New("build failed"), exhausted: true,
+			expected: []string{ticket.STATE_RELEASED, ticket.STATE_FAILED}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ledger := &fakeLedger{exhausted: test.exhausted}
+			server := httptest.NewServer(ledger)
+			defer server.Close()
+			client := dynamodb.New(dynamodb.Options{
+				Region:       "us-east-1",
+				BaseEndpoint: aws.String(server.URL),
+				Credentials:  aws.AnonymousCredentials{},
+			})
+
+			claims := &TicketClaims{}
+			claims.ID = "ticket-1"
+			if err := FinishTicket(context.Background(), client, "ledger", claims, test.executionErr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(ledger.states, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("expected state updates %v, got %v", test.expected, ledger.states)
+			}
+		})
+	}
+}
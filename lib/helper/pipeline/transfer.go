@@ -0,0 +1,376 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// Default settings of the object transfer.
+const (
+	TRANSFER_CONCURRENCY  = 16
+	TRANSFER_MAX_ATTEMPTS = 6
+	TRANSFER_BACKOFF      = 250 * time.Millisecond
+	TRANSFER_MAX_BACKOFF  = 8 * time.Second
+	// TRANSFER_PROGRESS_STEPS is the number of progress events written for a transfer with known size.
+	TRANSFER_PROGRESS_STEPS = 10
+	// S3_DELETE_MAX_OBJECTS is the maximum number of objects removed by one DeleteObjects call.
+	S3_DELETE_MAX_OBJECTS = 1000
+)
+
+type TransferOptions struct {
+	// Concurrency is the number of requests that run in parallel.
+	Concurrency int
+	// MaxAttempts limits how often a throttled request is sent.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, it is doubled on every further retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// CreateTransferOptions creates transfer options with the default retry settings and the specified concurrency.
+// If concurrency is not positive, the default concurrency is used.
+func CreateTransferOptions(concurrency int) *TransferOptions {
+	if concurrency <= 0 {
+		concurrency = TRANSFER_CONCURRENCY
+	}
+	return &TransferOptions{
+		Concurrency: concurrency,
+		MaxAttempts: TRANSFER_MAX_ATTEMPTS,
+		Backoff:     TRANSFER_BACKOFF,
+		MaxBackoff:  TRANSFER_MAX_BACKOFF,
+	}
+}
+
+// ObjectCopy describes an object that is copied from the source to the destination.
+type ObjectCopy struct {
+	SourceBucket string
+	SourceKey    string
+	Bucket       string
+	Key          string
+}
+
+// CopyObjects copies the objects with a bounded number of parallel CopyObject requests.
+// Throttled requests are retried with backoff, the first failed copy cancels the remaining ones.
+// If step is not nil, the progress is written to the step (e.g. "copied 1200/3400 objects").
+func CopyObjects(transportCtx context.Context, client *s3.Client, options *TransferOptions, step *LogStep, objects []ObjectCopy) error {
+	options = transferDefaults(options)
+	progress := newTransferProgress(step, "copied", len(objects))
+
+	pool := newTransferPool(transportCtx, options.Concurrency)
+	for _, obj := range objects {
+		ok := pool.submit(func(ctx context.Context) error {
+			err := retryThrottled(ctx, options, func() error {
+				_, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+					Bucket:     aws.String(obj.Bucket),
+					CopySource: aws.String(fmt.Sprintf("%s/%s", obj.SourceBucket, obj.SourceKey)),
+					Key:        aws.String(obj.Key),
+				})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to copy object '%s': %v", obj.Key, err)
+			}
+			progress.add(1)
+			return nil
+		})
+		if !ok {
+			break
+		}
+	}
+	return pool.wait()
+}
+
+// DeleteObjects deletes the objects below the prefix, each listed page is removed with one DeleteObjects request.
+// The pages are deleted in parallel, throttled requests are retried with backoff.
+// If filter is not nil, only keys for which it returns true are deleted.
+// If step is not nil, the number of deleted objects is written to the step after every page.
+func DeleteObjects(transportCtx context.Context, client *s3.Client, options *TransferOptions, step *LogStep, bucket, prefix string, filter func(key string) bool) error {
+	options = transferDefaults(options)
+	progress := newTransferProgress(step, "deleted", 0)
+
+	pool := newTransferPool(transportCtx, options.Concurrency)
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int32(S3_DELETE_MAX_OBJECTS),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(pool.ctx)
+		if err != nil {
+			pool.fail(fmt.Errorf("failed to list objects: %v", err))
+			break
+		}
+
+		deleteObjects := []s3types.ObjectIdentifier{}
+		for _, object := range page.Contents {
+			if filter != nil && !filter(aws.ToString(object.Key)) {
+				continue
+			}
+			deleteObjects = append(deleteObjects, s3types.ObjectIdentifier{
+				Key: object.Key,
+			})
+		}
+		if len(deleteObjects) < 1 {
+			continue
+		}
+
+		ok := pool.submit(func(ctx context.Context) error {
+			if err := deleteObjectPage(ctx, client, options, bucket, deleteObjects); err != nil {
+				return err
+			}
+			progress.add(len(deleteObjects))
+			return nil
+		})
+		if !ok {
+			break
+		}
+	}
+	return pool.wait()
+}
+
+// DeleteKeys deletes the specified objects, the keys are split into DeleteObjects requests that run in parallel.
+// If step is not nil, the progress is written to the step (e.g. "deleted 2000/4500 objects").
+func DeleteKeys(transportCtx context.Context, client *s3.Client, options *TransferOptions, step *LogStep, bucket string, keys []string) error {
+	options = transferDefaults(options)
+	progress := newTransferProgress(step, "deleted", len(keys))
+
+	pool := newTransferPool(transportCtx, options.Concurrency)
+	for start := 0; start < len(keys); start += S3_DELETE_MAX_OBJECTS {
+		deleteObjects := []s3types.ObjectIdentifier{}
+		for _, key := range keys[start:min(start+S3_DELETE_MAX_OBJECTS, len(keys))] {
+			deleteObjects = append(deleteObjects, s3types.ObjectIdentifier{
+				Key: aws.String(key),
+			})
+		}
+
+		ok := pool.submit(func(ctx context.Context) error {
+			if err := deleteObjectPage(ctx, client, options, bucket, deleteObjects); err != nil {
+				return err
+			}
+			progress.add(len(deleteObjects))
+			return nil
+		})
+		if !ok {
+			break
+		}
+	}
+	return pool.wait()
+}
+
+// deleteObjectPage deletes the objects with one DeleteObjects request.
+// Objects that are rejected because of throttling are retried, other rejections fail the deletion.
+func deleteObjectPage(transportCtx context.Context, client *s3.Client, options *TransferOptions, bucket string, objects []s3types.ObjectIdentifier) error {
+	err := retryThrottled(transportCtx, options, func() error {
+		deleteOutput, err := client.DeleteObjects(transportCtx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3types.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if len(deleteOutput.Errors) < 1 {
+			return nil
+		}
+
+		throttledObjects := []s3types.ObjectIdentifier{}
+		for _, deleteErr := range deleteOutput.Errors {
+			if _, ok := retry.DefaultThrottleErrorCodes[aws.ToString(deleteErr.Code)]; !ok {
+				return fmt.Errorf("object '%s' was rejected: %s", aws.ToString(deleteErr.Key), aws.ToString(deleteErr.Message))
+			}
+			throttledObjects = append(throttledObjects, s3types.ObjectIdentifier{
+				Key:       deleteErr.Key,
+				VersionId: deleteErr.VersionId,
+			})
+		}
+		// only the rejected objects are sent again on the next attempt.
+		objects = throttledObjects
+		return &throttleError{message: fmt.Sprintf("%d objects were throttled", len(throttledObjects))}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete objects: %v", err)
+	}
+	return nil
+}
+
+// throttleError is returned by requests that were partially throttled without failing with an api error.
+type throttleError struct {
+	message string
+}
+
+func (e *throttleError) Error() string {
+	return e.message
+}
+
+// isThrottleError checks if the error is caused by a request that was throttled by the service.
+func isThrottleError(err error) bool {
+	var tErr *throttleError
+	if errors.As(err, &tErr) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		_, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]
+		return ok
+	}
+	return false
+}
+
+// retryThrottled calls the request until it succeeds, fails with an error that is not caused by throttling or the attempts are exhausted.
+// Retries are delayed with exponential backoff and full jitter.
+func retryThrottled(transportCtx context.Context, options *TransferOptions, request func() error) error {
+	backoff := options.Backoff
+	for attempt := 1; ; attempt++ {
+		err := request()
+		if err == nil || attempt >= options.MaxAttempts || !isThrottleError(err) {
+			return err
+		}
+
+		delay := time.Duration(rand.Int64N(int64(backoff) + 1))
+		select {
+		case <-transportCtx.Done():
+			return fmt.Errorf("%v; retry interrupted: %v", err, transportCtx.Err())
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, options.MaxBackoff)
+	}
+}
+
+// transferDefaults fills the unset fields of the options with the default settings.
+func transferDefaults(options *TransferOptions) *TransferOptions {
+	transferOptions := *CreateTransferOptions(0)
+	if options == nil {
+		return &transferOptions
+	}
+	if options.Concurrency > 0 {
+		transferOptions.Concurrency = options.Concurrency
+	}
+	if options.MaxAttempts > 0 {
+		transferOptions.MaxAttempts = options.MaxAttempts
+	}
+	if options.Backoff > 0 {
+		transferOptions.Backoff = options.Backoff
+	}
+	if options.MaxBackoff > 0 {
+		transferOptions.MaxBackoff = options.MaxBackoff
+	}
+	return &transferOptions
+}
+
+// transferPool runs tasks on a bounded number of workers.
+// The first failed task cancels the context of the pool, remaining tasks are skipped.
+type transferPool struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	tasks   chan func(context.Context) error
+	workers sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+func newTransferPool(transportCtx context.Context, concurrency int) *transferPool {
+	ctx, cancel := context.WithCancel(transportCtx)
+	pool := &transferPool{
+		ctx:    ctx,
+		cancel: cancel,
+		tasks:  make(chan func(context.Context) error),
+	}
+	for i := 0; i < concurrency; i++ {
+		pool.workers.Add(1)
+		go func() {
+			defer pool.workers.Done()
+			for task := range pool.tasks {
+				if pool.ctx.Err() != nil {
+					continue
+				}
+				if err := task(pool.ctx); err != nil {
+					pool.fail(err)
+				}
+			}
+		}()
+	}
+	return pool
+}
+
+// submit hands the task to the next free worker, it returns false if the pool was cancelled.
+func (p *transferPool) submit(task func(context.Context) error) bool {
+	select {
+	case p.tasks <- task:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// fail records the error and cancels the pool, only the first error is kept.
+func (p *transferPool) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// wait waits until all submitted tasks are finished and returns the first error.
+func (p *transferPool) wait() error {
+	close(p.tasks)
+	p.workers.Wait()
+	// the context can also be cancelled by the caller, in this case skipped tasks do not record an error.
+	if err := p.ctx.Err(); err != nil {
+		p.fail(err)
+	}
+	p.cancel()
+	return p.err
+}
+
+// transferProgress writes the number of processed objects to a log step.
+// With a known total, an event is written every TRANSFER_PROGRESS_STEPS-th share of the total,
+// otherwise every call writes the current count.
+type transferProgress struct {
+	step   *LogStep
+	action string
+	total  int
+
+	mutex    sync.Mutex
+	count    int
+	reported int
+}
+
+func newTransferProgress(step *LogStep, action string, total int) *transferProgress {
+	return &transferProgress{
+		step:   step,
+		action: action,
+		total:  total,
+	}
+}
+
+func (p *transferProgress) add(n int) {
+	if p.step == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.count += n
+	if p.total < 1 {
+		p.step.WriteLog("%s %d objects", p.action, p.count)
+		return
+	}
+	interval := max(p.total/TRANSFER_PROGRESS_STEPS, 1)
+	if p.count-p.reported >= interval || p.count == p.total {
+		p.reported = p.count
+		p.step.WriteLog("%s %d/%d objects", p.action, p.count, p.total)
+	}
+}
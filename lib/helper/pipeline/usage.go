@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+// UsageFigure compares the consumed amount of a resource with the limit of the subscription.
+// The usage types are returned as is by the usage endpoints, therefore they carry json tags.
+type UsageFigure struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// ProjectUsage contains the per project figures, the asset sizes refer to the last successful deployment.
+type ProjectUsage struct {
+	ProjectName      string      `json:"name"`
+	Aliases          UsageFigure `json:"aliases"`
+	PrerenderRoutes  UsageFigure `json:"prerender_routes"`
+	ClientStorage    UsageFigure `json:"client_storage"`
+	PrerenderStorage UsageFigure `json:"prerender_storage"`
+	ServerStorage    UsageFigure `json:"server_storage"`
+}
+
+// Usage contains the consumption of a user compared to the limits of the subscription.
+type Usage struct {
+	Projects     UsageFigure    `json:"projects"`
+	Builds       UsageFigure    `json:"builds"`
+	Deployments  UsageFigure    `json:"deployments"`
+	ProjectUsage []ProjectUsage `json:"project_usage"`
+}
+
+// ComputeUsage compares the consumption of the user and its projects with the limits of the subscription.
+// Build and deployment figures refer to the current quota window.
+func ComputeUsage(userDoc *user.User, subscriptionDoc *subscription.Subscription, projectDocs []project.Project) *Usage {
+	specs := subscriptionDoc.ProjectSpecs
+	usage := &Usage{
+		Projects: UsageFigure{
+			Used:  int64(len(projectDocs)),
+			Limit: specs.ProjectCount,
+		},
+		Builds: UsageFigure{
+			Used: UsedQuota(userDoc, QUOTA_PIPELINE_BUILDS),
+		},
+		Deployments: UsageFigure{
+			Used: UsedQuota(userDoc, QUOTA_PIPELINE_DEPLOYMENTS),
+		},
+		ProjectUsage: []ProjectUsage{},
+	}
+	if spec, ok := ResolveQuota(subscriptionDoc, QUOTA_PIPELINE_BUILDS); ok {
+		usage.Builds.Limit = spec.Limit
+	}
+	if spec, ok := ResolveQuota(subscriptionDoc, QUOTA_PIPELINE_DEPLOYMENTS); ok {
+		usage.Deployments.Limit = spec.Limit
+	}
+
+	for _, projectDoc := range projectDocs {
+		usage.ProjectUsage = append(usage.ProjectUsage, ProjectUsage{
+			ProjectName: projectDoc.ProjectName,
+			Aliases: UsageFigure{
+				Used:  int64(len(projectDoc.Aliases)),
+				Limit: specs.AliasCount,
+			},
+			PrerenderRoutes: UsageFigure{
+				Used:  int64(len(projectDoc.SharedInfrastructure.PrerenderPageKeys)),
+				Limit: specs.PrerenderRoutes,
+			},
+			ClientStorage: UsageFigure{
+				Used:  projectDoc.Usage.ClientBytes,
+				Limit: specs.ClientStorage,
+			},
+			PrerenderStorage: UsageFigure{
+				Used:  projectDoc.Usage.PrerenderBytes,
+				Limit: specs.PrerenderStorage,
+			},
+			ServerStorage: UsageFigure{
+				Used:  projectDoc.Usage.ServerBytes,
+				Limit: specs.ServerStorage,
+			},
+		})
+	}
+	return usage
+}
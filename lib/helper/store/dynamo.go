@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/helper/database"
+)
+
+// dynamoTable implements the generic table operations on a DynamoDB table with a single hash key.
+type dynamoTable[T any] struct {
+	client *dynamodb.Client
+	table  string
+	key    string
+}
+
+func (t *dynamoTable[T]) get(transportCtx context.Context, key dynamodbtypes.AttributeValue) (*T, error) {
+	return t.getByIndex(transportCtx, "", t.key, key)
+}
+
+// getByIndex returns the first item where the attribute equals the value. Set the index to "" to query the main table.
+func (t *dynamoTable[T]) getByIndex(transportCtx context.Context, index, attribute string, value dynamodbtypes.AttributeValue) (*T, error) {
+	input := &database.GetSingleInput{
+		Table: aws.String(t.table),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":value": value,
+		},
+		ConditionExpr: aws.String(fmt.Sprintf("%s = :value", attribute)),
+	}
+	if index != "" {
+		input.Index = aws.String(index)
+	}
+	return database.GetSingle[T](transportCtx, t.client, input)
+}
+
+// listByIndex returns all items of the index where the attribute equals the value.
+func (t *dynamoTable[T]) listByIndex(transportCtx context.Context, index, attribute string, value dynamodbtypes.AttributeValue) ([]T, error) {
+	return database.GetMany[T](transportCtx, t.client, &database.GetManyInput{
+		Table: aws.String(t.table),
+		Index: aws.String(index),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":value": value,
+		},
+		ConditionExpr: aws.String(fmt.Sprintf("%s = :value", attribute)),
+	})
+}
+
+func (t *dynamoTable[T]) list(transportCtx context.Context) ([]T, error) {
+	return database.ScanMany[T](transportCtx, t.client, &database.ScanManyInput{
+		Table: aws.String(t.table),
+	})
+}
+
+// put inserts the item, if protect is set an existing item is not overwritten.
+func (t *dynamoTable[T]) put(transportCtx context.Context, item *T, protect bool) error {
+	input := &database.PutSingleInput[T]{
+		Table: aws.String(t.table),
+		Item:  *item,
+	}
+	if protect {
+		input.ProtectionAttributeName = aws.String(t.key)
+	}
+	return database.PutSingle(transportCtx, t.client, input)
+}
+
+func (t *dynamoTable[T]) update(transportCtx context.Context, key dynamodbtypes.AttributeValue, update Update, returnOld bool) (*T, error) {
+	if err := validateUpdate(update); err != nil {
+		return nil, err
+	}
+	updateBuilder := database.NewUpdate[T]()
+	for _, path := range sortedPaths(update.Set) {
+		updateBuilder.Set(path, update.Set[path])
+	}
+	for _, path := range sortedPaths(update.Condition) {
+		updateBuilder.AttributeEquals(path, update.Condition[path])
+	}
+	if update.Version != nil {
+		updateBuilder.IfVersion(*update.Version)
+	}
+	updateExpression, err := updateBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return database.UpdateSingle[T](transportCtx, t.client, &database.UpdateSingleInput{
+		Table: aws.String(t.table),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			t.key: key,
+		},
+		ReturnOld:       returnOld,
+		ExpectedVersion: updateExpression.ExpectedVersion,
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		ConditionExpr:   updateExpression.ConditionExpr,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+}
+
+func (t *dynamoTable[T]) delete(transportCtx context.Context, key dynamodbtypes.AttributeValue) error {
+	return database.DeleteSingle[T](transportCtx, t.client, &database.DeleteSingleInput{
+		Table: aws.String(t.table),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			t.key: key,
+		},
+	})
+}
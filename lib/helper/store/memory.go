@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/helper/database"
+)
+
+// memoryTable implements the generic table operations in memory.
+// Items are stored in their marshalled form, so that updates and conditions operate on the same
+// attribute paths as on DynamoDB and callers never share memory with the stored items.
+type memoryTable[T any] struct {
+	mutex sync.RWMutex
+	key   string
+	items map[string]map[string]dynamodbtypes.AttributeValue
+}
+
+func newMemoryTable[T any](key string) *memoryTable[T] {
+	return &memoryTable[T]{
+		key:   key,
+		items: map[string]map[string]dynamodbtypes.AttributeValue{},
+	}
+}
+
+func (t *memoryTable[T]) get(_ context.Context, key dynamodbtypes.AttributeValue) (*T, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	item, ok := t.items[keyString(key)]
+	if !ok {
+		return nil, itemNotFound()
+	}
+	return unmarshalItem[T](item)
+}
+
+// getByIndex returns the first item (ordered by key) where the attribute equals the value.
+// Indexes are not maintained in memory, the items are filtered on every request.
+func (t *memoryTable[T]) getByIndex(transportCtx context.Context, index, attribute string, value dynamodbtypes.AttributeValue) (*T, error) {
+	items, err := t.listByIndex(transportCtx, index, attribute, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) < 1 {
+		return nil, itemNotFound()
+	}
+	return &items[0], nil
+}
+
+// listByIndex returns all items (ordered by key) where the attribute equals the value.
+func (t *memoryTable[T]) listByIndex(_ context.Context, _, attribute string, value dynamodbtypes.AttributeValue) ([]T, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	items := []T{}
+	for _, key := range t.sortedKeys() {
+		item := t.items[key]
+		if !reflect.DeepEqual(item[attribute], value) {
+			continue
+		}
+		outputItem, err := unmarshalItem[T](item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *outputItem)
+	}
+	return items, nil
+}
+
+func (t *memoryTable[T]) list(_ context.Context) ([]T, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	items := []T{}
+	for _, key := range t.sortedKeys() {
+		outputItem, err := unmarshalItem[T](t.items[key])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *outputItem)
+	}
+	return items, nil
+}
+
+// put inserts the item, if protect is set an existing item is not overwritten.
+func (t *memoryTable[T]) put(_ context.Context, item *T, protect bool) error {
+	inputItem, err := attributevalue.MarshalMap(item)
+	if err != nil || len(inputItem) < 1 {
+		return fmt.Errorf("cannot serialize input item")
+	}
+	key, ok := inputItem[t.key]
+	if !ok {
+		return fmt.Errorf("item does not contain key attribute '%s'", t.key)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, exists := t.items[keyString(key)]; exists && protect {
+		return &dynamodbtypes.ConditionalCheckFailedException{
+			Message: aws.String("item already exists"),
+		}
+	}
+	t.items[keyString(key)] = inputItem
+	return nil
+}
+
+func (t *memoryTable[T]) update(_ context.Context, key dynamodbtypes.AttributeValue, update Update, returnOld bool) (*T, error) {
+	if err := validateUpdate(update); err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	oldItem, ok := t.items[keyString(key)]
+	if !ok {
+		return nil, itemNotFound()
+	}
+
+	versionPath, versioned := "", false
+	if path, err := database.AttributePath[T](database.VERSION_FIELD); err == nil {
+		versionPath, versioned = path, true
+	}
+	var version int64
+	if versioned {
+		if versionAttribute, ok := oldItem[versionPath]; ok {
+			if err := attributevalue.Unmarshal(versionAttribute, &version); err != nil {
+				return nil, fmt.Errorf("cannot deserialize item version")
+			}
+		}
+	}
+	if update.Version != nil {
+		if !versioned {
+			return nil, fmt.Errorf("item is not versioned")
+		}
+		if version != *update.Version {
+			return nil, database.ErrVersionConflict
+		}
+	}
+
+	for _, fieldPath := range sortedPaths(update.Condition) {
+		path, err := database.AttributePath[T](fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		expected, err := attributevalue.Marshal(update.Condition[fieldPath])
+		if err != nil {
+			return nil, fmt.Errorf("cannot serialize condition value of '%s'", fieldPath)
+		}
+		actual, ok := lookupPath(oldItem, path)
+		if !ok || !reflect.DeepEqual(actual, expected) {
+			return nil, &dynamodbtypes.ConditionalCheckFailedException{
+				Message: aws.String("the conditional request failed"),
+			}
+		}
+	}
+
+	newItem := copyItem(oldItem)
+	for _, fieldPath := range sortedPaths(update.Set) {
+		path, err := database.AttributePath[T](fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		value, err := attributevalue.Marshal(update.Set[fieldPath])
+		if err != nil {
+			return nil, fmt.Errorf("cannot serialize value of '%s'", fieldPath)
+		}
+		if err := assignPath(newItem, path, value); err != nil {
+			return nil, err
+		}
+	}
+	// Like the update builder, every update increments the version of a versioned item.
+	if versioned {
+		newItem[versionPath] = &dynamodbtypes.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)}
+	}
+	t.items[keyString(key)] = newItem
+
+	if returnOld {
+		return unmarshalItem[T](oldItem)
+	}
+	return unmarshalItem[T](newItem)
+}
+
+func (t *memoryTable[T]) delete(_ context.Context, key dynamodbtypes.AttributeValue) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.items, keyString(key))
+	return nil
+}
+
+func (t *memoryTable[T]) sortedKeys() []string {
+	keys := make([]string, 0, len(t.items))
+	for key := range t.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// itemNotFound returns the error database.GetSingle returns for a missing item.
+func itemNotFound() error {
+	return &dynamodbtypes.ConditionalCheckFailedException{
+		Message: aws.String("item not found"),
+	}
+}
+
+func unmarshalItem[T any](item map[string]dynamodbtypes.AttributeValue) (*T, error) {
+	var outputItem T
+	if err := attributevalue.UnmarshalMap(item, &outputItem); err != nil {
+		return nil, fmt.Errorf("cannot deserialize database item")
+	}
+	return &outputItem, nil
+}
+
+// keyString converts a key attribute to the string used to index the items.
+func keyString(key dynamodbtypes.AttributeValue) string {
+	switch value := key.(type) {
+	case *dynamodbtypes.AttributeValueMemberS:
+		return "S:" + value.Value
+	case *dynamodbtypes.AttributeValueMemberN:
+		return "N:" + value.Value
+	default:
+		return fmt.Sprintf("%T:%v", key, key)
+	}
+}
+
+// copyItem copies the item, nested maps are copied so that assignments do not modify the source item.
+func copyItem(item map[string]dynamodbtypes.AttributeValue) map[string]dynamodbtypes.AttributeValue {
+	newItem := make(map[string]dynamodbtypes.AttributeValue, len(item))
+	for name, value := range item {
+		if nested, ok := value.(*dynamodbtypes.AttributeValueMemberM); ok {
+			newItem[name] = &dynamodbtypes.AttributeValueMemberM{Value: copyItem(nested.Value)}
+			continue
+		}
+		newItem[name] = value
+	}
+	return newItem
+}
+
+// lookupPath resolves the dot separated attribute path on the item.
+func lookupPath(item map[string]dynamodbtypes.AttributeValue, path string) (dynamodbtypes.AttributeValue, bool) {
+	segments := strings.Split(path, ".")
+	current := item
+	for i, segment := range segments {
+		value, ok := current[segment]
+		if !ok {
+			return nil, false
+		}
+		if i == len(segments)-1 {
+			return value, true
+		}
+		nested, ok := value.(*dynamodbtypes.AttributeValueMemberM)
+		if !ok {
+			return nil, false
+		}
+		current = nested.Value
+	}
+	return nil, false
+}
+
+// assignPath sets the value on the dot separated attribute path.
+// Like on DynamoDB, the parent of a nested path must exist.
+func assignPath(item map[string]dynamodbtypes.AttributeValue, path string, value dynamodbtypes.AttributeValue) error {
+	segments := strings.Split(path, ".")
+	current := item
+	for _, segment := range segments[:len(segments)-1] {
+		nested, ok := current[segment].(*dynamodbtypes.AttributeValueMemberM)
+		if !ok {
+			return fmt.Errorf("the document path '%s' is invalid for update", path)
+		}
+		current = nested.Value
+	}
+	current[segments[len(segments)-1]] = value
+	return nil
+}
+
+// sortedPaths returns the paths of the map in a deterministic order.
+func sortedPaths(attributes map[string]any) []string {
+	paths := make([]string, 0, len(attributes))
+	for path := range attributes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
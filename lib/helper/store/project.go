@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/model/project"
+)
+
+type projectStore struct {
+	table table[project.Project]
+}
+
+// NewDynamoProjectStore creates a ProjectStore backed by the dynamodb project table.
+func NewDynamoProjectStore(dynamoClient *dynamodb.Client, projectTable string) ProjectStore {
+	return &projectStore{
+		table: &dynamoTable[project.Project]{client: dynamoClient, table: projectTable, key: "project_name"},
+	}
+}
+
+// NewMemoryProjectStore creates an empty in-memory ProjectStore.
+func NewMemoryProjectStore() ProjectStore {
+	return &projectStore{
+		table: newMemoryTable[project.Project]("project_name"),
+	}
+}
+
+func (s *projectStore) Get(transportCtx context.Context, projectName string) (*project.Project, error) {
+	return s.table.get(transportCtx, projectKey(projectName))
+}
+
+func (s *projectStore) ListByOwner(transportCtx context.Context, ownerId string) ([]project.Project, error) {
+	return s.table.listByIndex(transportCtx, project.GSI_OWNER_ID, "owner_id", &dynamodbtypes.AttributeValueMemberS{
+		Value: ownerId,
+	})
+}
+
+func (s *projectStore) Create(transportCtx context.Context, project *project.Project) error {
+	return s.table.put(transportCtx, project, true)
+}
+
+func (s *projectStore) Update(transportCtx context.Context, projectName string, update Update) (*project.Project, error) {
+	return s.table.update(transportCtx, projectKey(projectName), update, false)
+}
+
+func (s *projectStore) AcquireLock(transportCtx context.Context, projectName string) (*project.Project, error) {
+	// the lock is set unconditionally (except for deleted projects), the old state tells if it was already held.
+	oldProject, err := s.table.update(transportCtx, projectKey(projectName), Update{
+		Set:       map[string]any{"PipelineLock": true},
+		Condition: map[string]any{"Deleted": false},
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	if oldProject.PipelineLock {
+		return nil, ErrProjectLocked
+	}
+	oldProject.PipelineLock = true
+	return oldProject, nil
+}
+
+func (s *projectStore) ReleaseLock(transportCtx context.Context, projectName string, update Update) (*project.Project, error) {
+	set := map[string]any{}
+	for path, value := range update.Set {
+		set[path] = value
+	}
+	set["PipelineLock"] = false
+	return s.table.update(transportCtx, projectKey(projectName), Update{
+		Set:       set,
+		Condition: update.Condition,
+	}, false)
+}
+
+func (s *projectStore) Delete(transportCtx context.Context, projectName string) error {
+	return s.table.delete(transportCtx, projectKey(projectName))
+}
+
+func projectKey(projectName string) dynamodbtypes.AttributeValue {
+	return &dynamodbtypes.AttributeValueMemberS{Value: projectName}
+}
@@ -0,0 +1,99 @@
+// store package provides typed repositories for the battleshiper tables.
+// Every repository has a DynamoDB implementation and a thread-safe in-memory implementation
+// that follows the same conditional semantics (e.g. ConditionalCheckFailedException on a missing item).
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+// ErrProjectLocked is returned by AcquireLock if the pipeline lock is already held.
+var ErrProjectLocked = errors.New("project locked")
+
+// Update describes a partial update of a single item.
+// Attributes are addressed with go field paths of the item type (e.g. "SharedInfrastructure.StaticBucketPath"),
+// they are translated to attribute paths with database.AttributePath.
+type Update struct {
+	// Set assigns the values to the fields. The parent of a nested field must exist.
+	Set map[string]any
+	// Condition requires the fields to hold the values, otherwise a ConditionalCheckFailedException is returned.
+	Condition map[string]any
+	// Version requires the item to hold the version, otherwise database.ErrVersionConflict is returned.
+	Version *int64
+}
+
+// UserStore provides access to the user table.
+type UserStore interface {
+	// Get returns the user with the id.
+	Get(transportCtx context.Context, id string) (*user.User, error)
+	// GetByInstallation returns the user associated with the github app installation.
+	GetByInstallation(transportCtx context.Context, installationId int64) (*user.User, error)
+	// Create inserts the user, it fails with ConditionalCheckFailedException if the id is already taken.
+	Create(transportCtx context.Context, user *user.User) error
+	// Update updates the user and returns the updated user.
+	Update(transportCtx context.Context, id string, update Update) (*user.User, error)
+	// Delete removes the user, deleting a missing user is not an error.
+	Delete(transportCtx context.Context, id string) error
+}
+
+// ProjectStore provides access to the project table.
+type ProjectStore interface {
+	// Get returns the project with the name.
+	Get(transportCtx context.Context, projectName string) (*project.Project, error)
+	// ListByOwner returns all projects owned by the user.
+	ListByOwner(transportCtx context.Context, ownerId string) ([]project.Project, error)
+	// Create inserts the project, it fails with ConditionalCheckFailedException if the name is already taken.
+	Create(transportCtx context.Context, project *project.Project) error
+	// Update updates the project and returns the updated project.
+	Update(transportCtx context.Context, projectName string, update Update) (*project.Project, error)
+	// AcquireLock sets the pipeline lock of a project that is not deleted and returns the project.
+	// If the lock is already held ErrProjectLocked is returned.
+	AcquireLock(transportCtx context.Context, projectName string) (*project.Project, error)
+	// ReleaseLock releases the pipeline lock and applies the update in the same operation.
+	ReleaseLock(transportCtx context.Context, projectName string, update Update) (*project.Project, error)
+	// Delete removes the project, deleting a missing project is not an error.
+	Delete(transportCtx context.Context, projectName string) error
+}
+
+// SubscriptionStore provides access to the subscription table.
+type SubscriptionStore interface {
+	// Get returns the subscription with the id.
+	Get(transportCtx context.Context, id string) (*subscription.Subscription, error)
+	// List returns all subscriptions.
+	List(transportCtx context.Context) ([]subscription.Subscription, error)
+	// Put inserts or replaces the subscription.
+	Put(transportCtx context.Context, subscription *subscription.Subscription) error
+}
+
+// IsConditionFailed checks if the error is a ConditionalCheckFailedException, which indicates
+// a missing item or a failed update condition.
+func IsConditionFailed(err error) bool {
+	var cErr *dynamodbtypes.ConditionalCheckFailedException
+	return errors.As(err, &cErr)
+}
+
+// validateUpdate checks that the update sets at least one attribute.
+func validateUpdate(update Update) error {
+	if len(update.Set) < 1 {
+		return fmt.Errorf("update does not set any attribute")
+	}
+	return nil
+}
+
+// table is implemented by the storage backends of the typed stores.
+type table[T any] interface {
+	get(transportCtx context.Context, key dynamodbtypes.AttributeValue) (*T, error)
+	getByIndex(transportCtx context.Context, index, attribute string, value dynamodbtypes.AttributeValue) (*T, error)
+	listByIndex(transportCtx context.Context, index, attribute string, value dynamodbtypes.AttributeValue) ([]T, error)
+	list(transportCtx context.Context) ([]T, error)
+	put(transportCtx context.Context, item *T, protect bool) error
+	update(transportCtx context.Context, key dynamodbtypes.AttributeValue, update Update, returnOld bool) (*T, error)
+	delete(transportCtx context.Context, key dynamodbtypes.AttributeValue) error
+}
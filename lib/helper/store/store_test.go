@@ -0,0 +1,383 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+// newTestProjectStore creates an in-memory project store with the project "alpha" (owner "user-1", version 0),
+// the locked project "beta" and the deleted project "gamma".
+func newTestProjectStore(t *testing.T) ProjectStore {
+	projectStore := NewMemoryProjectStore()
+	projects := []project.Project{
+		{ProjectName: "alpha", OwnerId: "user-1", BuildCommand: "npm run build"},
+		{ProjectName: "beta", OwnerId: "user-2", PipelineLock: true},
+		{ProjectName: "gamma", OwnerId: "user-1", Deleted: true},
+	}
+	for i := range projects {
+		if err := projectStore.Create(context.Background(), &projects[i]); err != nil {
+			t.Fatalf("failed to create project '%s': %v", projects[i].ProjectName, err)
+		}
+	}
+	return projectStore
+}
+
+func TestProjectStoreCreate(t *testing.T) {
+	tests := []struct {
+		name            string
+		projectName     string
+		conditionFailed bool
+	}{
+		{name: "new project", projectName: "delta"},
+		{name: "existing project", projectName: "alpha", conditionFailed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectStore := newTestProjectStore(t)
+			err := projectStore.Create(context.Background(), &project.Project{ProjectName: test.projectName, OwnerId: "user-3"})
+			if test.conditionFailed {
+				if !IsConditionFailed(err) {
+					t.Fatalf("expected ConditionalCheckFailedException, got %v", err)
+				}
+				projectDoc, err := projectStore.Get(context.Background(), test.projectName)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if projectDoc.OwnerId == "user-3" {
+					t.Errorf("existing project was overwritten")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := projectStore.Get(context.Background(), test.projectName); err != nil {
+				t.Errorf("failed to get created project: %v", err)
+			}
+		})
+	}
+}
+
+func TestProjectStoreGet(t *testing.T) {
+	tests := []struct {
+		name            string
+		projectName     string
+		owner           string
+		conditionFailed bool
+	}{
+		{name: "existing project", projectName: "alpha", owner: "user-1"},
+		{name: "deleted project", projectName: "gamma", owner: "user-1"},
+		{name: "missing project", projectName: "delta", conditionFailed: true},
+	}
+
+	projectStore := newTestProjectStore(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectDoc, err := projectStore.Get(context.Background(), test.projectName)
+			if test.conditionFailed {
+				if !IsConditionFailed(err) {
+					t.Fatalf("expected ConditionalCheckFailedException, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if projectDoc.OwnerId != test.owner {
+				t.Errorf("expected owner '%s', got '%s'", test.owner, projectDoc.OwnerId)
+			}
+		})
+	}
+}
+
+func TestProjectStoreListByOwner(t *testing.T) {
+	tests := []struct {
+		name     string
+		owner    string
+		expected []string
+	}{
+		{name: "multiple projects", owner: "user-1", expected: []string{"alpha", "gamma"}},
+		{name: "single project", owner: "user-2", expected: []string{"beta"}},
+		{name: "no projects", owner: "user-3", expected: []string{}},
+	}
+
+	projectStore := newTestProjectStore(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectDocs, err := projectStore.ListByOwner(context.Background(), test.owner)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(projectDocs) != len(test.expected) {
+				t.Fatalf("expected %d projects, got %d", len(test.expected), len(projectDocs))
+			}
+			for i, projectDoc := range projectDocs {
+				if projectDoc.ProjectName != test.expected[i] {
+					t.Errorf("expected project '%s' at %d, got '%s'", test.expected[i], i, projectDoc.ProjectName)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectStoreUpdate(t *testing.T) {
+	tests := []struct {
+		name            string
+		projectName     string
+		update          Update
+		expectedCommand string
+		expectedVersion int64
+		conditionFailed bool
+		versionConflict bool
+		invalid         bool
+	}{
+		{name: "set field", projectName: "alpha",
+			update:          Update{Set: map[string]any{"BuildCommand": "make"}},
+			expectedCommand: "make", expectedVersion: 1},
+		{name: "condition holds", projectName: "alpha",
+			update:          Update{Set: map[string]any{"BuildCommand": "make"}, Condition: map[string]any{"OwnerId": "user-1", "Deleted": false}},
+			expectedCommand: "make", expectedVersion: 1},
+		{name: "condition fails", projectName: "alpha",
+			update:          Update{Set: map[string]any{"BuildCommand": "make"}, Condition: map[string]any{"OwnerId": "user-2"}},
+			conditionFailed: true},
+		{name: "version matches", projectName: "alpha",
+			update:          Update{Set: map[string]any{"BuildCommand": "make"}, Version: int64Ptr(0)},
+			expectedCommand: "make", expectedVersion: 1},
+		{name: "version conflict", projectName: "alpha",
+			update:          Update{Set: map[string]any{"BuildCommand": "make"}, Version: int64Ptr(3)},
+			versionConflict: true},
+		{name: "nested field", projectName: "alpha",
+			update:          Update{Set: map[string]any{"SharedInfrastructure.StaticBucketPath": "bucket/alpha"}},
+			expectedCommand: "npm run build", expectedVersion: 1},
+		{name: "missing project", projectName: "delta",
+			update:          Update{Set: map[string]any{"BuildCommand": "make"}},
+			conditionFailed: true},
+		{name: "empty update", projectName: "alpha", update: Update{}, invalid: true},
+		{name: "unknown field", projectName: "alpha",
+			update: Update{Set: map[string]any{"Missing": "value"}}, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectStore := newTestProjectStore(t)
+			projectDoc, err := projectStore.Update(context.Background(), test.projectName, test.update)
+			switch {
+			case test.conditionFailed:
+				if !IsConditionFailed(err) {
+					t.Fatalf("expected ConditionalCheckFailedException, got %v", err)
+				}
+				return
+			case test.versionConflict:
+				if !errors.Is(err, database.ErrVersionConflict) {
+					t.Fatalf("expected ErrVersionConflict, got %v", err)
+				}
+				return
+			case test.invalid:
+				if err == nil || IsConditionFailed(err) {
+					t.Fatalf("expected invalid update error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if projectDoc.BuildCommand != test.expectedCommand {
+				t.Errorf("expected build command '%s', got '%s'", test.expectedCommand, projectDoc.BuildCommand)
+			}
+			if projectDoc.Version != test.expectedVersion {
+				t.Errorf("expected version %d, got %d", test.expectedVersion, projectDoc.Version)
+			}
+			storedDoc, err := projectStore.Get(context.Background(), test.projectName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if storedDoc.BuildCommand != projectDoc.BuildCommand || storedDoc.Version != projectDoc.Version {
+				t.Errorf("stored project does not match the returned project")
+			}
+		})
+	}
+}
+
+func TestProjectStoreLock(t *testing.T) {
+	tests := []struct {
+		name            string
+		projectName     string
+		locked          bool
+		conditionFailed bool
+	}{
+		{name: "unlocked project", projectName: "alpha"},
+		{name: "locked project", projectName: "beta", locked: true},
+		{name: "deleted project", projectName: "gamma", conditionFailed: true},
+		{name: "missing project", projectName: "delta", conditionFailed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectStore := newTestProjectStore(t)
+			projectDoc, err := projectStore.AcquireLock(context.Background(), test.projectName)
+			switch {
+			case test.locked:
+				if !errors.Is(err, ErrProjectLocked) {
+					t.Fatalf("expected ErrProjectLocked, got %v", err)
+				}
+				return
+			case test.conditionFailed:
+				if !IsConditionFailed(err) {
+					t.Fatalf("expected ConditionalCheckFailedException, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !projectDoc.PipelineLock {
+				t.Errorf("expected the returned project to hold the lock")
+			}
+			if _, err := projectStore.AcquireLock(context.Background(), test.projectName); !errors.Is(err, ErrProjectLocked) {
+				t.Errorf("expected second acquire to fail with ErrProjectLocked, got %v", err)
+			}
+
+			projectDoc, err = projectStore.ReleaseLock(context.Background(), test.projectName, Update{
+				Set: map[string]any{"Status": "released"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if projectDoc.PipelineLock || projectDoc.Status != "released" {
+				t.Errorf("expected released lock with status 'released', got lock %t with status '%s'", projectDoc.PipelineLock, projectDoc.Status)
+			}
+		})
+	}
+}
+
+func TestProjectStoreDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		projectName string
+	}{
+		{name: "existing project", projectName: "alpha"},
+		{name: "missing project", projectName: "delta"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectStore := newTestProjectStore(t)
+			if err := projectStore.Delete(context.Background(), test.projectName); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := projectStore.Get(context.Background(), test.projectName); !IsConditionFailed(err) {
+				t.Errorf("expected deleted project to be missing, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProjectStoreIsolation(t *testing.T) {
+	projectStore := newTestProjectStore(t)
+	projectDoc, err := projectStore.Get(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	projectDoc.BuildCommand = "modified"
+
+	storedDoc, err := projectStore.Get(context.Background(), "alpha")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if storedDoc.BuildCommand != "npm run build" {
+		t.Errorf("modifying a returned project changed the stored project")
+	}
+}
+
+func TestUserStore(t *testing.T) {
+	tests := []struct {
+		name            string
+		installationId  int64
+		expected        string
+		conditionFailed bool
+	}{
+		{name: "installed user", installationId: 42, expected: "user-1"},
+		{name: "unknown installation", installationId: 7, conditionFailed: true},
+	}
+
+	userStore := NewMemoryUserStore()
+	for _, userDoc := range []user.User{{Id: "user-1", InstallationId: 42}, {Id: "user-2"}} {
+		if err := userStore.Create(context.Background(), &userDoc); err != nil {
+			t.Fatalf("failed to create user '%s': %v", userDoc.Id, err)
+		}
+	}
+	if err := userStore.Create(context.Background(), &user.User{Id: "user-1"}); !IsConditionFailed(err) {
+		t.Errorf("expected duplicate user to fail with ConditionalCheckFailedException, got %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userDoc, err := userStore.GetByInstallation(context.Background(), test.installationId)
+			if test.conditionFailed {
+				if !IsConditionFailed(err) {
+					t.Fatalf("expected ConditionalCheckFailedException, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if userDoc.Id != test.expected {
+				t.Errorf("expected user '%s', got '%s'", test.expected, userDoc.Id)
+			}
+		})
+	}
+}
+
+func TestSubscriptionStore(t *testing.T) {
+	tests := []struct {
+		name          string
+		subscriptions []subscription.Subscription
+		id            string
+		expected      string
+		count         int
+	}{
+		{name: "insert",
+			subscriptions: []subscription.Subscription{{Id: "basic", Name: "Basic"}, {Id: "pro", Name: "Pro"}},
+			id:            "pro", expected: "Pro", count: 2},
+		{name: "replace",
+			subscriptions: []subscription.Subscription{{Id: "basic", Name: "Basic"}, {Id: "basic", Name: "Basic v2"}},
+			id:            "basic", expected: "Basic v2", count: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subscriptionStore := NewMemorySubscriptionStore()
+			for i := range test.subscriptions {
+				if err := subscriptionStore.Put(context.Background(), &test.subscriptions[i]); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			subscriptionDoc, err := subscriptionStore.Get(context.Background(), test.id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if subscriptionDoc.Name != test.expected {
+				t.Errorf("expected name '%s', got '%s'", test.expected, subscriptionDoc.Name)
+			}
+			subscriptionDocs, err := subscriptionStore.List(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(subscriptionDocs) != test.count {
+				t.Errorf("expected %d subscriptions, got %d", test.count, len(subscriptionDocs))
+			}
+		})
+	}
+}
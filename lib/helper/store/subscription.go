@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+)
+
+type subscriptionStore struct {
+	table table[subscription.Subscription]
+}
+
+// NewDynamoSubscriptionStore creates a SubscriptionStore backed by the dynamodb subscription table.
+func NewDynamoSubscriptionStore(dynamoClient *dynamodb.Client, subscriptionTable string) SubscriptionStore {
+	return &subscriptionStore{
+		table: &dynamoTable[subscription.Subscription]{client: dynamoClient, table: subscriptionTable, key: "id"},
+	}
+}
+
+// NewMemorySubscriptionStore creates an empty in-memory SubscriptionStore.
+func NewMemorySubscriptionStore() SubscriptionStore {
+	return &subscriptionStore{
+		table: newMemoryTable[subscription.Subscription]("id"),
+	}
+}
+
+func (s *subscriptionStore) Get(transportCtx context.Context, id string) (*subscription.Subscription, error) {
+	return s.table.get(transportCtx, &dynamodbtypes.AttributeValueMemberS{Value: id})
+}
+
+func (s *subscriptionStore) List(transportCtx context.Context) ([]subscription.Subscription, error) {
+	return s.table.list(transportCtx)
+}
+
+func (s *subscriptionStore) Put(transportCtx context.Context, subscription *subscription.Subscription) error {
+	return s.table.put(transportCtx, subscription, false)
+}
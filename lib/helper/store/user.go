@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+type userStore struct {
+	table table[user.User]
+}
+
+// NewDynamoUserStore creates a UserStore backed by the dynamodb user table.
+func NewDynamoUserStore(dynamoClient *dynamodb.Client, userTable string) UserStore {
+	return &userStore{
+		table: &dynamoTable[user.User]{client: dynamoClient, table: userTable, key: "id"},
+	}
+}
+
+// NewMemoryUserStore creates an empty in-memory UserStore.
+func NewMemoryUserStore() UserStore {
+	return &userStore{
+		table: newMemoryTable[user.User]("id"),
+	}
+}
+
+func (s *userStore) Get(transportCtx context.Context, id string) (*user.User, error) {
+	return s.table.get(transportCtx, userKey(id))
+}
+
+func (s *userStore) GetByInstallation(transportCtx context.Context, installationId int64) (*user.User, error) {
+	return s.table.getByIndex(transportCtx, user.GSI_INSTALLATION_ID, "installation_id", &dynamodbtypes.AttributeValueMemberN{
+		Value: strconv.FormatInt(installationId, 10),
+	})
+}
+
+func (s *userStore) Create(transportCtx context.Context, user *user.User) error {
+	return s.table.put(transportCtx, user, true)
+}
+
+func (s *userStore) Update(transportCtx context.Context, id string, update Update) (*user.User, error) {
+	return s.table.update(transportCtx, userKey(id), update, false)
+}
+
+func (s *userStore) Delete(transportCtx context.Context, id string) error {
+	return s.table.delete(transportCtx, userKey(id))
+}
+
+func userKey(id string) dynamodbtypes.AttributeValue {
+	return &dynamodbtypes.AttributeValueMemberS{Value: id}
+}
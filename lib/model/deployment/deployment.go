@@ -0,0 +1,15 @@
+// Contains database types for the deployment history collection.
+package deployment
+
+// Deployment records a successful deployment of a project.
+// The build artifacts are retained at ArtifactPath, so that the deployment can be restored without rebuilding.
+type Deployment struct {
+	ProjectName         string `dynamodbav:"project_name"`
+	ExecutionIdentifier string `dynamodbav:"execution_identifier"`
+	Timepoint           int64  `dynamodbav:"timepoint"`
+	Commit              string `dynamodbav:"commit"`
+	// ArtifactPath is the location of the retained build output (e.g. artifact_bucket/project/execution_identifier).
+	ArtifactPath    string            `dynamodbav:"artifact_path"`
+	ServerObjectKey string            `dynamodbav:"server_object_key"`
+	PageKeys        map[string]string `dynamodbav:"page_keys"`
+}
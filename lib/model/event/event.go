@@ -5,6 +5,12 @@ type InitRequest struct {
 	InitTicket string `json:"init_ticket"`
 }
 
+// EnvironmentVariable uses the key format of the aws batch container overrides.
+type EnvironmentVariable struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
 type BuildRequest struct {
 	DeployTicket        string `json:"deploy_ticket"`
 	ExecutionIdentifier string `json:"execution_identifier"`
@@ -12,6 +18,9 @@ type BuildRequest struct {
 	RepositoryBranch    string `json:"repository_branch"`
 	BuildCommand        string `json:"build_command"`
 	OutputDirectory     string `json:"output_directory"`
+	// Environment contains the fields above in the format of the build container environment.
+	// Build variables of the project are never part of the event, the build job loads them from the build environment secret.
+	Environment []EnvironmentVariable `json:"environment"`
 }
 
 // the deploy request is not created manually, but emitted by aws.batch
@@ -26,6 +35,13 @@ type DeployRequest struct {
 	StatusReason string           `json:"statusReason"`
 }
 
+// RollbackRequest restores the deployment with the DeploymentIdentifier,
+// the rollback itself is executed with the identifier of the ticket.
+type RollbackRequest struct {
+	RollbackTicket       string `json:"rollback_ticket"`
+	DeploymentIdentifier string `json:"deployment_identifier"`
+}
+
 type DeleteRequest struct {
 	DeleteTicket string `json:"delete_ticket"`
 }
@@ -3,6 +3,27 @@ package project
 
 const GSI_OWNER_ID = "gsi_owner_id"
 
+// STATIC_VERSION_KEY_PREFIX is prepended to the project name to build the cdn store key of the live static asset version.
+const STATIC_VERSION_KEY_PREFIX = "static@"
+
+// STATIC_MANIFEST_SUFFIX is appended to the static version to build the key of the version manifest below the project prefix
+// (e.g. static_bucket/project/version.manifest.json).
+const STATIC_MANIFEST_SUFFIX = ".manifest.json"
+
+// StaticObject references the static bucket object that holds the content of a relative key.
+// ETag and Size describe the build object the content was copied from.
+type StaticObject struct {
+	Key  string `json:"key"`
+	ETag string `json:"etag"`
+	Size int64  `json:"size"`
+}
+
+// StaticManifest maps the relative keys of a static version to their objects.
+// Objects are written once below the version that introduced them, later versions reference them as long as they are unchanged.
+type StaticManifest struct {
+	Objects map[string]StaticObject `json:"objects"`
+}
+
 type EventResult struct {
 	ExecutionIdentifier string `dynamodbav:"execution_identifier"`
 	Timepoint           int64  `dynamodbav:"timepoint"`
@@ -41,6 +62,62 @@ type SharedInfrastructure struct {
 	PrerenderPageKeys    map[string]string `dynamodbav:"prerender_page_keys"`
 }
 
+// Usage holds the asset sizes measured on the last successful deployment.
+type Usage struct {
+	ClientBytes    int64 `dynamodbav:"client_bytes"`
+	PrerenderBytes int64 `dynamodbav:"prerender_bytes"`
+	ServerBytes    int64 `dynamodbav:"server_bytes"`
+}
+
+const (
+	TRAFFIC_SHIFT_ALL_AT_ONCE = "all_at_once"
+	TRAFFIC_SHIFT_CANARY      = "canary"
+	TRAFFIC_SHIFT_LINEAR      = "linear"
+)
+
+// TrafficShift configures how traffic is moved from the live server version to a newly released version.
+// Percentage is the share of the canary step (canary) or the share added per step (linear), Interval is the number of seconds
+// between the steps. If the error rate (percent) of the new version exceeds the ErrorThreshold, traffic is moved back.
+type TrafficShift struct {
+	Strategy       string `dynamodbav:"strategy"`
+	Percentage     int64  `dynamodbav:"percentage"`
+	Interval       int64  `dynamodbav:"interval"`
+	ErrorThreshold int64  `dynamodbav:"error_threshold"`
+}
+
+// ShiftWeights returns the traffic percentages routed to the new version before it receives all traffic.
+// If no steps are returned, all traffic is moved at once.
+func (t TrafficShift) ShiftWeights() []int64 {
+	if t.Percentage < 1 || t.Percentage > 99 {
+		return nil
+	}
+	switch t.Strategy {
+	case TRAFFIC_SHIFT_CANARY:
+		return []int64{t.Percentage}
+	case TRAFFIC_SHIFT_LINEAR:
+		weights := []int64{}
+		for weight := t.Percentage; weight < 100; weight += t.Percentage {
+			weights = append(weights, weight)
+		}
+		return weights
+	default:
+		return nil
+	}
+}
+
+// EnvironmentVariable holds the value of a project environment variable.
+// Values of secret variables are encrypted and are never returned to the user.
+type EnvironmentVariable struct {
+	Value  string `dynamodbav:"value"`
+	Secret bool   `dynamodbav:"secret"`
+}
+
+// Environment holds the variables injected into the build job (Build) and the server function (Runtime).
+type Environment struct {
+	Build   map[string]EnvironmentVariable `dynamodbav:"build"`
+	Runtime map[string]EnvironmentVariable `dynamodbav:"runtime"`
+}
+
 // structure is not implemented and will be used for dedicated cdn feature in the future.
 type CDNInfrastructure struct {
 	Enabled   bool   `dynamodbav:"enabled"`
@@ -53,6 +130,7 @@ type Project struct {
 	Deleted     bool   `dynamodbav:"deleted"`
 	Initialized bool   `dynamodbav:"initialized"`
 	Status      string `dynamodbav:"status"`
+	Version     int64  `dynamodbav:"version"`
 
 	Repository           Repository          `dynamodbav:"repository"`
 	Aliases              map[string]struct{} `dynamodbav:"aliases"`
@@ -62,6 +140,10 @@ type Project struct {
 	LastEventResult      EventResult         `dynamodbav:"last_event_result"`
 	LastBuildResult      BuildResult         `dynamodbav:"last_build_result"`
 	LastDeploymentResult DeploymentResult    `dynamodbav:"last_deployment_result"`
+	ActiveDeployment     string              `dynamodbav:"active_deployment"`
+	TrafficShift         TrafficShift        `dynamodbav:"traffic_shift"`
+	Usage                Usage               `dynamodbav:"usage"`
+	Environment          Environment         `dynamodbav:"environment"`
 
 	PipelineLock            bool                    `dynamodbav:"pipeline_lock"`
 	DedicatedInfrastructure DedicatedInfrastructure `dynamodbav:"dedicated_infrastructure"`
@@ -22,6 +22,7 @@ const (
 	WRITE_PROJECT      ACCESS = "WRITE_PROJECT"
 	WRITE_SUBSCRIPTION ACCESS = "WRITE_SUBSCRIPTION"
 	WRITE_ROLE         ACCESS = "WRITE_ROLE"
+	WRITE_CREDENTIAL   ACCESS = "WRITE_CREDENTIAL"
 )
 
 var RBAC_MAP = map[ROLE]map[ACCESS]struct{}{
@@ -31,11 +32,12 @@ var RBAC_MAP = map[ROLE]map[ACCESS]struct{}{
 		READ_PROJECT: struct{}{},
 	},
 	MAINTAINER: {
-		READ_USER:     struct{}{},
-		READ_PROJECT:  struct{}{},
-		READ_LOGS:     struct{}{},
-		WRITE_USER:    struct{}{},
-		WRITE_PROJECT: struct{}{},
+		READ_USER:        struct{}{},
+		READ_PROJECT:     struct{}{},
+		READ_LOGS:        struct{}{},
+		WRITE_USER:       struct{}{},
+		WRITE_PROJECT:    struct{}{},
+		WRITE_CREDENTIAL: struct{}{},
 	},
 	SUBSCRIPTION_MANAGER: {
 		READ_SUBSCRIPTION:  struct{}{},
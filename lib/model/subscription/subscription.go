@@ -1,6 +1,20 @@
 // Contains database types for the subscription collection.
 package subscription
 
+// Windows define the period after which a quota counter is reset.
+// Rolling windows start with the first counted execution, calendar windows are aligned to UTC.
+const (
+	QUOTA_WINDOW_DAILY          = "daily"
+	QUOTA_WINDOW_MONTHLY        = "monthly"
+	QUOTA_WINDOW_CALENDAR_DAY   = "calendar_day"
+	QUOTA_WINDOW_CALENDAR_MONTH = "calendar_month"
+)
+
+type QuotaSpec struct {
+	Limit  int64  `dynamodbav:"limit"`
+	Window string `dynamodbav:"window"`
+}
+
 type PipelineSpecs struct {
 	DailyBuilds      int64 `dynamodbav:"daily_builds"`
 	DailyDeployments int64 `dynamodbav:"daily_deployments"`
@@ -13,6 +27,8 @@ type ProjectSpecs struct {
 	ServerStorage    int64 `dynamodbav:"server_storage"`
 	ClientStorage    int64 `dynamodbav:"client_storage"`
 	PrerenderStorage int64 `dynamodbav:"prerender_storage"`
+	// DeploymentRetention is the number of deployments retained for rollbacks (including the active deployment).
+	DeploymentRetention int64 `dynamodbav:"deployment_retention"`
 }
 
 type CDNSpecs struct {
@@ -25,4 +41,6 @@ type Subscription struct {
 	PipelineSpecs PipelineSpecs `dynamodbav:"pipeline_specs"`
 	ProjectSpecs  ProjectSpecs  `dynamodbav:"project_specs"`
 	CDNSpecs      CDNSpecs      `dynamodbav:"cdn_specs"`
+	// Quotas declares additional named quotas (e.g. "build_minutes"), they override the quotas derived from the specs.
+	Quotas map[string]QuotaSpec `dynamodbav:"quotas"`
 }
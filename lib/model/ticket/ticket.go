@@ -0,0 +1,27 @@
+// Contains database types for the ticket ledger collection.
+package ticket
+
+// States of a ticket in the ledger.
+// Entries without state were recorded before claims were introduced, they are treated as completed.
+const (
+	// STATE_CLAIMED is set while an execution of the ticket is running.
+	STATE_CLAIMED = "claimed"
+	// STATE_RELEASED is set if the execution failed, the ticket can be claimed again by a retry of the event.
+	STATE_RELEASED = "released"
+	// STATE_COMPLETED is set if the execution finished, the ticket cannot be used again.
+	STATE_COMPLETED = "completed"
+	// STATE_FAILED is set if the last allowed attempt of the execution failed, the ticket cannot be used again.
+	STATE_FAILED = "failed"
+)
+
+// Ticket records a pipeline ticket used by an execution. The item expires (table ttl) together with the ticket.
+type Ticket struct {
+	Id         string `dynamodbav:"jti"`
+	Action     string `dynamodbav:"action"`
+	Project    string `dynamodbav:"project"`
+	UserId     string `dynamodbav:"user_id"`
+	State      string `dynamodbav:"state"`
+	Consumed   int64  `dynamodbav:"consumed"`
+	Attempts   int64  `dynamodbav:"attempts"`
+	Expiration int64  `dynamodbav:"expiration"`
+}
@@ -5,11 +5,9 @@ import "github.com/megakuul/battleshiper/lib/model/rbac"
 
 const GSI_INSTALLATION_ID = "gsi_installation_id"
 
-type ExecutionLimitCounter struct {
-	PipelineBuilds                int64 `dynamodbav:"pipeline_builds"`
-	PipelineBuildsExpiration      int64 `dynamodbav:"pipeline_builds_exp"`
-	PipelineDeployments           int64 `dynamodbav:"pipeline_deployments"`
-	PipelineDeploymentsExpiration int64 `dynamodbav:"pipeline_deployments_exp"`
+type QuotaCounter struct {
+	Count      int64 `dynamodbav:"count"`
+	Expiration int64 `dynamodbav:"exp"`
 }
 
 type Repository struct {
@@ -19,13 +17,14 @@ type Repository struct {
 }
 
 type User struct {
-	Id             string                 `dynamodbav:"id"`
-	Privileged     bool                   `dynamodbav:"privileged"`
-	Provider       string                 `dynamodbav:"provider"`
-	Roles          map[rbac.ROLE]struct{} `dynamodbav:"roles"`
-	RefreshToken   string                 `dynamodbav:"refresh_token"`
-	LimitCounter   ExecutionLimitCounter  `dynamodbav:"limit_counter"`
-	SubscriptionId string                 `dynamodbav:"subscription_id"`
-	InstallationId int64                  `dynamodbav:"installation_id"`
-	Repositories   map[int64]Repository   `dynamodbav:"repositories"`
+	Id             string                  `dynamodbav:"id"`
+	Privileged     bool                    `dynamodbav:"privileged"`
+	Provider       string                  `dynamodbav:"provider"`
+	Roles          map[rbac.ROLE]struct{}  `dynamodbav:"roles"`
+	RefreshToken   string                  `dynamodbav:"refresh_token"`
+	Quotas         map[string]QuotaCounter `dynamodbav:"quotas,omitempty"`
+	SubscriptionId string                  `dynamodbav:"subscription_id"`
+	InstallationId int64                   `dynamodbav:"installation_id"`
+	Repositories   map[int64]Repository    `dynamodbav:"repositories"`
+	Version        int64                   `dynamodbav:"version"`
 }
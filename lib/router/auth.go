@@ -0,0 +1,123 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/megakuul/battleshiper/lib/helper/auth"
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/model/rbac"
+	"github.com/megakuul/battleshiper/lib/model/user"
+)
+
+var logger = log.New(os.Stderr, "ROUTER: ", 0)
+
+type contextKey string
+
+const (
+	userClaimsKey contextKey = "user_claims"
+	userKey       contextKey = "user"
+)
+
+// Authenticate creates a middleware that parses the user_token cookie of the request.
+// The resolved claims are attached to the transport context and can be obtained with GetUserClaims.
+func Authenticate(jwtOptions *auth.JwtOptions) Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+			userTokenCookie, err := (&http.Request{Header: http.Header{"Cookie": request.Cookies}}).Cookie("user_token")
+			if err != nil {
+				return ErrorResponse(request, http.StatusUnauthorized, "no user_token provided"), nil
+			}
+
+			userToken, err := auth.ParseJWT(ctx, jwtOptions, userTokenCookie.Value)
+			if err != nil {
+				return ErrorResponse(request, http.StatusUnauthorized, fmt.Sprintf("user_token is invalid: %v", err)), nil
+			}
+
+			return next(request, WithUserClaims(ctx, userToken))
+		}
+	}
+}
+
+// LoadUser creates a middleware that loads the user record of the authenticated user from the database.
+// The middleware must run after Authenticate, the user is attached to the transport context and can be obtained with GetUser.
+func LoadUser(dynamoClient *dynamodb.Client, userTable string) Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+			userToken, ok := GetUserClaims(ctx)
+			if !ok {
+				return ErrorResponse(request, http.StatusUnauthorized, "user is not authenticated"), nil
+			}
+
+			userDoc, err := database.GetSingle[user.User](ctx, dynamoClient, &database.GetSingleInput{
+				Table: aws.String(userTable),
+				AttributeValues: map[string]dynamodbtypes.AttributeValue{
+					":id": &dynamodbtypes.AttributeValueMemberS{Value: userToken.Id},
+				},
+				ConditionExpr: aws.String("id = :id"),
+			})
+			if err != nil {
+				var cErr *dynamodbtypes.ConditionalCheckFailedException
+				if ok := errors.As(err, &cErr); ok {
+					return ErrorResponse(request, http.StatusNotFound, "user not found"), nil
+				}
+				logger.Printf("failed to load user record from database: %v\n", err)
+				return ErrorResponse(request, http.StatusInternalServerError, "failed to load user record from database"), nil
+			}
+
+			return next(request, WithUser(ctx, userDoc))
+		}
+	}
+}
+
+// RequireAccess creates a middleware that rejects the request if the user lacks any of the specified accesses.
+// The middleware must run after LoadUser.
+func RequireAccess(accesses ...rbac.ACCESS) Middleware {
+	return func(next Handler) Handler {
+		return func(request events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+			userDoc, ok := GetUser(ctx)
+			if !ok {
+				return ErrorResponse(request, http.StatusUnauthorized, "user is not authenticated"), nil
+			}
+
+			for _, access := range accesses {
+				if !rbac.CheckPermission(userDoc.Roles, access) {
+					return ErrorResponse(request, http.StatusForbidden, "user does not have sufficient permissions for this action"), nil
+				}
+			}
+
+			return next(request, ctx)
+		}
+	}
+}
+
+// GetUserClaims returns the user claims attached by the Authenticate middleware.
+func GetUserClaims(ctx context.Context) (*auth.UserClaims, bool) {
+	userClaims, ok := ctx.Value(userClaimsKey).(*auth.UserClaims)
+	return userClaims, ok
+}
+
+// GetUser returns the user record attached by the LoadUser middleware.
+func GetUser(ctx context.Context) (*user.User, bool) {
+	userDoc, ok := ctx.Value(userKey).(*user.User)
+	return userDoc, ok
+}
+
+// WithUserClaims attaches the user claims to the context like the Authenticate middleware does.
+func WithUserClaims(ctx context.Context, userClaims *auth.UserClaims) context.Context {
+	return context.WithValue(ctx, userClaimsKey, userClaims)
+}
+
+// WithUser attaches the user record to the context like the LoadUser middleware does.
+func WithUser(ctx context.Context, userDoc *user.User) context.Context {
+	return context.WithValue(ctx, userKey, userDoc)
+}
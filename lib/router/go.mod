@@ -2,6 +2,32 @@ module github.com/megakuul/battleshiper/lib/router
 
 go 1.22.4
 
-require github.com/aws/aws-lambda-go v1.47.0
+require (
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/aws/aws-sdk-go-v2 v1.31.0
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.35.3
+	github.com/megakuul/battleshiper/lib/helper v1.2.5
+	github.com/megakuul/battleshiper/lib/model v1.2.1
+)
 
-require github.com/stretchr/testify v1.9.0 // indirect
+require (
+	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.15.8 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.18 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodbstreams v1.23.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.5 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.19 // indirect
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.33.3 // indirect
+	github.com/aws/smithy-go v1.21.0 // indirect
+	github.com/go-playground/webhooks/v6 v6.4.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
+	github.com/google/go-github/v63 v63.0.0 // indirect
+	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	golang.org/x/oauth2 v0.23.0 // indirect
+)
+
+replace (
+	github.com/megakuul/battleshiper/lib/helper => ../helper
+	github.com/megakuul/battleshiper/lib/model => ../model
+)
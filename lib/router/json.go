@@ -0,0 +1,178 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HTTPError is an error that is returned to the client with the specified status code.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// Errorf creates a new HTTPError with the specified status code and a formatted message.
+func Errorf(code int, format string, args ...any) error {
+	return &HTTPError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ErrorBody is the json body that is returned by the router if a request fails.
+type ErrorBody struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"request_id"`
+}
+
+// AddJSONRoute adds a new route with a typed json handler to the router.
+// The input is decoded from the json body and from fields tagged with `query:"<name>"` from the query string,
+// afterwards it is validated with the `validate` struct tags (see Validate).
+// The tags are compiled on registration, AddJSONRoute panics if a tag of the input type is malformed.
+// The output is serialized to json, errors are returned as ErrorBody with the code of the HTTPError;
+// errors that are no HTTPError are returned as internal server error.
+func AddJSONRoute[T, In, Out any](r *Router[T], method string, path string, handler func(*In, events.APIGatewayV2HTTPRequest, context.Context, T) (*Out, error), middlewares ...Middleware) {
+	inputValidator, err := compileValidator(reflect.TypeFor[*In]())
+	if err != nil {
+		panic(fmt.Sprintf("router: invalid input of route '%s:%s': %v", method, path, err))
+	}
+
+	jsonHandler := func(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx T) (events.APIGatewayV2HTTPResponse, error) {
+		input := new(In)
+		if err := decodeInput(request, input); err != nil {
+			return ErrorResponse(request, http.StatusBadRequest, err.Error()), nil
+		}
+		if err := inputValidator.validate(reflect.ValueOf(input), ""); err != nil {
+			return ErrorResponse(request, http.StatusBadRequest, err.Error()), nil
+		}
+
+		output, err := handler(input, request, transportCtx, routeCtx)
+		if err != nil {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) {
+				return ErrorResponse(request, httpErr.Code, httpErr.Message), nil
+			}
+			logger.Printf("unhandled error on route '%s:%s': %v\n", method, path, err)
+			return ErrorResponse(request, http.StatusInternalServerError, "internal server error"), nil
+		}
+
+		rawOutput, err := json.Marshal(output)
+		if err != nil {
+			return ErrorResponse(request, http.StatusInternalServerError, "failed to serialize response"), nil
+		}
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: http.StatusOK,
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Body: string(rawOutput),
+		}, nil
+	}
+
+	r.addRoute(route[T]{
+		method:      method,
+		path:        path,
+		handler:     jsonHandler,
+		middlewares: middlewares,
+		input:       reflect.TypeFor[In](),
+		output:      reflect.TypeFor[Out](),
+	})
+}
+
+// ErrorResponse creates a json response with an ErrorBody.
+func ErrorResponse(request events.APIGatewayV2HTTPRequest, code int, message string) events.APIGatewayV2HTTPResponse {
+	rawBody, err := json.Marshal(&ErrorBody{
+		Code:      code,
+		Message:   message,
+		RequestId: request.RequestContext.RequestID,
+	})
+	if err != nil {
+		rawBody = []byte(`{"code":500,"message":"failed to serialize error"}`)
+	}
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: code,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: string(rawBody),
+	}
+}
+
+// decodeInput decodes the request body and query string into the input struct.
+func decodeInput(request events.APIGatewayV2HTTPRequest, input any) error {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decodedBody, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize request: invalid body encoding")
+		}
+		body = decodedBody
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, input); err != nil {
+			return fmt.Errorf("failed to deserialize request: invalid body")
+		}
+	}
+
+	inputValue := reflect.ValueOf(input).Elem()
+	if inputValue.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < inputValue.NumField(); i++ {
+		field := inputValue.Type().Field(i)
+		name, ok := field.Tag.Lookup("query")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		rawValue, ok := request.QueryStringParameters[name]
+		if !ok {
+			continue
+		}
+		if err := setQueryValue(inputValue.Field(i), rawValue); err != nil {
+			return fmt.Errorf("failed to deserialize request: invalid query parameter '%s'", name)
+		}
+	}
+	return nil
+}
+
+// setQueryValue parses the raw query value into the field.
+func setQueryValue(field reflect.Value, rawValue string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(rawValue)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(rawValue)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(rawValue, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(rawValue, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(value)
+	default:
+		return fmt.Errorf("unsupported query field type '%s'", field.Type())
+	}
+	return nil
+}
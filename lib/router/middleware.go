@@ -0,0 +1,27 @@
+package router
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Handler is the route handler as seen by a middleware.
+// The route context is already bound to the handler, middlewares can pass data to the handler by
+// attaching it to the transport context.
+type Handler func(events.APIGatewayV2HTTPRequest, context.Context) (events.APIGatewayV2HTTPResponse, error)
+
+// Middleware wraps a handler with additional logic.
+// The middleware decides whether the next handler is called or the request is rejected.
+type Middleware func(next Handler) Handler
+
+// chain wraps the handler with the provided middleware sets.
+// The first middleware of the first set becomes the outermost handler.
+func chain(handler Handler, middlewareSets ...[]Middleware) Handler {
+	for i := len(middlewareSets) - 1; i >= 0; i-- {
+		for j := len(middlewareSets[i]) - 1; j >= 0; j-- {
+			handler = middlewareSets[i][j](handler)
+		}
+	}
+	return handler
+}
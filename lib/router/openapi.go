@@ -0,0 +1,307 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const OPENAPI_VERSION = "3.1.0"
+
+// OpenAPIInfo holds the metadata of a generated OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string
+	Description string
+	Version     string
+}
+
+// AddOpenAPIRoute adds a GET route that serves the OpenAPI document of all routes registered on the router.
+// The route is public, router middlewares are not applied to it.
+func (r *Router[T]) AddOpenAPIRoute(path string, info OpenAPIInfo) {
+	r.addRoute(route[T]{
+		method: http.MethodGet,
+		path:   path,
+		public: true,
+		handler: func(request events.APIGatewayV2HTTPRequest, _ context.Context, _ T) (events.APIGatewayV2HTTPResponse, error) {
+			rawDocument, err := json.Marshal(GenerateOpenAPI(info, r.Routes()))
+			if err != nil {
+				return ErrorResponse(request, http.StatusInternalServerError, "failed to serialize openapi document"), nil
+			}
+			return events.APIGatewayV2HTTPResponse{
+				StatusCode: http.StatusOK,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+				},
+				Body: string(rawDocument),
+			}, nil
+		},
+	})
+}
+
+// GenerateOpenAPI generates an OpenAPI document from the provided routes.
+// Request and response schemas are derived from the input and output types of typed routes,
+// the json, query and validate struct tags are reflected in the schemas.
+// The document is returned as generic map so that it can be serialized to json or yaml.
+func GenerateOpenAPI(info OpenAPIInfo, routes []RouteInfo) map[string]any {
+	paths := map[string]any{}
+	for _, route := range routes {
+		openapiPath, pathParams := openapiPath(route.Path)
+		pathItem, ok := paths[openapiPath].(map[string]any)
+		if !ok {
+			pathItem = map[string]any{}
+			paths[openapiPath] = pathItem
+		}
+		pathItem[strings.ToLower(route.Method)] = openapiOperation(route, pathParams)
+	}
+
+	infoObject := map[string]any{
+		"title":   info.Title,
+		"version": info.Version,
+	}
+	if info.Description != "" {
+		infoObject["description"] = info.Description
+	}
+
+	return map[string]any{
+		"openapi": OPENAPI_VERSION,
+		"info":    infoObject,
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"ErrorBody": typeSchema(reflect.TypeFor[ErrorBody](), map[reflect.Type]bool{}),
+			},
+		},
+	}
+}
+
+// openapiPath converts the route path to an OpenAPI path and returns the names of the path parameters.
+func openapiPath(path string) (string, []string) {
+	params := []string{}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"), "...")
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// openapiOperation creates the OpenAPI operation object of the route.
+func openapiOperation(route RouteInfo, pathParams []string) map[string]any {
+	parameters := []any{}
+	for _, name := range pathParams {
+		parameters = append(parameters, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+
+	operation := map[string]any{}
+	responses := map[string]any{
+		"default": map[string]any{
+			"description": "error",
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": map[string]any{"$ref": "#/components/schemas/ErrorBody"},
+				},
+			},
+		},
+	}
+
+	if route.Input != nil && route.Input.Kind() == reflect.Struct {
+		bodyFields := 0
+		for i := 0; i < route.Input.NumField(); i++ {
+			field := route.Input.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, ok := field.Tag.Lookup("query")
+			if !ok {
+				bodyFields++
+				continue
+			}
+			schema := typeSchema(field.Type, map[reflect.Type]bool{})
+			applyRules(schema, field.Type, field.Tag.Get("validate"))
+			parameters = append(parameters, map[string]any{
+				"name":     name,
+				"in":       "query",
+				"required": hasRule(field.Tag.Get("validate"), "required"),
+				"schema":   schema,
+			})
+		}
+		if bodyFields > 0 {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": typeSchema(route.Input, map[reflect.Type]bool{}),
+					},
+				},
+			}
+		}
+	}
+
+	if route.Output != nil {
+		responses["200"] = map[string]any{
+			"description": "success",
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": typeSchema(route.Output, map[reflect.Type]bool{}),
+				},
+			},
+		}
+	} else {
+		responses["200"] = map[string]any{
+			"description": "success",
+		}
+	}
+
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+// typeSchema creates the json schema of the type.
+// Struct fields tagged with `query` are omitted, as they are not part of the json body.
+func typeSchema(t reflect.Type, visited map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), visited)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), visited)}
+	case reflect.Struct:
+		if visited[t] {
+			// recursive types are not expanded again.
+			return map[string]any{"type": "object"}
+		}
+		visited[t] = true
+		defer delete(visited, t)
+
+		properties := map[string]any{}
+		required := []any{}
+		collectProperties(t, visited, properties, &required)
+		schema := map[string]any{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// collectProperties adds the json properties of the struct fields to the properties map.
+// Embedded structs without json name are flattened into the parent.
+func collectProperties(t reflect.Type, visited map[reflect.Type]bool, properties map[string]any, required *[]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("query"); ok {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if field.Anonymous && jsonName == "" && field.Type.Kind() == reflect.Struct {
+			collectProperties(field.Type, visited, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		schema := typeSchema(field.Type, visited)
+		applyRules(schema, field.Type, field.Tag.Get("validate"))
+		properties[name] = schema
+		if hasRule(field.Tag.Get("validate"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// applyRules adds the constraints of the validate tag to the schema.
+func applyRules(schema map[string]any, t reflect.Type, tag string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for _, rule := range parseRules(tag) {
+		switch rule.name {
+		case "min", "max":
+			limit := json.Number(rule.arg)
+			schema[limitKeyword(t.Kind(), rule.name)] = limit
+		case "pattern":
+			schema["pattern"] = rule.arg
+		case "keymin", "keymax", "keypattern":
+			propertyNames, ok := schema["propertyNames"].(map[string]any)
+			if !ok {
+				propertyNames = map[string]any{"type": "string"}
+				schema["propertyNames"] = propertyNames
+			}
+			if rule.name == "keypattern" {
+				propertyNames["pattern"] = rule.arg
+			} else {
+				propertyNames[limitKeyword(reflect.String, strings.TrimPrefix(rule.name, "key"))] = json.Number(rule.arg)
+			}
+		}
+	}
+}
+
+// limitKeyword returns the json schema keyword for a min or max rule on the specified kind.
+func limitKeyword(kind reflect.Kind, name string) string {
+	var suffix string
+	switch kind {
+	case reflect.String:
+		suffix = "Length"
+	case reflect.Map:
+		suffix = "Properties"
+	case reflect.Slice, reflect.Array:
+		suffix = "Items"
+	default:
+		if name == "min" {
+			return "minimum"
+		}
+		return "maximum"
+	}
+	return name + suffix
+}
+
+// hasRule checks if the validate tag contains the rule.
+func hasRule(tag, name string) bool {
+	for _, rule := range parseRules(tag) {
+		if rule.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	// staticSegment matches the exact segment.
+	staticSegment segmentKind = iota
+	// paramSegment matches one arbitrary segment, written as "{name}".
+	paramSegment
+	// wildcardSegment matches all remaining segments, written as "{name...}".
+	wildcardSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+// pattern is a parsed route path.
+type pattern struct {
+	segments []segment
+}
+
+// parsePattern parses a route path into its segments.
+func parsePattern(path string) (*pattern, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must start with '/'")
+	}
+
+	rawSegments := strings.Split(path, "/")
+	params := map[string]struct{}{}
+	pattern := &pattern{segments: []segment{}}
+	for i, rawSegment := range rawSegments {
+		if !strings.HasPrefix(rawSegment, "{") || !strings.HasSuffix(rawSegment, "}") {
+			if strings.ContainsAny(rawSegment, "{}") {
+				return nil, fmt.Errorf("segment '%s' must be a parameter or must not contain braces", rawSegment)
+			}
+			pattern.segments = append(pattern.segments, segment{kind: staticSegment, value: rawSegment})
+			continue
+		}
+
+		kind := paramSegment
+		name := strings.TrimSuffix(strings.TrimPrefix(rawSegment, "{"), "}")
+		if strings.HasSuffix(name, "...") {
+			if i != len(rawSegments)-1 {
+				return nil, fmt.Errorf("wildcard segment '%s' must be the last segment", rawSegment)
+			}
+			kind = wildcardSegment
+			name = strings.TrimSuffix(name, "...")
+		}
+		if name == "" || strings.ContainsAny(name, "{}.") {
+			return nil, fmt.Errorf("invalid parameter name in segment '%s'", rawSegment)
+		}
+		if _, ok := params[name]; ok {
+			return nil, fmt.Errorf("duplicate parameter name '%s'", name)
+		}
+		params[name] = struct{}{}
+		pattern.segments = append(pattern.segments, segment{kind: kind, value: name})
+	}
+	return pattern, nil
+}
+
+// match checks if the path segments match the pattern and returns the extracted parameters.
+func (p *pattern) match(pathSegments []string) (map[string]string, bool) {
+	params := map[string]string{}
+	for i, segment := range p.segments {
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		switch segment.kind {
+		case staticSegment:
+			if pathSegments[i] != segment.value {
+				return nil, false
+			}
+		case paramSegment:
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[segment.value] = pathSegments[i]
+		case wildcardSegment:
+			params[segment.value] = strings.Join(pathSegments[i:], "/")
+			return params, true
+		}
+	}
+	if len(pathSegments) != len(p.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// moreSpecific reports whether the pattern is more specific than the other pattern.
+// Segments are compared from left to right, static segments are more specific than parameters
+// and parameters are more specific than wildcards.
+func (p *pattern) moreSpecific(other *pattern) bool {
+	for i := 0; i < len(p.segments) && i < len(other.segments); i++ {
+		if p.segments[i].kind != other.segments[i].kind {
+			return p.segments[i].kind < other.segments[i].kind
+		}
+	}
+	return len(p.segments) > len(other.segments)
+}
+
+// equivalent reports whether both patterns match exactly the same paths.
+// Parameter names are irrelevant, "/{id}" and "/{name}" are equivalent.
+func (p *pattern) equivalent(other *pattern) bool {
+	if len(p.segments) != len(other.segments) {
+		return false
+	}
+	for i := range p.segments {
+		if p.segments[i].kind != other.segments[i].kind {
+			return false
+		}
+		if p.segments[i].kind == staticSegment && p.segments[i].value != other.segments[i].value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package router
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{path: "/api/project", valid: true},
+		{path: "/api/project/{id}", valid: true},
+		{path: "/static/{path...}", valid: true},
+		{path: "/", valid: true},
+		{path: "api/project", valid: false},
+		{path: "/api/{id}/{id}", valid: false},
+		{path: "/api/{}", valid: false},
+		{path: "/api/{...}", valid: false},
+		{path: "/api/{path...}/tail", valid: false},
+		{path: "/api/pre{id}", valid: false},
+		{path: "/api/{a.b}", valid: false},
+	}
+
+	for _, test := range tests {
+		_, err := parsePattern(test.path)
+		if test.valid && err != nil {
+			t.Errorf("parsePattern(%q) returned unexpected error: %v", test.path, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("parsePattern(%q) did not return an error", test.path)
+		}
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+		params  map[string]string
+	}{
+		{pattern: "/api/project", path: "/api/project", match: true, params: map[string]string{}},
+		{pattern: "/api/project", path: "/api/project/", match: false},
+		{pattern: "/api/project", path: "/api/projects", match: false},
+		{pattern: "/api/project", path: "/api", match: false},
+		{pattern: "/api/{id}", path: "/api/abc", match: true, params: map[string]string{"id": "abc"}},
+		{pattern: "/api/{id}", path: "/api/", match: false},
+		{pattern: "/api/{id}", path: "/api/abc/def", match: false},
+		{pattern: "/api/{id}/log", path: "/api/abc/log", match: true, params: map[string]string{"id": "abc"}},
+		{pattern: "/static/{path...}", path: "/static/a/b/c.js", match: true, params: map[string]string{"path": "a/b/c.js"}},
+		{pattern: "/static/{path...}", path: "/static/", match: true, params: map[string]string{"path": ""}},
+		{pattern: "/static/{path...}", path: "/static", match: false},
+	}
+
+	for _, test := range tests {
+		pattern, err := parsePattern(test.pattern)
+		if err != nil {
+			t.Fatalf("parsePattern(%q) returned unexpected error: %v", test.pattern, err)
+		}
+		params, ok := pattern.match(strings.Split(test.path, "/"))
+		if ok != test.match {
+			t.Errorf("pattern %q match %q = %v, expected %v", test.pattern, test.path, ok, test.match)
+			continue
+		}
+		if ok && !reflect.DeepEqual(params, test.params) {
+			t.Errorf("pattern %q match %q params = %v, expected %v", test.pattern, test.path, params, test.params)
+		}
+	}
+}
+
+func TestPatternMoreSpecific(t *testing.T) {
+	tests := []struct {
+		pattern string
+		other   string
+		more    bool
+	}{
+		{pattern: "/api/project", other: "/api/{id}", more: true},
+		{pattern: "/api/{id}", other: "/api/project", more: false},
+		{pattern: "/api/{id}", other: "/api/{path...}", more: true},
+		{pattern: "/api/{path...}", other: "/api/{id}", more: false},
+		{pattern: "/api/project/{id}", other: "/api/{name}/log", more: true},
+		{pattern: "/api/{id}/log", other: "/api/{id}", more: true},
+		{pattern: "/api/{id}", other: "/api/{name}", more: false},
+	}
+
+	for _, test := range tests {
+		pattern, _ := parsePattern(test.pattern)
+		other, _ := parsePattern(test.other)
+		if more := pattern.moreSpecific(other); more != test.more {
+			t.Errorf("%q moreSpecific %q = %v, expected %v", test.pattern, test.other, more, test.more)
+		}
+	}
+}
+
+func TestPatternEquivalent(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		other      string
+		equivalent bool
+	}{
+		{pattern: "/api/project", other: "/api/project", equivalent: true},
+		{pattern: "/api/{id}", other: "/api/{name}", equivalent: true},
+		{pattern: "/api/{path...}", other: "/api/{rest...}", equivalent: true},
+		{pattern: "/api/{id}", other: "/api/{path...}", equivalent: false},
+		{pattern: "/api/project", other: "/api/{id}", equivalent: false},
+		{pattern: "/api/project", other: "/api/project/{id}", equivalent: false},
+	}
+
+	for _, test := range tests {
+		pattern, _ := parsePattern(test.pattern)
+		other, _ := parsePattern(test.other)
+		if equivalent := pattern.equivalent(other); equivalent != test.equivalent {
+			t.Errorf("%q equivalent %q = %v, expected %v", test.pattern, test.other, equivalent, test.equivalent)
+		}
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -13,7 +16,30 @@ import (
 // that is then routed to the corresponding route based on a method + path.
 type Router[T any] struct {
 	defaultContext T
-	routes         map[string]func(events.APIGatewayV2HTTPRequest, context.Context, T) (events.APIGatewayV2HTTPResponse, error)
+	middlewares    []Middleware
+	routes         []route[T]
+}
+
+// route holds the handler of a route together with the middlewares that are specific to this route.
+type route[T any] struct {
+	method      string
+	path        string
+	pattern     *pattern
+	handler     func(events.APIGatewayV2HTTPRequest, context.Context, T) (events.APIGatewayV2HTTPResponse, error)
+	middlewares []Middleware
+	// public routes are not wrapped with the router middlewares.
+	public bool
+	input  reflect.Type
+	output reflect.Type
+}
+
+// RouteInfo describes a registered route.
+// Input and Output are only set on routes with typed handlers (see AddJSONRoute).
+type RouteInfo struct {
+	Method string
+	Path   string
+	Input  reflect.Type
+	Output reflect.Type
 }
 
 // NewRouter creates a new router for this endpoint.
@@ -21,35 +47,165 @@ type Router[T any] struct {
 func NewRouter[T any](defaultContext T) *Router[T] {
 	return &Router[T]{
 		defaultContext: defaultContext,
-		routes:         map[string]func(events.APIGatewayV2HTTPRequest, context.Context, T) (events.APIGatewayV2HTTPResponse, error){},
+		middlewares:    []Middleware{},
+		routes:         []route[T]{},
 	}
 }
 
+// Use adds middlewares that are applied to every route of the router.
+// Middlewares are executed in the order they are added, router middlewares always run before route middlewares.
+func (r *Router[T]) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
 // AddRoute adds a new handle to the router which is invoked when the method + path matches.
-func (r *Router[T]) AddRoute(method string, path string, handler func(events.APIGatewayV2HTTPRequest, context.Context, T) (events.APIGatewayV2HTTPResponse, error)) {
-	routeKey := fmt.Sprintf(
-		"%s:%s", method, path,
-	)
-	r.routes[routeKey] = handler
+// The path can contain parameter segments like "{name}" and a trailing wildcard segment like "{path...}",
+// matched values are provided to the handler in request.PathParameters.
+// Optionally route specific middlewares can be provided, they are executed after the router middlewares.
+// AddRoute panics if the path is not a valid pattern or if the method + pattern is already registered.
+func (r *Router[T]) AddRoute(method string, path string, handler func(events.APIGatewayV2HTTPRequest, context.Context, T) (events.APIGatewayV2HTTPResponse, error), middlewares ...Middleware) {
+	r.addRoute(route[T]{
+		method:      method,
+		path:        path,
+		handler:     handler,
+		middlewares: middlewares,
+	})
+}
+
+// addRoute parses the route path and registers the route.
+// It panics if the path is invalid or if an equivalent pattern is already registered for the method.
+func (r *Router[T]) addRoute(route route[T]) {
+	pattern, err := parsePattern(route.path)
+	if err != nil {
+		panic(fmt.Sprintf("router: invalid route '%s:%s': %v", route.method, route.path, err))
+	}
+	for _, existing := range r.routes {
+		if existing.method == route.method && existing.pattern.equivalent(pattern) {
+			panic(fmt.Sprintf("router: route '%s:%s' conflicts with registered route '%s:%s'",
+				route.method, route.path, existing.method, existing.path))
+		}
+	}
+	route.pattern = pattern
+	r.routes = append(r.routes, route)
+}
+
+// Routes returns a description of all registered routes.
+func (r *Router[T]) Routes() []RouteInfo {
+	routes := []RouteInfo{}
+	for _, route := range r.routes {
+		routes = append(routes, RouteInfo{
+			Method: route.method,
+			Path:   route.path,
+			Input:  route.input,
+			Output: route.output,
+		})
+	}
+	return routes
 }
 
 // Route routes a request to the corresponding route and calls its route handler.
-// If no route with matching method + path is found, a 404 message is returned.
+// If no route with matching path is found, a 404 message is returned.
+// If the path matches but no route is registered for the method, a 405 message with an Allow header is returned.
+// HEAD requests fall back to the GET route and OPTIONS requests are answered with the allowed methods,
+// unless a dedicated route is registered for them.
 func (r *Router[T]) Route(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	routeKey := fmt.Sprintf(
-		"%s:%s", request.RequestContext.HTTP.Method, request.RequestContext.HTTP.Path,
-	)
-
-	handler, ok := r.routes[routeKey]
-	if ok {
-		return handler(request, ctx, r.defaultContext)
-	} else {
+	method := request.RequestContext.HTTP.Method
+	path := request.RequestContext.HTTP.Path
+
+	matches := r.match(path)
+	if len(matches) < 1 {
+		return ErrorResponse(request, http.StatusNotFound, fmt.Sprintf("No valid handler found for pattern: '%s:%s'", method, path)), nil
+	}
+
+	if match, ok := matches[method]; ok {
+		return r.call(ctx, request, match)
+	}
+
+	switch method {
+	case http.MethodHead:
+		if match, ok := matches[http.MethodGet]; ok {
+			response, err := r.call(ctx, request, match)
+			response.Body = ""
+			response.IsBase64Encoded = false
+			return response, err
+		}
+	case http.MethodOptions:
 		return events.APIGatewayV2HTTPResponse{
-			StatusCode: http.StatusNotFound,
+			StatusCode: http.StatusNoContent,
 			Headers: map[string]string{
-				"Content-Type": "text/plain",
+				"Allow": allowHeader(matches),
 			},
-			Body: fmt.Sprintf("No valid handler found for pattern: '%s'", routeKey),
 		}, nil
 	}
+
+	response := ErrorResponse(request, http.StatusMethodNotAllowed, fmt.Sprintf("Method '%s' is not allowed for path: '%s'", method, path))
+	response.Headers["Allow"] = allowHeader(matches)
+	return response, nil
+}
+
+// routeMatch describes a route that matched the request path.
+type routeMatch[T any] struct {
+	route  *route[T]
+	params map[string]string
+}
+
+// match returns the most specific route matching the path for every registered method.
+func (r *Router[T]) match(path string) map[string]*routeMatch[T] {
+	segments := strings.Split(path, "/")
+
+	matches := map[string]*routeMatch[T]{}
+	for i := range r.routes {
+		candidate := &r.routes[i]
+		params, ok := candidate.pattern.match(segments)
+		if !ok {
+			continue
+		}
+		if current, ok := matches[candidate.method]; ok && !candidate.pattern.moreSpecific(current.route.pattern) {
+			continue
+		}
+		matches[candidate.method] = &routeMatch[T]{route: candidate, params: params}
+	}
+	return matches
+}
+
+// call executes the matched route handler wrapped with the router and route middlewares.
+func (r *Router[T]) call(ctx context.Context, request events.APIGatewayV2HTTPRequest, match *routeMatch[T]) (events.APIGatewayV2HTTPResponse, error) {
+	if len(match.params) > 0 {
+		pathParameters := map[string]string{}
+		for key, value := range request.PathParameters {
+			pathParameters[key] = value
+		}
+		for key, value := range match.params {
+			pathParameters[key] = value
+		}
+		request.PathParameters = pathParameters
+	}
+
+	next := func(request events.APIGatewayV2HTTPRequest, ctx context.Context) (events.APIGatewayV2HTTPResponse, error) {
+		return match.route.handler(request, ctx, r.defaultContext)
+	}
+	if match.route.public {
+		return chain(next, match.route.middlewares)(request, ctx)
+	}
+	return chain(next, r.middlewares, match.route.middlewares)(request, ctx)
+}
+
+// allowHeader constructs the Allow header value from the methods of the matched routes.
+func allowHeader[T any](matches map[string]*routeMatch[T]) string {
+	methodSet := map[string]struct{}{
+		http.MethodOptions: {},
+	}
+	for method := range matches {
+		methodSet[method] = struct{}{}
+		if method == http.MethodGet {
+			methodSet[http.MethodHead] = struct{}{}
+		}
+	}
+
+	methods := []string{}
+	for method := range methodSet {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
 }
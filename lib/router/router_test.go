@@ -0,0 +1,130 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// testHandler responds with the handler name in the body and the path parameters in the headers.
+func testHandler(name string) func(events.APIGatewayV2HTTPRequest, context.Context, struct{}) (events.APIGatewayV2HTTPResponse, error) {
+	return func(request events.APIGatewayV2HTTPRequest, ctx context.Context, _ struct{}) (events.APIGatewayV2HTTPResponse, error) {
+		headers := map[string]string{}
+		for key, value := range request.PathParameters {
+			headers["X-Param-"+key] = value
+		}
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: http.StatusOK,
+			Headers:    headers,
+			Body:       name,
+		}, nil
+	}
+}
+
+func testRequest(method, path string) events.APIGatewayV2HTTPRequest {
+	request := events.APIGatewayV2HTTPRequest{}
+	request.RequestContext.HTTP.Method = method
+	request.RequestContext.HTTP.Path = path
+	return request
+}
+
+func newTestRouter() *Router[struct{}] {
+	router := NewRouter(struct{}{})
+	router.AddRoute("GET", "/api/project", testHandler("listproject"))
+	router.AddRoute("POST", "/api/project", testHandler("createproject"))
+	router.AddRoute("GET", "/api/project/{id}", testHandler("getproject"))
+	router.AddRoute("GET", "/api/project/new", testHandler("newproject"))
+	router.AddRoute("DELETE", "/api/project/{id}", testHandler("deleteproject"))
+	router.AddRoute("GET", "/api/static/{path...}", testHandler("static"))
+	router.AddRoute("GET", "/api/static/{dir}/index", testHandler("staticindex"))
+	router.AddRoute("OPTIONS", "/api/cors", testHandler("cors"))
+	return router
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+		params map[string]string
+		allow  string
+	}{
+		{name: "static route", method: "GET", path: "/api/project", status: http.StatusOK, body: "listproject"},
+		{name: "method selects route", method: "POST", path: "/api/project", status: http.StatusOK, body: "createproject"},
+		{name: "parameter route", method: "GET", path: "/api/project/abc", status: http.StatusOK, body: "getproject",
+			params: map[string]string{"id": "abc"}},
+		{name: "static before parameter", method: "GET", path: "/api/project/new", status: http.StatusOK, body: "newproject"},
+		{name: "parameter route of other method", method: "DELETE", path: "/api/project/new", status: http.StatusOK, body: "deleteproject",
+			params: map[string]string{"id": "new"}},
+		{name: "wildcard route", method: "GET", path: "/api/static/a/b.js", status: http.StatusOK, body: "static",
+			params: map[string]string{"path": "a/b.js"}},
+		{name: "parameter before wildcard", method: "GET", path: "/api/static/docs/index", status: http.StatusOK, body: "staticindex",
+			params: map[string]string{"dir": "docs"}},
+		{name: "unknown path", method: "GET", path: "/api/unknown", status: http.StatusNotFound},
+		{name: "method not allowed", method: "PUT", path: "/api/project", status: http.StatusMethodNotAllowed,
+			allow: "GET, HEAD, OPTIONS, POST"},
+		{name: "method not allowed without get", method: "PUT", path: "/api/cors", status: http.StatusMethodNotAllowed,
+			allow: "OPTIONS"},
+		{name: "head falls back to get", method: "HEAD", path: "/api/project/abc", status: http.StatusOK,
+			params: map[string]string{"id": "abc"}},
+		{name: "options lists methods", method: "OPTIONS", path: "/api/project/abc", status: http.StatusNoContent,
+			allow: "DELETE, GET, HEAD, OPTIONS"},
+		{name: "dedicated options route", method: "OPTIONS", path: "/api/cors", status: http.StatusOK, body: "cors"},
+	}
+
+	router := newTestRouter()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := router.Route(context.Background(), testRequest(test.method, test.path))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.StatusCode != test.status {
+				t.Fatalf("status = %d, expected %d", response.StatusCode, test.status)
+			}
+			if test.body != "" && response.Body != test.body {
+				t.Errorf("body = %q, expected %q", response.Body, test.body)
+			}
+			if test.method == http.MethodHead && response.Body != "" {
+				t.Errorf("head response contains body %q", response.Body)
+			}
+			for key, value := range test.params {
+				if response.Headers["X-Param-"+key] != value {
+					t.Errorf("path parameter %s = %q, expected %q", key, response.Headers["X-Param-"+key], value)
+				}
+			}
+			if test.allow != "" && response.Headers["Allow"] != test.allow {
+				t.Errorf("allow = %q, expected %q", response.Headers["Allow"], test.allow)
+			}
+		})
+	}
+}
+
+func TestAddRoutePanics(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "invalid pattern", method: "GET", path: "api/project"},
+		{name: "duplicate route", method: "GET", path: "/api/project"},
+		{name: "duplicate parameter route", method: "GET", path: "/api/project/{name}"},
+		{name: "duplicate wildcard route", method: "GET", path: "/api/static/{rest...}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newTestRouter()
+			defer func() {
+				if recover() == nil {
+					t.Errorf("AddRoute(%q, %q) did not panic", test.method, test.path)
+				}
+			}()
+			router.AddRoute(test.method, test.path, testHandler("duplicate"))
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// validatorCache holds the compiled validators of the validated types.
+var validatorCache = sync.Map{}
+
+// Validate validates the struct fields based on their `validate` tag.
+// Rules are separated by a comma, the following rules are supported:
+//
+//   - required: the field must not be the zero value.
+//   - min=<n>, max=<n>: length limits for strings, maps and slices; value limits for numbers.
+//   - keymin=<n>, keymax=<n>: length limits for the keys of a map.
+//   - pattern=<regex>: the string must match the regex.
+//   - keypattern=<regex>: the keys of a map must match the regex.
+//
+// Because a regex can contain commas, pattern and keypattern consume the rest of the tag and must be the last rule.
+// Nested structs (also behind pointers and slices) are validated recursively.
+// The tags of a type are compiled once, an error is also returned if a tag of the type is malformed.
+func Validate(input any) error {
+	value := reflect.ValueOf(input)
+	if !value.IsValid() {
+		return nil
+	}
+	validator, err := compileValidator(value.Type())
+	if err != nil {
+		return err
+	}
+	return validator.validate(value, "")
+}
+
+// validator validates values of a single type.
+type validator struct {
+	// elem validates the element of pointer, slice and array types.
+	elem *validator
+	// fields validate the exported fields of struct types.
+	fields []fieldValidator
+}
+
+// fieldValidator holds the compiled rules of a struct field.
+type fieldValidator struct {
+	index     int
+	name      string
+	rules     []rule
+	validator *validator
+}
+
+// rule is a single rule of a validate tag.
+// The limit and regex are only set on compiled rules (see compileRules).
+type rule struct {
+	name  string
+	arg   string
+	limit float64
+	regex *regexp.Regexp
+}
+
+// compileValidator returns the validator of the type from the cache or compiles it.
+// An error is returned if a validate tag of the type or its nested types is malformed.
+func compileValidator(t reflect.Type) (*validator, error) {
+	if cached, ok := validatorCache.Load(t); ok {
+		return cached.(*validator), nil
+	}
+	validator, err := buildValidator(t, map[reflect.Type]*validator{})
+	if err != nil {
+		return nil, err
+	}
+	validatorCache.Store(t, validator)
+	return validator, nil
+}
+
+// buildValidator compiles the validator of the type, visited holds the validators of recursive types.
+func buildValidator(t reflect.Type, visited map[reflect.Type]*validator) (*validator, error) {
+	if existing, ok := visited[t]; ok {
+		return existing, nil
+	}
+	result := &validator{}
+	visited[t] = result
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		elem, err := buildValidator(t.Elem(), visited)
+		if err != nil {
+			return nil, err
+		}
+		result.elem = elem
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			rules, err := compileRules(field.Tag.Get("validate"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid validate tag on field '%s.%s': %v", t.Name(), field.Name, err)
+			}
+			nested, err := buildValidator(field.Type, visited)
+			if err != nil {
+				return nil, err
+			}
+			result.fields = append(result.fields, fieldValidator{
+				index:     i,
+				name:      fieldName(field),
+				rules:     rules,
+				validator: nested,
+			})
+		}
+	}
+	return result, nil
+}
+
+// validate validates the value and all its nested values.
+func (v *validator) validate(value reflect.Value, path string) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+		return v.elem.validate(value.Elem(), path)
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		// the dynamic type is only known at runtime, its validator is compiled on first use.
+		validator, err := compileValidator(value.Elem().Type())
+		if err != nil {
+			return err
+		}
+		return validator.validate(value.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := v.elem.validate(value.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		for _, field := range v.fields {
+			fieldPath := field.name
+			if path != "" {
+				fieldPath = path + "." + fieldPath
+			}
+			if err := validateField(value.Field(field.index), fieldPath, field.rules); err != nil {
+				return err
+			}
+			if err := field.validator.validate(value.Field(field.index), fieldPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// parseRules splits the validate tag into its rules.
+func parseRules(tag string) []rule {
+	rules := []rule{}
+	for tag != "" {
+		rawRule, rest, _ := strings.Cut(tag, ",")
+		name, arg, _ := strings.Cut(rawRule, "=")
+		if name == "pattern" || name == "keypattern" {
+			_, arg, _ = strings.Cut(tag, "=")
+			rest = ""
+		}
+		tag = rest
+		rules = append(rules, rule{name: name, arg: arg})
+	}
+	return rules
+}
+
+// compileRules parses the validate tag and compiles the limits and regexes of its rules.
+func compileRules(tag string) ([]rule, error) {
+	rules := parseRules(tag)
+	for i := range rules {
+		var err error
+		switch rules[i].name {
+		case "required":
+		case "min", "max", "keymin", "keymax":
+			rules[i].limit, err = strconv.ParseFloat(rules[i].arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid limit '%s' for validation rule '%s'", rules[i].arg, rules[i].name)
+			}
+		case "pattern", "keypattern":
+			rules[i].regex, err = regexp.Compile(rules[i].arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex for validation rule '%s': %v", rules[i].name, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown validation rule '%s'", rules[i].name)
+		}
+	}
+	return rules, nil
+}
+
+// validateField applies the compiled rules to the field.
+func validateField(field reflect.Value, path string, rules []rule) error {
+	for _, rule := range rules {
+		var err error
+		switch rule.name {
+		case "required":
+			if field.IsZero() {
+				err = fmt.Errorf("is required")
+			}
+		case "min", "max":
+			err = validateLimit(field, rule.name, rule.arg, rule.limit)
+		case "keymin", "keymax":
+			err = validateKeys(field, func(key reflect.Value) error {
+				return validateLimit(key, strings.TrimPrefix(rule.name, "key"), rule.arg, rule.limit)
+			})
+		case "pattern":
+			err = validatePattern(field, rule.regex)
+		case "keypattern":
+			err = validateKeys(field, func(key reflect.Value) error {
+				return validatePattern(key, rule.regex)
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("invalid field '%s': %v", path, err)
+		}
+	}
+	return nil
+}
+
+// validateLimit checks if the field respects the min or max limit.
+func validateLimit(field reflect.Value, name, arg string, limit float64) error {
+	var (
+		value float64
+		unit  string
+	)
+	switch field.Kind() {
+	case reflect.String:
+		value, unit = float64(len(field.String())), " characters"
+	case reflect.Map, reflect.Slice, reflect.Array:
+		value, unit = float64(field.Len()), " elements"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = field.Float()
+	default:
+		return nil
+	}
+
+	if name == "min" && value < limit {
+		return fmt.Errorf("must be at least %s%s", arg, unit)
+	}
+	if name == "max" && value > limit {
+		return fmt.Errorf("must be at most %s%s", arg, unit)
+	}
+	return nil
+}
+
+// validatePattern checks if the string field matches the regex.
+func validatePattern(field reflect.Value, regex *regexp.Regexp) error {
+	if field.Kind() != reflect.String {
+		return nil
+	}
+	if !regex.MatchString(field.String()) {
+		return fmt.Errorf("'%s' does not match the required format", field.String())
+	}
+	return nil
+}
+
+// validateKeys runs the validation function on every key of a map field.
+func validateKeys(field reflect.Value, validate func(reflect.Value) error) error {
+	if field.Kind() != reflect.Map {
+		return nil
+	}
+	for _, key := range field.MapKeys() {
+		if err := validate(key); err != nil {
+			return fmt.Errorf("key %v", err)
+		}
+	}
+	return nil
+}
+
+// fieldName returns the json name of the struct field.
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	if name := field.Tag.Get("query"); name != "" {
+		return name
+	}
+	return field.Name
+}
@@ -0,0 +1,92 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type validateNested struct {
+	Name string `json:"name" validate:"required,pattern=^[a-z]+$"`
+}
+
+type validateInput struct {
+	Name     string            `json:"name" validate:"required,min=4,max=8"`
+	Count    int32             `json:"count" validate:"max=50"`
+	Labels   map[string]string `json:"labels" validate:"keymax=3,keypattern=^[a-z]+$"`
+	Nested   *validateNested   `json:"nested"`
+	Children []validateNested  `json:"children"`
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input validateInput
+		valid bool
+	}{
+		{name: "valid", input: validateInput{Name: "abcd"}, valid: true},
+		{name: "required", input: validateInput{}, valid: false},
+		{name: "min length", input: validateInput{Name: "abc"}, valid: false},
+		{name: "max length", input: validateInput{Name: "abcdefghi"}, valid: false},
+		{name: "max value", input: validateInput{Name: "abcd", Count: 51}, valid: false},
+		{name: "key length", input: validateInput{Name: "abcd", Labels: map[string]string{"abcd": ""}}, valid: false},
+		{name: "key pattern", input: validateInput{Name: "abcd", Labels: map[string]string{"AB": ""}}, valid: false},
+		{name: "valid keys", input: validateInput{Name: "abcd", Labels: map[string]string{"abc": ""}}, valid: true},
+		{name: "nested pointer", input: validateInput{Name: "abcd", Nested: &validateNested{Name: "A"}}, valid: false},
+		{name: "nested slice", input: validateInput{Name: "abcd", Children: []validateNested{{Name: "a"}, {}}}, valid: false},
+		{name: "valid nested", input: validateInput{Name: "abcd", Children: []validateNested{{Name: "a"}}}, valid: true},
+	}
+
+	for _, test := range tests {
+		err := Validate(&test.input)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestValidateMalformedTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		input any
+	}{
+		{name: "unknown rule", input: &struct {
+			Name string `validate:"unknown"`
+		}{}},
+		{name: "invalid limit", input: &struct {
+			Name string `validate:"min=abc"`
+		}{}},
+		{name: "invalid regex", input: &struct {
+			Name string `validate:"pattern=^[a-z"`
+		}{}},
+		{name: "nested invalid rule", input: &struct {
+			Nested []struct {
+				Name string `validate:"max=x"`
+			}
+		}{}},
+	}
+
+	for _, test := range tests {
+		if err := Validate(test.input); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestAddJSONRouteMalformedTag(t *testing.T) {
+	type malformedInput struct {
+		Name string `validate:"pattern=("`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("AddJSONRoute did not panic on a malformed validate tag")
+		}
+	}()
+	AddJSONRoute(NewRouter(struct{}{}), "POST", "/api/malformed", func(*malformedInput, events.APIGatewayV2HTTPRequest, context.Context, struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+}
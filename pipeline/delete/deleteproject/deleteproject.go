@@ -32,13 +32,13 @@ func HandleDeleteProject(eventCtx eventcontext.Context) func(context.Context, ev
 	}
 }
 
-func runHandleDeleteProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) error {
+func runHandleDeleteProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) (err error) {
 	deleteRequest := &event.DeleteRequest{}
 	if err := json.Unmarshal(request.Detail, &deleteRequest); err != nil {
 		return fmt.Errorf("failed to deserialize deploy request")
 	}
 
-	deleteClaims, err := pipeline.ParseTicket(eventCtx.TicketOptions, deleteRequest.DeleteTicket)
+	deleteClaims, err := pipeline.ParseTicket(transportCtx, eventCtx.TicketOptions, deleteRequest.DeleteTicket)
 	if err != nil {
 		return fmt.Errorf("failed to parse ticket: %v", err)
 	}
@@ -47,6 +47,20 @@ func runHandleDeleteProject(request events.CloudWatchEvent, transportCtx context
 		return fmt.Errorf("action mismatch: provided ticket was not issued for the specified action")
 	}
 
+	// tickets are single-use, replayed events are rejected before they touch any resources.
+	// A failed execution releases the ticket, so that retries of the event are accepted.
+	if err := pipeline.ClaimTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, deleteClaims); err != nil {
+		if errors.Is(err, pipeline.ErrTicketConsumed) {
+			return fmt.Errorf("ticket replay rejected: ticket of execution '%s' was already consumed", deleteClaims.ID)
+		}
+		return err
+	}
+	defer func() {
+		if fErr := pipeline.FinishTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, deleteClaims, err); fErr != nil {
+			logger.Printf("%v\n", fErr)
+		}
+	}()
+
 	projectDoc, err := database.GetSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
 		Table: aws.String(eventCtx.ProjectTable),
 		AttributeValues: map[string]dynamodbtypes.AttributeValue{
@@ -70,18 +84,20 @@ func runHandleDeleteProject(request events.CloudWatchEvent, transportCtx context
 	}
 
 	if err := deleteProject(transportCtx, eventCtx, projectDoc); err != nil {
+		updateExpression, bErr := database.NewUpdate[project.Project]().
+			Set("Status", fmt.Sprintf("DELETION FAILED: %v", err)).
+			Build()
+		if bErr != nil {
+			return fmt.Errorf("failed to build update expression: %v", bErr)
+		}
 		if _, err := database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
 			Table: aws.String(eventCtx.ProjectTable),
 			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 			},
-			AttributeNames: map[string]string{
-				"#status": "status",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":status": &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("DELETION FAILED: %v", err)},
-			},
-			UpdateExpr: aws.String("SET #status = :status"),
+			AttributeNames:  updateExpression.AttributeNames,
+			AttributeValues: updateExpression.AttributeValues,
+			UpdateExpr:      updateExpression.UpdateExpr,
 		}); err != nil {
 			return fmt.Errorf("failed to update project: %v", err)
 		}
@@ -108,10 +124,24 @@ func deleteProject(transportCtx context.Context, eventCtx eventcontext.Context,
 		return err
 	}
 
+	if err := deleteStaticVersion(transportCtx, eventCtx, projectDoc.ProjectName); err != nil {
+		return err
+	}
+
 	if err := deleteStack(transportCtx, eventCtx, projectDoc); err != nil {
 		return err
 	}
 
+	// environment secrets are removed after the stack, the build job and server function reference them.
+	if err := deleteEnvironment(transportCtx, eventCtx, projectDoc.ProjectName); err != nil {
+		return err
+	}
+
+	// deployments are removed after the stack, the server function may still reference a retained artifact.
+	if err := deleteDeployments(transportCtx, eventCtx, projectDoc); err != nil {
+		return err
+	}
+
 	if err := database.DeleteSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.DeleteSingleInput{
 		Table: aws.String(eventCtx.ProjectTable),
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
@@ -2,14 +2,17 @@ package deleteproject
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	cloudfrontkeyvaluetypes "github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/deployment"
 	"github.com/megakuul/battleshiper/lib/model/project"
 	"github.com/megakuul/battleshiper/pipeline/delete/eventcontext"
 )
@@ -29,41 +32,10 @@ func deleteStaticAssets(transportCtx context.Context, eventCtx eventcontext.Cont
 	}
 	bucketPrefix := fmt.Sprintf("%s/", bucketPathSegments[1])
 
-	paginator := s3.NewListObjectsV2Paginator(eventCtx.S3Client, &s3.ListObjectsV2Input{
-		Bucket:  aws.String(bucketName),
-		Prefix:  aws.String(bucketPrefix),
-		MaxKeys: aws.Int32(1000), // DeleteObjects call deletes max 1000 objects, one page should only use one DeleteObjects call.
-	})
-
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(transportCtx)
-		if err != nil {
-			return fmt.Errorf("failed to list objects: %v", err)
-		}
-
-		if len(page.Contents) < 1 {
-			continue
-		}
-
-		deleteObjects := []s3types.ObjectIdentifier{}
-		for _, object := range page.Contents {
-			deleteObjects = append(deleteObjects, s3types.ObjectIdentifier{
-				Key: object.Key,
-			})
-		}
-
-		_, err = eventCtx.S3Client.DeleteObjects(transportCtx, &s3.DeleteObjectsInput{
-			Bucket: aws.String(bucketName),
-			Delete: &s3types.Delete{
-				Objects: deleteObjects,
-				Quiet:   aws.Bool(true),
-			},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to delete objects in static bucket: %v", err)
-		}
+	err := pipeline.DeleteObjects(transportCtx, eventCtx.S3Client, eventCtx.TransferOptions, nil, bucketName, bucketPrefix, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete objects in static bucket: %v", err)
 	}
-
 	return nil
 }
 
@@ -124,3 +96,93 @@ func deleteStaticPageKeys(transportCtx context.Context, eventCtx eventcontext.Co
 
 	return nil
 }
+
+// deleteStaticVersion removes the key pointing to the live static asset version of the project.
+func deleteStaticVersion(transportCtx context.Context, eventCtx eventcontext.Context, projectName string) error {
+	storeMetadata, err := eventCtx.CloudfrontCacheClient.DescribeKeyValueStore(transportCtx, &cloudfrontkeyvaluestore.DescribeKeyValueStoreInput{
+		KvsARN: aws.String(eventCtx.CloudfrontConfiguration.CacheArn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe cdn store: %v", err)
+	}
+	_, err = eventCtx.CloudfrontCacheClient.DeleteKey(transportCtx, &cloudfrontkeyvaluestore.DeleteKeyInput{
+		KvsARN:  aws.String(eventCtx.CloudfrontConfiguration.CacheArn),
+		Key:     aws.String(fmt.Sprintf("%s%s", project.STATIC_VERSION_KEY_PREFIX, projectName)),
+		IfMatch: storeMetadata.ETag,
+	})
+	if err != nil {
+		// projects that were never released (or released before static versioning) have no version key.
+		var rnfe *cloudfrontkeyvaluetypes.ResourceNotFoundException
+		if ok := errors.As(err, &rnfe); ok {
+			return nil
+		}
+		return fmt.Errorf("failed to delete static version from cdn store: %v", err)
+	}
+
+	return nil
+}
+
+// deleteEnvironment removes the build and runtime environment secrets of the project.
+func deleteEnvironment(transportCtx context.Context, eventCtx eventcontext.Context, projectName string) error {
+	err := pipeline.DeleteEnvironmentSecret(transportCtx, eventCtx.SecretClient, pipeline.BuildEnvironmentSecretName(projectName))
+	if err != nil {
+		return fmt.Errorf("failed to delete build environment: %v", err)
+	}
+	err = pipeline.DeleteEnvironmentSecret(transportCtx, eventCtx.SecretClient, pipeline.RuntimeEnvironmentSecretName(projectName))
+	if err != nil {
+		return fmt.Errorf("failed to delete runtime environment: %v", err)
+	}
+
+	return nil
+}
+
+// deleteDeployments removes the deployment history of the project including the retained artifacts.
+func deleteDeployments(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) error {
+	deploymentDocs, err := database.GetMany[deployment.Deployment](transportCtx, eventCtx.DynamoClient, &database.GetManyInput{
+		Table: aws.String(eventCtx.DeploymentTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load deployment history: %v", err)
+	}
+
+	for _, deploymentDoc := range deploymentDocs {
+		if err := deleteArtifacts(transportCtx, eventCtx, deploymentDoc.ArtifactPath); err != nil {
+			return err
+		}
+		err := database.DeleteSingle[deployment.Deployment](transportCtx, eventCtx.DynamoClient, &database.DeleteSingleInput{
+			Table: aws.String(eventCtx.DeploymentTable),
+			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+				"project_name":         &dynamodbtypes.AttributeValueMemberS{Value: deploymentDoc.ProjectName},
+				"execution_identifier": &dynamodbtypes.AttributeValueMemberS{Value: deploymentDoc.ExecutionIdentifier},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete deployment record: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func deleteArtifacts(transportCtx context.Context, eventCtx eventcontext.Context, artifactPath string) error {
+	bucketPathSegments := strings.SplitN(artifactPath, "/", 2)
+	if len(bucketPathSegments) != 2 {
+		return fmt.Errorf("failed to decode artifact path")
+	}
+	bucketName := bucketPathSegments[0]
+	// Check ensuring that, for whatever reason, bucketPrefix is NEVER "", which could lead to dangerous behavior.
+	if bucketPathSegments[1] == "" {
+		return fmt.Errorf("malformed artifact prefix detected")
+	}
+	bucketPrefix := fmt.Sprintf("%s/", bucketPathSegments[1])
+
+	err := pipeline.DeleteObjects(transportCtx, eventCtx.S3Client, eventCtx.TransferOptions, nil, bucketName, bucketPrefix, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete objects in artifact bucket: %v", err)
+	}
+	return nil
+}
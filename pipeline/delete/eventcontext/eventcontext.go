@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 )
 
@@ -26,9 +27,13 @@ type CloudfrontConfiguration struct {
 type Context struct {
 	DynamoClient            *dynamodb.Client
 	ProjectTable            string
+	TicketTable             string
+	DeploymentTable         string
 	TicketOptions           *pipeline.TicketOptions
 	S3Client                *s3.Client
+	TransferOptions         *pipeline.TransferOptions
 	CloudformationClient    *cloudformation.Client
+	SecretClient            *secretsmanager.Client
 	CloudfrontCacheClient   *cloudfrontkeyvaluestore.Client
 	DeletionConfiguration   *DeletionConfiguration
 	BucketConfiguration     *BucketConfiguration
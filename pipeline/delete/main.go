@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
@@ -13,6 +14,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 	"github.com/megakuul/battleshiper/pipeline/delete/deleteproject"
 	"github.com/megakuul/battleshiper/pipeline/delete/eventcontext"
@@ -22,10 +25,13 @@ var (
 	REGION                = os.Getenv("AWS_REGION")
 	BOOTSTRAP_TIMEOUT     = os.Getenv("BOOTSTRAP_TIMEOUT")
 	PROJECTTABLE          = os.Getenv("PROJECTTABLE")
-	TICKET_CREDENTIAL_ARN = os.Getenv("TICKET_CREDENTIAL_ARN")
+	TICKETTABLE           = os.Getenv("TICKETTABLE")
+	DEPLOYMENTTABLE       = os.Getenv("DEPLOYMENTTABLE")
+	TICKET_PUBLIC_KEY_ARN = os.Getenv("TICKET_PUBLIC_KEY_ARN")
 	DELETION_TIMEOUT      = os.Getenv("DELETION_TIMEOUT")
 	STATIC_BUCKET_NAME    = os.Getenv("STATIC_BUCKET_NAME")
 	CLOUDFRONT_CACHE_ARN  = os.Getenv("CLOUDFRONT_CACHE_ARN")
+	TRANSFER_CONCURRENCY  = os.Getenv("TRANSFER_CONCURRENCY")
 )
 
 func main() {
@@ -56,22 +62,34 @@ func run() error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
-	ticketOptions, err := pipeline.CreateTicketOptions(awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, "", "", 0)
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+
+	ticketVerifier, err := auth.CreateVerifier(awsConfig, bootstrapContext, TICKET_PUBLIC_KEY_ARN)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load ticket public keys: %v", err)
 	}
+	ticketOptions := pipeline.CreateTicketVerificationOptions(ticketVerifier)
 
 	deletionTimeout, err := time.ParseDuration(DELETION_TIMEOUT)
 	if err != nil {
 		return fmt.Errorf("failed to parse DELETION_TIMEOUT environment variable")
 	}
 
+	transferConcurrency, err := strconv.Atoi(TRANSFER_CONCURRENCY)
+	if err != nil {
+		return fmt.Errorf("failed to parse TRANSFER_CONCURRENCY environment variable")
+	}
+
 	lambda.Start(deleteproject.HandleDeleteProject(eventcontext.Context{
 		DynamoClient:          dynamoClient,
 		ProjectTable:          PROJECTTABLE,
+		TicketTable:           TICKETTABLE,
+		DeploymentTable:       DEPLOYMENTTABLE,
 		TicketOptions:         ticketOptions,
 		S3Client:              s3Client,
+		TransferOptions:       pipeline.CreateTransferOptions(transferConcurrency),
 		CloudformationClient:  cloudformationClient,
+		SecretClient:          secretClient,
 		CloudfrontCacheClient: cloudfrontClient,
 		DeletionConfiguration: &eventcontext.DeletionConfiguration{
 			Timeout: deletionTimeout,
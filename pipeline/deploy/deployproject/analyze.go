@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -18,6 +19,8 @@ const (
 	SERVER_PATH    = "server/handler.zip"
 	CLIENT_PATH    = "client/"
 	PRERENDER_PATH = "prerendered/"
+	// COMMIT_PATH contains the commit hash of the build, it is written by the build job next to the build output.
+	COMMIT_PATH = "commit"
 )
 
 // ObjectDescription provides information about the location of an s3 object.
@@ -29,6 +32,9 @@ type ObjectDescription struct {
 	SourceBucket string
 	// Source bucket key of the object.
 	SourceKey string
+	// ETag and Size of the source object, they are used to detect unchanged objects on incremental releases.
+	ETag string
+	Size int64
 }
 
 // BuildInformation provides information about the content of the build output.
@@ -37,45 +43,68 @@ type BuildInformation struct {
 	PrerenderedObjects []ObjectDescription
 	ServerObject       ObjectDescription
 	PageKeys           map[string]string
+	// Commit is the commit hash of the build, it is empty if the build job did not record it.
+	Commit string
+	// Usage contains the measured size of the build assets.
+	Usage project.Usage
 }
 
 // analyzeBuildAssets analyzes the content of the build assets, expecting to find sveltekit build output from adapter-battleshiper.
-func analyzeBuildAssets(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, subscriptionDoc *subscription.Subscription, execIdentifier string) (*BuildInformation, error) {
-	bucketPathSegments := strings.SplitN(projectDoc.SharedInfrastructure.BuildAssetBucketPath, "/", 2)
+// The assetPath points to the build output (e.g. build_asset_bucket/project/execution_identifier).
+func analyzeBuildAssets(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, subscriptionDoc *subscription.Subscription, assetPath string) (*BuildInformation, error) {
+	bucketPathSegments := strings.SplitN(assetPath, "/", 2)
 	if len(bucketPathSegments) != 2 {
-		return nil, fmt.Errorf("failed to decode build asset bucket path")
+		return nil, fmt.Errorf("failed to decode build asset path")
 	}
 	bucketName := bucketPathSegments[0]
 	bucketPrefix := bucketPathSegments[1]
 
-	clientObjects, err := analyzeClientObjects(
-		transportCtx, eventCtx.S3Client, bucketName, bucketPrefix, execIdentifier, subscriptionDoc.ProjectSpecs.ClientStorage)
+	clientObjects, clientSize, err := analyzeClientObjects(
+		transportCtx, eventCtx.S3Client, bucketName, bucketPrefix, subscriptionDoc.ProjectSpecs.ClientStorage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze client assets: %v", err)
 	}
 
-	prerenderObjects, err := analyzePrerenderObjects(
-		transportCtx, eventCtx.S3Client, bucketName, bucketPrefix, execIdentifier, subscriptionDoc.ProjectSpecs.PrerenderStorage)
+	prerenderObjects, prerenderSize, err := analyzePrerenderObjects(
+		transportCtx, eventCtx.S3Client, bucketName, bucketPrefix, subscriptionDoc.ProjectSpecs.PrerenderStorage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze prerendered assets: %v", err)
 	}
 
-	serverObject, err := analyzeServerObject(
-		transportCtx, eventCtx.S3Client, bucketName, bucketPrefix, execIdentifier, subscriptionDoc.ProjectSpecs.ServerStorage)
+	serverObject, serverSize, err := analyzeServerObject(
+		transportCtx, eventCtx.S3Client, bucketName, bucketPrefix, subscriptionDoc.ProjectSpecs.ServerStorage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze server asset: %v", err)
 	}
 
+	commit, err := analyzeCommit(transportCtx, eventCtx.S3Client, bucketName, bucketPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze build commit: %v", err)
+	}
+
+	// page keys are stored in the keyvaluestore shared by all projects, therefore the number of routes is limited.
+	pageKeys := extractPageKeys(prerenderObjects, projectDoc.ProjectName)
+	if int64(len(pageKeys)) > subscriptionDoc.ProjectSpecs.PrerenderRoutes {
+		return nil, fmt.Errorf("found %d prerendered routes; exceeded maximum of %d prerendered routes",
+			len(pageKeys), subscriptionDoc.ProjectSpecs.PrerenderRoutes)
+	}
+
 	return &BuildInformation{
 		ClientObjects:      clientObjects,
 		PrerenderedObjects: prerenderObjects,
 		ServerObject:       *serverObject,
-		PageKeys:           extractPageKeys(prerenderObjects, projectDoc.ProjectName),
+		PageKeys:           pageKeys,
+		Commit:             commit,
+		Usage: project.Usage{
+			ClientBytes:    clientSize,
+			PrerenderBytes: prerenderSize,
+			ServerBytes:    serverSize,
+		},
 	}, nil
 }
 
-func analyzeClientObjects(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix, execIdentifier string, maxBytes int64) ([]ObjectDescription, error) {
-	clientPrefix := fmt.Sprintf("%s/%s/%s", bucketPrefix, execIdentifier, CLIENT_PATH)
+func analyzeClientObjects(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix string, maxBytes int64) ([]ObjectDescription, int64, error) {
+	clientPrefix := fmt.Sprintf("%s/%s", bucketPrefix, CLIENT_PATH)
 	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucketName),
 		Prefix: aws.String(clientPrefix),
@@ -87,28 +116,30 @@ func analyzeClientObjects(transportCtx context.Context, s3Client *s3.Client, buc
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(transportCtx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list objects: %v", err)
+			return nil, 0, fmt.Errorf("failed to list objects: %v", err)
 		}
 
 		for _, obj := range page.Contents {
 			clientSize += *obj.Size
 			if clientSize > maxBytes {
-				return nil, fmt.Errorf("exceeded maximum asset size of %d bytes", maxBytes)
+				return nil, 0, fmt.Errorf("exceeded maximum asset size of %d bytes", maxBytes)
 			}
 
 			clientObjects = append(clientObjects, ObjectDescription{
 				SourceBucket: bucketName,
 				SourceKey:    *obj.Key,
 				RelativeKey:  strings.TrimPrefix(*obj.Key, clientPrefix),
+				ETag:         aws.ToString(obj.ETag),
+				Size:         aws.ToInt64(obj.Size),
 			})
 		}
 	}
 
-	return clientObjects, nil
+	return clientObjects, clientSize, nil
 }
 
-func analyzePrerenderObjects(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix, execIdentifier string, maxBytes int64) ([]ObjectDescription, error) {
-	prerenderPrefix := fmt.Sprintf("%s/%s/%s", bucketPrefix, execIdentifier, PRERENDER_PATH)
+func analyzePrerenderObjects(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix string, maxBytes int64) ([]ObjectDescription, int64, error) {
+	prerenderPrefix := fmt.Sprintf("%s/%s", bucketPrefix, PRERENDER_PATH)
 	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucketName),
 		Prefix: aws.String(prerenderPrefix),
@@ -120,32 +151,34 @@ func analyzePrerenderObjects(transportCtx context.Context, s3Client *s3.Client,
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(transportCtx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list objects: %v", err)
+			return nil, 0, fmt.Errorf("failed to list objects: %v", err)
 		}
 
 		for _, obj := range page.Contents {
 			prerenderSize += *obj.Size
 			if prerenderSize > maxBytes {
-				return nil, fmt.Errorf("exceeded maximum asset size of %d bytes", maxBytes)
+				return nil, 0, fmt.Errorf("exceeded maximum asset size of %d bytes", maxBytes)
 			}
 
 			if !strings.HasSuffix(*obj.Key, ".html") {
-				return nil, fmt.Errorf("prerendered objects ('%s') are expected to have the '.html' extension", *obj.Key)
+				return nil, 0, fmt.Errorf("prerendered objects ('%s') are expected to have the '.html' extension", *obj.Key)
 			}
 
 			prerenderObjects = append(prerenderObjects, ObjectDescription{
 				SourceBucket: bucketName,
 				SourceKey:    *obj.Key,
 				RelativeKey:  strings.TrimPrefix(*obj.Key, prerenderPrefix),
+				ETag:         aws.ToString(obj.ETag),
+				Size:         aws.ToInt64(obj.Size),
 			})
 		}
 	}
 
-	return prerenderObjects, nil
+	return prerenderObjects, prerenderSize, nil
 }
 
-func analyzeServerObject(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix, execIdentifier string, maxBytes int64) (*ObjectDescription, error) {
-	serverKey := fmt.Sprintf("%s/%s/%s", bucketPrefix, execIdentifier, SERVER_PATH)
+func analyzeServerObject(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix string, maxBytes int64) (*ObjectDescription, int64, error) {
+	serverKey := fmt.Sprintf("%s/%s", bucketPrefix, SERVER_PATH)
 
 	serverObject, err := s3Client.HeadObject(transportCtx, &s3.HeadObjectInput{
 		Bucket: aws.String(bucketName),
@@ -154,21 +187,45 @@ func analyzeServerObject(transportCtx context.Context, s3Client *s3.Client, buck
 	if err != nil {
 		nfe := &s3types.NotFound{}
 		if ok := errors.As(err, &nfe); ok {
-			return nil, fmt.Errorf("expected server object at '%s'", serverKey)
+			return nil, 0, fmt.Errorf("expected server object at '%s'", serverKey)
 		} else {
-			return nil, fmt.Errorf("failed to fetch object: %v", err)
+			return nil, 0, fmt.Errorf("failed to fetch object: %v", err)
 		}
 	}
 
 	if *serverObject.ContentLength > maxBytes {
-		return nil, fmt.Errorf("exceeded maximum asset size of %d bytes", maxBytes)
+		return nil, 0, fmt.Errorf("exceeded maximum asset size of %d bytes", maxBytes)
 	}
 
 	return &ObjectDescription{
 		SourceBucket: bucketName,
 		SourceKey:    serverKey,
 		RelativeKey:  SERVER_PATH,
-	}, nil
+	}, *serverObject.ContentLength, nil
+}
+
+// analyzeCommit reads the commit hash recorded by the build job.
+// Build jobs created before the commit was recorded do not upload it, in this case an empty commit is returned.
+func analyzeCommit(transportCtx context.Context, s3Client *s3.Client, bucketName, bucketPrefix string) (string, error) {
+	commitObject, err := s3Client.GetObject(transportCtx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fmt.Sprintf("%s/%s", bucketPrefix, COMMIT_PATH)),
+	})
+	if err != nil {
+		nske := &s3types.NoSuchKey{}
+		if ok := errors.As(err, &nske); ok {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to fetch object: %v", err)
+	}
+	defer commitObject.Body.Close()
+
+	// a commit hash has at most 64 characters (sha256), the rest of the object is ignored.
+	commit, err := io.ReadAll(io.LimitReader(commitObject.Body, 64))
+	if err != nil {
+		return "", fmt.Errorf("failed to read object: %v", err)
+	}
+	return strings.TrimSpace(string(commit)), nil
 }
 
 func extractPageKeys(prerenderObjects []ObjectDescription, projectName string) map[string]string {
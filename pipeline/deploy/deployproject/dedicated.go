@@ -41,7 +41,7 @@ func validateStackState(transportCtx context.Context, eventCtx eventcontext.Cont
 }
 
 // createChangeSet loads the current stack, builds a changeset with the new system and pushes the change set to cloudformation.
-func createChangeSet(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, execId string, serverAsset ObjectDescription) (string, error) {
+func createChangeSet(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, execId string, serverAsset ObjectDescription, serverEnvironment map[string]string) (string, error) {
 	stackTemplate, err := eventCtx.CloudformationClient.GetTemplate(transportCtx, &cloudformation.GetTemplateInput{
 		StackName: aws.String(projectDoc.DedicatedInfrastructure.StackName),
 	})
@@ -76,7 +76,7 @@ func createChangeSet(transportCtx context.Context, eventCtx eventcontext.Context
 		return "", fmt.Errorf("failed to parse stack template: %v", err)
 	}
 
-	attachServerSystem(stackBody, eventCtx, projectDoc, serverAsset.SourceBucket, serverAsset.SourceKey)
+	attachServerSystem(stackBody, eventCtx, projectDoc, serverAsset.SourceBucket, serverAsset.SourceKey, serverEnvironment)
 
 	stackBodyRaw, err := stackBody.JSON()
 	if err != nil {
@@ -174,7 +174,8 @@ func executeChangeSet(transportCtx context.Context, eventCtx eventcontext.Contex
 }
 
 // attachServerSystem adds the project server system to the stack.
-func attachServerSystem(stackTemplate *goformation.Template, eventCtx eventcontext.Context, projectDoc *project.Project, serverBucketName, serverBucketKey string) {
+// The environment contains the plain runtime variables and dynamic references to the secret runtime variables of the project.
+func attachServerSystem(stackTemplate *goformation.Template, eventCtx eventcontext.Context, projectDoc *project.Project, serverBucketName, serverBucketKey string, environment map[string]string) {
 	// ServerLogGroup is deployed at initialization (combined with all other log groups)
 	const SERVER_LOG_GROUP string = "ServerLogGroup"
 
@@ -215,7 +216,7 @@ func attachServerSystem(stackTemplate *goformation.Template, eventCtx eventconte
 	}
 
 	const SERVER_FUNCTION string = "ServerFunction"
-	stackTemplate.Resources[SERVER_FUNCTION] = &lambda.Function{
+	serverFunction := &lambda.Function{
 		FunctionName:  aws.String(fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)),
 		Description:   aws.String(fmt.Sprintf("Server backend for battleshiper project %s", projectDoc.ProjectName)),
 		Architectures: []string{"x86_64"},
@@ -233,4 +234,10 @@ func attachServerSystem(stackTemplate *goformation.Template, eventCtx eventconte
 			LogFormat: aws.String("Text"),
 		},
 	}
+	if len(environment) > 0 {
+		serverFunction.Environment = &lambda.Function_Environment{
+			Variables: environment,
+		}
+	}
+	stackTemplate.Resources[SERVER_FUNCTION] = serverFunction
 }
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -37,13 +36,13 @@ func HandleDeployProject(eventCtx eventcontext.Context) func(context.Context, ev
 	}
 }
 
-func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) error {
+func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) (err error) {
 	deployRequest := &event.DeployRequest{}
 	if err := json.Unmarshal(request.Detail, &deployRequest); err != nil {
 		return fmt.Errorf("failed to deserialize deploy request")
 	}
 
-	deployClaims, err := pipeline.ParseTicket(eventCtx.TicketOptions, deployRequest.Parameters.DeployTicket)
+	deployClaims, err := pipeline.ParseTicket(transportCtx, eventCtx.TicketOptions, deployRequest.Parameters.DeployTicket)
 	if err != nil {
 		return fmt.Errorf("failed to parse ticket: %v", err)
 	}
@@ -51,6 +50,24 @@ func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context
 	if deployClaims.Action != request.DetailType {
 		return fmt.Errorf("action mismatch: provided ticket was not issued for the specified action")
 	}
+	if deployClaims.ID != deployRequest.Parameters.ExecutionIdentifier {
+		return fmt.Errorf("execution mismatch: provided ticket was issued for execution '%s' not for '%s'",
+			deployClaims.ID, deployRequest.Parameters.ExecutionIdentifier)
+	}
+
+	// tickets are single-use, replayed events are rejected before they touch any resources.
+	// A failed execution releases the ticket, so that retries of the event are accepted.
+	if err := pipeline.ClaimTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, deployClaims); err != nil {
+		if errors.Is(err, pipeline.ErrTicketConsumed) {
+			return fmt.Errorf("ticket replay rejected: ticket of execution '%s' was already consumed", deployClaims.ID)
+		}
+		return err
+	}
+	defer func() {
+		if fErr := pipeline.FinishTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, deployClaims, err); fErr != nil {
+			logger.Printf("%v\n", fErr)
+		}
+	}()
 
 	userDoc, err := database.GetSingle[user.User](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
 		Table: aws.String(eventCtx.UserTable),
@@ -96,9 +113,12 @@ func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context
 	if strings.ToUpper(deployRequest.Status) != "SUCCEEDED" {
 		buildResult.Successful = false
 
-		buildResultAttributes, sErr := attributevalue.Marshal(&buildResult)
-		if sErr != nil {
-			return fmt.Errorf("failed to serialize buildresult")
+		updateExpression, bErr := database.NewUpdate[project.Project]().
+			Set("LastBuildResult", &buildResult).
+			Set("Status", "BUILD FAILED").
+			Build()
+		if bErr != nil {
+			return fmt.Errorf("failed to build update expression: %v", bErr)
 		}
 
 		_, uErr := database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
@@ -106,15 +126,9 @@ func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context
 			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 			},
-			AttributeNames: map[string]string{
-				"#last_build_result": "last_build_result",
-				"#status":            "status",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":last_build_result": buildResultAttributes,
-				":status":            &dynamodbtypes.AttributeValueMemberS{Value: "BUILD FAILED"},
-			},
-			UpdateExpr: aws.String("SET #last_build_result = :last_build_result, #status = :status"),
+			AttributeNames:  updateExpression.AttributeNames,
+			AttributeValues: updateExpression.AttributeValues,
+			UpdateExpr:      updateExpression.UpdateExpr,
 		})
 		if uErr != nil {
 			return fmt.Errorf("failed to update project: %v", uErr)
@@ -123,9 +137,11 @@ func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context
 	} else {
 		buildResult.Successful = true
 
-		buildResultAttributes, sErr := attributevalue.Marshal(&buildResult)
-		if sErr != nil {
-			return fmt.Errorf("failed to serialize buildresult")
+		updateExpression, bErr := database.NewUpdate[project.Project]().
+			Set("LastBuildResult", &buildResult).
+			Build()
+		if bErr != nil {
+			return fmt.Errorf("failed to build update expression: %v", bErr)
 		}
 
 		_, uErr := database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
@@ -133,128 +149,189 @@ func runHandleDeployProject(request events.CloudWatchEvent, transportCtx context
 			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 			},
-			AttributeNames: map[string]string{
-				"#last_build_result": "last_build_result",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":last_build_result": buildResultAttributes,
-			},
-			UpdateExpr: aws.String("SET #last_build_result = :last_build_result"),
+			AttributeNames:  updateExpression.AttributeNames,
+			AttributeValues: updateExpression.AttributeValues,
+			UpdateExpr:      updateExpression.UpdateExpr,
 		})
 		if uErr != nil {
 			return fmt.Errorf("failed to update project: %v", uErr)
 		}
 	}
 
+	// the quota is enforced before the pipeline is locked, a rejected deployment does not touch the project resources.
+	quotaUsage, err := pipeline.CheckQuota(transportCtx, eventCtx.DynamoClient, &pipeline.CheckQuotaInput{
+		UserTable:         eventCtx.UserTable,
+		SubscriptionTable: eventCtx.SubscriptionTable,
+		UserDoc:           *userDoc,
+		Name:              pipeline.QUOTA_PIPELINE_DEPLOYMENTS,
+	})
+	if err != nil {
+		if !errors.Is(err, pipeline.ErrQuotaExceeded) {
+			return fmt.Errorf("failed to check deployment quota: %v", err)
+		}
+		return rejectDeployment(transportCtx, eventCtx, projectDoc, deployRequest.Parameters.ExecutionIdentifier, err)
+	}
+
+	projectDoc, err = lockProject(transportCtx, eventCtx, projectDoc)
+	if err != nil {
+		// the deployment is not executed if the project is locked, therefore the counted deployment is refunded.
+		if rErr := pipeline.RefundQuota(transportCtx, eventCtx.DynamoClient, quotaUsage); rErr != nil {
+			logger.Printf("failed to refund deployment quota: %v\n", rErr)
+		}
+		return err
+	}
+
+	// Start actual deployment step
+	deployErr := deployProject(transportCtx, eventCtx, projectDoc, userDoc.SubscriptionId, deployRequest.Parameters.ExecutionIdentifier)
+	return finishDeployment(transportCtx, eventCtx, projectDoc, deployRequest.Parameters.ExecutionIdentifier, deployErr)
+}
+
+// lockProject acquires the pipeline lock of the project and returns the project as it was before the lock.
+func lockProject(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) (*project.Project, error) {
+	lockExpression, err := database.NewUpdate[project.Project]().
+		Set("PipelineLock", true).
+		AttributeEquals("Deleted", false).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build lock expression: %v", err)
+	}
+
 	projectDoc, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
 		Table: aws.String(eventCtx.ProjectTable),
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 		},
-		AttributeNames: map[string]string{
-			"#deleted":       "deleted",
-			"#pipeline_lock": "pipeline_lock",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":deleted":       &dynamodbtypes.AttributeValueMemberBOOL{Value: false},
-			":pipeline_lock": &dynamodbtypes.AttributeValueMemberBOOL{Value: true},
-		},
-		ConditionExpr: aws.String("#deleted = :deleted"),
-		UpdateExpr:    aws.String("SET #pipeline_lock = :pipeline_lock"),
-		ReturnOld:     true,
+		AttributeNames:  lockExpression.AttributeNames,
+		AttributeValues: lockExpression.AttributeValues,
+		ConditionExpr:   lockExpression.ConditionExpr,
+		UpdateExpr:      lockExpression.UpdateExpr,
+		ReturnOld:       true,
 	})
 	if err != nil {
 		var cErr *dynamodbtypes.ConditionalCheckFailedException
 		if ok := errors.As(err, &cErr); ok {
-			return fmt.Errorf("project not found")
+			return nil, fmt.Errorf("project not found")
 		}
-		return fmt.Errorf("failed to lock project on database: %v", err)
+		return nil, fmt.Errorf("failed to lock project on database: %v", err)
 	}
 	if projectDoc.PipelineLock {
-		return fmt.Errorf("project locked")
+		return nil, fmt.Errorf("project locked")
 	}
+	return projectDoc, nil
+}
 
-	// Start actual deployment step
+// finishDeployment records the result of the deployment and releases the pipeline lock.
+func finishDeployment(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, execId string, deployErr error) error {
 	deploymentResult := project.DeploymentResult{
-		ExecutionIdentifier: deployRequest.Parameters.ExecutionIdentifier,
+		ExecutionIdentifier: execId,
+		Timepoint:           time.Now().Unix(),
+		Successful:          deployErr == nil,
+	}
+	status := ""
+	if deployErr != nil {
+		status = fmt.Sprintf("DEPLOYMENT FAILED: %v", deployErr)
 	}
-	if err := deployProject(transportCtx, eventCtx, projectDoc, userDoc.SubscriptionId, deployRequest.Parameters.ExecutionIdentifier); err != nil {
-		deploymentResult.Timepoint = time.Now().Unix()
-		deploymentResult.Successful = false
 
-		deploymentResultAttributes, sErr := attributevalue.Marshal(&deploymentResult)
-		if sErr != nil {
-			return fmt.Errorf("failed to serialize deployresult")
-		}
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("LastDeploymentResult", &deploymentResult).
+		Set("Status", status).
+		Set("PipelineLock", false).
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to build update expression: %v", err)
+	}
 
-		_, uErr := database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
-			Table: aws.String(eventCtx.ProjectTable),
-			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
-			},
-			AttributeNames: map[string]string{
-				"#last_deployment_result": "last_deployment_result",
-				"#status":                 "status",
-				"#pipeline_lock":          "pipeline_lock",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":last_deployment_result": deploymentResultAttributes,
-				":status":                 &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("DEPLOYMENT FAILED: %v", err)},
-				":pipeline_lock":          &dynamodbtypes.AttributeValueMemberBOOL{Value: false},
-			},
-			UpdateExpr: aws.String("SET #last_deployment_result = :last_deployment_result, #status = :status, #pipeline_lock = :pipeline_lock"),
-		})
-		if uErr != nil {
-			return fmt.Errorf("failed to update project: %v", uErr)
-		}
-		return nil
-	} else {
-		deploymentResult.Timepoint = time.Now().Unix()
-		deploymentResult.Successful = true
+	_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(eventCtx.ProjectTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+		},
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update project: %v", err)
+	}
+	return nil
+}
 
-		deploymentResultAttributes, sErr := attributevalue.Marshal(&deploymentResult)
-		if sErr != nil {
-			return fmt.Errorf("failed to serialize deployresult")
+// rejectDeployment records a deployment that was rejected because the deployment quota is exhausted.
+func rejectDeployment(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, execId string, reason error) (err error) {
+	cloudLogger, err := pipeline.NewCloudLogger(
+		transportCtx,
+		eventCtx.CloudwatchClient,
+		projectDoc.DedicatedInfrastructure.DeployLogGroup,
+		execId,
+		pipeline.LOG_PHASE_DEPLOY,
+		eventCtx.CloudLoggerOptions,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := cloudLogger.Close(); cErr != nil && err == nil {
+			err = cErr
 		}
+	}()
+	cloudLogger.WriteEvent(pipeline.LogEvent{
+		Level:   pipeline.LOG_WARN,
+		Message: "DEPLOYMENT REJECTED: quota exceeded",
+		Error:   reason.Error(),
+	})
 
-		_, uErr := database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
-			Table: aws.String(eventCtx.ProjectTable),
-			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
-				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
-			},
-			AttributeNames: map[string]string{
-				"#last_deployment_result": "last_deployment_result",
-				"#status":                 "status",
-				"#pipeline_lock":          "pipeline_lock",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":last_deployment_result": deploymentResultAttributes,
-				":status":                 &dynamodbtypes.AttributeValueMemberS{Value: ""},
-				":pipeline_lock":          &dynamodbtypes.AttributeValueMemberBOOL{Value: false},
-			},
-			UpdateExpr: aws.String("SET #last_deployment_result = :last_deployment_result, #status = :status, #pipeline_lock = :pipeline_lock"),
-		})
-		if uErr != nil {
-			return fmt.Errorf("failed to update project: %v", uErr)
-		}
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("LastDeploymentResult", &project.DeploymentResult{
+			ExecutionIdentifier: execId,
+			Timepoint:           time.Now().Unix(),
+			Successful:          false,
+		}).
+		Set("Status", "DEPLOYMENT REJECTED: quota exceeded").
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to build update expression: %v", err)
 	}
 
+	_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
+		Table: aws.String(eventCtx.ProjectTable),
+		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+		},
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update project: %v", err)
+	}
 	return nil
 }
 
-func deployProject(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, subscriptionId string, execId string) error {
+func deployProject(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, subscriptionId string, execId string) (err error) {
 	cloudLogger, err := pipeline.NewCloudLogger(
 		transportCtx,
 		eventCtx.CloudwatchClient,
 		projectDoc.DedicatedInfrastructure.DeployLogGroup,
 		execId,
+		pipeline.LOG_PHASE_DEPLOY,
+		eventCtx.CloudLoggerOptions,
 	)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if cErr := cloudLogger.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
 
-	cloudLogger.WriteLog("START DEPLOYMENT %s", execId)
+	// the deployment step records the total duration, the nested steps record the duration of each operation.
+	deploymentStep := cloudLogger.StartStep("deployment", fmt.Sprintf("START DEPLOYMENT %s", execId))
+	defer func() {
+		deploymentStep.Finish(err)
+	}()
 
+	step := cloudLogger.StartStep("fetch_subscription", "fetching subscription...")
 	subscriptionDoc, err := database.GetSingle[subscription.Subscription](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
 		Table: aws.String(eventCtx.SubscriptionTable),
 		AttributeValues: map[string]dynamodbtypes.AttributeValue{
@@ -263,79 +340,181 @@ func deployProject(transportCtx context.Context, eventCtx eventcontext.Context,
 		ConditionExpr: aws.String("id = :id"),
 	})
 	if err != nil {
-		cloudLogger.WriteLog("failed to fetch subscription from database")
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
-		return fmt.Errorf("failed to fetch subscription from database")
+		err = fmt.Errorf("failed to fetch subscription from database")
+		step.Finish(err)
+		return err
 	}
+	step.Finish(nil)
 
-	cloudLogger.WriteLog("analyzing build assets...")
-	buildInformation, err := analyzeBuildAssets(transportCtx, eventCtx, projectDoc, subscriptionDoc, execId)
+	step = cloudLogger.StartStep("analyze_assets", "analyzing build assets...")
+	buildInformation, err := analyzeBuildAssets(transportCtx, eventCtx, projectDoc, subscriptionDoc,
+		fmt.Sprintf("%s/%s", projectDoc.SharedInfrastructure.BuildAssetBucketPath, execId))
+	step.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	// the build output is retained before the release, the server function is deployed from the retained object.
+	step = cloudLogger.StartStep("retain_artifacts", "retaining build artifacts...")
+	artifactPath, buildInformation, err := retainBuildArtifacts(transportCtx, eventCtx, step, projectDoc, buildInformation, execId)
+	step.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	if err = releaseProject(transportCtx, eventCtx, cloudLogger, projectDoc, buildInformation, projectDoc.TrafficShift, execId, execId); err != nil {
+		return err
+	}
+
+	step = cloudLogger.StartStep("record_deployment", "recording deployment in history...")
+	err = recordDeployment(transportCtx, eventCtx, projectDoc, buildInformation, artifactPath, execId)
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 		return err
 	}
 
+	// pruning is not part of the release, a failure is logged without failing the deployment.
+	step = cloudLogger.StartStep("prune_deployments", "removing deployments exceeding the retention...")
+	step.Finish(pruneDeployments(transportCtx, eventCtx, step, projectDoc, subscriptionDoc.ProjectSpecs.DeploymentRetention, execId))
+
+	return nil
+}
+
+// releaseProject deploys the build to the project infrastructure (server stack, page keys and static assets).
+// Static assets are released under the execution identifier as new version and made live by switching the cdn store pointer,
+// the live static version is never modified. Only added and changed objects are copied to the new version,
+// the version manifest references unchanged objects at their existing location.
+// The deploymentId is the deployment record the build belongs to, it is set as active deployment of the project.
+func releaseProject(transportCtx context.Context, eventCtx eventcontext.Context, cloudLogger *pipeline.CloudLogger, projectDoc *project.Project, buildInformation *BuildInformation, shift project.TrafficShift, execId, deploymentId string) error {
 	// the stack state is validated to provide a more descriptive error message to the user
-	cloudLogger.WriteLog("validating stack state...")
-	err = validateStackState(transportCtx, eventCtx, projectDoc)
+	step := cloudLogger.StartStep("validate_stack", "validating stack state...")
+	err := validateStackState(transportCtx, eventCtx, projectDoc)
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 		return err
 	}
 
-	cloudLogger.WriteLog("creating stack changeset...")
-	if err := cloudLogger.PushLogs(); err != nil {
+	step = cloudLogger.StartStep("load_environment", "loading runtime environment...")
+	serverEnvironment, err := loadServerEnvironment(transportCtx, eventCtx, projectDoc)
+	step.Finish(err)
+	if err != nil {
 		return err
 	}
-	changeSetName, err := createChangeSet(transportCtx, eventCtx, projectDoc, execId, buildInformation.ServerObject)
+
+	step = cloudLogger.StartStep("create_changeset", "creating stack changeset...")
+	changeSetName, err := createChangeSet(transportCtx, eventCtx, projectDoc, execId, buildInformation.ServerObject, serverEnvironment)
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 		return err
 	}
 
-	cloudLogger.WriteLog("describing stack changeset...")
+	step = cloudLogger.StartStep("describe_changeset", "describing stack changeset...")
 	changeSetDescription, err := describeChangeSet(transportCtx, eventCtx, projectDoc, changeSetName)
+	if err == nil {
+		step.WriteLog("%s", changeSetDescription)
+	}
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 		return err
 	}
-	cloudLogger.WriteLog(changeSetDescription)
 
-	cloudLogger.WriteLog("executing stack changeset...")
-	if err := cloudLogger.PushLogs(); err != nil {
+	step = cloudLogger.StartStep("execute_changeset", "executing stack changeset...")
+	err = executeChangeSet(transportCtx, eventCtx, projectDoc, changeSetName)
+	step.Finish(err)
+	if err != nil {
 		return err
 	}
-	err = executeChangeSet(transportCtx, eventCtx, projectDoc, changeSetName)
+
+	step = cloudLogger.StartStep("publish_version", "publishing server version...")
+	serverVersion, err := publishServerVersion(transportCtx, eventCtx, projectDoc, execId)
+	if err == nil {
+		step.WriteLog("published server version %s", serverVersion)
+	}
+	step.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	step = cloudLogger.StartStep("fetch_live_version", "fetching live server and static version...")
+	previousServerVersion, err := fetchServerVersion(transportCtx, eventCtx, projectDoc)
+	if err != nil {
+		step.Finish(err)
+		return err
+	}
+	previousVersion, err := fetchStaticVersion(transportCtx, eventCtx, projectDoc)
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 		return err
 	}
 
-	cloudLogger.WriteLog("updating page keys on database...")
-	if err := cloudLogger.PushLogs(); err != nil {
+	// every release is written to a new version prefix, the live version is never modified.
+	// Objects that are unchanged since the live version are referenced by the new manifest, all others are copied from the build.
+	releaseVersion := execId
+	step = cloudLogger.StartStep("diff_assets", "comparing static assets with live version...")
+	staticDiff, err := diffStaticAssets(transportCtx, eventCtx, projectDoc, previousVersion, buildInformation)
+	if err == nil {
+		step.WriteLog("%s", staticDiff.Summary())
+	}
+	step.Finish(err)
+	if err != nil {
 		return err
 	}
 
-	pageKeyAttributes, err := attributevalue.Marshal(&buildInformation.PageKeys)
+	step = cloudLogger.StartStep("copy_assets", "transferring changed static assets to new version...")
+	releaseManifest, err := releaseStaticVersion(transportCtx, eventCtx, step, projectDoc, releaseVersion, staticDiff)
+	step.Finish(err)
 	if err != nil {
-		return fmt.Errorf("failed to serialize page key attributes")
+		return err
+	}
+
+	// while the traffic is shifted gradually, both server versions render pages that reference their own static assets.
+	// The bridge version references the objects of both releases, it is live until the shift is completed.
+	bridgeVersion := ""
+	livePageKeys := projectDoc.SharedInfrastructure.PrerenderPageKeys
+	if len(shift.ShiftWeights()) > 0 && previousServerVersion != "" && previousServerVersion != serverVersion {
+		bridgeVersion = fmt.Sprintf("%s%s", releaseVersion, STATIC_BRIDGE_SUFFIX)
+		step = cloudLogger.StartStep("bridge_assets", "bridging static assets of live and new version...")
+		err = writeStaticManifest(transportCtx, eventCtx, projectDoc, bridgeVersion, staticDiff.BridgeManifest(releaseManifest))
+		if err == nil {
+			err = switchStaticVersion(transportCtx, eventCtx, projectDoc, bridgeVersion, buildInformation.PageKeys, livePageKeys)
+		}
+		step.Finish(err)
+		if err != nil {
+			return err
+		}
+		livePageKeys = buildInformation.PageKeys
+	}
+
+	// failures after the traffic shift revert the release,
+	// otherwise the new server version keeps serving its pages against the previous static version.
+	revert := func(cause error, staticSwitched bool) error {
+		return revertRelease(eventCtx, cloudLogger, projectDoc, buildInformation, staticDiff, execId, previousServerVersion, serverVersion, previousVersion, staticSwitched, cause)
+	}
+
+	step = cloudLogger.StartStep("shift_traffic", "shifting server traffic to new version...")
+	err = shiftServerTraffic(transportCtx, eventCtx, step, projectDoc, shift, previousServerVersion, serverVersion)
+	step.Finish(err)
+	if err != nil {
+		return revert(err, bridgeVersion != "")
+	}
+
+	step = cloudLogger.StartStep("switch_version", "switching live static version on cdn...")
+	err = switchStaticVersion(transportCtx, eventCtx, projectDoc, releaseVersion, buildInformation.PageKeys, livePageKeys)
+	step.Finish(err)
+	if err != nil {
+		return revert(err, bridgeVersion != "")
+	}
+
+	step = cloudLogger.StartStep("update_database", "updating page keys and usage on database...")
+	pageKeyExpression, err := database.NewUpdate[project.Project]().
+		Set("SharedInfrastructure.PrerenderPageKeys", buildInformation.PageKeys).
+		Set("Usage", buildInformation.Usage).
+		Set("ActiveDeployment", deploymentId).
+		Build()
+	if err != nil {
+		err = fmt.Errorf("failed to build page key expression: %v", err)
+		step.Finish(err)
+		return revert(err, true)
 	}
 
 	_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
@@ -343,79 +522,130 @@ func deployProject(transportCtx context.Context, eventCtx eventcontext.Context,
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 		},
-		AttributeNames: map[string]string{
-			"#shared_infrastructure": "shared_infrastructure",
-			"#prerender_page_keys":   "prerender_page_keys",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":prerender_page_keys": pageKeyAttributes,
-		},
-		UpdateExpr: aws.String("SET #shared_infrastructure.#prerender_page_keys = :prerender_page_keys"),
+		AttributeNames:  pageKeyExpression.AttributeNames,
+		AttributeValues: pageKeyExpression.AttributeValues,
+		UpdateExpr:      pageKeyExpression.UpdateExpr,
 	})
 	if err != nil {
-		cloudLogger.WriteLog("failed to update page keys on database")
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
+		err = fmt.Errorf("failed to update page keys on database")
+		step.Finish(err)
+		return revert(err, true)
+	}
+	step.Finish(nil)
+
+	// the cdn store propagates to the edge locations with a short delay, therefore the previous versions are kept
+	// until the next release. A failure is logged without failing the deployment, the new release is already live.
+	// Objects are removed once no retained version references them.
+	// An unversioned previous release is located directly below the project prefix, its cleanup is deferred to the next release.
+	step = cloudLogger.StartStep("clean_static", "removing unreferenced static objects...")
+	if previousVersion != "" {
+		retainedVersions := []string{releaseVersion, previousVersion}
+		if bridgeVersion != "" {
+			retainedVersions = append(retainedVersions, bridgeVersion)
 		}
-		return fmt.Errorf("failed to update page keys on database")
+		step.Finish(cleanStaticVersions(transportCtx, eventCtx, step, projectDoc, retainedVersions...))
+	} else {
+		step.WriteLog("no versioned previous release; cleanup is deferred to the next release")
+		step.Finish(nil)
 	}
 
-	cloudLogger.WriteLog("updating page keys on cdn...")
-	err = updateStaticPageKeys(transportCtx, eventCtx, buildInformation.PageKeys, projectDoc.SharedInfrastructure.PrerenderPageKeys)
+	// the previous server version is retained, it allows to revert the alias without redeploying the stack.
+	step = cloudLogger.StartStep("clean_server", "removing old server versions...")
+	step.Finish(cleanServerVersions(transportCtx, eventCtx, projectDoc, serverVersion, previousServerVersion))
+
+	// the cdn caches objects by their path, only the paths with changed or removed content are invalidated.
+	step = cloudLogger.StartStep("invalidate_cache", "invalidating changed paths on static cdn cache...")
+	invalidatedKeys := staticDiff.InvalidatedKeys()
+	step.WriteLog("invalidating %d changed paths", len(invalidatedKeys))
+	err = invalidateStaticObjects(transportCtx, eventCtx, projectDoc, fmt.Sprintf("%s-%s", projectDoc.ProjectName, execId), invalidatedKeys)
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
 		return err
 	}
 
-	cloudLogger.WriteLog("removing old static asset data...")
-	if err := cloudLogger.PushLogs(); err != nil {
-		return err
+	return nil
+}
+
+// revertRelease routes the live alias back to the previous server version after the release failed behind the traffic shift.
+// If the static version was already switched, the previous static version and page keys are restored as well
+// and the paths added or changed by the release are invalidated.
+// The cause is returned (extended with the revert error if the revert failed).
+func revertRelease(eventCtx eventcontext.Context, cloudLogger *pipeline.CloudLogger, projectDoc *project.Project, buildInformation *BuildInformation, staticDiff *StaticDiff, execId, previousServerVersion, serverVersion, previousStaticVersion string, staticSwitched bool, cause error) error {
+	// without a previous server version, the failed release is the only release and there is nothing to revert to.
+	if previousServerVersion == "" || previousServerVersion == serverVersion {
+		return cause
+	}
+
+	// the revert must also succeed if the release was interrupted by the transport context.
+	revertCtx, cancel := context.WithTimeout(context.Background(), RELEASE_REVERT_TIMEOUT)
+	defer cancel()
+
+	step := cloudLogger.StartStep("revert_release", "reverting live release to previous version...")
+	err := routeServerVersion(revertCtx, eventCtx, projectDoc, previousServerVersion, "", 0)
+	if err == nil {
+		step.WriteLog("reverted live alias to version %s", previousServerVersion)
+		if staticSwitched {
+			if previousStaticVersion == "" {
+				err = fmt.Errorf("previous static release is unversioned and cannot be restored")
+			} else {
+				// the database still holds the page keys of the previous release.
+				err = switchStaticVersion(revertCtx, eventCtx, projectDoc, previousStaticVersion,
+					projectDoc.SharedInfrastructure.PrerenderPageKeys, buildInformation.PageKeys)
+				if err == nil {
+					step.WriteLog("reverted live static version to %s", previousStaticVersion)
+					err = invalidateStaticObjects(revertCtx, eventCtx, projectDoc,
+						fmt.Sprintf("%s-%s-revert", projectDoc.ProjectName, execId), staticDiff.RevertedKeys())
+				}
+			}
+		}
 	}
-	err = cleanStaticBucket(transportCtx, eventCtx, projectDoc)
+	step.Finish(err)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
-		return err
+		return fmt.Errorf("%v; failed to revert release: %v", cause, err)
 	}
+	return cause
+}
 
-	cloudLogger.WriteLog("transferring new static assets...")
-	err = copyStaticAssets(transportCtx, eventCtx, projectDoc, buildInformation.ClientObjects)
+// loadServerEnvironment decrypts the runtime variables of the project and checks them against the lambda environment size limit.
+// Secret variables are written to the runtime environment secret, the returned environment only references them
+// with dynamic references pinned to the written secret version (resolved by cloudformation, never part of the template).
+func loadServerEnvironment(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) (map[string]string, error) {
+	decryptedEnvironment, err := pipeline.DecryptEnvironment(eventCtx.EnvironmentCipher, projectDoc.Environment.Runtime)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
-		return err
+		return nil, err
+	}
+	environmentBytes := 0
+	for name, value := range decryptedEnvironment {
+		environmentBytes += len(name) + len(value)
+	}
+	if environmentBytes > pipeline.ENV_MAX_RUNTIME_BYTES {
+		return nil, fmt.Errorf("runtime environment exceeds the maximum size of %d bytes", pipeline.ENV_MAX_RUNTIME_BYTES)
 	}
 
-	cloudLogger.WriteLog("transferring new static pages...")
-	err = copyStaticPages(transportCtx, eventCtx, projectDoc, buildInformation.PrerenderedObjects)
-	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
+	environment := map[string]string{}
+	secretEnvironment := map[string]string{}
+	for name, variable := range projectDoc.Environment.Runtime {
+		if variable.Secret {
+			secretEnvironment[name] = decryptedEnvironment[name]
+		} else {
+			environment[name] = decryptedEnvironment[name]
 		}
-		return err
+	}
+	if len(secretEnvironment) < 1 {
+		return environment, nil
 	}
 
-	cloudLogger.WriteLog("invalidating static cdn cache...")
-	err = invalidateStaticCache(transportCtx, eventCtx, projectDoc, execId)
+	secretEnvironmentRaw, err := json.Marshal(secretEnvironment)
 	if err != nil {
-		cloudLogger.WriteLog(err.Error())
-		if err := cloudLogger.PushLogs(); err != nil {
-			return err
-		}
-		return err
+		return nil, fmt.Errorf("failed to serialize secret variables: %v", err)
 	}
-
-	if err := cloudLogger.PushLogs(); err != nil {
-		return err
+	secret, err := pipeline.WriteEnvironmentSecret(transportCtx, eventCtx.SecretClient,
+		pipeline.RuntimeEnvironmentSecretName(projectDoc.ProjectName), string(secretEnvironmentRaw))
+	if err != nil {
+		return nil, err
 	}
-
-	return nil
+	for name := range secretEnvironment {
+		environment[name] = fmt.Sprintf("{{resolve:secretsmanager:%s:SecretString:%s::%s}}", secret.Arn, name, secret.VersionId)
+	}
+	return environment, nil
 }
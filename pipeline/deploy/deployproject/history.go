@@ -0,0 +1,179 @@
+package deployproject
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/deployment"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
+)
+
+// DEFAULT_DEPLOYMENT_RETENTION is applied to subscriptions without a deployment retention (e.g. subscriptions created before the retention was introduced).
+const DEFAULT_DEPLOYMENT_RETENTION = 5
+
+// retainBuildArtifacts copies the build output to the artifact bucket, where it outlives the build asset bucket expiration.
+// It returns the artifact path and the build information pointing to the retained objects.
+func retainBuildArtifacts(transportCtx context.Context, eventCtx eventcontext.Context, step *pipeline.LogStep, projectDoc *project.Project, buildInformation *BuildInformation, execId string) (string, *BuildInformation, error) {
+	bucketName := eventCtx.BucketConfiguration.ArtifactBucketName
+	bucketPrefix := fmt.Sprintf("%s/%s", projectDoc.ProjectName, execId)
+
+	artifactCopies := []pipeline.ObjectCopy{}
+	retainObject := func(obj ObjectDescription, path string) ObjectDescription {
+		key := fmt.Sprintf("%s/%s%s", bucketPrefix, path, obj.RelativeKey)
+		artifactCopies = append(artifactCopies, pipeline.ObjectCopy{
+			SourceBucket: obj.SourceBucket,
+			SourceKey:    obj.SourceKey,
+			Bucket:       bucketName,
+			Key:          key,
+		})
+		// a server side copy keeps the content, the etag of a single part object is retained.
+		return ObjectDescription{
+			RelativeKey:  obj.RelativeKey,
+			SourceBucket: bucketName,
+			SourceKey:    key,
+			ETag:         obj.ETag,
+			Size:         obj.Size,
+		}
+	}
+
+	retainedInformation := *buildInformation
+	retainedInformation.ClientObjects = []ObjectDescription{}
+	for _, obj := range buildInformation.ClientObjects {
+		retainedInformation.ClientObjects = append(retainedInformation.ClientObjects, retainObject(obj, CLIENT_PATH))
+	}
+	retainedInformation.PrerenderedObjects = []ObjectDescription{}
+	for _, obj := range buildInformation.PrerenderedObjects {
+		retainedInformation.PrerenderedObjects = append(retainedInformation.PrerenderedObjects, retainObject(obj, PRERENDER_PATH))
+	}
+	// the relative key of the server object already contains the server path.
+	retainedInformation.ServerObject = retainObject(buildInformation.ServerObject, "")
+
+	err := pipeline.CopyObjects(transportCtx, eventCtx.S3Client, eventCtx.TransferOptions, step, artifactCopies)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to copy build artifacts to artifact bucket: %v", err)
+	}
+
+	if buildInformation.Commit != "" {
+		_, err = eventCtx.S3Client.PutObject(transportCtx, &s3.PutObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(fmt.Sprintf("%s/%s", bucketPrefix, COMMIT_PATH)),
+			Body:   strings.NewReader(buildInformation.Commit),
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to write build commit to artifact bucket: %v", err)
+		}
+	}
+
+	return fmt.Sprintf("%s/%s", bucketName, bucketPrefix), &retainedInformation, nil
+}
+
+// recordDeployment adds the deployment to the deployment history of the project.
+func recordDeployment(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, buildInformation *BuildInformation, artifactPath, execId string) error {
+	err := database.PutSingle(transportCtx, eventCtx.DynamoClient, &database.PutSingleInput[deployment.Deployment]{
+		Table: aws.String(eventCtx.DeploymentTable),
+		Item: deployment.Deployment{
+			ProjectName:         projectDoc.ProjectName,
+			ExecutionIdentifier: execId,
+			Timepoint:           time.Now().Unix(),
+			Commit:              buildInformation.Commit,
+			ArtifactPath:        artifactPath,
+			ServerObjectKey:     buildInformation.ServerObject.SourceKey,
+			PageKeys:            buildInformation.PageKeys,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert deployment record: %v", err)
+	}
+	return nil
+}
+
+// pruneDeployments removes the records and artifacts of deployments exceeding the retention of the subscription.
+// The newest deployments are retained, the active deployment is never removed (see expiredDeployments).
+func pruneDeployments(transportCtx context.Context, eventCtx eventcontext.Context, step *pipeline.LogStep, projectDoc *project.Project, retention int64, activeDeployment string) error {
+	deploymentDocs, err := database.GetMany[deployment.Deployment](transportCtx, eventCtx.DynamoClient, &database.GetManyInput{
+		Table: aws.String(eventCtx.DeploymentTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load deployment history: %v", err)
+	}
+
+	for _, deploymentDoc := range expiredDeployments(deploymentDocs, retention, activeDeployment) {
+		if err := deleteArtifacts(transportCtx, eventCtx, deploymentDoc.ArtifactPath); err != nil {
+			return err
+		}
+		err := database.DeleteSingle[deployment.Deployment](transportCtx, eventCtx.DynamoClient, &database.DeleteSingleInput{
+			Table: aws.String(eventCtx.DeploymentTable),
+			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
+				"project_name":         &dynamodbtypes.AttributeValueMemberS{Value: deploymentDoc.ProjectName},
+				"execution_identifier": &dynamodbtypes.AttributeValueMemberS{Value: deploymentDoc.ExecutionIdentifier},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete deployment record: %v", err)
+		}
+		step.WriteLog("removed deployment %s", deploymentDoc.ExecutionIdentifier)
+	}
+
+	return nil
+}
+
+// expiredDeployments returns the deployments exceeding the retention, a retention <= 0 is replaced by the DEFAULT_DEPLOYMENT_RETENTION.
+// The active deployment occupies one slot of the retention, the remaining slots are used by the newest deployments.
+func expiredDeployments(deploymentDocs []deployment.Deployment, retention int64, activeDeployment string) []deployment.Deployment {
+	if retention <= 0 {
+		retention = DEFAULT_DEPLOYMENT_RETENTION
+	}
+
+	sortedDocs := append([]deployment.Deployment{}, deploymentDocs...)
+	sort.Slice(sortedDocs, func(i, j int) bool {
+		return sortedDocs[i].Timepoint > sortedDocs[j].Timepoint
+	})
+
+	expiredDocs := []deployment.Deployment{}
+	var retained int64 = 0
+	for _, deploymentDoc := range sortedDocs {
+		if deploymentDoc.ExecutionIdentifier == activeDeployment {
+			continue
+		}
+		if retained < retention-1 {
+			retained++
+			continue
+		}
+		expiredDocs = append(expiredDocs, deploymentDoc)
+	}
+	return expiredDocs
+}
+
+// deleteArtifacts removes all objects of the artifact path.
+func deleteArtifacts(transportCtx context.Context, eventCtx eventcontext.Context, artifactPath string) error {
+	bucketPathSegments := strings.SplitN(artifactPath, "/", 2)
+	if len(bucketPathSegments) != 2 {
+		return fmt.Errorf("failed to decode artifact path")
+	}
+	bucketName := bucketPathSegments[0]
+	// Check ensuring that, for whatever reason, bucketPrefix is NEVER "", which could lead to dangerous behavior.
+	if bucketPathSegments[1] == "" {
+		return fmt.Errorf("malformed artifact prefix detected")
+	}
+	bucketPrefix := fmt.Sprintf("%s/", bucketPathSegments[1])
+
+	err := pipeline.DeleteObjects(transportCtx, eventCtx.S3Client, eventCtx.TransferOptions, nil, bucketName, bucketPrefix, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete objects in artifact bucket: %v", err)
+	}
+	return nil
+}
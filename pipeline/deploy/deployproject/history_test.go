@@ -0,0 +1,45 @@
+package deployproject
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/megakuul/battleshiper/lib/model/deployment"
+)
+
+func TestExpiredDeployments(t *testing.T) {
+	// deployments d1 (oldest) to d8 (newest), d3 is the active deployment.
+	deploymentDocs := []deployment.Deployment{}
+	for i := 1; i <= 8; i++ {
+		deploymentDocs = append(deploymentDocs, deployment.Deployment{
+			ExecutionIdentifier: fmt.Sprintf("d%d", i),
+			Timepoint:           int64(i),
+		})
+	}
+
+	tests := []struct {
+		name      string
+		retention int64
+		expected  []string
+	}{
+		{name: "unset retention uses default", retention: 0, expected: []string{"d4", "d2", "d1"}},
+		{name: "negative retention uses default", retention: -1, expected: []string{"d4", "d2", "d1"}},
+		{name: "retention of one keeps the active deployment", retention: 1,
+			expected: []string{"d8", "d7", "d6", "d5", "d4", "d2", "d1"}},
+		{name: "retention of three", retention: 3, expected: []string{"d6", "d5", "d4", "d2", "d1"}},
+		{name: "retention exceeding history", retention: 20, expected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expired := []string{}
+			for _, deploymentDoc := range expiredDeployments(deploymentDocs, test.retention, "d3") {
+				expired = append(expired, deploymentDoc.ExecutionIdentifier)
+			}
+			if strings.Join(expired, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("expected expired deployments %v, got %v", test.expected, expired)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package deployproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
+)
+
+// INVALIDATION_MAX_PATHS limits the number of paths invalidated one by one,
+// larger diffs invalidate the whole project to stay below the cloudfront limit of concurrent invalidation paths.
+const INVALIDATION_MAX_PATHS = 1000
+
+// StaticDiff describes the difference between the static objects of a build and the objects of the live static version.
+// Unchanged and Removed objects reference the live static version, Added and Changed objects reference the build.
+type StaticDiff struct {
+	// Added objects do not exist in the live version.
+	Added []ObjectDescription
+	// Changed objects exist in the live version with a different etag or size.
+	Changed []ObjectDescription
+	// Unchanged objects are identical in the build and the live version.
+	Unchanged []ObjectDescription
+	// Removed objects exist in the live version but are not part of the build.
+	Removed []ObjectDescription
+}
+
+// Summary returns a one line description of the diff for the deployment log.
+func (d *StaticDiff) Summary() string {
+	return fmt.Sprintf("%d added, %d changed, %d removed, %d unchanged",
+		len(d.Added), len(d.Changed), len(d.Removed), len(d.Unchanged))
+}
+
+// TransferObjects returns the objects that must be copied to the new version, unchanged objects are referenced instead.
+func (d *StaticDiff) TransferObjects() []ObjectDescription {
+	objs := []ObjectDescription{}
+	objs = append(objs, d.Added...)
+	objs = append(objs, d.Changed...)
+	return objs
+}
+
+// InvalidatedKeys returns the relative keys whose cached content is outdated by the release (changed and removed objects).
+// Added objects have no cached content, the router does not allow caching of missing objects.
+func (d *StaticDiff) InvalidatedKeys() []string {
+	keys := []string{}
+	for _, obj := range d.Changed {
+		keys = append(keys, obj.RelativeKey)
+	}
+	for _, obj := range d.Removed {
+		keys = append(keys, obj.RelativeKey)
+	}
+	return keys
+}
+
+// RevertedKeys returns the relative keys whose cached content is outdated if the release is reverted (added and changed objects).
+func (d *StaticDiff) RevertedKeys() []string {
+	keys := []string{}
+	for _, obj := range d.TransferObjects() {
+		keys = append(keys, obj.RelativeKey)
+	}
+	return keys
+}
+
+// Manifest returns the manifest of the release. Unchanged objects reference their object in the live version,
+// added and changed objects reference their copy below the versionPrefix (e.g. "project/version/").
+func (d *StaticDiff) Manifest(versionPrefix string) *project.StaticManifest {
+	manifest := &project.StaticManifest{Objects: map[string]project.StaticObject{}}
+	for _, obj := range d.Unchanged {
+		manifest.Objects[obj.RelativeKey] = project.StaticObject{Key: obj.SourceKey, ETag: obj.ETag, Size: obj.Size}
+	}
+	for _, obj := range d.TransferObjects() {
+		manifest.Objects[obj.RelativeKey] = project.StaticObject{
+			Key:  fmt.Sprintf("%s%s", versionPrefix, obj.RelativeKey),
+			ETag: obj.ETag,
+			Size: obj.Size,
+		}
+	}
+	return manifest
+}
+
+// BridgeManifest extends the release manifest with the removed objects of the live version.
+func (d *StaticDiff) BridgeManifest(releaseManifest *project.StaticManifest) *project.StaticManifest {
+	manifest := &project.StaticManifest{Objects: map[string]project.StaticObject{}}
+	for relativeKey, obj := range releaseManifest.Objects {
+		manifest.Objects[relativeKey] = obj
+	}
+	for _, obj := range d.Removed {
+		manifest.Objects[obj.RelativeKey] = project.StaticObject{Key: obj.SourceKey, ETag: obj.ETag, Size: obj.Size}
+	}
+	return manifest
+}
+
+// diffStaticAssets compares the client and prerendered objects of the build with the objects of the live static version.
+func diffStaticAssets(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, liveVersion string, buildInformation *BuildInformation) (*StaticDiff, error) {
+	liveObjects, err := loadStaticObjects(transportCtx, eventCtx, projectDoc, liveVersion)
+	if err != nil {
+		return nil, err
+	}
+	buildObjects := append(append([]ObjectDescription{}, buildInformation.ClientObjects...), buildInformation.PrerenderedObjects...)
+	return diffStaticObjects(liveObjects, buildObjects), nil
+}
+
+// diffStaticObjects compares the build objects with the live objects by their relative key, etag and size.
+func diffStaticObjects(liveObjects map[string]ObjectDescription, buildObjects []ObjectDescription) *StaticDiff {
+	remainingObjects := map[string]ObjectDescription{}
+	for relativeKey, liveObj := range liveObjects {
+		remainingObjects[relativeKey] = liveObj
+	}
+
+	diff := &StaticDiff{}
+	for _, obj := range buildObjects {
+		liveObj, ok := remainingObjects[obj.RelativeKey]
+		if !ok {
+			diff.Added = append(diff.Added, obj)
+			continue
+		}
+		delete(remainingObjects, obj.RelativeKey)
+		// objects without etag (e.g. from emulators) are always treated as changed.
+		if obj.ETag == "" || obj.ETag != liveObj.ETag || obj.Size != liveObj.Size {
+			diff.Changed = append(diff.Changed, obj)
+			continue
+		}
+		diff.Unchanged = append(diff.Unchanged, liveObj)
+	}
+	for _, liveObj := range remainingObjects {
+		diff.Removed = append(diff.Removed, liveObj)
+	}
+	return diff
+}
+
+// loadStaticObjects reads the objects of the static version from the version manifest.
+// If the version is empty, the objects of the unversioned layout (directly below the project prefix) are listed,
+// versions released before manifests were introduced are listed below their version prefix.
+func loadStaticObjects(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, version string) (map[string]ObjectDescription, error) {
+	bucketName, bucketPrefix, err := staticBucketLocation(projectDoc)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		return listStaticObjects(transportCtx, eventCtx, bucketName, bucketPrefix)
+	}
+
+	manifestOutput, err := eventCtx.S3Client.GetObject(transportCtx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(fmt.Sprintf("%s%s%s", bucketPrefix, version, project.STATIC_MANIFEST_SUFFIX)),
+	})
+	if err != nil {
+		var nsk *s3types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return listStaticObjects(transportCtx, eventCtx, bucketName, fmt.Sprintf("%s%s/", bucketPrefix, version))
+		}
+		return nil, fmt.Errorf("failed to load manifest of static version %s: %v", version, err)
+	}
+	defer manifestOutput.Body.Close()
+
+	manifest := &project.StaticManifest{}
+	if err := json.NewDecoder(manifestOutput.Body).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest of static version %s: %v", version, err)
+	}
+	objects := map[string]ObjectDescription{}
+	for relativeKey, obj := range manifest.Objects {
+		objects[relativeKey] = ObjectDescription{
+			RelativeKey:  relativeKey,
+			SourceBucket: bucketName,
+			SourceKey:    obj.Key,
+			ETag:         obj.ETag,
+			Size:         obj.Size,
+		}
+	}
+	return objects, nil
+}
+
+// listStaticObjects lists the objects below the prefix of the static bucket by their key relative to the prefix.
+func listStaticObjects(transportCtx context.Context, eventCtx eventcontext.Context, bucketName, bucketPrefix string) (map[string]ObjectDescription, error) {
+	objects := map[string]ObjectDescription{}
+	paginator := s3.NewListObjectsV2Paginator(eventCtx.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(bucketPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(transportCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list live static objects: %v", err)
+		}
+		for _, obj := range page.Contents {
+			relativeKey := strings.TrimPrefix(aws.ToString(obj.Key), bucketPrefix)
+			objects[relativeKey] = ObjectDescription{
+				RelativeKey:  relativeKey,
+				SourceBucket: bucketName,
+				SourceKey:    aws.ToString(obj.Key),
+				ETag:         aws.ToString(obj.ETag),
+				Size:         aws.ToInt64(obj.Size),
+			}
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,138 @@
+package deployproject
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/megakuul/battleshiper/lib/model/project"
+)
+
+func TestDiffStaticObjects(t *testing.T) {
+	liveObjects := map[string]ObjectDescription{
+		"favicon.png":         {RelativeKey: "favicon.png", SourceKey: "project/v1/favicon.png", ETag: "a", Size: 1},
+		"_app/version.json":   {RelativeKey: "_app/version.json", SourceKey: "project/v0/_app/version.json", ETag: "b", Size: 2},
+		"_app/immutable/x.js": {RelativeKey: "_app/immutable/x.js", SourceKey: "project/v1/_app/immutable/x.js", ETag: "c", Size: 3},
+	}
+	buildObjects := []ObjectDescription{
+		{RelativeKey: "favicon.png", SourceKey: "build/favicon.png", ETag: "a", Size: 1},
+		{RelativeKey: "_app/version.json", SourceKey: "build/_app/version.json", ETag: "d", Size: 2},
+		{RelativeKey: "_app/immutable/y.js", SourceKey: "build/_app/immutable/y.js", ETag: "e", Size: 4},
+	}
+
+	diff := diffStaticObjects(liveObjects, buildObjects)
+	if diff.Summary() != "1 added, 1 changed, 1 removed, 1 unchanged" {
+		t.Fatalf("unexpected diff: %s", diff.Summary())
+	}
+	if len(liveObjects) != 3 {
+		t.Errorf("expected live objects to be unmodified, got %d objects", len(liveObjects))
+	}
+
+	release := diff.Manifest("project/v2/")
+	expectedRelease := map[string]project.StaticObject{
+		// unchanged objects reference the object of the version that introduced them.
+		"favicon.png":         {Key: "project/v1/favicon.png", ETag: "a", Size: 1},
+		"_app/version.json":   {Key: "project/v2/_app/version.json", ETag: "d", Size: 2},
+		"_app/immutable/y.js": {Key: "project/v2/_app/immutable/y.js", ETag: "e", Size: 4},
+	}
+	if !reflect.DeepEqual(release.Objects, expectedRelease) {
+		t.Errorf("expected release manifest %v, got %v", expectedRelease, release.Objects)
+	}
+
+	bridge := diff.BridgeManifest(release)
+	if len(bridge.Objects) != 4 || bridge.Objects["_app/immutable/x.js"].Key != "project/v1/_app/immutable/x.js" {
+		t.Errorf("expected bridge manifest to contain the removed object, got %v", bridge.Objects)
+	}
+	if len(release.Objects) != 3 {
+		t.Errorf("expected release manifest to be unmodified, got %d objects", len(release.Objects))
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{name: "invalidated keys", keys: diff.InvalidatedKeys(), expected: []string{"_app/immutable/x.js", "_app/version.json"}},
+		{name: "reverted keys", keys: diff.RevertedKeys(), expected: []string{"_app/immutable/y.js", "_app/version.json"}},
+		{name: "transferred keys", keys: relativeKeys(diff.TransferObjects()), expected: []string{"_app/immutable/y.js", "_app/version.json"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sort.Strings(test.keys)
+			if !reflect.DeepEqual(test.keys, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, test.keys)
+			}
+		})
+	}
+}
+
+func TestDiffStaticObjectsWithoutETag(t *testing.T) {
+	liveObjects := map[string]ObjectDescription{
+		"favicon.png": {RelativeKey: "favicon.png", SourceKey: "project/v1/favicon.png", Size: 1},
+	}
+	diff := diffStaticObjects(liveObjects, []ObjectDescription{{RelativeKey: "favicon.png", Size: 1}})
+	if len(diff.Changed) != 1 || len(diff.Unchanged) != 0 {
+		t.Errorf("expected object without etag to be changed, got %s", diff.Summary())
+	}
+}
+
+func TestReferencedStaticKeys(t *testing.T) {
+	keys := referencedStaticKeys("project/", map[string]map[string]ObjectDescription{
+		"v1": {"favicon.png": {SourceKey: "project/v0/favicon.png"}},
+		"v2": {"favicon.png": {SourceKey: "project/v0/favicon.png"}, "x.js": {SourceKey: "project/v2/x.js"}},
+	})
+
+	tests := []struct {
+		key      string
+		retained bool
+	}{
+		{key: "project/v1.manifest.json", retained: true},
+		{key: "project/v2.manifest.json", retained: true},
+		{key: "project/v0.manifest.json", retained: false},
+		{key: "project/v0/favicon.png", retained: true},
+		{key: "project/v2/x.js", retained: true},
+		{key: "project/v1/x.js", retained: false},
+		{key: "project/favicon.png", retained: false},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			if _, retained := keys[test.key]; retained != test.retained {
+				t.Errorf("expected retained %t, got %t", test.retained, retained)
+			}
+		})
+	}
+}
+
+func TestStaticInvalidationPaths(t *testing.T) {
+	manyKeys := []string{}
+	for i := 0; i <= INVALIDATION_MAX_PATHS; i++ {
+		manyKeys = append(manyKeys, fmt.Sprintf("%d.js", i))
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{name: "single key", keys: []string{"_app/version.json"}, expected: []string{"/project/_app/version.json"}},
+		{name: "escaped key", keys: []string{"images/my image.png"}, expected: []string{"/project/images/my%20image.png"}},
+		{name: "exceeded paths", keys: manyKeys, expected: []string{"/project/*"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paths := staticInvalidationPaths("project", test.keys)
+			if !reflect.DeepEqual(paths, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, paths)
+			}
+		})
+	}
+}
+
+func relativeKeys(objs []ObjectDescription) []string {
+	keys := []string{}
+	for _, obj := range objs {
+		keys = append(keys, obj.RelativeKey)
+	}
+	return keys
+}
@@ -0,0 +1,201 @@
+package deployproject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/deployment"
+	"github.com/megakuul/battleshiper/lib/model/event"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/lib/model/subscription"
+	"github.com/megakuul/battleshiper/lib/model/user"
+	"github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
+)
+
+// HandleRollbackProject restores a previous deployment of the project from its retained artifacts.
+func HandleRollbackProject(eventCtx eventcontext.Context) func(context.Context, events.CloudWatchEvent) error {
+	return func(ctx context.Context, event events.CloudWatchEvent) error {
+		err := runHandleRollbackProject(event, ctx, eventCtx)
+		if err != nil {
+			logger.Printf("%v\n", err)
+			return err
+		}
+		return nil
+	}
+}
+
+func runHandleRollbackProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) (err error) {
+	rollbackRequest := &event.RollbackRequest{}
+	if err := json.Unmarshal(request.Detail, &rollbackRequest); err != nil {
+		return fmt.Errorf("failed to deserialize rollback request")
+	}
+
+	rollbackClaims, err := pipeline.ParseTicket(transportCtx, eventCtx.TicketOptions, rollbackRequest.RollbackTicket)
+	if err != nil {
+		return fmt.Errorf("failed to parse ticket: %v", err)
+	}
+
+	if rollbackClaims.Action != request.DetailType {
+		return fmt.Errorf("action mismatch: provided ticket was not issued for the specified action")
+	}
+
+	// tickets are single-use, replayed events are rejected before they touch any resources.
+	// A failed execution releases the ticket, so that retries of the event are accepted.
+	if err := pipeline.ClaimTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, rollbackClaims); err != nil {
+		if errors.Is(err, pipeline.ErrTicketConsumed) {
+			return fmt.Errorf("ticket replay rejected: ticket of execution '%s' was already consumed", rollbackClaims.ID)
+		}
+		return err
+	}
+	defer func() {
+		if fErr := pipeline.FinishTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, rollbackClaims, err); fErr != nil {
+			logger.Printf("%v\n", fErr)
+		}
+	}()
+
+	userDoc, err := database.GetSingle[user.User](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(eventCtx.UserTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":id": &dynamodbtypes.AttributeValueMemberS{Value: rollbackClaims.UserID},
+		},
+		ConditionExpr: aws.String("id = :id"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to load user record from database: %v", err)
+	}
+
+	projectDoc, err := database.GetSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(eventCtx.ProjectTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name": &dynamodbtypes.AttributeValueMemberS{Value: rollbackClaims.Project},
+		},
+		ConditionExpr: aws.String("project_name = :project_name"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			return fmt.Errorf("project not found")
+		}
+		return fmt.Errorf("failed to load project from database: %v", err)
+	}
+	if projectDoc.OwnerId != rollbackClaims.UserID {
+		return fmt.Errorf("user '%s' is not authorized to roll back this project", rollbackClaims.UserID)
+	}
+	if projectDoc.Deleted {
+		return fmt.Errorf("project cannot be rolled back: it is marked for deletion")
+	}
+
+	// a rollback counts as deployment, it is rejected like a deployment if the quota is exhausted.
+	quotaUsage, err := pipeline.CheckQuota(transportCtx, eventCtx.DynamoClient, &pipeline.CheckQuotaInput{
+		UserTable:         eventCtx.UserTable,
+		SubscriptionTable: eventCtx.SubscriptionTable,
+		UserDoc:           *userDoc,
+		Name:              pipeline.QUOTA_PIPELINE_DEPLOYMENTS,
+	})
+	if err != nil {
+		if !errors.Is(err, pipeline.ErrQuotaExceeded) {
+			return fmt.Errorf("failed to check deployment quota: %v", err)
+		}
+		return rejectDeployment(transportCtx, eventCtx, projectDoc, rollbackClaims.ID, err)
+	}
+
+	projectDoc, err = lockProject(transportCtx, eventCtx, projectDoc)
+	if err != nil {
+		// the rollback is not executed if the project is locked, therefore the counted deployment is refunded.
+		if rErr := pipeline.RefundQuota(transportCtx, eventCtx.DynamoClient, quotaUsage); rErr != nil {
+			logger.Printf("failed to refund deployment quota: %v\n", rErr)
+		}
+		return err
+	}
+
+	rollbackErr := rollbackProject(transportCtx, eventCtx, projectDoc, userDoc.SubscriptionId, rollbackClaims.ID, rollbackRequest.DeploymentIdentifier)
+	return finishDeployment(transportCtx, eventCtx, projectDoc, rollbackClaims.ID, rollbackErr)
+}
+
+// rollbackProject releases the retained artifacts of the deployment without rebuilding the project.
+func rollbackProject(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, subscriptionId, execId, deploymentId string) (err error) {
+	cloudLogger, err := pipeline.NewCloudLogger(
+		transportCtx,
+		eventCtx.CloudwatchClient,
+		projectDoc.DedicatedInfrastructure.DeployLogGroup,
+		execId,
+		pipeline.LOG_PHASE_DEPLOY,
+		eventCtx.CloudLoggerOptions,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := cloudLogger.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
+
+	rollbackStep := cloudLogger.StartStep("rollback", fmt.Sprintf("START ROLLBACK %s TO DEPLOYMENT %s", execId, deploymentId))
+	defer func() {
+		rollbackStep.Finish(err)
+	}()
+
+	step := cloudLogger.StartStep("fetch_subscription", "fetching subscription...")
+	subscriptionDoc, err := database.GetSingle[subscription.Subscription](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(eventCtx.SubscriptionTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":id": &dynamodbtypes.AttributeValueMemberS{Value: subscriptionId},
+		},
+		ConditionExpr: aws.String("id = :id"),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to fetch subscription from database")
+		step.Finish(err)
+		return err
+	}
+	step.Finish(nil)
+
+	step = cloudLogger.StartStep("fetch_deployment", "fetching deployment record...")
+	deploymentDoc, err := database.GetSingle[deployment.Deployment](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
+		Table: aws.String(eventCtx.DeploymentTable),
+		AttributeValues: map[string]dynamodbtypes.AttributeValue{
+			":project_name":         &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
+			":execution_identifier": &dynamodbtypes.AttributeValueMemberS{Value: deploymentId},
+		},
+		ConditionExpr: aws.String("project_name = :project_name AND execution_identifier = :execution_identifier"),
+	})
+	if err != nil {
+		var cErr *dynamodbtypes.ConditionalCheckFailedException
+		if ok := errors.As(err, &cErr); ok {
+			err = fmt.Errorf("deployment '%s' is not retained", deploymentId)
+		} else {
+			err = fmt.Errorf("failed to fetch deployment from database")
+		}
+		step.Finish(err)
+		return err
+	}
+	if deploymentDoc.Commit != "" {
+		step.WriteLog("restoring commit %s", deploymentDoc.Commit)
+	}
+	step.Finish(nil)
+
+	// the artifacts are analyzed again, the subscription limits may have changed since the deployment.
+	step = cloudLogger.StartStep("analyze_assets", "analyzing retained build assets...")
+	buildInformation, err := analyzeBuildAssets(transportCtx, eventCtx, projectDoc, subscriptionDoc, deploymentDoc.ArtifactPath)
+	step.Finish(err)
+	if err != nil {
+		return err
+	}
+
+	// a rollback replaces a faulty release, the restored server version takes over all traffic at once.
+	rollbackShift := project.TrafficShift{Strategy: project.TRAFFIC_SHIFT_ALL_AT_ONCE}
+	return releaseProject(transportCtx, eventCtx, cloudLogger, projectDoc, buildInformation, rollbackShift, execId, deploymentDoc.ExecutionIdentifier)
+}
@@ -1,8 +1,12 @@
 package deployproject
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -11,113 +15,144 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
 	cloudfrontkeyvaluetypes "github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 	"github.com/megakuul/battleshiper/lib/model/project"
 	"github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
 )
 
-func cleanStaticBucket(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) error {
+// STATIC_BRIDGE_SUFFIX is appended to the release version to build the static version that is live while traffic is shifted.
+const STATIC_BRIDGE_SUFFIX = "-bridge"
+
+// staticBucketLocation decodes the static bucket path of the project into the bucket name and the project prefix (e.g. "project/").
+func staticBucketLocation(projectDoc *project.Project) (string, string, error) {
 	bucketPathSegments := strings.SplitN(projectDoc.SharedInfrastructure.StaticBucketPath, "/", 2)
 	if len(bucketPathSegments) != 2 {
-		return fmt.Errorf("failed to decode static bucket path")
+		return "", "", fmt.Errorf("failed to decode static bucket path")
 	}
-	bucketName := bucketPathSegments[0]
 	// Check ensuring that, for whatever reason, bucketPrefix is NEVER "", which could lead to dangerous behavior.
 	if bucketPathSegments[1] == "" {
-		return fmt.Errorf("malformed bucket prefix detected")
+		return "", "", fmt.Errorf("malformed bucket prefix detected")
 	}
-	bucketPrefix := fmt.Sprintf("%s/", bucketPathSegments[1])
-
-	paginator := s3.NewListObjectsV2Paginator(eventCtx.S3Client, &s3.ListObjectsV2Input{
-		Bucket:  aws.String(bucketName),
-		Prefix:  aws.String(bucketPrefix),
-		MaxKeys: aws.Int32(1000), // DeleteObjects call deletes max 1000 objects, one page should only use one DeleteObjects call.
-	})
+	return bucketPathSegments[0], fmt.Sprintf("%s/", bucketPathSegments[1]), nil
+}
 
-	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(transportCtx)
-		if err != nil {
-			return fmt.Errorf("failed to list objects: %v", err)
-		}
+// releaseStaticVersion copies the added and changed objects of the diff to the version prefix and writes the version manifest.
+// Unchanged objects are not copied, the manifest references their existing objects.
+func releaseStaticVersion(transportCtx context.Context, eventCtx eventcontext.Context, step *pipeline.LogStep, projectDoc *project.Project, version string, diff *StaticDiff) (*project.StaticManifest, error) {
+	bucketName, bucketPrefix, err := staticBucketLocation(projectDoc)
+	if err != nil {
+		return nil, err
+	}
+	versionPrefix := fmt.Sprintf("%s%s/", bucketPrefix, version)
 
-		deleteObjects := []s3types.ObjectIdentifier{}
-		for _, object := range page.Contents {
-			deleteObjects = append(deleteObjects, s3types.ObjectIdentifier{
-				Key: object.Key,
-			})
-		}
+	err = pipeline.CopyObjects(transportCtx, eventCtx.S3Client, eventCtx.TransferOptions, step,
+		staticObjectCopies(bucketName, versionPrefix, diff.TransferObjects()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy objects to static version: %v", err)
+	}
 
-		if len(deleteObjects) < 1 {
-			continue
-		}
+	manifest := diff.Manifest(versionPrefix)
+	if err := writeStaticManifest(transportCtx, eventCtx, projectDoc, version, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
 
-		_, err = eventCtx.S3Client.DeleteObjects(transportCtx, &s3.DeleteObjectsInput{
-			Bucket: aws.String(bucketName),
-			Delete: &s3types.Delete{
-				Objects: deleteObjects,
-				Quiet:   aws.Bool(true),
-			},
+// staticObjectCopies maps the objects to their relative key below the prefix of the static bucket.
+func staticObjectCopies(bucketName, bucketPrefix string, objs []ObjectDescription) []pipeline.ObjectCopy {
+	copies := []pipeline.ObjectCopy{}
+	for _, obj := range objs {
+		copies = append(copies, pipeline.ObjectCopy{
+			SourceBucket: obj.SourceBucket,
+			SourceKey:    obj.SourceKey,
+			Bucket:       bucketName,
+			Key:          fmt.Sprintf("%s%s", bucketPrefix, obj.RelativeKey),
 		})
-		if err != nil {
-			return fmt.Errorf("failed to delete objects in static bucket: %v", err)
-		}
 	}
+	return copies
+}
 
+// writeStaticManifest writes the manifest of the static version next to the version prefix.
+// The manifest is written after the objects, a version is never switched live with incomplete objects.
+func writeStaticManifest(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, version string, manifest *project.StaticManifest) error {
+	bucketName, bucketPrefix, err := staticBucketLocation(projectDoc)
+	if err != nil {
+		return err
+	}
+	manifestRaw, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to serialize static manifest: %v", err)
+	}
+	_, err = eventCtx.S3Client.PutObject(transportCtx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(fmt.Sprintf("%s%s%s", bucketPrefix, version, project.STATIC_MANIFEST_SUFFIX)),
+		Body:        bytes.NewReader(manifestRaw),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write static manifest: %v", err)
+	}
 	return nil
 }
 
-func copyStaticAssets(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, assets []ObjectDescription) error {
-	bucketPathSegments := strings.SplitN(projectDoc.SharedInfrastructure.StaticBucketPath, "/", 2)
-	if len(bucketPathSegments) != 2 {
-		return fmt.Errorf("failed to decode static bucket path")
+// cleanStaticVersions removes all static objects of the project that are not referenced by one of the retained versions.
+// This includes the manifests of removed versions and objects of the unversioned layout used before static assets were versioned.
+func cleanStaticVersions(transportCtx context.Context, eventCtx eventcontext.Context, step *pipeline.LogStep, projectDoc *project.Project, retainedVersions ...string) error {
+	bucketName, bucketPrefix, err := staticBucketLocation(projectDoc)
+	if err != nil {
+		return err
 	}
-	bucketName := bucketPathSegments[0]
-	bucketPrefix := fmt.Sprintf("%s/", bucketPathSegments[1])
 
-	for _, obj := range assets {
-		_, err := eventCtx.S3Client.CopyObject(transportCtx, &s3.CopyObjectInput{
-			Bucket:     aws.String(bucketName),
-			CopySource: aws.String(fmt.Sprintf("%s/%s", obj.SourceBucket, obj.SourceKey)),
-			Key:        aws.String(fmt.Sprintf("%s%s", bucketPrefix, obj.RelativeKey)),
-		})
+	retainedManifests := map[string]map[string]ObjectDescription{}
+	for _, version := range retainedVersions {
+		if version == "" {
+			return fmt.Errorf("malformed static version detected")
+		}
+		// a retained version without readable manifest aborts the cleanup, its objects are unknown.
+		objects, err := loadStaticObjects(transportCtx, eventCtx, projectDoc, version)
 		if err != nil {
-			return fmt.Errorf("failed to move build assets to static bucket: %v", err)
+			return err
 		}
+		retainedManifests[version] = objects
 	}
+	retainedKeys := referencedStaticKeys(bucketPrefix, retainedManifests)
 
+	err = pipeline.DeleteObjects(transportCtx, eventCtx.S3Client, eventCtx.TransferOptions, step, bucketName, bucketPrefix, func(key string) bool {
+		_, retained := retainedKeys[key]
+		return !retained
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete objects in static bucket: %v", err)
+	}
 	return nil
 }
 
-func copyStaticPages(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, pages []ObjectDescription) error {
-	bucketPathSegments := strings.SplitN(projectDoc.SharedInfrastructure.StaticBucketPath, "/", 2)
-	if len(bucketPathSegments) != 2 {
-		return fmt.Errorf("failed to decode static bucket path")
-	}
-	bucketName := bucketPathSegments[0]
-	bucketPrefix := fmt.Sprintf("%s/", bucketPathSegments[1])
-
-	for _, obj := range pages {
-		_, err := eventCtx.S3Client.CopyObject(transportCtx, &s3.CopyObjectInput{
-			Bucket:     aws.String(bucketName),
-			CopySource: aws.String(fmt.Sprintf("%s/%s", obj.SourceBucket, obj.SourceKey)),
-			Key:        aws.String(fmt.Sprintf("%s%s", bucketPrefix, obj.RelativeKey)),
-		})
-		if err != nil {
-			return fmt.Errorf("failed to move build pages to static bucket: %v", err)
+// referencedStaticKeys returns the static bucket keys of the manifests and of all objects referenced by them.
+func referencedStaticKeys(bucketPrefix string, manifests map[string]map[string]ObjectDescription) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for version, objects := range manifests {
+		keys[fmt.Sprintf("%s%s%s", bucketPrefix, version, project.STATIC_MANIFEST_SUFFIX)] = struct{}{}
+		for _, obj := range objects {
+			keys[obj.SourceKey] = struct{}{}
 		}
 	}
-
-	return nil
+	return keys
 }
 
-func invalidateStaticCache(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, execIdentifier string) error {
+// invalidateStaticObjects drops the cached content of the relative keys on the cdn.
+// If the keys exceed the INVALIDATION_MAX_PATHS, the whole project is invalidated instead.
+func invalidateStaticObjects(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, callerReference string, relativeKeys []string) error {
+	if len(relativeKeys) < 1 {
+		return nil
+	}
+	paths := staticInvalidationPaths(projectDoc.ProjectName, relativeKeys)
 	_, err := eventCtx.CloudfrontClient.CreateInvalidation(transportCtx, &cloudfront.CreateInvalidationInput{
 		DistributionId: aws.String(eventCtx.ProjectConfiguration.CloudfrontDistributionId),
 		InvalidationBatch: &cloudfronttypes.InvalidationBatch{
-			CallerReference: aws.String(fmt.Sprintf("%s-%s", projectDoc.ProjectName, execIdentifier)),
+			CallerReference: aws.String(callerReference),
 			Paths: &cloudfronttypes.Paths{
-				Quantity: aws.Int32(1),
-				Items:    []string{fmt.Sprintf("/%s/*", projectDoc.ProjectName)},
+				Quantity: aws.Int32(int32(len(paths))),
+				Items:    paths,
 			},
 		},
 	})
@@ -127,28 +162,62 @@ func invalidateStaticCache(transportCtx context.Context, eventCtx eventcontext.C
 	return nil
 }
 
-func updateStaticPageKeys(transportCtx context.Context, eventCtx eventcontext.Context, newStaticPages map[string]string, oldStaticPages map[string]string) error {
-	addStaticPageKeys := []cloudfrontkeyvaluetypes.PutKeyRequestListItem{}
+// staticInvalidationPaths maps the relative keys to the paths cached by the cdn (the cdn function prepends the project).
+func staticInvalidationPaths(projectName string, relativeKeys []string) []string {
+	if len(relativeKeys) > INVALIDATION_MAX_PATHS {
+		return []string{fmt.Sprintf("/%s/*", projectName)}
+	}
+	paths := []string{}
+	for _, relativeKey := range relativeKeys {
+		segments := strings.Split(relativeKey, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		paths = append(paths, fmt.Sprintf("/%s/%s", projectName, strings.Join(segments, "/")))
+	}
+	return paths
+}
+
+// fetchStaticVersion reads the live static asset version of the project from the cdn store.
+// If the project was never released with a versioned layout, an empty version is returned.
+func fetchStaticVersion(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) (string, error) {
+	versionOutput, err := eventCtx.CloudfrontCacheClient.GetKey(transportCtx, &cloudfrontkeyvaluestore.GetKeyInput{
+		KvsARN: aws.String(eventCtx.ProjectConfiguration.CloudfrontCacheArn),
+		Key:    aws.String(fmt.Sprintf("%s%s", project.STATIC_VERSION_KEY_PREFIX, projectDoc.ProjectName)),
+	})
+	if err != nil {
+		var rnfe *cloudfrontkeyvaluetypes.ResourceNotFoundException
+		if ok := errors.As(err, &rnfe); ok {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to fetch static version from cdn store: %v", err)
+	}
+	return aws.ToString(versionOutput.Value), nil
+}
+
+// switchStaticVersion points the project to the static asset version and replaces the page keys.
+// Both are updated with one store update, requests either resolve the old or the new version but never a mix.
+func switchStaticVersion(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, version string, newStaticPages map[string]string, oldStaticPages map[string]string) error {
+	addStaticKeys := []cloudfrontkeyvaluetypes.PutKeyRequestListItem{{
+		Key:   aws.String(fmt.Sprintf("%s%s", project.STATIC_VERSION_KEY_PREFIX, projectDoc.ProjectName)),
+		Value: aws.String(version),
+	}}
 	for key, path := range newStaticPages {
-		addStaticPageKeys = append(addStaticPageKeys, cloudfrontkeyvaluetypes.PutKeyRequestListItem{
+		addStaticKeys = append(addStaticKeys, cloudfrontkeyvaluetypes.PutKeyRequestListItem{
 			Key:   aws.String(key),
 			Value: aws.String(path),
 		})
 	}
 
-	deleteStaticPageKeys := []cloudfrontkeyvaluetypes.DeleteKeyRequestListItem{}
+	deleteStaticKeys := []cloudfrontkeyvaluetypes.DeleteKeyRequestListItem{}
 	for key := range oldStaticPages {
 		if _, exists := newStaticPages[key]; !exists {
-			deleteStaticPageKeys = append(deleteStaticPageKeys, cloudfrontkeyvaluetypes.DeleteKeyRequestListItem{
+			deleteStaticKeys = append(deleteStaticKeys, cloudfrontkeyvaluetypes.DeleteKeyRequestListItem{
 				Key: aws.String(key),
 			})
 		}
 	}
 
-	if len(addStaticPageKeys) < 1 && len(deleteStaticPageKeys) < 1 {
-		return nil
-	}
-
 	storeMetadata, err := eventCtx.CloudfrontCacheClient.DescribeKeyValueStore(transportCtx, &cloudfrontkeyvaluestore.DescribeKeyValueStoreInput{
 		KvsARN: aws.String(eventCtx.ProjectConfiguration.CloudfrontCacheArn),
 	})
@@ -157,8 +226,8 @@ func updateStaticPageKeys(transportCtx context.Context, eventCtx eventcontext.Co
 	}
 	_, err = eventCtx.CloudfrontCacheClient.UpdateKeys(transportCtx, &cloudfrontkeyvaluestore.UpdateKeysInput{
 		KvsARN:  aws.String(eventCtx.ProjectConfiguration.CloudfrontCacheArn),
-		Puts:    addStaticPageKeys,
-		Deletes: deleteStaticPageKeys,
+		Puts:    addStaticKeys,
+		Deletes: deleteStaticKeys,
 		IfMatch: storeMetadata.ETag,
 	})
 	if err != nil {
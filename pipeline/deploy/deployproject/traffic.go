@@ -0,0 +1,237 @@
+package deployproject
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatchtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
+	"github.com/megakuul/battleshiper/lib/model/project"
+	"github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
+)
+
+// SERVER_ALIAS is the alias of the server function that is invoked by the router.
+const SERVER_ALIAS = "live"
+
+// RELEASE_REVERT_TIMEOUT limits reverts of failed releases, they run detached from the transport context.
+const RELEASE_REVERT_TIMEOUT = 30 * time.Second
+
+// publishServerVersion publishes the current code and configuration of the server function as immutable version.
+// If nothing changed since the last published version, lambda returns the existing version.
+func publishServerVersion(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, execId string) (string, error) {
+	versionOutput, err := eventCtx.FunctionClient.PublishVersion(transportCtx, &lambda.PublishVersionInput{
+		FunctionName: aws.String(fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)),
+		Description:  aws.String(execId),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to publish server version: %v", err)
+	}
+	return aws.ToString(versionOutput.Version), nil
+}
+
+// fetchServerVersion reads the version the live alias of the server function points to.
+// If the alias does not exist yet, an empty version is returned.
+func fetchServerVersion(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) (string, error) {
+	aliasOutput, err := eventCtx.FunctionClient.GetAlias(transportCtx, &lambda.GetAliasInput{
+		FunctionName: aws.String(fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)),
+		Name:         aws.String(SERVER_ALIAS),
+	})
+	if err != nil {
+		var rnfe *lambdatypes.ResourceNotFoundException
+		if ok := errors.As(err, &rnfe); ok {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to fetch server alias: %v", err)
+	}
+	return aws.ToString(aliasOutput.FunctionVersion), nil
+}
+
+// routeServerVersion points the live alias to the version and sends the weighted share of the traffic to the canary version.
+// With a weight of 0 the additional routing is removed and all traffic is sent to the version.
+func routeServerVersion(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, version, canaryVersion string, weight int64) error {
+	routingConfig := &lambdatypes.AliasRoutingConfiguration{
+		AdditionalVersionWeights: map[string]float64{},
+	}
+	if weight > 0 {
+		routingConfig.AdditionalVersionWeights[canaryVersion] = float64(weight) / 100
+	}
+
+	_, err := eventCtx.FunctionClient.UpdateAlias(transportCtx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)),
+		Name:            aws.String(SERVER_ALIAS),
+		FunctionVersion: aws.String(version),
+		RoutingConfig:   routingConfig,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update server alias: %v", err)
+	}
+	return nil
+}
+
+// shiftServerTraffic moves the live alias of the server function from the previous to the new version.
+// Canary and linear strategies route the traffic in steps and watch the error rate of the new version after every step,
+// if the error threshold is exceeded, the alias is reverted to the previous version and an error is returned.
+func shiftServerTraffic(transportCtx context.Context, eventCtx eventcontext.Context, step *pipeline.LogStep, projectDoc *project.Project, shift project.TrafficShift, previousVersion, version string) error {
+	if previousVersion == "" {
+		_, err := eventCtx.FunctionClient.CreateAlias(transportCtx, &lambda.CreateAliasInput{
+			FunctionName:    aws.String(fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)),
+			Name:            aws.String(SERVER_ALIAS),
+			FunctionVersion: aws.String(version),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create server alias: %v", err)
+		}
+		step.WriteLog("created live alias on version %s", version)
+		return nil
+	}
+	if previousVersion == version {
+		step.WriteLog("server function is unchanged; live alias stays on version %s", version)
+		return nil
+	}
+
+	weights := shift.ShiftWeights()
+	if len(weights) < 1 {
+		step.WriteLog("shifting all traffic from version %s to %s", previousVersion, version)
+		return routeServerVersion(transportCtx, eventCtx, projectDoc, version, "", 0)
+	}
+
+	shiftStart := time.Now()
+	err := func() error {
+		for _, weight := range weights {
+			if err := routeServerVersion(transportCtx, eventCtx, projectDoc, previousVersion, version, weight); err != nil {
+				return err
+			}
+			step.WriteLog("routing %d%% of the traffic to version %s", weight, version)
+
+			select {
+			case <-transportCtx.Done():
+				return fmt.Errorf("traffic shift interrupted: %v", transportCtx.Err())
+			case <-time.After(time.Duration(shift.Interval) * time.Second):
+			}
+
+			failures, invocations, err := fetchServerErrors(transportCtx, eventCtx, projectDoc, version, shiftStart)
+			if err != nil {
+				return err
+			}
+			if invocations < 1 {
+				step.WriteLog("version %s received no invocations yet", version)
+				continue
+			}
+			errorRate := failures / invocations * 100
+			step.WriteLog("version %s failed %.0f of %.0f invocations (%.2f%%)", version, failures, invocations, errorRate)
+			if errorRate > float64(shift.ErrorThreshold) {
+				return fmt.Errorf("error rate of version %s (%.2f%%) exceeded threshold of %d%%", version, errorRate, shift.ErrorThreshold)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		// the revert must also succeed if the shift was interrupted by the transport context.
+		revertCtx, cancel := context.WithTimeout(context.Background(), RELEASE_REVERT_TIMEOUT)
+		defer cancel()
+		if revertErr := routeServerVersion(revertCtx, eventCtx, projectDoc, previousVersion, "", 0); revertErr != nil {
+			return fmt.Errorf("%v; failed to revert live alias to version %s: %v", err, previousVersion, revertErr)
+		}
+		step.WriteLog("reverted live alias to version %s", previousVersion)
+		return err
+	}
+
+	step.WriteLog("shifting remaining traffic to version %s", version)
+	return routeServerVersion(transportCtx, eventCtx, projectDoc, version, "", 0)
+}
+
+// fetchServerErrors sums the errors and invocations the version served through the live alias since the start time.
+func fetchServerErrors(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, version string, start time.Time) (float64, float64, error) {
+	functionName := fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)
+	dimensions := []cloudwatchtypes.Dimension{
+		{Name: aws.String("FunctionName"), Value: aws.String(functionName)},
+		{Name: aws.String("Resource"), Value: aws.String(fmt.Sprintf("%s:%s", functionName, SERVER_ALIAS))},
+		{Name: aws.String("ExecutedVersion"), Value: aws.String(version)},
+	}
+	metricQuery := func(id, metricName string) cloudwatchtypes.MetricDataQuery {
+		return cloudwatchtypes.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &cloudwatchtypes.MetricStat{
+				Metric: &cloudwatchtypes.Metric{
+					Namespace:  aws.String("AWS/Lambda"),
+					MetricName: aws.String(metricName),
+					Dimensions: dimensions,
+				},
+				Period: aws.Int32(60),
+				Stat:   aws.String("Sum"),
+			},
+		}
+	}
+
+	metricOutput, err := eventCtx.MetricClient.GetMetricData(transportCtx, &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(start.Add(-time.Minute).Truncate(time.Minute)),
+		EndTime:   aws.Time(time.Now()),
+		MetricDataQueries: []cloudwatchtypes.MetricDataQuery{
+			metricQuery("errors", "Errors"),
+			metricQuery("invocations", "Invocations"),
+		},
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to fetch server metrics: %v", err)
+	}
+
+	var failures, invocations float64
+	for _, result := range metricOutput.MetricDataResults {
+		sum := 0.0
+		for _, value := range result.Values {
+			sum += value
+		}
+		switch aws.ToString(result.Id) {
+		case "errors":
+			failures = sum
+		case "invocations":
+			invocations = sum
+		}
+	}
+	return failures, invocations, nil
+}
+
+// cleanServerVersions deletes all published versions of the server function except the retained versions.
+func cleanServerVersions(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project, retainedVersions ...string) error {
+	functionName := fmt.Sprintf("%s%s", eventCtx.ProjectConfiguration.ServerNamePrefix, projectDoc.ProjectName)
+
+	retained := map[string]struct{}{}
+	for _, version := range retainedVersions {
+		retained[version] = struct{}{}
+	}
+
+	paginator := lambda.NewListVersionsByFunctionPaginator(eventCtx.FunctionClient, &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(transportCtx)
+		if err != nil {
+			return fmt.Errorf("failed to list server versions: %v", err)
+		}
+		for _, function := range page.Versions {
+			version := aws.ToString(function.Version)
+			// $LATEST is part of the version list but cannot be deleted with a qualifier.
+			if _, err := strconv.Atoi(version); err != nil {
+				continue
+			}
+			if _, ok := retained[version]; ok {
+				continue
+			}
+			_, err := eventCtx.FunctionClient.DeleteFunction(transportCtx, &lambda.DeleteFunctionInput{
+				FunctionName: aws.String(functionName),
+				Qualifier:    aws.String(version),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete server version %s: %v", version, err)
+			}
+		}
+	}
+	return nil
+}
@@ -6,9 +6,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 )
 
@@ -17,6 +21,11 @@ type DeploymentConfiguration struct {
 	DeplyomentTimeout time.Duration
 }
 
+type BucketConfiguration struct {
+	// ArtifactBucketName is the bucket where the build output of deployments is retained for rollbacks.
+	ArtifactBucketName string
+}
+
 type ProjectConfiguration struct {
 	ServerNamePrefix         string
 	ServerRuntime            string
@@ -31,13 +40,22 @@ type Context struct {
 	DynamoClient            *dynamodb.Client
 	UserTable               string
 	ProjectTable            string
+	TicketTable             string
 	SubscriptionTable       string
+	DeploymentTable         string
 	TicketOptions           *pipeline.TicketOptions
+	EnvironmentCipher       *auth.Cipher
 	CloudformationClient    *cloudformation.Client
+	SecretClient            *secretsmanager.Client
 	S3Client                *s3.Client
 	CloudwatchClient        *cloudwatchlogs.Client
+	CloudLoggerOptions      *pipeline.CloudLoggerOptions
+	TransferOptions         *pipeline.TransferOptions
 	CloudfrontClient        *cloudfront.Client
 	CloudfrontCacheClient   *cloudfrontkeyvaluestore.Client
+	FunctionClient          *lambda.Client
+	MetricClient            *cloudwatch.Client
 	DeploymentConfiguration *DeploymentConfiguration
+	BucketConfiguration     *BucketConfiguration
 	ProjectConfiguration    *ProjectConfiguration
 }
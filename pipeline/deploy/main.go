@@ -8,14 +8,19 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfrontkeyvaluestore"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	function "github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 	"github.com/megakuul/battleshiper/pipeline/deploy/deployproject"
 	"github.com/megakuul/battleshiper/pipeline/deploy/eventcontext"
@@ -26,16 +31,22 @@ var (
 	BOOTSTRAP_TIMEOUT          = os.Getenv("BOOTSTRAP_TIMEOUT")
 	USERTABLE                  = os.Getenv("USERTABLE")
 	PROJECTTABLE               = os.Getenv("PROJECTTABLE")
+	TICKETTABLE                = os.Getenv("TICKETTABLE")
 	SUBSCRIPTIONTABLE          = os.Getenv("SUBSCRIPTIONTABLE")
-	TICKET_CREDENTIAL_ARN      = os.Getenv("TICKET_CREDENTIAL_ARN")
+	DEPLOYMENTTABLE            = os.Getenv("DEPLOYMENTTABLE")
+	TICKET_PUBLIC_KEY_ARN      = os.Getenv("TICKET_PUBLIC_KEY_ARN")
+	ENVIRONMENT_CREDENTIAL_ARN = os.Getenv("ENVIRONMENT_CREDENTIAL_ARN")
 	CHANGESET_TIMEOUT          = os.Getenv("CHANGESET_TIMEOUT")
 	DEPLOYMENT_TIMEOUT         = os.Getenv("DEPLOYMENT_TIMEOUT")
+	ROLLBACK_EVENT_ACTION      = os.Getenv("ROLLBACK_EVENT_ACTION")
+	ARTIFACT_BUCKET_NAME       = os.Getenv("ARTIFACT_BUCKET_NAME")
 	CLOUDFRONT_DISTRIBUTION_ID = os.Getenv("CLOUDFRONT_DISTRIBUTION_ID")
 	CLOUDFRONT_CACHE_ARN       = os.Getenv("CLOUDFRONT_CACHE_ARN")
 	SERVER_NAME_PREFIX         = os.Getenv("SERVER_NAME_PREFIX")
 	SERVER_RUNTIME             = os.Getenv("SERVER_RUNTIME")
 	SERVER_MEMORY              = os.Getenv("SERVER_MEMORY")
 	SERVER_TIMEOUT             = os.Getenv("SERVER_TIMEOUT")
+	TRANSFER_CONCURRENCY       = os.Getenv("TRANSFER_CONCURRENCY")
 )
 
 func main() {
@@ -68,11 +79,23 @@ func run() error {
 
 	cloudfrontCacheClient := cloudfrontkeyvaluestore.NewFromConfig(awsConfig)
 
+	functionClient := function.NewFromConfig(awsConfig)
+
+	metricClient := cloudwatch.NewFromConfig(awsConfig)
+
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
-	ticketOptions, err := pipeline.CreateTicketOptions(awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, "", "", 0)
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+
+	ticketVerifier, err := auth.CreateVerifier(awsConfig, bootstrapContext, TICKET_PUBLIC_KEY_ARN)
+	if err != nil {
+		return fmt.Errorf("failed to load ticket public keys: %v", err)
+	}
+	ticketOptions := pipeline.CreateTicketVerificationOptions(ticketVerifier)
+
+	environmentCipher, err := auth.CreateCipher(awsConfig, bootstrapContext, ENVIRONMENT_CREDENTIAL_ARN)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load environment cipher: %v", err)
 	}
 
 	changesetTimeout, err := time.ParseDuration(CHANGESET_TIMEOUT)
@@ -95,21 +118,36 @@ func run() error {
 		return fmt.Errorf("failed to parse SERVER_TIMEOUT environment variable")
 	}
 
-	lambda.Start(deployproject.HandleDeployProject(eventcontext.Context{
+	transferConcurrency, err := strconv.Atoi(TRANSFER_CONCURRENCY)
+	if err != nil {
+		return fmt.Errorf("failed to parse TRANSFER_CONCURRENCY environment variable")
+	}
+
+	eventCtx := eventcontext.Context{
 		DynamoClient:          dynamoClient,
 		UserTable:             USERTABLE,
 		ProjectTable:          PROJECTTABLE,
+		TicketTable:           TICKETTABLE,
 		SubscriptionTable:     SUBSCRIPTIONTABLE,
+		DeploymentTable:       DEPLOYMENTTABLE,
 		TicketOptions:         ticketOptions,
+		EnvironmentCipher:     environmentCipher,
 		CloudformationClient:  cloudformationClient,
+		SecretClient:          secretClient,
 		S3Client:              s3Client,
 		CloudwatchClient:      cloudwatchClient,
+		TransferOptions:       pipeline.CreateTransferOptions(transferConcurrency),
 		CloudfrontClient:      cloudfrontClient,
 		CloudfrontCacheClient: cloudfrontCacheClient,
+		FunctionClient:        functionClient,
+		MetricClient:          metricClient,
 		DeploymentConfiguration: &eventcontext.DeploymentConfiguration{
 			ChangeSetTimeout:  changesetTimeout,
 			DeplyomentTimeout: deploymentTimeout,
 		},
+		BucketConfiguration: &eventcontext.BucketConfiguration{
+			ArtifactBucketName: ARTIFACT_BUCKET_NAME,
+		},
 		ProjectConfiguration: &eventcontext.ProjectConfiguration{
 			ServerNamePrefix:         SERVER_NAME_PREFIX,
 			ServerRuntime:            SERVER_RUNTIME,
@@ -118,7 +156,17 @@ func run() error {
 			CloudfrontDistributionId: CLOUDFRONT_DISTRIBUTION_ID,
 			CloudfrontCacheArn:       CLOUDFRONT_CACHE_ARN,
 		},
-	}))
+	}
+
+	// batch job events start a deployment, rollback events (emitted by the api) restore a retained deployment.
+	deployHandler := deployproject.HandleDeployProject(eventCtx)
+	rollbackHandler := deployproject.HandleRollbackProject(eventCtx)
+	lambda.Start(func(ctx context.Context, request events.CloudWatchEvent) error {
+		if request.DetailType == ROLLBACK_EVENT_ACTION {
+			return rollbackHandler(ctx, request)
+		}
+		return deployHandler(ctx, request)
+	})
 
 	return nil
 }
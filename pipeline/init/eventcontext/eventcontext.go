@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 )
 
@@ -38,9 +39,11 @@ type Context struct {
 	DynamoClient            *dynamodb.Client
 	UserTable               string
 	ProjectTable            string
+	TicketTable             string
 	SubscriptionTable       string
 	TicketOptions           *pipeline.TicketOptions
 	CloudformationClient    *cloudformation.Client
+	SecretClient            *secretsmanager.Client
 	DeploymentConfiguration *DeploymentConfiguration
 	BucketConfiguration     *BucketConfiguration
 	ProjectConfiguration    *ProjectConfiguration
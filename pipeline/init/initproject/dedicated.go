@@ -5,9 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -17,6 +17,7 @@ import (
 	"github.com/awslabs/goformation/v7/cloudformation/iam"
 	"github.com/awslabs/goformation/v7/cloudformation/logs"
 	"github.com/megakuul/battleshiper/lib/helper/database"
+	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 
 	"github.com/megakuul/battleshiper/lib/model/event"
 	"github.com/megakuul/battleshiper/lib/model/project"
@@ -31,9 +32,16 @@ func createStack(transportCtx context.Context, eventCtx eventcontext.Context, pr
 
 	projectDoc.DedicatedInfrastructure = generateDedicatedInfrastructure(eventCtx, projectDoc.ProjectName)
 
+	// the build environment secret must exist before the first build job, builds overwrite it with the project variables.
+	buildEnvironmentSecret, err := pipeline.WriteEnvironmentSecret(transportCtx, eventCtx.SecretClient,
+		pipeline.BuildEnvironmentSecretName(projectDoc.ProjectName), pipeline.CreateBuildScript(nil))
+	if err != nil {
+		return fmt.Errorf("failed to create build environment: %v", err)
+	}
+
 	stackBody := goformation.NewTemplate()
 	attachLogSystem(stackBody, eventCtx, projectDoc)
-	if err := attachBuildSystem(stackBody, eventCtx, projectDoc); err != nil {
+	if err := attachBuildSystem(stackBody, eventCtx, projectDoc, buildEnvironmentSecret.Arn); err != nil {
 		return fmt.Errorf("failed to serialize build system blueprint")
 	}
 
@@ -52,9 +60,11 @@ func createStack(transportCtx context.Context, eventCtx eventcontext.Context, pr
 		return fmt.Errorf("failed to create cloudformation stack: %v", err)
 	}
 
-	dedicatedInfrastructureAttributes, err := attributevalue.Marshal(&projectDoc.DedicatedInfrastructure)
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("DedicatedInfrastructure", &projectDoc.DedicatedInfrastructure).
+		Build()
 	if err != nil {
-		return fmt.Errorf("failed to serialize dedicated infrastructure")
+		return fmt.Errorf("failed to build update expression: %v", err)
 	}
 
 	_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
@@ -62,13 +72,9 @@ func createStack(transportCtx context.Context, eventCtx eventcontext.Context, pr
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 		},
-		AttributeNames: map[string]string{
-			"#dedicated_infrastructure": "dedicated_infrastructure",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":dedicated_infrastructure": dedicatedInfrastructureAttributes,
-		},
-		UpdateExpr: aws.String("SET #dedicated_infrastructure = :dedicated_infrastructure"),
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
 	})
 	if err != nil {
 		_, err := eventCtx.CloudformationClient.DeleteStack(transportCtx, &cloudformation.DeleteStackInput{
@@ -146,13 +152,8 @@ func attachLogSystem(stackTemplate *goformation.Template, eventCtx eventcontext.
 	}
 }
 
-type inputEnvironmentVariable struct {
-	Name  string `json:"Name"`
-	Value string `json:"Value"`
-}
-
 type inputContainerOverrides struct {
-	Environment []inputEnvironmentVariable `json:"Environment"`
+	Environment string `json:"Environment"`
 }
 
 type inputTransformTemplate struct {
@@ -161,7 +162,7 @@ type inputTransformTemplate struct {
 }
 
 // attachBuildSystem adds the project pipeline build system to the stack.
-func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontext.Context, projectDoc *project.Project) error {
+func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontext.Context, projectDoc *project.Project, environmentSecretArn string) error {
 	const BUILD_LOG_GROUP string = "BuildLogGroup"
 	stackTemplate.Resources[BUILD_LOG_GROUP] = &logs.LogGroup{
 		LogGroupName:    aws.String(projectDoc.DedicatedInfrastructure.BuildLogGroup),
@@ -203,6 +204,19 @@ func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontex
 					},
 				},
 			},
+			{
+				PolicyName: fmt.Sprintf("battleshiper-pipeline-build-env-%s-exec-access", projectDoc.ProjectName),
+				PolicyDocument: map[string]interface{}{
+					"Version": "2012-10-17",
+					"Statement": []map[string]interface{}{
+						{
+							"Effect":   "Allow",
+							"Action":   "secretsmanager:GetSecretValue",
+							"Resource": environmentSecretArn,
+						},
+					},
+				},
+			},
 		},
 	}
 
@@ -272,14 +286,24 @@ func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontex
 					Value: aws.String(projectDoc.SharedInfrastructure.BuildAssetBucketPath),
 				},
 			},
+			// the build environment secret is injected by batch and evaluated by the shell, project variables never leave secrets manager.
+			Secrets: []batch.JobDefinition_Secret{
+				{
+					Name:      pipeline.BUILD_ENVIRONMENT_VARIABLE,
+					ValueFrom: environmentSecretArn,
+				},
+			},
 			Command: []string{
 				"/bin/sh", "-c",
-				fmt.Sprintf("%s && %s && %s && %s && %s",
+				fmt.Sprintf("%s && %s && %s && %s && %s && %s && %s",
 					"echo \"START BUILD $EXECUTION_IDENTIFIER\"",
+					fmt.Sprintf("eval \"$%s\"", pipeline.BUILD_ENVIRONMENT_VARIABLE),
 					"mkdir -p out && cd out",
 					"git clone --branch $REPOSITORY_BRANCH $REPOSITORY_URL .",
 					"/bin/sh -c \"$BUILD_COMMAND\"",
 					"aws s3 cp $OUTPUT_DIRECTORY s3://$BUILD_ASSET_BUCKET_PATH/$EXECUTION_IDENTIFIER --recursive",
+					// the commit is recorded next to the build output, the deploy step stores it in the deployment history.
+					"git rev-parse HEAD | aws s3 cp - s3://$BUILD_ASSET_BUCKET_PATH/$EXECUTION_IDENTIFIER/commit",
 				),
 			},
 			NetworkConfiguration: &batch.JobDefinition_NetworkConfiguration{
@@ -330,10 +354,7 @@ func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontex
 	inputPathsMap := map[string]string{
 		"TMPL_EXECUTION_IDENTIFIER": "$.detail.execution_identifier",
 		"TMPL_DEPLOY_TICKET":        "$.detail.deploy_ticket",
-		"TMPL_REPOSITORY_URL":       "$.detail.repository_url",
-		"TMPL_REPOSITORY_BRANCH":    "$.detail.repository_branch",
-		"TMPL_BUILD_COMMAND":        "$.detail.build_command",
-		"TMPL_OUTPUT_DIRECTORY":     "$.detail.output_directory",
+		"TMPL_ENVIRONMENT":          "$.detail.environment",
 	}
 
 	inputTemplate := &inputTransformTemplate{
@@ -342,28 +363,7 @@ func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontex
 			ExecutionIdentifier: "<TMPL_EXECUTION_IDENTIFIER>",
 		},
 		ContainerOverrides: inputContainerOverrides{
-			Environment: []inputEnvironmentVariable{
-				{
-					Name:  "EXECUTION_IDENTIFIER",
-					Value: "<TMPL_EXECUTION_IDENTIFIER>",
-				},
-				{
-					Name:  "REPOSITORY_URL",
-					Value: "<TMPL_REPOSITORY_URL>",
-				},
-				{
-					Name:  "REPOSITORY_BRANCH",
-					Value: "<TMPL_REPOSITORY_BRANCH>",
-				},
-				{
-					Name:  "BUILD_COMMAND",
-					Value: "<TMPL_BUILD_COMMAND>",
-				},
-				{
-					Name:  "OUTPUT_DIRECTORY",
-					Value: "<TMPL_OUTPUT_DIRECTORY>",
-				},
-			},
+			Environment: "<TMPL_ENVIRONMENT>",
 		},
 	}
 	inputTemplateBuffer := bytes.Buffer{}
@@ -372,6 +372,9 @@ func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontex
 	if err := encoder.Encode(inputTemplate); err != nil {
 		return err
 	}
+	// the environment is inserted as json array (created by pipeline.CreateBuildEnvironment),
+	// eventbridge only inserts raw json values for placeholders that are not enclosed in quotes.
+	inputTemplateString := strings.Replace(inputTemplateBuffer.String(), `"<TMPL_ENVIRONMENT>"`, "<TMPL_ENVIRONMENT>", 1)
 
 	const BUILD_RULE string = "BuildRule"
 	stackTemplate.Resources[BUILD_RULE] = &events.Rule{
@@ -402,7 +405,7 @@ func attachBuildSystem(stackTemplate *goformation.Template, eventCtx eventcontex
 				},
 				InputTransformer: &events.Rule_InputTransformer{
 					InputPathsMap: inputPathsMap,
-					InputTemplate: inputTemplateBuffer.String(),
+					InputTemplate: inputTemplateString,
 				},
 			},
 		},
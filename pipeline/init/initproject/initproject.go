@@ -31,13 +31,13 @@ func HandleInitProject(eventCtx eventcontext.Context) func(context.Context, even
 	}
 }
 
-func runHandleInitProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) error {
+func runHandleInitProject(request events.CloudWatchEvent, transportCtx context.Context, eventCtx eventcontext.Context) (err error) {
 	initRequest := &event.InitRequest{}
 	if err := json.Unmarshal(request.Detail, &initRequest); err != nil {
 		return fmt.Errorf("failed to deserialize init request")
 	}
 
-	initClaims, err := pipeline.ParseTicket(eventCtx.TicketOptions, initRequest.InitTicket)
+	initClaims, err := pipeline.ParseTicket(transportCtx, eventCtx.TicketOptions, initRequest.InitTicket)
 	if err != nil {
 		return fmt.Errorf("failed to parse ticket: %v", err)
 	}
@@ -49,6 +49,20 @@ func runHandleInitProject(request events.CloudWatchEvent, transportCtx context.C
 		return fmt.Errorf("action mismatch: provided ticket was not issued for the specified action")
 	}
 
+	// tickets are single-use, replayed events are rejected before they touch any resources.
+	// A failed execution releases the ticket, so that retries of the event are accepted.
+	if err := pipeline.ClaimTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, initClaims); err != nil {
+		if errors.Is(err, pipeline.ErrTicketConsumed) {
+			return fmt.Errorf("ticket replay rejected: ticket of execution '%s' was already consumed", initClaims.ID)
+		}
+		return err
+	}
+	defer func() {
+		if fErr := pipeline.FinishTicket(transportCtx, eventCtx.DynamoClient, eventCtx.TicketTable, initClaims, err); fErr != nil {
+			logger.Printf("%v\n", fErr)
+		}
+	}()
+
 	projectDoc, err := database.GetSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.GetSingleInput{
 		Table: aws.String(eventCtx.ProjectTable),
 		AttributeValues: map[string]dynamodbtypes.AttributeValue{
@@ -69,20 +83,22 @@ func runHandleInitProject(request events.CloudWatchEvent, transportCtx context.C
 
 	err = initProject(transportCtx, eventCtx, projectDoc)
 	if err != nil {
+		updateExpression, bErr := database.NewUpdate[project.Project]().
+			Set("PipelineLock", false).
+			Set("Status", fmt.Sprintf("INITIALIZATION FAILED: %v", err)).
+			Build()
+		if bErr != nil {
+			return fmt.Errorf("failed to build update expression: %v", bErr)
+		}
+
 		_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
 			Table: aws.String(eventCtx.ProjectTable),
 			PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 				"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 			},
-			AttributeNames: map[string]string{
-				"#status":        "status",
-				"#pipeline_lock": "pipeline_lock",
-			},
-			AttributeValues: map[string]dynamodbtypes.AttributeValue{
-				":status":        &dynamodbtypes.AttributeValueMemberS{Value: fmt.Sprintf("INITIALIZATION FAILED: %v", err)},
-				":pipeline_lock": &dynamodbtypes.AttributeValueMemberBOOL{Value: false},
-			},
-			UpdateExpr: aws.String("SET #pipeline_lock = :pipeline_lock, #status = :status"),
+			AttributeNames:  updateExpression.AttributeNames,
+			AttributeValues: updateExpression.AttributeValues,
+			UpdateExpr:      updateExpression.UpdateExpr,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to update project: %v", err)
@@ -90,22 +106,23 @@ func runHandleInitProject(request events.CloudWatchEvent, transportCtx context.C
 		return nil
 	}
 
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("Initialized", true).
+		Set("PipelineLock", false).
+		Set("Status", "").
+		Build()
+	if err != nil {
+		return fmt.Errorf("failed to build update expression: %v", err)
+	}
+
 	_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
 		Table: aws.String(eventCtx.ProjectTable),
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 		},
-		AttributeNames: map[string]string{
-			"#initialized":   "initialized",
-			"#status":        "status",
-			"#pipeline_lock": "pipeline_lock",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":initialized":   &dynamodbtypes.AttributeValueMemberBOOL{Value: true},
-			":status":        &dynamodbtypes.AttributeValueMemberS{Value: ""},
-			":pipeline_lock": &dynamodbtypes.AttributeValueMemberBOOL{Value: false},
-		},
-		UpdateExpr: aws.String("SET #initialized = :initialized, #pipeline_lock = :pipeline_lock, #status = :status"),
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to update project: %v", err)
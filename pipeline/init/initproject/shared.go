@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/megakuul/battleshiper/lib/helper/database"
 	"github.com/megakuul/battleshiper/lib/model/project"
@@ -16,9 +15,11 @@ import (
 func initSharedInfrastructure(transportCtx context.Context, eventCtx eventcontext.Context, projectDoc *project.Project) error {
 	projectDoc.SharedInfrastructure = generateSharedInfrastructure(eventCtx, projectDoc.ProjectName)
 
-	sharedInfrastructureAttributes, err := attributevalue.Marshal(&projectDoc.SharedInfrastructure)
+	updateExpression, err := database.NewUpdate[project.Project]().
+		Set("SharedInfrastructure", &projectDoc.SharedInfrastructure).
+		Build()
 	if err != nil {
-		return fmt.Errorf("failed to serialize shared infrastructure")
+		return fmt.Errorf("failed to build update expression: %v", err)
 	}
 
 	_, err = database.UpdateSingle[project.Project](transportCtx, eventCtx.DynamoClient, &database.UpdateSingleInput{
@@ -26,13 +27,9 @@ func initSharedInfrastructure(transportCtx context.Context, eventCtx eventcontex
 		PrimaryKey: map[string]dynamodbtypes.AttributeValue{
 			"project_name": &dynamodbtypes.AttributeValueMemberS{Value: projectDoc.ProjectName},
 		},
-		AttributeNames: map[string]string{
-			"#shared_infrastructure": "shared_infrastructure",
-		},
-		AttributeValues: map[string]dynamodbtypes.AttributeValue{
-			":shared_infrastructure": sharedInfrastructureAttributes,
-		},
-		UpdateExpr: aws.String("SET #shared_infrastructure = :shared_infrastructure"),
+		AttributeNames:  updateExpression.AttributeNames,
+		AttributeValues: updateExpression.AttributeValues,
+		UpdateExpr:      updateExpression.UpdateExpr,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to update project: %v", err)
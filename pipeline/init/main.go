@@ -12,6 +12,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/megakuul/battleshiper/lib/helper/auth"
 	"github.com/megakuul/battleshiper/lib/helper/pipeline"
 	"github.com/megakuul/battleshiper/pipeline/init/eventcontext"
 	"github.com/megakuul/battleshiper/pipeline/init/initproject"
@@ -22,8 +24,9 @@ var (
 	BOOTSTRAP_TIMEOUT           = os.Getenv("BOOTSTRAP_TIMEOUT")
 	USERTABLE                   = os.Getenv("USERTABLE")
 	PROJECTTABLE                = os.Getenv("PROJECTTABLE")
+	TICKETTABLE                 = os.Getenv("TICKETTABLE")
 	SUBSCRIPTIONTABLE           = os.Getenv("SUBSCRIPTIONTABLE")
-	TICKET_CREDENTIAL_ARN       = os.Getenv("TICKET_CREDENTIAL_ARN")
+	TICKET_PUBLIC_KEY_ARN       = os.Getenv("TICKET_PUBLIC_KEY_ARN")
 	DEPLOYMENT_SERVICE_ROLE_ARN = os.Getenv("DEPLOYMENT_SERVICE_ROLE_ARN")
 	DEPLOYMENT_TIMEOUT          = os.Getenv("DEPLOYMENT_TIMEOUT")
 	STATIC_BUCKET_NAME          = os.Getenv("STATIC_BUCKET_NAME")
@@ -66,6 +69,8 @@ func run() error {
 
 	dynamoClient := dynamodb.NewFromConfig(awsConfig)
 
+	secretClient := secretsmanager.NewFromConfig(awsConfig)
+
 	deploymentTimeout, err := time.ParseDuration(DEPLOYMENT_TIMEOUT)
 	if err != nil {
 		return fmt.Errorf("failed to parse DEPLOYMENT_TIMEOUT environment variable")
@@ -81,18 +86,21 @@ func run() error {
 		return fmt.Errorf("failed to parse BUILD_JOB_TIMEOUT environment variable")
 	}
 
-	ticketOptions, err := pipeline.CreateTicketOptions(awsConfig, bootstrapContext, TICKET_CREDENTIAL_ARN, "", "", 0)
+	ticketVerifier, err := auth.CreateVerifier(awsConfig, bootstrapContext, TICKET_PUBLIC_KEY_ARN)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load ticket public keys: %v", err)
 	}
+	ticketOptions := pipeline.CreateTicketVerificationOptions(ticketVerifier)
 
 	lambda.Start(initproject.HandleInitProject(eventcontext.Context{
 		DynamoClient:         dynamoClient,
 		UserTable:            USERTABLE,
 		ProjectTable:         PROJECTTABLE,
+		TicketTable:          TICKETTABLE,
 		SubscriptionTable:    SUBSCRIPTIONTABLE,
 		TicketOptions:        ticketOptions,
 		CloudformationClient: cloudformationClient,
+		SecretClient:         secretClient,
 		DeploymentConfiguration: &eventcontext.DeploymentConfiguration{
 			ServiceRoleArn: DEPLOYMENT_SERVICE_ROLE_ARN,
 			Timeout:        deploymentTimeout,
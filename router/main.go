@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/megakuul/battleshiper/api/user/routecontext"
 	"github.com/megakuul/battleshiper/api/user/routerequest"
+	"github.com/megakuul/battleshiper/api/user/staticmanifest"
 )
 
 var (
@@ -21,6 +22,9 @@ var (
 	ERROR_PAGE         = os.Getenv("ERROR_PAGE")
 )
 
+// MANIFEST_CACHE_CAPACITY is the number of static version manifests kept in memory between invocations.
+const MANIFEST_CACHE_CAPACITY = 256
+
 func main() {
 	if err := run(); err != nil {
 		log.Printf("ERROR INITIALIZATION: %v\n", err)
@@ -41,6 +45,7 @@ func run() error {
 	lambda.Start(routerequest.HandleRouteRequest(routecontext.Context{
 		S3Client:         s3Client,
 		StaticBucketName: STATIC_BUCKET_NAME,
+		Manifests:        staticmanifest.NewCache(MANIFEST_CACHE_CAPACITY),
 		FunctionClient:   functionClient,
 		ServerNamePrefix: SERVER_NAME_PREFIX,
 		ErrorPage:        ERROR_PAGE,
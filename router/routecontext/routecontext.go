@@ -3,12 +3,14 @@ package routecontext
 import (
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/megakuul/battleshiper/api/user/staticmanifest"
 )
 
 // Context provides data to route handlers.
 type Context struct {
 	S3Client         *s3.Client
 	StaticBucketName string
+	Manifests        *staticmanifest.Cache
 	FunctionClient   *lambda.Client
 	ServerNamePrefix string
 	ErrorPage        string
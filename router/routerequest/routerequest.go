@@ -2,15 +2,19 @@ package routerequest
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -20,10 +24,22 @@ import (
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	"github.com/megakuul/battleshiper/api/user/routecontext"
+	"github.com/megakuul/battleshiper/api/user/staticmanifest"
 )
 
 var logger = log.New(os.Stderr, "ROUTEREQUEST: ", 0)
 
+// SERVER_ALIAS is the alias of the server function the deploy pipeline shifts the traffic on.
+const SERVER_ALIAS = "live"
+
+const (
+	// STATIC_INLINE_MAX_BYTES limits the size of static objects returned in the response body (base64 encoded),
+	// it keeps the response below the lambda response limit of 6MB. Larger objects are redirected to a presigned url.
+	STATIC_INLINE_MAX_BYTES = 4 * 1024 * 1024
+	// STATIC_PRESIGN_EXPIRATION is the validity of presigned urls to large static objects.
+	STATIC_PRESIGN_EXPIRATION = 15 * time.Minute
+)
+
 // HandleRouteRequest routes request either to s3 or to the corresponding server function.
 func HandleRouteRequest(routeCtx routecontext.Context) func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 	return func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
@@ -33,9 +49,29 @@ func HandleRouteRequest(routeCtx routecontext.Context) func(context.Context, eve
 
 func runHandleRouteRequest(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context) (events.APIGatewayV2HTTPResponse, error) {
 	project := request.Headers["battleshiper-project"]
+	// the live static version is resolved by the cdn function, projects without version use the unversioned layout.
+	staticVersion := request.Headers["battleshiper-static-version"]
+
+	// static objects are requested by the cdn cache function with the requested path of the object.
+	if staticPath, ok := request.Headers["battleshiper-static-path"]; ok {
+		response, code, err := proxyStaticObject(request, transportCtx, routeCtx, project, staticVersion, staticPath)
+		if err != nil {
+			// errors are not cached, the object may be released shortly after.
+			return events.APIGatewayV2HTTPResponse{
+				StatusCode: code,
+				Headers: map[string]string{
+					"Content-Type":  "text/plain",
+					"Cache-Control": "no-store",
+				},
+				Body: err.Error(),
+			}, nil
+		}
+		response.StatusCode = code
+		return *response, nil
+	}
 
 	if strings.HasSuffix(request.RawPath, ".html") && request.RequestContext.HTTP.Method == "GET" {
-		response, code, err := proxyStatic(request, transportCtx, routeCtx, project)
+		response, code, err := proxyStatic(request, transportCtx, routeCtx, project, staticVersion)
 		if err != nil {
 			return events.APIGatewayV2HTTPResponse{
 				StatusCode: http.StatusFound,
@@ -68,20 +104,12 @@ func runHandleRouteRequest(request events.APIGatewayV2HTTPRequest, transportCtx
 }
 
 // proxyStatic reads the requested path from the static s3 bucket and returns it as Content-Type text/html.
-func proxyStatic(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context, projectName string) (*events.APIGatewayV2HTTPResponse, int, error) {
-	objectOutput, err := routeCtx.S3Client.GetObject(transportCtx, &s3.GetObjectInput{
-		Bucket: aws.String(routeCtx.StaticBucketName),
-		Key:    aws.String(fmt.Sprintf("%s%s", projectName, request.RawPath)),
-	})
+func proxyStatic(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context, projectName, staticVersion string) (*events.APIGatewayV2HTTPResponse, int, error) {
+	objectOutput, _, code, err := loadStaticObject(transportCtx, routeCtx, projectName, staticVersion, strings.TrimPrefix(request.RawPath, "/"))
 	if err != nil {
-		var nsk *s3types.NoSuchKey
-		if errors.As(err, &nsk) {
-			return nil, http.StatusNotFound, fmt.Errorf("static asset not found")
-		} else {
-			logger.Printf("failed to load static asset: %v\n", err)
-			return nil, http.StatusInternalServerError, fmt.Errorf("failed to load static asset")
-		}
+		return nil, code, err
 	}
+	defer objectOutput.Body.Close()
 
 	body, err := io.ReadAll(objectOutput.Body)
 	if err != nil {
@@ -96,6 +124,95 @@ func proxyStatic(request events.APIGatewayV2HTTPRequest, transportCtx context.Co
 	}, http.StatusOK, nil
 }
 
+// proxyStaticObject reads the requested static object from the static s3 bucket.
+// The body is base64 encoded, objects exceeding the STATIC_INLINE_MAX_BYTES are redirected to a presigned url.
+func proxyStaticObject(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context, projectName, staticVersion, staticPath string) (*events.APIGatewayV2HTTPResponse, int, error) {
+	method := request.RequestContext.HTTP.Method
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed")
+	}
+	relativeKey, err := url.PathUnescape(strings.TrimPrefix(staticPath, "/"))
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("malformed static path")
+	}
+
+	objectOutput, key, code, err := loadStaticObject(transportCtx, routeCtx, projectName, staticVersion, relativeKey)
+	if err != nil {
+		return nil, code, err
+	}
+	defer objectOutput.Body.Close()
+
+	if aws.ToInt64(objectOutput.ContentLength) > STATIC_INLINE_MAX_BYTES {
+		presignedRequest, err := s3.NewPresignClient(routeCtx.S3Client).PresignGetObject(transportCtx, &s3.GetObjectInput{
+			Bucket: aws.String(routeCtx.StaticBucketName),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(STATIC_PRESIGN_EXPIRATION))
+		if err != nil {
+			logger.Printf("failed to presign static asset: %v\n", err)
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to load static asset")
+		}
+		// the redirect is not cached, the presigned url expires.
+		return &events.APIGatewayV2HTTPResponse{
+			Headers: map[string]string{
+				"Location":      presignedRequest.URL,
+				"Cache-Control": "no-store",
+			},
+		}, http.StatusTemporaryRedirect, nil
+	}
+
+	body, err := io.ReadAll(objectOutput.Body)
+	if err != nil {
+		logger.Printf("failed to read static asset data: %v\n", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to read static asset data")
+	}
+
+	contentType := aws.ToString(objectOutput.ContentType)
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(relativeKey))
+	}
+	headers := map[string]string{
+		"Content-Type": contentType,
+	}
+	if objectOutput.CacheControl != nil {
+		headers["Cache-Control"] = aws.ToString(objectOutput.CacheControl)
+	}
+	if objectOutput.ETag != nil {
+		headers["ETag"] = aws.ToString(objectOutput.ETag)
+	}
+	return &events.APIGatewayV2HTTPResponse{
+		Headers:         headers,
+		Body:            base64.StdEncoding.EncodeToString(body),
+		IsBase64Encoded: true,
+	}, http.StatusOK, nil
+}
+
+// loadStaticObject resolves the relative key in the static version of the project and opens the object.
+// The static bucket key of the object is returned with the object.
+func loadStaticObject(transportCtx context.Context, routeCtx routecontext.Context, projectName, staticVersion, relativeKey string) (*s3.GetObjectOutput, string, int, error) {
+	key, err := routeCtx.Manifests.ResolveKey(transportCtx, routeCtx.S3Client, routeCtx.StaticBucketName, projectName, staticVersion, relativeKey)
+	if err != nil {
+		if errors.Is(err, staticmanifest.ErrObjectNotFound) {
+			return nil, "", http.StatusNotFound, fmt.Errorf("static asset not found")
+		}
+		logger.Printf("failed to resolve static asset: %v\n", err)
+		return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to load static asset")
+	}
+
+	objectOutput, err := routeCtx.S3Client.GetObject(transportCtx, &s3.GetObjectInput{
+		Bucket: aws.String(routeCtx.StaticBucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var nsk *s3types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, "", http.StatusNotFound, fmt.Errorf("static asset not found")
+		}
+		logger.Printf("failed to load static asset: %v\n", err)
+		return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to load static asset")
+	}
+	return objectOutput, key, http.StatusOK, nil
+}
+
 // proxyServer invokes the origin server function (LambdaPrefix-ProjectName) and returns the server response.
 func proxyServer(request events.APIGatewayV2HTTPRequest, transportCtx context.Context, routeCtx routecontext.Context, projectName string) (*events.APIGatewayV2HTTPResponse, int, error) {
 	requestRaw, err := json.Marshal(request)
@@ -103,11 +220,21 @@ func proxyServer(request events.APIGatewayV2HTTPRequest, transportCtx context.Co
 		return nil, http.StatusBadRequest, fmt.Errorf("failed to serialize api request")
 	}
 
-	result, err := routeCtx.FunctionClient.Invoke(transportCtx, &lambda.InvokeInput{
+	invokeInput := &lambda.InvokeInput{
 		FunctionName:   aws.String(fmt.Sprintf("%s%s", routeCtx.ServerNamePrefix, projectName)),
+		Qualifier:      aws.String(SERVER_ALIAS),
 		Payload:        requestRaw,
 		InvocationType: lambdatypes.InvocationTypeRequestResponse,
-	})
+	}
+	result, err := routeCtx.FunctionClient.Invoke(transportCtx, invokeInput)
+	if err != nil {
+		// projects that were not released since server versions were introduced have no alias yet.
+		var rnfe *lambdatypes.ResourceNotFoundException
+		if ok := errors.As(err, &rnfe); ok {
+			invokeInput.Qualifier = nil
+			result, err = routeCtx.FunctionClient.Invoke(transportCtx, invokeInput)
+		}
+	}
 	if err != nil {
 		logger.Printf("failed to invoke origin server: %v", err)
 		return nil, http.StatusInternalServerError, fmt.Errorf("failed to invoke origin server")
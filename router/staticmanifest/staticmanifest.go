@@ -0,0 +1,92 @@
+package staticmanifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/megakuul/battleshiper/lib/model/project"
+)
+
+// ErrObjectNotFound is returned if the requested relative key is not part of the static version.
+var ErrObjectNotFound = errors.New("static object not found")
+
+// Cache holds the manifests of static versions. Versions are never modified after release,
+// therefore cached manifests never become stale. If the capacity is exceeded, the cache is cleared.
+type Cache struct {
+	mutex     sync.Mutex
+	capacity  int
+	manifests map[string]*project.StaticManifest
+}
+
+// NewCache creates a manifest cache holding up to capacity manifests.
+func NewCache(capacity int) *Cache {
+	return &Cache{
+		capacity:  capacity,
+		manifests: map[string]*project.StaticManifest{},
+	}
+}
+
+// ResolveKey returns the static bucket key holding the content of the relative key in the static version of the project.
+// Projects without static version use the unversioned layout, where objects are located directly below the project prefix.
+// Versions released before manifests were introduced contain all objects below their version prefix.
+func (c *Cache) ResolveKey(transportCtx context.Context, client *s3.Client, bucketName, projectName, version, relativeKey string) (string, error) {
+	if version == "" {
+		return fmt.Sprintf("%s/%s", projectName, relativeKey), nil
+	}
+	manifest, err := c.load(transportCtx, client, bucketName, projectName, version)
+	if err != nil {
+		return "", err
+	}
+	if manifest == nil {
+		return fmt.Sprintf("%s/%s/%s", projectName, version, relativeKey), nil
+	}
+	obj, ok := manifest.Objects[relativeKey]
+	if !ok {
+		return "", ErrObjectNotFound
+	}
+	return obj.Key, nil
+}
+
+// load returns the cached manifest of the static version or reads it from the static bucket.
+// Versions without manifest are cached with a nil manifest.
+func (c *Cache) load(transportCtx context.Context, client *s3.Client, bucketName, projectName, version string) (*project.StaticManifest, error) {
+	manifestKey := fmt.Sprintf("%s/%s%s", projectName, version, project.STATIC_MANIFEST_SUFFIX)
+
+	c.mutex.Lock()
+	manifest, ok := c.manifests[manifestKey]
+	c.mutex.Unlock()
+	if ok {
+		return manifest, nil
+	}
+
+	manifestOutput, err := client.GetObject(transportCtx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(manifestKey),
+	})
+	if err != nil {
+		var nsk *s3types.NoSuchKey
+		if !errors.As(err, &nsk) {
+			return nil, fmt.Errorf("failed to load manifest of static version %s: %v", version, err)
+		}
+	} else {
+		defer manifestOutput.Body.Close()
+		manifest = &project.StaticManifest{}
+		if err := json.NewDecoder(manifestOutput.Body).Decode(manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode manifest of static version %s: %v", version, err)
+		}
+	}
+
+	c.mutex.Lock()
+	if len(c.manifests) >= c.capacity {
+		c.manifests = map[string]*project.StaticManifest{}
+	}
+	c.manifests[manifestKey] = manifest
+	c.mutex.Unlock()
+	return manifest, nil
+}
@@ -0,0 +1,95 @@
+package staticmanifest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeBucket serves the manifests of the static bucket and counts the requests per path.
+type fakeBucket struct {
+	mutex    sync.Mutex
+	objects  map[string]string
+	requests map[string]int
+}
+
+func (f *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	f.requests[r.URL.Path]++
+	f.mutex.Unlock()
+
+	object, ok := f.objects[r.URL.Path]
+	if !ok {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		return
+	}
+	w.Write([]byte(object))
+}
+
+func TestResolveKey(t *testing.T) {
+	bucket := &fakeBucket{
+		objects: map[string]string{
+			"/static/project/v2.manifest.json": `{"objects":{"favicon.png":{"key":"project/v1/favicon.png"},"_app/x.js":{"key":"project/v2/_app/x.js"}}}`,
+		},
+		requests: map[string]int{},
+	}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+
+	tests := []struct {
+		name        string
+		version     string
+		relativeKey string
+		expected    string
+		expectedErr error
+	}{
+		{name: "unversioned layout", relativeKey: "favicon.png", expected: "project/favicon.png"},
+		{name: "unchanged object", version: "v2", relativeKey: "favicon.png", expected: "project/v1/favicon.png"},
+		{name: "changed object", version: "v2", relativeKey: "_app/x.js", expected: "project/v2/_app/x.js"},
+		{name: "missing object", version: "v2", relativeKey: "robots.txt", expectedErr: ErrObjectNotFound},
+		{name: "version without manifest", version: "v1", relativeKey: "favicon.png", expected: "project/v1/favicon.png"},
+	}
+
+	cache := NewCache(8)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := cache.ResolveKey(context.Background(), client, "static", "project", test.version, test.relativeKey)
+			if test.expectedErr != nil {
+				if !errors.Is(err, test.expectedErr) {
+					t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key != test.expected {
+				t.Errorf("expected key %s, got %s", test.expected, key)
+			}
+		})
+	}
+
+	for _, test := range tests {
+		cache.ResolveKey(context.Background(), client, "static", "project", test.version, test.relativeKey)
+	}
+	if count := bucket.requests["/static/project/v2.manifest.json"]; count != 1 {
+		t.Errorf("expected manifest to be loaded once, got %d requests", count)
+	}
+	if count := bucket.requests["/static/project/v1.manifest.json"]; count != 1 {
+		t.Errorf("expected missing manifest to be loaded once, got %d requests", count)
+	}
+}
@@ -3,6 +3,7 @@ import { AdapterError } from "../error";
 /**
  * @typedef {Object} deleteProjectInput
  * @property {string} project_name
+ * @property {number} [version]
  */
 
 /**
@@ -27,6 +28,6 @@ export const DeleteProject = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
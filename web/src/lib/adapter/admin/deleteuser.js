@@ -27,6 +27,6 @@ export const DeleteUser = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
@@ -0,0 +1,53 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} fetchUsageInput
+ * @property {string} user_id
+ */
+
+/**
+ * @typedef {Object} usageFigureOutput
+ * @property {number} used
+ * @property {number} limit
+ */
+
+/**
+ * @typedef {Object} projectUsageOutput
+ * @property {string} name
+ * @property {usageFigureOutput} aliases
+ * @property {usageFigureOutput} prerender_routes
+ * @property {usageFigureOutput} client_storage
+ * @property {usageFigureOutput} prerender_storage
+ * @property {usageFigureOutput} server_storage
+ */
+
+/**
+ * @typedef {Object} usageOutput
+ * @property {usageFigureOutput} projects
+ * @property {usageFigureOutput} builds
+ * @property {usageFigureOutput} deployments
+ * @property {projectUsageOutput[]} project_usage
+ */
+
+/**
+ * @typedef {Object} fetchUsageOutput
+ * @property {string} message
+ * @property {usageOutput} usage
+ */
+
+/**
+ * Fetches the resource usage of the specified user compared to its subscription limits.
+ * @param {fetchUsageInput} input
+ * @returns {Promise<fetchUsageOutput>}
+ * @throws {AdapterError}
+ */
+export const FetchUsage = async (input) => {
+  const res = await fetch(`/api/admin/usage?${new URLSearchParams(input).toString()}`, {
+    method: "GET",
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
\ No newline at end of file
@@ -19,6 +19,7 @@ import { AdapterError } from "../error";
  * @property {boolean} deleted
  * @property {boolean} initialized
  * @property {string} status
+ * @property {number} version
  * @property {Object.<string, Object>} aliases
  * @property {repositoryOutput} repository
  * @property {string} owner_id
@@ -28,21 +29,31 @@ import { AdapterError } from "../error";
  * @typedef {Object} findProjectOutput
  * @property {string} message
  * @property {projectOutput[]} projects
+ * @property {string} next_cursor
  */
 
 /**
- * Find the specified project on the database.
+ * Find the specified project on the database (all pages are fetched and merged into one output).
  * @param {findProjectInput} input
  * @returns {Promise<findProjectOutput>}
  * @throws {AdapterError}
  */
 export const FindProject = async (input) => {
-  const res = await fetch(`/api/admin/findproject?${new URLSearchParams(input).toString()}`, {
-    method: "GET",
-  })
-  if (res.ok) {
-    return await res.json();
-  } else {
-    throw new AdapterError(await res.text(), res.status);
-  }
+  /** @type {findProjectOutput} */
+  const output = { message: "", projects: [], next_cursor: "" };
+  let cursor = "";
+  do {
+    const res = await fetch(`/api/admin/findproject?${new URLSearchParams({ ...input, cursor }).toString()}`, {
+      method: "GET",
+    })
+    if (!res.ok) {
+      throw await AdapterError.fromResponse(res);
+    }
+    /** @type {findProjectOutput} */
+    const page = await res.json();
+    output.message = page.message;
+    output.projects.push(...page.projects);
+    cursor = page.next_cursor;
+  } while (cursor);
+  return output;
 }
\ No newline at end of file
@@ -12,6 +12,7 @@ import { AdapterError } from "../error";
  * @property {string} provider
  * @property {Object.<string, Object>} roles
  * @property {string} subscription_id
+ * @property {number} version
  */
 
 /**
@@ -33,6 +34,6 @@ export const FindUser = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
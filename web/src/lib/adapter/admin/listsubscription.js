@@ -14,6 +14,7 @@ import { AdapterError } from "../error";
  * @property {number} server_storage
  * @property {number} client_storage
  * @property {number} prerender_storage
+ * @property {number} deployment_retention
  */
 
 /**
@@ -21,6 +22,12 @@ import { AdapterError } from "../error";
  * @property {number} instance_count
  */
 
+/**
+ * @typedef {Object} quotaSpecOutput
+ * @property {number} limit
+ * @property {"daily"|"monthly"|"calendar_day"|"calendar_month"} window
+ */
+
 /**
  * @typedef {Object} subscriptionOutput
  * @property {string} id
@@ -28,26 +35,37 @@ import { AdapterError } from "../error";
  * @property {pipelineSpecsOutput} pipeline_specs
  * @property {projectSpecsOutput} project_specs
  * @property {cdnSpecsOutput} cdn_specs
+ * @property {Object.<string, quotaSpecOutput>} quotas
  */
 
 /**
  * @typedef {Object} listSubscriptionOutput
  * @property {string} message
  * @property {subscriptionOutput[]} subscriptions
+ * @property {string} next_cursor
  */
 
 /**
- * Lists all subscriptions that exist.
+ * Lists all subscriptions that exist (all pages are fetched and merged into one output).
  * @returns {Promise<listSubscriptionOutput>}
  * @throws {AdapterError}
  */
 export const ListSubscription = async () => {
-  const res = await fetch("/api/admin/listsubscription", {
-    method: "GET",
-  })
-  if (res.ok) {
-    return await res.json();
-  } else {
-    throw new AdapterError(await res.text(), res.status);
-  }
+  /** @type {listSubscriptionOutput} */
+  const output = { message: "", subscriptions: [], next_cursor: "" };
+  let cursor = "";
+  do {
+    const res = await fetch(`/api/admin/listsubscription?${new URLSearchParams({ cursor }).toString()}`, {
+      method: "GET",
+    })
+    if (!res.ok) {
+      throw await AdapterError.fromResponse(res);
+    }
+    /** @type {listSubscriptionOutput} */
+    const page = await res.json();
+    output.message = page.message;
+    output.subscriptions.push(...page.subscriptions);
+    cursor = page.next_cursor;
+  } while (cursor);
+  return output;
 }
\ No newline at end of file
@@ -0,0 +1,34 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} rotateTicketKeyInput
+ * @property {"EdDSA"|"RS256"} [algorithm]
+ */
+
+/**
+ * @typedef {Object} rotateTicketKeyOutput
+ * @property {string} message
+ * @property {string} kid
+ * @property {string} alg
+ */
+
+/**
+ * Rotates the pipeline ticket signing key.
+ * @param {rotateTicketKeyInput} input
+ * @returns {Promise<rotateTicketKeyOutput>}
+ * @throws {AdapterError}
+ */
+export const RotateTicketKey = async (input) => {
+  const res = await fetch("/api/admin/rotateticketkey", {
+    method: "POST",
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify(input),
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
\ No newline at end of file
@@ -9,6 +9,7 @@ import { AdapterError } from "../error";
  * @typedef {Object} updateUserInput
  * @property {string} user_id
  * @property {updateInput} update
+ * @property {number} [version]
  */
 
 /**
@@ -34,6 +35,6 @@ export const UpdateUser = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
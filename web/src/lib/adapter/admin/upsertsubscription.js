@@ -14,6 +14,7 @@ import { AdapterError } from "../error";
  * @property {number} server_storage
  * @property {number} client_storage
  * @property {number} prerender_storage
+ * @property {number} deployment_retention
  */
 
 /**
@@ -21,6 +22,12 @@ import { AdapterError } from "../error";
  * @property {number} instance_count
  */
 
+/**
+ * @typedef {Object} quotaSpecInput
+ * @property {number} limit
+ * @property {"daily"|"monthly"|"calendar_day"|"calendar_month"} window
+ */
+
 /**
  * @typedef {Object} upsertSubscriptionInput
  * @property {string} id
@@ -28,6 +35,7 @@ import { AdapterError } from "../error";
  * @property {pipelineSpecsInput} pipeline_specs
  * @property {projectSpecsInput} project_specs
  * @property {cdnSpecsInput} cdn_specs
+ * @property {Object.<string, quotaSpecInput>} quotas
  */
 
 /**
@@ -52,6 +60,6 @@ export const UpsertSubscription = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
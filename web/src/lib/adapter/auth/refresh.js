@@ -11,6 +11,6 @@ export const Refresh = async () => {
   if (res.ok) {
     return;
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
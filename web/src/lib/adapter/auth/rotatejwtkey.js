@@ -0,0 +1,34 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} rotateJwtKeyInput
+ * @property {"EdDSA"|"RS256"} [algorithm]
+ */
+
+/**
+ * @typedef {Object} rotateJwtKeyOutput
+ * @property {string} message
+ * @property {string} kid
+ * @property {string} alg
+ */
+
+/**
+ * Rotates the user token signing key.
+ * @param {rotateJwtKeyInput} input
+ * @returns {Promise<rotateJwtKeyOutput>}
+ * @throws {AdapterError}
+ */
+export const RotateJwtKey = async (input) => {
+  const res = await fetch("/api/auth/rotatejwtkey", {
+    method: "POST",
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify(input),
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
\ No newline at end of file
@@ -2,9 +2,29 @@ export class AdapterError extends Error {
   /** 
    * @param {string} message
    * @param {number} statusCode 
+   * @param {string} [requestId]
    */
-  constructor(message, statusCode) {
+  constructor(message, statusCode, requestId) {
     super(message);
     this.statusCode = statusCode;
+    this.requestId = requestId;
   }
-}
\ No newline at end of file
+
+  /**
+   * Creates an AdapterError from a failed api response.
+   * The api returns errors as json object ({code, message, request_id}),
+   * other responses (e.g. from the gateway) are taken as plain text.
+   * @param {Response} res
+   * @returns {Promise<AdapterError>}
+   */
+  static async fromResponse(res) {
+    const body = await res.text();
+    try {
+      const error = JSON.parse(body);
+      if (typeof error.message === "string") {
+        return new AdapterError(error.message, error.code ?? res.status, error.request_id);
+      }
+    } catch {}
+    return new AdapterError(body, res.status);
+  }
+}
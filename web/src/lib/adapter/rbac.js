@@ -6,6 +6,6 @@
 
 /**
  * @typedef {(
- * "READ_USER" | "READ_PROJECT" | "READ_LOGS" | "READ_SUBSCRIPTION" | "WRITE_USER" | "WRITE_PROJECT" | "WRITE_SUBSCRIPTION" | "WRITE_ROLE"
+ * "READ_USER" | "READ_PROJECT" | "READ_LOGS" | "READ_SUBSCRIPTION" | "WRITE_USER" | "WRITE_PROJECT" | "WRITE_SUBSCRIPTION" | "WRITE_ROLE" | "WRITE_CREDENTIAL"
  * )} ACCESS
  */
\ No newline at end of file
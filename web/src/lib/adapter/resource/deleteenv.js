@@ -0,0 +1,35 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} deleteEnvInput
+ * @property {string} project_name
+ * @property {string} name
+ * @property {"build"|"runtime"} scope
+ * @property {number} [version]
+ */
+
+/**
+ * @typedef {Object} deleteEnvOutput
+ * @property {string} message
+ */
+
+/**
+ * Deletes an environment variable of the project.
+ * @param {deleteEnvInput} input
+ * @returns {Promise<deleteEnvOutput>}
+ * @throws {AdapterError}
+ */
+export const DeleteEnv = async (input) => {
+  const res = await fetch("/api/resource/project/env", {
+    method: "DELETE",
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify(input),
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
\ No newline at end of file
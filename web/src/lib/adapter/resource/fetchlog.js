@@ -8,12 +8,19 @@ import { AdapterError } from "../error";
  * @property {number} end_time
  * @property {number} count
  * @property {boolean} filter_lambda
+ * @property {string} [filter] structured event filter (e.g. "phase=deploy level=error duration_ms>1000")
  */
 
 /**
  * @typedef {Object} eventOutput
  * @property {number} timestamp
  * @property {string} message
+ * @property {string} [execution_identifier]
+ * @property {string} [phase]
+ * @property {string} [step]
+ * @property {string} [level]
+ * @property {number} [duration_ms]
+ * @property {string} [error]
  */
 
 /**
@@ -39,6 +46,6 @@ export const FetchLog = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
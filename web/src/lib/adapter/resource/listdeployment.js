@@ -0,0 +1,38 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} listDeploymentInput
+ * @property {string} project_name
+ */
+
+/**
+ * @typedef {Object} deploymentOutput
+ * @property {string} execution_identifier
+ * @property {number} timestamp
+ * @property {string} commit
+ * @property {number} page_count
+ * @property {boolean} active
+ */
+
+/**
+ * @typedef {Object} listDeploymentOutput
+ * @property {string} message
+ * @property {deploymentOutput[]} deployments
+ */
+
+/**
+ * Fetches the retained deployments of the project (newest deployment first).
+ * @param {listDeploymentInput} input
+ * @returns {Promise<listDeploymentOutput>}
+ * @throws {AdapterError}
+ */
+export const ListDeployment = async (input) => {
+  const res = await fetch(`/api/resource/listdeployment?${new URLSearchParams({ ...input }).toString()}`, {
+    method: "GET",
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
@@ -0,0 +1,38 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} listEnvInput
+ * @property {string} project_name
+ */
+
+/**
+ * @typedef {Object} variableOutput
+ * @property {string} name
+ * @property {string} scope
+ * @property {string} value
+ * @property {boolean} secret
+ */
+
+/**
+ * @typedef {Object} listEnvOutput
+ * @property {string} message
+ * @property {variableOutput[]} variables
+ * @property {number} version
+ */
+
+/**
+ * Fetches the environment variables of the project (values of secret variables are empty).
+ * @param {listEnvInput} input
+ * @returns {Promise<listEnvOutput>}
+ * @throws {AdapterError}
+ */
+export const ListEnv = async (input) => {
+  const res = await fetch(`/api/resource/project/env?${new URLSearchParams({ ...input }).toString()}`, {
+    method: "GET",
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
\ No newline at end of file
@@ -28,12 +28,26 @@ import { AdapterError } from "../error";
  * @property {string} branch
  */
 
+/**
+ * @typedef {Object} trafficShiftOutput
+ * @property {string} strategy
+ * @property {number} percentage
+ * @property {number} interval
+ * @property {number} error_threshold
+ */
+
+/**
+ * @typedef {Object} usageOutput
+ * @property {number} prerender_routes
+ */
+
 /**
  * @typedef {Object} projectOutput
  * @property {string} name
  * @property {boolean} deleted
  * @property {boolean} initialized
  * @property {string} status
+ * @property {number} version
  * @property {string} build_image
  * @property {string} build_command
  * @property {string} output_directory
@@ -42,26 +56,39 @@ import { AdapterError } from "../error";
  * @property {eventResultOutput} last_event_result
  * @property {buildResultOutput} last_build_result
  * @property {deploymentResultOutput} last_deployment_result
+ * @property {string} active_deployment
+ * @property {trafficShiftOutput} traffic_shift
+ * @property {usageOutput} usage
  */
 
 /**
  * @typedef {Object} listProjectOutput
  * @property {string} message
  * @property {projectOutput[]} projects
+ * @property {string} next_cursor
  */
 
 /**
- * Fetches all projects.
+ * Fetches all projects (all pages are fetched and merged into one output).
  * @returns {Promise<listProjectOutput>}
  * @throws {AdapterError}
  */
 export const ListProject = async () => {
-  const res = await fetch("/api/resource/listproject", {
-    method: "GET",
-  })
-  if (res.ok) {
-    return await res.json();
-  } else {
-    throw new AdapterError(await res.text(), res.status);
-  }
+  /** @type {listProjectOutput} */
+  const output = { message: "", projects: [], next_cursor: "" };
+  let cursor = "";
+  do {
+    const res = await fetch(`/api/resource/listproject?${new URLSearchParams({ cursor }).toString()}`, {
+      method: "GET",
+    })
+    if (!res.ok) {
+      throw await AdapterError.fromResponse(res);
+    }
+    /** @type {listProjectOutput} */
+    const page = await res.json();
+    output.message = page.message;
+    output.projects.push(...page.projects);
+    cursor = page.next_cursor;
+  } while (cursor);
+  return output;
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} rollbackProjectInput
+ * @property {string} project_name
+ * @property {string} execution_identifier
+ */
+
+/**
+ * @typedef {Object} rollbackProjectOutput
+ * @property {string} message
+ */
+
+/**
+ * Rolls the project back to a retained deployment.
+ * @param {rollbackProjectInput} input
+ * @returns {Promise<rollbackProjectOutput>}
+ * @throws {AdapterError}
+ */
+export const RollbackProject = async (input) => {
+  const res = await fetch("/api/resource/rollbackproject", {
+    method: "POST",
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify(input),
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
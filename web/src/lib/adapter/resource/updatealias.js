@@ -4,6 +4,7 @@ import { AdapterError } from "../error";
  * @typedef {Object} updateAliasInput
  * @property {string} project_name
  * @property {Object.<string, null>} aliases
+ * @property {number} [version]
  */
 
 /**
@@ -28,6 +29,6 @@ export const UpdateAlias = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
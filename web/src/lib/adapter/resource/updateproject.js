@@ -7,12 +7,22 @@ import { AdapterError } from "../error";
  * @property {string} branch
  */
 
+/**
+ * @typedef {Object} trafficShiftInput
+ * @property {"all_at_once"|"canary"|"linear"} strategy
+ * @property {number} percentage
+ * @property {number} interval
+ * @property {number} error_threshold
+ */
+
 /**
  * @typedef {Object} updateProjectInput
  * @property {string} project_name
  * @property {string} build_command
  * @property {string} output_directory
  * @property {repositoryInput} repository
+ * @property {trafficShiftInput} [traffic_shift]
+ * @property {number} [version]
  */
 
 /**
@@ -38,6 +48,6 @@ export const UpdateProject = async (input) => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
@@ -0,0 +1,37 @@
+import { AdapterError } from "../error";
+
+/**
+ * @typedef {Object} upsertEnvInput
+ * @property {string} project_name
+ * @property {string} name
+ * @property {string} value
+ * @property {boolean} secret
+ * @property {"build"|"runtime"} scope
+ * @property {number} [version]
+ */
+
+/**
+ * @typedef {Object} upsertEnvOutput
+ * @property {string} message
+ */
+
+/**
+ * Creates or replaces an environment variable of the project.
+ * @param {upsertEnvInput} input
+ * @returns {Promise<upsertEnvOutput>}
+ * @throws {AdapterError}
+ */
+export const UpsertEnv = async (input) => {
+  const res = await fetch("/api/resource/project/env", {
+    method: "PUT",
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify(input),
+  })
+  if (res.ok) {
+    return await res.json();
+  } else {
+    throw await AdapterError.fromResponse(res);
+  }
+}
\ No newline at end of file
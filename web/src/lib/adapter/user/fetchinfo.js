@@ -14,6 +14,7 @@ import { AdapterError } from "../error";
  * @property {number} server_storage
  * @property {number} client_storage
  * @property {number} prerender_storage
+ * @property {number} deployment_retention
  */
 
 /**
@@ -21,6 +22,11 @@ import { AdapterError } from "../error";
  * @property {number} instance_count
  */
 
+/**
+ * @typedef {Object} remainingOutput
+ * @property {number} daily_deployments
+ */
+
 /**
  * @typedef {Object} subscriptionOutput
  * @property {string} id
@@ -28,6 +34,7 @@ import { AdapterError } from "../error";
  * @property {pipelineSpecsOutput} pipeline_specs
  * @property {projectSpecsOutput} project_specs
  * @property {cdnSpecsOutput} cdn_specs
+ * @property {remainingOutput} remaining
  */
 
 /**
@@ -52,6 +59,6 @@ export const FetchInfo = async () => {
   if (res.ok) {
     return await res.json();
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file
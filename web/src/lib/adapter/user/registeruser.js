@@ -14,6 +14,6 @@ export const RegisterUser = async () => {
   } else if (res.status === 401) {
     Authorize()
   } else {
-    throw new AdapterError(await res.text(), res.status);
+    throw await AdapterError.fromResponse(res);
   }
 }
\ No newline at end of file